@@ -120,12 +120,21 @@ func DefaultConfig() SsmagentConfig {
 	var agent = AgentInfo{
 		Name:                 "amazon-ssm-agent",
 		OrchestrationRootDir: defaultOrchestrationRootDirName,
+		ChannelType:          ChannelTypeFile,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
 		Version: "1",
 	}
 	var birdwatcher BirdwatcherCfg
+	var session = SessionCfg{
+		IdleSessionTimeoutMinutes: DefaultIdleSessionTimeoutMinutes,
+	}
+	var reboot = RebootCfg{
+		WindowDurationMinutes: DefaultRebootWindowDurationMinutes,
+		MaxDeferralMinutes:    DefaultRebootMaxDeferralMinutes,
+		HookTimeoutSeconds:    DefaultPreRebootHookTimeoutSeconds,
+	}
 
 	var ssmagentCfg = SsmagentConfig{
 		Profile:     credsProfile,
@@ -135,6 +144,8 @@ func DefaultConfig() SsmagentConfig {
 		Os:          os,
 		S3:          s3,
 		Birdwatcher: birdwatcher,
+		Session:     session,
+		Reboot:      reboot,
 	}
 
 	return ssmagentCfg
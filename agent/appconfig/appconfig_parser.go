@@ -28,6 +28,9 @@ func parser(config *SsmagentConfig) {
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	if config.Agent.ChannelType != ChannelTypeSocket {
+		config.Agent.ChannelType = ChannelTypeFile
+	}
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -68,6 +71,33 @@ func parser(config *SsmagentConfig) {
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
 
+	// Resource limits config, invalid limits are ignored
+	config.ResourceLimits.CPUQuotaPercent = getNumericValueAboveMin(config.ResourceLimits.CPUQuotaPercent, 0, 0)
+	config.ResourceLimits.MemoryLimitMB = getNumericValueAboveMin(config.ResourceLimits.MemoryLimitMB, 0, 0)
+	config.ResourceLimits.IOWeight = getNumericValue(config.ResourceLimits.IOWeight, 0, ResourceLimitsIOWeightMax, 0)
+
+	// Session config
+	config.Session.IdleSessionTimeoutMinutes = getNumericValue(
+		config.Session.IdleSessionTimeoutMinutes,
+		IdleSessionTimeoutMinutesMin,
+		IdleSessionTimeoutMinutesMax,
+		DefaultIdleSessionTimeoutMinutes)
+
+	// Reboot config
+	config.Reboot.WindowDurationMinutes = getNumericValue(
+		config.Reboot.WindowDurationMinutes,
+		RebootWindowDurationMinutesMin,
+		RebootWindowDurationMinutesMax,
+		DefaultRebootWindowDurationMinutes)
+	config.Reboot.MaxDeferralMinutes = getNumericValue(
+		config.Reboot.MaxDeferralMinutes,
+		0,
+		RebootMaxDeferralMinutesMax,
+		DefaultRebootMaxDeferralMinutes)
+	config.Reboot.HookTimeoutSeconds = getNumericValueAboveMin(
+		config.Reboot.HookTimeoutSeconds,
+		1,
+		DefaultPreRebootHookTimeoutSeconds)
 }
 
 // TODO https://sim.amazon.com/issues/SSM-3439
@@ -31,6 +31,10 @@ const (
 	DefaultStopTimeoutMillisMin = 10000
 	DefaultStopTimeoutMillisMax = 1000000
 
+	// DefaultParallelGroupMaxConcurrency is the number of steps of a parallel group running at the same time
+	// when none of the steps of the group sets maxConcurrency
+	DefaultParallelGroupMaxConcurrency = 4
+
 	// SSM defaults
 	DefaultSsmHealthFrequencyMinutes    = 5
 	DefaultSsmHealthFrequencyMinutesMin = 5
@@ -47,12 +51,37 @@ const (
 	DefaultLocationOfCorrupt     = "corrupt"
 	DefaultLocationOfState       = "state"
 	DefaultLocationOfAssociation = "association"
+	DefaultLocationOfHistory     = "history"
 
 	//aws-ssm-agent state and orchestration logs duration for Run Command and Association
 	DefaultAssociationLogsRetentionDurationHours           = 24  // 1 day default retention
 	DefaultRunCommandLogsRetentionDurationHours            = 336 // 14 days default retention
 	DefaultStateOrchestrationLogsRetentionDurationHoursMin = 8   // Min retention of 8hrs as some processes may not timeout before this and don't want logs to be deleted before the process completes
 
+	//aws-ssm-agent local history of the executed documents, the records of each document type are bounded by this limit
+	//on top of the Run Command and Association logs retention durations
+	DefaultHistoryRecordsLimit = 1000
+
+	//aws-ssm-agent transports of the messages between the agent and the document workers
+	ChannelTypeFile   = "file"
+	ChannelTypeSocket = "socket"
+
+	//aws-ssm-agent resource limits of the document worker, the io weight follows the cgroup v2 range 1-10000
+	ResourceLimitsIOWeightMax = 10000
+
+	//aws-ssm-agent idle timeout of the interactive sessions
+	DefaultIdleSessionTimeoutMinutes = 20
+	IdleSessionTimeoutMinutesMin     = 1
+	IdleSessionTimeoutMinutesMax     = 60
+
+	//aws-ssm-agent coordination of the reboots, a reboot waits for the other documents and the reboot window up to a day by default
+	DefaultRebootWindowDurationMinutes = 60
+	RebootWindowDurationMinutesMin     = 1
+	RebootWindowDurationMinutesMax     = 1440
+	DefaultRebootMaxDeferralMinutes    = 1440
+	RebootMaxDeferralMinutesMax        = 43200
+	DefaultPreRebootHookTimeoutSeconds = 300
+
 	//aws-ssm-agent bookkeeping constants for long running plugins
 	LongRunningPluginsLocation         = "longrunningplugins"
 	LongRunningPluginsHealthCheck      = "healthcheck"
@@ -70,6 +99,11 @@ const (
 	//aws-ssm-agent bookkeeping constants for failed sent replies
 	RepliesRootDirName = "replies"
 
+	//aws-ssm-agent bookkeeping constants for the pending reboot, and the directory of the pre-reboot hooks in the program folder
+	RebootRootDirName     = "reboot"
+	PendingRebootFileName = "pending"
+	PreRebootHooksDirName = "prereboot.d"
+
 	//aws-ssm-agent bookkeeping constants for compliance
 	ComplianceRootDirName         = "compliance"
 	ComplianceContentHashFileName = "contentHash"
@@ -99,6 +133,12 @@ const (
 	SuccessExitCode = 0
 	ErrorExitCode   = 1
 
+	// RebootExitCode is returned by a step that completed and needs a reboot, the step does not run again after the reboot
+	RebootExitCode = 3010
+
+	// RebootAndRerunExitCode is returned by a step that needs a reboot and runs again after the reboot
+	RebootAndRerunExitCode = 194
+
 	// DefaultPluginConfig is a default config with which the plugins are initialized
 	DefaultPluginConfig = "aws:defaultPluginConfig"
 
@@ -128,6 +168,9 @@ const (
 	// PluginRunDocument is the name of the run document plugin
 	PluginRunDocument = "aws:runDocument"
 
+	// PluginNameAwsBranch is the name of the branch step, which is run by the document engine itself
+	PluginNameAwsBranch = "aws:branch"
+
 	// PluginNameAwsSoftwareInventory is the name for inventory plugin
 	PluginNameAwsSoftwareInventory = "aws:softwareInventory"
 
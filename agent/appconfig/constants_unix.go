@@ -44,6 +44,14 @@ const (
 	// are moved if the service cannot validate the document (generally impossible via cli)
 	LocalCommandRootInvalid = "/var/lib/amazon/ssm/localcommands/invalid"
 
+	// LocalCommandRootCancel is the directory where users can submit requests to cancel
+	// locally submitted commands
+	LocalCommandRootCancel = "/var/lib/amazon/ssm/localcommands/cancel"
+
+	// LocalAssociationRoot specifies the directory of the association definition files scheduled by the agent
+	// without the Systems Manager service
+	LocalAssociationRoot = "/var/lib/amazon/ssm/localassociations"
+
 	// DownloadRoot specifies the directory under which files will be downloaded
 	DownloadRoot = "/var/log/amazon/ssm/download/"
 
@@ -67,9 +75,6 @@ const (
 
 	// List all plugin names, unfortunately golang doesn't support const arrays of strings
 
-	// RebootExitCode that would trigger a Soft Reboot
-	RebootExitCode = 194
-
 	// Default Custom Inventory Inventory Folder
 	DefaultCustomInventoryFolder = DefaultDataStorePath + "inventory/custom"
 
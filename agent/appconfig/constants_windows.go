@@ -37,9 +37,6 @@ const (
 	// ManifestCacheFolder path under local app data
 	ManifestCacheFolder = "Amazon\\SSM\\Manifests"
 
-	// Exit Code that would trigger a Soft Reboot
-	RebootExitCode = 3010
-
 	// PackagePlatform is the platform name to use when looking for packages
 	PackagePlatform = "windows"
 
@@ -102,6 +99,14 @@ var LocalCommandRootCompleted string
 // are moved if the service cannot validate the document (generally impossible via cli)
 var LocalCommandRootInvalid string
 
+// LocalCommandRootCancel is the directory where users can submit requests to cancel
+// locally submitted commands
+var LocalCommandRootCancel string
+
+// LocalAssociationRoot specifies the directory of the association definition files scheduled by the agent
+// without the Systems Manager service
+var LocalAssociationRoot string
+
 // DefaultPluginPath represents the directory for storing plugins in SSM
 var DefaultPluginPath string
 
@@ -175,6 +180,8 @@ func init() {
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
 	LocalCommandRootCompleted = filepath.Join(LocalCommandRoot, "Completed")
 	LocalCommandRootInvalid = filepath.Join(LocalCommandRoot, "Invalid")
+	LocalCommandRootCancel = filepath.Join(LocalCommandRoot, "Cancel")
+	LocalAssociationRoot = filepath.Join(SSMDataPath, "LocalAssociations")
 	DownloadRoot = filepath.Join(temp, SSMFolder, "Download")
 	UpdaterArtifactsRoot = filepath.Join(temp, SSMFolder, "Update")
 	EC2UpdateArtifactsRoot = filepath.Join(EnvWinDir, EC2ConfigServiceFolder, "Update")
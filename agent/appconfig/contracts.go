@@ -51,6 +51,8 @@ type AgentInfo struct {
 	Region               string
 	OrchestrationRootDir string
 	DownloadRootDir      string
+	// ChannelType is the transport of the messages between the agent and the document workers, file or socket
+	ChannelType string
 }
 
 // MfsCfg represents configuration for HummingBird service (MFS)
@@ -75,6 +77,37 @@ type S3Cfg struct {
 	LogKey    string
 }
 
+// SecretsCfg represents configuration for the secret providers resolving document parameters
+type SecretsCfg struct {
+	VaultAddress   string
+	VaultTokenPath string
+}
+
+// ResourceLimitsCfg represents the default resource limits of the processes running documents, zero means unlimited
+type ResourceLimitsCfg struct {
+	CPUQuotaPercent int
+	MemoryLimitMB   int
+	IOWeight        int
+}
+
+// SessionCfg represents configuration for the interactive sessions opened over the HummingBird channel
+type SessionCfg struct {
+	// RunAsUser is the name of the user the sessions run as, the sessions run as the agent user if empty
+	RunAsUser                 string
+	IdleSessionTimeoutMinutes int
+}
+
+// RebootCfg represents configuration for the coordination of the reboots requested by the documents
+type RebootCfg struct {
+	// WindowExpression is the cron expression of the starts of the windows allowing reboots, reboots are allowed any time if empty
+	WindowExpression      string
+	WindowDurationMinutes int
+	// MaxDeferralMinutes bounds the wait for the other documents and the reboot window, zero reboots right away
+	MaxDeferralMinutes int
+	// HookTimeoutSeconds is the timeout of each of the pre-reboot hooks
+	HookTimeoutSeconds int
+}
+
 // BirdwatcherCfg represents configuration related to ConfigurePackage Birdwatcher integration
 type BirdwatcherCfg struct {
 	ForceEnable bool
@@ -82,12 +115,16 @@ type BirdwatcherCfg struct {
 
 // SsmagentConfig stores agent configuration values.
 type SsmagentConfig struct {
-	Profile     CredentialProfile
-	Mds         MdsCfg
-	Ssm         SsmCfg
-	Mfs         MfsCfg
-	Agent       AgentInfo
-	Os          OsInfo
-	S3          S3Cfg
-	Birdwatcher BirdwatcherCfg
+	Profile        CredentialProfile
+	Mds            MdsCfg
+	Ssm            SsmCfg
+	Mfs            MfsCfg
+	Agent          AgentInfo
+	Os             OsInfo
+	S3             S3Cfg
+	Birdwatcher    BirdwatcherCfg
+	Secrets        SecretsCfg
+	ResourceLimits ResourceLimitsCfg
+	Session        SessionCfg
+	Reboot         RebootCfg
 }
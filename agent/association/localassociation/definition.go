@@ -0,0 +1,196 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localassociation schedules the associations defined in local files, without the Systems Manager service
+package localassociation
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// validAssociationID matches the association IDs that can be used in file names
+var validAssociationID = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Definition is the content of an association definition file, in JSON or YAML.
+// The document is either inline in DocumentContent or at DocumentPath, relative to the definition file unless absolute.
+// The association ID and the document name default to the name of the definition file.
+type Definition struct {
+	AssociationId      string                     `json:"AssociationId,omitempty"`
+	Name               string                     `json:"Name,omitempty"`
+	DocumentPath       string                     `json:"DocumentPath,omitempty"`
+	DocumentContent    *contracts.DocumentContent `json:"DocumentContent,omitempty"`
+	Parameters         map[string]interface{}     `json:"Parameters,omitempty"`
+	ScheduleExpression string                     `json:"ScheduleExpression,omitempty"`
+	OutputS3BucketName string                     `json:"OutputS3BucketName,omitempty"`
+	OutputS3KeyPrefix  string                     `json:"OutputS3KeyPrefix,omitempty"`
+}
+
+// LoadAssociations returns the associations defined in the files of the directory, the invalid definitions are skipped
+func LoadAssociations(log log.T, dir string, instanceID string) []*model.InstanceAssociation {
+	associations := []*model.InstanceAssociation{}
+	if !fileutil.Exists(dir) {
+		return associations
+	}
+	fileNames, err := fileutil.GetFileNames(dir)
+	if err != nil {
+		log.Errorf("Unable to read local association definitions in %v, %v", dir, err)
+		return associations
+	}
+
+	associationIDs := make(map[string]string)
+	for _, fileName := range fileNames {
+		filePath := filepath.Join(dir, fileName)
+		assoc, err := newAssociation(filePath, instanceID)
+		if err != nil {
+			log.Errorf("Skipping local association definition %v, %v", filePath, err)
+			continue
+		}
+		associationID := *assoc.Association.AssociationId
+		if other, exists := associationIDs[associationID]; exists {
+			log.Errorf("Skipping local association definition %v, association ID %v is already defined by %v", filePath, associationID, other)
+			continue
+		}
+		associationIDs[associationID] = filePath
+		applyStatus(log, assoc, instanceID)
+		associations = append(associations, assoc)
+	}
+	log.Debugf("Number of local associations is %v", len(associations))
+	return associations
+}
+
+// newAssociation builds the association of the definition file
+func newAssociation(filePath string, instanceID string) (*model.InstanceAssociation, error) {
+	var definition Definition
+	if err := docparser.UnmarshalFile(filePath, &definition); err != nil {
+		return nil, err
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	associationID := definition.AssociationId
+	if associationID == "" {
+		associationID = baseName
+	}
+	if !validAssociationID.MatchString(associationID) {
+		return nil, fmt.Errorf("association ID %v can only contain letters, digits, '_', '-' and '.'", associationID)
+	}
+
+	var content contracts.DocumentContent
+	documentName := definition.Name
+	if definition.DocumentContent != nil {
+		content = *definition.DocumentContent
+	} else if definition.DocumentPath != "" {
+		documentPath := definition.DocumentPath
+		if !filepath.IsAbs(documentPath) {
+			documentPath = filepath.Join(filepath.Dir(filePath), documentPath)
+		}
+		var err error
+		if content, err = docparser.LoadDocumentContent(documentPath); err != nil {
+			return nil, fmt.Errorf("failed to load document %v, %v", documentPath, err)
+		}
+		if documentName == "" {
+			documentName = strings.TrimSuffix(filepath.Base(documentPath), filepath.Ext(documentPath))
+		}
+	} else {
+		return nil, fmt.Errorf("DocumentContent or DocumentPath is required")
+	}
+	if documentName == "" {
+		documentName = baseName
+	}
+
+	document, err := jsonutil.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := newParameters(definition.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	assoc := &model.InstanceAssociation{
+		CreateDate: time.Now().UTC(),
+		Document:   aws.String(document),
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId:   aws.String(associationID),
+			Name:            aws.String(documentName),
+			DocumentVersion: aws.String(""),
+			InstanceId:      aws.String(instanceID),
+			Checksum:        aws.String(""),
+			Parameters:      parameters,
+			DetailedStatus:  aws.String(contracts.AssociationStatusAssociated),
+		},
+	}
+	if definition.ScheduleExpression != "" {
+		assoc.Association.ScheduleExpression = aws.String(definition.ScheduleExpression)
+	}
+	if definition.OutputS3BucketName != "" {
+		assoc.Association.OutputLocation = &ssm.InstanceAssociationOutputLocation{
+			S3Location: &ssm.S3OutputLocation{
+				OutputS3BucketName: aws.String(definition.OutputS3BucketName),
+				OutputS3KeyPrefix:  aws.String(definition.OutputS3KeyPrefix),
+			},
+		}
+	}
+	return assoc, nil
+}
+
+// newParameters converts the parameters of the definition to the lists of values of the association parameters.
+// The maps are passed as JSON, as the StringMap parameters sent by the service.
+func newParameters(definitionParameters map[string]interface{}) (map[string][]*string, error) {
+	parameters := make(map[string][]*string, len(definitionParameters))
+	for name, value := range definitionParameters {
+		switch value := value.(type) {
+		case []interface{}:
+			values := make([]*string, 0, len(value))
+			for _, item := range value {
+				text, err := parameterText(item)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value of parameter %v, %v", name, err)
+				}
+				values = append(values, aws.String(text))
+			}
+			parameters[name] = values
+		default:
+			text, err := parameterText(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of parameter %v, %v", name, err)
+			}
+			parameters[name] = []*string{aws.String(text)}
+		}
+	}
+	return parameters, nil
+}
+
+// parameterText returns the text of a parameter value
+func parameterText(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case map[string]interface{}, []interface{}:
+		return jsonutil.Marshal(value)
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
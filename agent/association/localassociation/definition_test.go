@@ -0,0 +1,134 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testInstanceID      = "i-1234567890"
+	testAssociationsDir = "testdata/associations"
+)
+
+// useTempDataStore points the status files to a temporary folder for the test
+func useTempDataStore(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "localassociation")
+	assert.NoError(t, err)
+	dataStorePath = dir
+	return func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadAssociations(t *testing.T) {
+	defer useTempDataStore(t)()
+	associations := LoadAssociations(log.NewMockLog(), testAssociationsDir, testInstanceID)
+
+	// the invalid definition and the duplicate of update-agent are skipped
+	assert.Len(t, associations, 2)
+
+	inventory := associations[0].Association
+	assert.Equal(t, "inventory", *inventory.AssociationId)
+	assert.Equal(t, "Inventory", *inventory.Name)
+	assert.Equal(t, testInstanceID, *inventory.InstanceId)
+	assert.Equal(t, contracts.AssociationStatusAssociated, *inventory.DetailedStatus)
+	assert.Nil(t, inventory.ScheduleExpression)
+	assert.Nil(t, inventory.OutputLocation)
+	assert.Equal(t, []*string{aws.String("echo inventory")}, inventory.Parameters["commands"])
+	assert.Equal(t, []*string{aws.String(`{"interval":30}`)}, inventory.Parameters["settings"])
+	assert.True(t, associations[0].IsRunOnceAssociation())
+
+	updateAgent := associations[1].Association
+	assert.Equal(t, "update-agent", *updateAgent.AssociationId)
+	assert.Equal(t, "RunShellScript", *updateAgent.Name)
+	assert.Equal(t, "", *updateAgent.DocumentVersion)
+	assert.Equal(t, "rate(30 minutes)", *updateAgent.ScheduleExpression)
+	assert.Equal(t, "bucket", *updateAgent.OutputLocation.S3Location.OutputS3BucketName)
+	assert.Equal(t, "prefix", *updateAgent.OutputLocation.S3Location.OutputS3KeyPrefix)
+	assert.Equal(t, []*string{aws.String("echo hello"), aws.String("echo world")}, updateAgent.Parameters["commands"])
+
+	var document contracts.DocumentContent
+	assert.NoError(t, json.Unmarshal([]byte(*associations[1].Document), &document))
+	assert.Equal(t, "2.2", document.SchemaVersion)
+	assert.Len(t, document.MainSteps, 1)
+}
+
+func TestLoadAssociationsMissingDir(t *testing.T) {
+	associations := LoadAssociations(log.NewMockLog(), filepath.Join(testAssociationsDir, "missing"), testInstanceID)
+	assert.Empty(t, associations)
+}
+
+func TestNewAssociationInvalidID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localassociation")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, ioutil.WriteFile(filePath, []byte(`{"AssociationId": "../escape", "DocumentPath": "doc.json"}`), 0600))
+	_, err = newAssociation(filePath, testInstanceID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "../escape")
+}
+
+func TestApplyStatus(t *testing.T) {
+	defer useTempDataStore(t)()
+	logger := log.NewMockLog()
+	executionDate := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	recordStatus(logger, testInstanceID, Status{AssociationID: "update-agent", Name: "RunShellScript", Status: contracts.AssociationStatusPending, ExecutionDate: executionDate})
+	recordStatus(logger, testInstanceID, Status{AssociationID: "update-agent", Status: contracts.AssociationStatusSuccess, ExecutionDate: executionDate.Add(time.Minute)})
+
+	status, err := LoadStatus(testInstanceID, "update-agent")
+	assert.NoError(t, err)
+	assert.Equal(t, "RunShellScript", status.Name)
+	assert.Equal(t, contracts.AssociationStatusSuccess, status.Status)
+	assert.Equal(t, executionDate, *status.LastExecutionDate)
+
+	assoc, err := newAssociation(filepath.Join(testAssociationsDir, "updateagent.json"), testInstanceID)
+	assert.NoError(t, err)
+	applyStatus(logger, assoc, testInstanceID)
+	assert.Equal(t, contracts.AssociationStatusSuccess, *assoc.Association.DetailedStatus)
+	assert.Equal(t, executionDate, *assoc.Association.LastExecutionDate)
+
+	// the association is scheduled from its last execution
+	assoc.SetNextScheduledDate(logger)
+	assert.Equal(t, executionDate.Add(30*time.Minute), *assoc.NextScheduledDate)
+}
+
+func TestApplyStatusInterrupted(t *testing.T) {
+	defer useTempDataStore(t)()
+	logger := log.NewMockLog()
+
+	recordStatus(logger, testInstanceID, Status{AssociationID: "inventory", Status: contracts.AssociationStatusPending, ExecutionDate: time.Now().UTC()})
+
+	assoc, err := newAssociation(filepath.Join(testAssociationsDir, "inventory.yaml"), testInstanceID)
+	assert.NoError(t, err)
+	applyStatus(logger, assoc, testInstanceID)
+
+	// a pending association is not run again on the next refresh
+	assert.Equal(t, contracts.AssociationStatusInProgress, *assoc.Association.DetailedStatus)
+	assoc.SetNextScheduledDate(logger)
+	assert.Nil(t, assoc.NextScheduledDate)
+}
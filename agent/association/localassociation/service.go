@@ -0,0 +1,174 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
+	"github.com/aws/amazon-ssm-agent/agent/association/service"
+	complianceUploader "github.com/aws/amazon-ssm-agent/agent/compliance/uploader"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// AssociationService merges the local associations with the associations of the Systems Manager service.
+// The status of the local associations is recorded locally instead of being sent to the service.
+type AssociationService struct {
+	service.T
+	dir string
+
+	mutex              sync.Mutex
+	remoteAssociations []*model.InstanceAssociation
+	localIDs           map[string]bool
+}
+
+// NewAssociationService wraps the association service with the local associations defined in the directory
+func NewAssociationService(remote service.T, dir string) *AssociationService {
+	return &AssociationService{
+		T:   remote,
+		dir: dir,
+	}
+}
+
+// ListInstanceAssociations returns the associations of the service and the local associations.
+// The last associations of the service are kept when it cannot be reached, an error is returned only if there is no local association.
+func (s *AssociationService) ListInstanceAssociations(log log.T, instanceID string) ([]*model.InstanceAssociation, error) {
+	remoteAssociations, remoteErr := s.T.ListInstanceAssociations(log, instanceID)
+	localAssociations := LoadAssociations(log, s.dir, instanceID)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.localIDs = make(map[string]bool, len(localAssociations))
+	for _, assoc := range localAssociations {
+		s.localIDs[*assoc.Association.AssociationId] = true
+	}
+	if remoteErr != nil {
+		if len(localAssociations) == 0 {
+			return nil, remoteErr
+		}
+		log.Warnf("Unable to load instance associations from the service, scheduling the local associations, %v", remoteErr)
+		remoteAssociations = s.remoteAssociations
+	} else {
+		s.remoteAssociations = remoteAssociations
+	}
+
+	associations := make([]*model.InstanceAssociation, 0, len(remoteAssociations)+len(localAssociations))
+	for _, assoc := range remoteAssociations {
+		if s.localIDs[*assoc.Association.AssociationId] {
+			log.Warnf("Local association %v has the ID of an association of the service, skipping the local one", *assoc.Association.AssociationId)
+			delete(s.localIDs, *assoc.Association.AssociationId)
+		}
+		associations = append(associations, assoc)
+	}
+	for _, assoc := range localAssociations {
+		if s.localIDs[*assoc.Association.AssociationId] {
+			associations = append(associations, assoc)
+		}
+	}
+	return associations, nil
+}
+
+// LoadAssociationDetail loads the document of the association from the service, the local associations already hold theirs
+func (s *AssociationService) LoadAssociationDetail(log log.T, assoc *model.InstanceAssociation) error {
+	if s.IsLocalAssociation(*assoc.Association.AssociationId) {
+		return nil
+	}
+	return s.T.LoadAssociationDetail(log, assoc)
+}
+
+// UpdateInstanceAssociationStatus records the status of the local associations, and sends the others to the service
+func (s *AssociationService) UpdateInstanceAssociationStatus(
+	log log.T,
+	associationID string,
+	associationName string,
+	instanceID string,
+	status string,
+	errorCode string,
+	executionDate string,
+	executionSummary string,
+	outputUrl string) {
+
+	if !s.IsLocalAssociation(associationID) {
+		s.T.UpdateInstanceAssociationStatus(log, associationID, associationName, instanceID, status, errorCode, executionDate, executionSummary, outputUrl)
+		return
+	}
+
+	// Update status in schedulemanager as the service does
+	schedulemanager.UpdateAssociationStatus(associationID, status)
+	schedulemanager.Persist(log, instanceID)
+
+	log.Infof("Updating local association %v status to %v", associationID, status)
+	recordStatus(log, instanceID, Status{
+		AssociationID:    associationID,
+		Name:             associationName,
+		Status:           status,
+		ErrorCode:        errorCode,
+		ExecutionDate:    times.ParseIso8601UTC(executionDate),
+		ExecutionSummary: executionSummary,
+		OutputUrl:        outputUrl,
+	})
+}
+
+// IsLocalAssociation returns true if the association is defined locally.
+// Until the associations are listed, the association is looked up in the definition files.
+func (s *AssociationService) IsLocalAssociation(associationID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.localIDs != nil {
+		return s.localIDs[associationID]
+	}
+	return hasDefinitionFile(s.dir, associationID)
+}
+
+// hasDefinitionFile returns true if a definition file of the directory defines the association
+func hasDefinitionFile(dir string, associationID string) bool {
+	fileNames, err := fileutil.GetFileNames(dir)
+	if err != nil {
+		return false
+	}
+	for _, fileName := range fileNames {
+		if assoc, err := newAssociation(filepath.Join(dir, fileName), ""); err == nil && *assoc.Association.AssociationId == associationID {
+			return true
+		}
+	}
+	return false
+}
+
+// ComplianceUploader skips the compliance of the local associations, which are unknown to the service
+type ComplianceUploader struct {
+	complianceUploader.T
+	assocSvc *AssociationService
+}
+
+// NewComplianceUploader wraps the compliance uploader to skip the local associations of the association service
+func NewComplianceUploader(remote complianceUploader.T, assocSvc *AssociationService) *ComplianceUploader {
+	return &ComplianceUploader{
+		T:        remote,
+		assocSvc: assocSvc,
+	}
+}
+
+// UpdateAssociationCompliance uploads the compliance of the associations of the service
+func (u *ComplianceUploader) UpdateAssociationCompliance(associationId string, instanceId string, documentName string, documentVersion string, associationStatus string, executionTime time.Time) error {
+	if u.assocSvc.IsLocalAssociation(associationId) {
+		return nil
+	}
+	return u.T.UpdateAssociationCompliance(associationId, instanceId, documentName, documentVersion, associationStatus, executionTime)
+}
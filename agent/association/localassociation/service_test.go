@@ -0,0 +1,129 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/association/service"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRemoteAssociation(associationID string) *model.InstanceAssociation {
+	return &model.InstanceAssociation{
+		Association: &ssm.InstanceAssociationSummary{
+			AssociationId: aws.String(associationID),
+			Name:          aws.String("AWS-UpdateSSMAgent"),
+			InstanceId:    aws.String(testInstanceID),
+		},
+	}
+}
+
+func associationIDs(associations []*model.InstanceAssociation) (ids []string) {
+	for _, assoc := range associations {
+		ids = append(ids, *assoc.Association.AssociationId)
+	}
+	return ids
+}
+
+func TestListInstanceAssociations(t *testing.T) {
+	defer useTempDataStore(t)()
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	remote := service.NewMockDefault()
+	remote.On("ListInstanceAssociations", logger, testInstanceID).Return([]*model.InstanceAssociation{newRemoteAssociation("remote")}, nil).Once()
+	remote.On("ListInstanceAssociations", logger, testInstanceID).Return([]*model.InstanceAssociation{}, fmt.Errorf("unreachable"))
+
+	assocSvc := NewAssociationService(remote, testAssociationsDir)
+	associations, err := assocSvc.ListInstanceAssociations(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"remote", "inventory", "update-agent"}, associationIDs(associations))
+	assert.True(t, assocSvc.IsLocalAssociation("update-agent"))
+	assert.False(t, assocSvc.IsLocalAssociation("remote"))
+
+	// the last associations of the service are kept when it cannot be reached
+	associations, err = assocSvc.ListInstanceAssociations(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"remote", "inventory", "update-agent"}, associationIDs(associations))
+}
+
+func TestListInstanceAssociationsNoLocalAssociation(t *testing.T) {
+	logger := log.NewMockLog()
+	remote := service.NewMockDefault()
+	remote.On("ListInstanceAssociations", logger, testInstanceID).Return([]*model.InstanceAssociation{}, fmt.Errorf("unreachable"))
+
+	assocSvc := NewAssociationService(remote, "testdata/missing")
+	_, err := assocSvc.ListInstanceAssociations(logger, testInstanceID)
+	assert.Error(t, err)
+}
+
+func TestListInstanceAssociationsConflict(t *testing.T) {
+	defer useTempDataStore(t)()
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	remote := service.NewMockDefault()
+	remote.On("ListInstanceAssociations", logger, testInstanceID).Return([]*model.InstanceAssociation{newRemoteAssociation("inventory")}, nil)
+
+	// the association of the service wins over the local one with the same ID
+	assocSvc := NewAssociationService(remote, testAssociationsDir)
+	associations, err := assocSvc.ListInstanceAssociations(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"inventory", "update-agent"}, associationIDs(associations))
+	assert.Equal(t, "AWS-UpdateSSMAgent", *associations[0].Association.Name)
+	assert.False(t, assocSvc.IsLocalAssociation("inventory"))
+}
+
+func TestUpdateInstanceAssociationStatus(t *testing.T) {
+	defer useTempDataStore(t)()
+	logger := log.NewMockLog()
+	remote := service.NewMockDefault()
+	remote.On("UpdateInstanceAssociationStatus", logger, "remote", "AWS-UpdateSSMAgent", testInstanceID, mock.Anything).Return()
+	executionDate := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// the local associations are found in the definition files before being listed
+	assocSvc := NewAssociationService(remote, testAssociationsDir)
+	assocSvc.UpdateInstanceAssociationStatus(logger, "update-agent", "RunShellScript", testInstanceID,
+		contracts.AssociationStatusFailed, contracts.AssociationErrorCodeExecutionError, times.ToIso8601UTC(executionDate), "summary", service.NoOutputUrl)
+	assocSvc.UpdateInstanceAssociationStatus(logger, "remote", "AWS-UpdateSSMAgent", testInstanceID,
+		contracts.AssociationStatusSuccess, contracts.AssociationErrorCodeNoError, times.ToIso8601UTC(executionDate), "summary", service.NoOutputUrl)
+
+	remote.AssertNumberOfCalls(t, "UpdateInstanceAssociationStatus", 1)
+	status, err := LoadStatus(testInstanceID, "update-agent")
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.AssociationStatusFailed, status.Status)
+	assert.Equal(t, contracts.AssociationErrorCodeExecutionError, status.ErrorCode)
+	assert.Equal(t, executionDate, status.ExecutionDate)
+	assert.Equal(t, "summary", status.ExecutionSummary)
+}
+
+func TestLoadAssociationDetail(t *testing.T) {
+	logger := log.NewMockLog()
+	remote := service.NewMockDefault()
+	remoteAssociation := newRemoteAssociation("remote")
+	remote.On("LoadAssociationDetail", logger, remoteAssociation).Return(nil)
+
+	assocSvc := NewAssociationService(remote, testAssociationsDir)
+	assert.NoError(t, assocSvc.LoadAssociationDetail(logger, remoteAssociation))
+	assert.NoError(t, assocSvc.LoadAssociationDetail(logger, newRemoteAssociation("update-agent")))
+	remote.AssertNumberOfCalls(t, "LoadAssociationDetail", 1)
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localassociation
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/model"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// statusDirName is the folder of the association data store holding the status of the local associations
+const statusDirName = "local"
+
+var (
+	statusLock sync.Mutex
+	// dataStorePath is the root of the data store holding the status files
+	dataStorePath = appconfig.DefaultDataStorePath
+)
+
+// Status is the last status of a local association, recorded in place of the service
+type Status struct {
+	AssociationID     string
+	Name              string
+	Status            string
+	ErrorCode         string
+	ExecutionDate     time.Time
+	ExecutionSummary  string
+	OutputUrl         string
+	LastExecutionDate *time.Time `json:",omitempty"`
+}
+
+// LoadStatus returns the last status recorded for the local association
+func LoadStatus(instanceID string, associationID string) (status Status, err error) {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	err = jsonutil.UnmarshalFile(statusFilePath(instanceID, associationID), &status)
+	return status, err
+}
+
+// recordStatus saves the status of the local association.
+// An association run starts with the Pending status, its date is kept as the last execution date for the schedule.
+func recordStatus(log log.T, instanceID string, status Status) {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+
+	filePath := statusFilePath(instanceID, status.AssociationID)
+	var previous Status
+	if err := jsonutil.UnmarshalFile(filePath, &previous); err == nil {
+		status.LastExecutionDate = previous.LastExecutionDate
+		if status.Name == "" {
+			status.Name = previous.Name
+		}
+	}
+	if status.Status == contracts.AssociationStatusPending {
+		status.LastExecutionDate = aws.Time(status.ExecutionDate)
+	}
+
+	content, err := jsonutil.Marshal(status)
+	if err != nil {
+		log.Errorf("failed to marshal status of local association %v, %v", status.AssociationID, err)
+		return
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(filePath)); err != nil {
+		log.Errorf("failed to create directory %v, %v", filepath.Dir(filePath), err)
+		return
+	}
+	if _, err = fileutil.WriteIntoFileWithPermissions(filePath, content, os.FileMode(int(appconfig.ReadWriteAccess))); err != nil {
+		log.Errorf("failed to save status of local association %v, %v", status.AssociationID, err)
+	}
+}
+
+// applyStatus restores the recorded status of the local association, so that its schedule survives the agent restarts.
+// An association interrupted before it started is not run again right away.
+func applyStatus(log log.T, assoc *model.InstanceAssociation, instanceID string) {
+	status, err := LoadStatus(instanceID, *assoc.Association.AssociationId)
+	if err != nil {
+		return
+	}
+	log.Debugf("Last status of local association %v is %v at %v", status.AssociationID, status.Status, times.ToIso8601UTC(status.ExecutionDate))
+	detailedStatus := status.Status
+	if detailedStatus == contracts.AssociationStatusPending {
+		detailedStatus = contracts.AssociationStatusInProgress
+	}
+	assoc.Association.DetailedStatus = aws.String(detailedStatus)
+	assoc.Association.LastExecutionDate = status.LastExecutionDate
+}
+
+// statusFilePath returns the path of the status file of the local association
+func statusFilePath(instanceID string, associationID string) string {
+	return filepath.Join(dataStorePath,
+		instanceID,
+		appconfig.DefaultDocumentRootDirName,
+		appconfig.DefaultLocationOfAssociation,
+		statusDirName,
+		associationID+".json")
+}
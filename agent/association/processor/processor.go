@@ -22,7 +22,9 @@ import (
 	"path"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/association/cache"
+	"github.com/aws/amazon-ssm-agent/agent/association/localassociation"
 	"github.com/aws/amazon-ssm-agent/agent/association/model"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
 	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager/signal"
@@ -77,8 +79,9 @@ func NewAssociationProcessor(context context.T) *Processor {
 		OsVersion: config.Os.Version,
 	}
 
-	assocSvc := service.NewAssociationService(name)
-	uploader := complianceUploader.NewComplianceUploader(context)
+	// the associations defined in local files are scheduled along with the ones of the service
+	assocSvc := localassociation.NewAssociationService(service.NewAssociationService(name), appconfig.LocalAssociationRoot)
+	uploader := localassociation.NewComplianceUploader(complianceUploader.NewComplianceUploader(context), assocSvc)
 
 	//TODO Rename everything to service and move package to framework
 	//association has no cancel worker
@@ -249,6 +252,7 @@ func (p *Processor) ProcessAssociation() {
 	}
 
 	schedulemanager.Refresh(log, associations)
+	schedulemanager.Persist(log, instanceID)
 
 	log.Debug("ProcessAssociation is triggering execution")
 
@@ -582,6 +586,7 @@ func (r *Processor) listenToResponses() {
 				r.context.AppConfig().Ssm.AssociationLogsRetentionDurationHours)
 			//TODO move this part to service
 			schedulemanager.UpdateNextScheduledDate(log, res.AssociationID)
+			schedulemanager.Persist(log, instanceID)
 			signal.ExecuteAssociation(log)
 
 		}
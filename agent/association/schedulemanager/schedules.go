@@ -0,0 +1,87 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package schedulemanager
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// SchedulesFileName is the name of the file holding the schedules of the associations of the instance
+const SchedulesFileName = "schedules.json"
+
+// Schedule is the persisted schedule of an association, read by the tools outside of the agent process
+type Schedule struct {
+	AssociationID      string
+	Name               string
+	DocumentVersion    string
+	ScheduleExpression string
+	DetailedStatus     string
+	LastExecutionDate  *time.Time
+	NextScheduledDate  *time.Time
+}
+
+// Persist saves the schedules of the cached associations, so that the cli can show them
+func Persist(log log.T, instanceID string) {
+	lock.RLock()
+	schedules := make([]Schedule, 0, len(associations))
+	for _, assoc := range associations {
+		schedules = append(schedules, Schedule{
+			AssociationID:      aws.StringValue(assoc.Association.AssociationId),
+			Name:               aws.StringValue(assoc.Association.Name),
+			DocumentVersion:    aws.StringValue(assoc.Association.DocumentVersion),
+			ScheduleExpression: aws.StringValue(assoc.Association.ScheduleExpression),
+			DetailedStatus:     aws.StringValue(assoc.Association.DetailedStatus),
+			LastExecutionDate:  assoc.Association.LastExecutionDate,
+			NextScheduledDate:  assoc.NextScheduledDate,
+		})
+	}
+	lock.RUnlock()
+
+	content, err := jsonutil.Marshal(schedules)
+	if err != nil {
+		log.Errorf("failed to marshal association schedules: %v", err)
+		return
+	}
+	dir := schedulesDir(instanceID)
+	if err = fileutil.MakeDirs(dir); err != nil {
+		log.Errorf("failed to create directory %v: %v", dir, err)
+		return
+	}
+	fileName := filepath.Join(dir, SchedulesFileName)
+	if _, err = fileutil.WriteIntoFileWithPermissions(fileName, content, os.FileMode(int(appconfig.ReadWriteAccess))); err != nil {
+		log.Errorf("failed to write association schedules to %v: %v", fileName, err)
+	}
+}
+
+// LoadSchedules reads the schedules the agent last persisted for the instance
+func LoadSchedules(instanceID string) (schedules []Schedule, err error) {
+	err = jsonutil.UnmarshalFile(filepath.Join(schedulesDir(instanceID), SchedulesFileName), &schedules)
+	return
+}
+
+// schedulesDir returns the directory of the schedules file, next to the record of the last associated document
+func schedulesDir(instanceID string) string {
+	return filepath.Join(appconfig.DefaultDataStorePath,
+		instanceID,
+		appconfig.DefaultDocumentRootDirName,
+		appconfig.DefaultLocationOfAssociation)
+}
@@ -221,6 +221,7 @@ func (s *AssociationService) UpdateInstanceAssociationStatus(
 	var err error
 	// Update status in schedulemanager to ensure state matches with the one on the service
 	schedulemanager.UpdateAssociationStatus(associationID, status)
+	schedulemanager.Persist(log, instanceID)
 
 	if s.IsInstanceAssociationApiMode() {
 		date := times.ParseIso8601UTC(executionDate)
@@ -0,0 +1,52 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cgroup places the processes running a document in a control group limiting their CPU, memory and IO.
+// Control groups are supported on Linux only, for both the v1 and the v2 (unified) hierarchies.
+package cgroup
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Cgroup is a control group holding the processes of a document
+type Cgroup interface {
+	// Command returns the command running the given one inside the cgroup from its start
+	Command(name string, argv []string) (string, []string)
+	// AddProcess moves the process to the cgroup, the children it creates afterwards belong to the cgroup as well
+	AddProcess(pid int) error
+	// OOMKillCount returns the number of processes of the cgroup killed for exceeding the memory limit
+	OOMKillCount() (int, error)
+	// Destroy kills the processes left in the cgroup and removes it
+	Destroy() error
+}
+
+// New creates the cgroup with the given name and limits under the cgroup of the agent, or reuses it if it already exists
+func New(log log.T, name string, limits contracts.ResourceLimits) (Cgroup, error) {
+	return newCgroup(log, name, limits)
+}
+
+// MergeLimits returns the limits of the document, falling back to the default limits for those not set
+func MergeLimits(defaults contracts.ResourceLimits, limits contracts.ResourceLimits) contracts.ResourceLimits {
+	if limits.CPUQuotaPercent == 0 {
+		limits.CPUQuotaPercent = defaults.CPUQuotaPercent
+	}
+	if limits.MemoryLimitMB == 0 {
+		limits.MemoryLimitMB = defaults.MemoryLimitMB
+	}
+	if limits.IOWeight == 0 {
+		limits.IOWeight = defaults.IOWeight
+	}
+	return limits
+}
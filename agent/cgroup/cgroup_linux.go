@@ -0,0 +1,391 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cgroup places the processes running a document in a control group limiting their CPU, memory and IO.
+// Control groups are supported on Linux only, for both the v1 and the v2 (unified) hierarchies.
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// documentsCgroupName is the cgroup, nested in the cgroup of the agent, holding the cgroups of the documents
+	documentsCgroupName = "documents"
+	// agentLeafCgroupName is the cgroup the processes of the agent cgroup are moved to in the v2 hierarchy,
+	// where a cgroup enabling controllers for its children cannot hold processes
+	agentLeafCgroupName = "agent"
+
+	// joinScript adds the shell to the cgroup.procs files given before "--", then executes the command given after
+	joinScript = `while [ "$1" != "--" ]; do echo $$ > "$1" || exit 1; shift; done; shift; exec "$@"`
+
+	// cpuPeriodMicroseconds is the period the cpu quota applies to
+	cpuPeriodMicroseconds = 100000
+
+	// the io weight is expressed in the cgroup v2 range, and converted to the blkio range of cgroup v1
+	ioWeightDefault    = 100
+	blkioWeightDefault = 500
+	blkioWeightMin     = 10
+	blkioWeightMax     = 1000
+
+	// the processes killed when destroying the cgroup may take a while to exit
+	destroyRetries       = 20
+	destroyRetryInterval = 100 * time.Millisecond
+
+	procsFileName = "cgroup.procs"
+)
+
+// Assign method to global variables to allow unittest to override
+var mountsFile = "/proc/mounts"
+var selfCgroupFile = "/proc/self/cgroup"
+var shell = "/bin/sh"
+var removeDirectory = os.Remove
+
+// setting is a value written to a file of a cgroup
+type setting struct {
+	file  string
+	value string
+}
+
+// linuxCgroup is a cgroup created in one or more hierarchies
+type linuxCgroup struct {
+	log log.T
+	// paths are the directories of the cgroup, one per hierarchy
+	paths []string
+	// oomFile is the file counting the oom kills, empty when the memory is not limited
+	oomFile string
+}
+
+func newCgroup(log log.T, name string, limits contracts.ResourceLimits) (Cgroup, error) {
+	v1Mounts, v2Mount, err := readMounts()
+	if err != nil {
+		return nil, err
+	}
+	v1Paths, v2Path, err := readSelfCgroups()
+	if err != nil {
+		return nil, err
+	}
+	// on hybrid systems the controllers are mounted in the v1 hierarchies
+	if len(v1Mounts) > 0 {
+		return newV1Cgroup(log, v1Mounts, v1Paths, name, limits)
+	}
+	if v2Mount != "" {
+		return newV2Cgroup(log, v2Mount, v2Path, name, limits)
+	}
+	return nil, fmt.Errorf("no cgroup hierarchy is mounted")
+}
+
+// readSelfCgroups returns the cgroups of the agent, per v1 controller used by the agent and in the v2 hierarchy,
+// e.g. /system.slice/amazon-ssm-agent.service when the agent runs as a systemd service
+func readSelfCgroups() (v1Paths map[string]string, v2Path string, err error) {
+	content, err := ioutil.ReadFile(selfCgroupFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the cgroups of the agent: %v", err)
+	}
+
+	v1Paths = make(map[string]string)
+	v2Path = "/"
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// each line is hierarchy-ID:controller-list:cgroup-path, the v2 hierarchy has the ID 0 and no controllers
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			v2Path = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			switch controller {
+			case "cpu", "memory", "blkio":
+				v1Paths[controller] = fields[2]
+			}
+		}
+	}
+	return v1Paths, v2Path, nil
+}
+
+// readMounts returns the mount points of the v1 controllers used by the agent, and the mount point of the v2 hierarchy
+func readMounts() (v1Mounts map[string]string, v2Mount string, err error) {
+	content, err := ioutil.ReadFile(mountsFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read mounts: %v", err)
+	}
+
+	v1Mounts = make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		switch fields[2] {
+		case "cgroup":
+			for _, option := range strings.Split(fields[3], ",") {
+				switch option {
+				case "cpu", "memory", "blkio":
+					v1Mounts[option] = fields[1]
+				}
+			}
+		case "cgroup2":
+			v2Mount = fields[1]
+		}
+	}
+	return v1Mounts, v2Mount, nil
+}
+
+// newV1Cgroup creates the cgroup, under the cgroup of the agent, in the hierarchy of every controller needed by the limits
+func newV1Cgroup(log log.T, mounts map[string]string, selfPaths map[string]string, name string, limits contracts.ResourceLimits) (*linuxCgroup, error) {
+	settings := make(map[string][]setting)
+	if limits.CPUQuotaPercent > 0 {
+		settings["cpu"] = []setting{
+			{"cpu.cfs_period_us", strconv.Itoa(cpuPeriodMicroseconds)},
+			{"cpu.cfs_quota_us", strconv.Itoa(cpuQuota(limits.CPUQuotaPercent))},
+		}
+	}
+	if limits.MemoryLimitMB > 0 {
+		settings["memory"] = []setting{
+			{"memory.limit_in_bytes", strconv.FormatInt(memoryBytes(limits.MemoryLimitMB), 10)},
+		}
+	}
+	if limits.IOWeight > 0 {
+		settings["blkio"] = []setting{
+			{"blkio.weight", strconv.Itoa(blkioWeight(limits.IOWeight))},
+		}
+	}
+
+	c := &linuxCgroup{log: log}
+	for controller, controllerSettings := range settings {
+		mount, found := mounts[controller]
+		if !found {
+			c.Destroy()
+			return nil, fmt.Errorf("cgroup controller %v is not mounted", controller)
+		}
+		path := filepath.Join(mount, selfPaths[controller], documentsCgroupName, name)
+		if err := c.create(path, controllerSettings); err != nil {
+			c.Destroy()
+			return nil, err
+		}
+		if controller == "memory" {
+			c.oomFile = filepath.Join(path, "memory.oom_control")
+		}
+	}
+	return c, nil
+}
+
+// newV2Cgroup creates the cgroup, under the cgroup of the agent, in the unified hierarchy after enabling the controllers needed by the limits
+func newV2Cgroup(log log.T, mount string, selfPath string, name string, limits contracts.ResourceLimits) (*linuxCgroup, error) {
+	var controllers []string
+	var settings []setting
+	if limits.CPUQuotaPercent > 0 {
+		controllers = append(controllers, "cpu")
+		settings = append(settings, setting{"cpu.max", fmt.Sprintf("%v %v", cpuQuota(limits.CPUQuotaPercent), cpuPeriodMicroseconds)})
+	}
+	if limits.MemoryLimitMB > 0 {
+		controllers = append(controllers, "memory")
+		settings = append(settings, setting{"memory.max", strconv.FormatInt(memoryBytes(limits.MemoryLimitMB), 10)})
+	}
+	if limits.IOWeight > 0 {
+		controllers = append(controllers, "io")
+		settings = append(settings, setting{"io.weight", fmt.Sprintf("default %v", limits.IOWeight)})
+	}
+
+	self := filepath.Join(mount, selfPath)
+	parent := filepath.Join(self, documentsCgroupName)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup %v: %v", parent, err)
+	}
+	if self != mount {
+		if err := moveProcesses(self, filepath.Join(self, agentLeafCgroupName)); err != nil {
+			return nil, err
+		}
+	}
+
+	// the controllers must be enabled in every ancestor of the cgroup, from the root of the hierarchy down
+	ancestors := []string{mount}
+	for _, dir := range strings.Split(strings.Trim(filepath.Join(selfPath, documentsCgroupName), "/"), "/") {
+		ancestors = append(ancestors, filepath.Join(ancestors[len(ancestors)-1], dir))
+	}
+	for _, ancestor := range ancestors {
+		for _, controller := range controllers {
+			if err := writeFile(filepath.Join(ancestor, "cgroup.subtree_control"), "+"+controller); err != nil {
+				return nil, fmt.Errorf("failed to enable cgroup controller %v: %v", controller, err)
+			}
+		}
+	}
+
+	c := &linuxCgroup{log: log}
+	path := filepath.Join(parent, name)
+	if err := c.create(path, settings); err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	if limits.MemoryLimitMB > 0 {
+		c.oomFile = filepath.Join(path, "memory.events")
+	}
+	return c, nil
+}
+
+// create creates the directory of the cgroup and writes its settings
+func (c *linuxCgroup) create(path string, settings []setting) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %v: %v", path, err)
+	}
+	c.paths = append(c.paths, path)
+	for _, s := range settings {
+		if err := writeFile(filepath.Join(path, s.file), s.value); err != nil {
+			return fmt.Errorf("failed to set %v of cgroup %v: %v", s.file, path, err)
+		}
+	}
+	c.log.Debugf("created cgroup %v with settings %v", path, settings)
+	return nil
+}
+
+// AddProcess writes the pid to the processes of the cgroup in every hierarchy
+func (c *linuxCgroup) AddProcess(pid int) error {
+	for _, path := range c.paths {
+		if err := writeFile(filepath.Join(path, procsFileName), strconv.Itoa(pid)); err != nil {
+			return fmt.Errorf("failed to add process %v to cgroup %v: %v", pid, path, err)
+		}
+	}
+	return nil
+}
+
+// Command returns the command running the given one inside the cgroup, a shell adds itself to the cgroup
+// in every hierarchy before executing the command, so that none of its processes runs without the limits
+func (c *linuxCgroup) Command(name string, argv []string) (string, []string) {
+	args := []string{"-c", joinScript, "sh"}
+	for _, path := range c.paths {
+		args = append(args, filepath.Join(path, procsFileName))
+	}
+	args = append(append(args, "--", name), argv...)
+	return shell, args
+}
+
+// OOMKillCount reads the oom_kill counter of the memory controller, memory.oom_control in v1 and memory.events in v2
+func (c *linuxCgroup) OOMKillCount() (int, error) {
+	if c.oomFile == "" {
+		return 0, nil
+	}
+	content, err := ioutil.ReadFile(c.oomFile)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.Atoi(fields[1])
+		}
+	}
+	return 0, nil
+}
+
+// Destroy kills the processes left in the cgroup, and removes the cgroup once they exited
+func (c *linuxCgroup) Destroy() error {
+	var lastErr error
+	for _, path := range c.paths {
+		var err error
+		for i := 0; i < destroyRetries; i++ {
+			killProcesses(c.log, path)
+			if err = removeDirectory(path); err == nil || os.IsNotExist(err) {
+				err = nil
+				break
+			}
+			time.Sleep(destroyRetryInterval)
+		}
+		if err != nil {
+			c.log.Errorf("failed to remove cgroup %v: %v", path, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// moveProcesses moves the processes of the cgroup to another cgroup, creating it if needed
+func moveProcesses(from string, to string) error {
+	content, err := ioutil.ReadFile(filepath.Join(from, procsFileName))
+	if err != nil {
+		return fmt.Errorf("failed to read the processes of cgroup %v: %v", from, err)
+	}
+	pids := strings.Fields(string(content))
+	if len(pids) == 0 {
+		return nil
+	}
+	if err = os.MkdirAll(to, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %v: %v", to, err)
+	}
+	for _, pid := range pids {
+		if err = writeFile(filepath.Join(to, procsFileName), pid); err != nil {
+			return fmt.Errorf("failed to move process %v to cgroup %v: %v", pid, to, err)
+		}
+	}
+	return nil
+}
+
+// killProcesses kills the processes of the cgroup
+func killProcesses(log log.T, path string) {
+	content, err := ioutil.ReadFile(filepath.Join(path, procsFileName))
+	if err != nil {
+		return
+	}
+	for _, field := range strings.Fields(string(content)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil || pid <= 0 {
+			continue
+		}
+		log.Debugf("killing process %v left in cgroup %v", pid, path)
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+}
+
+// writeFile writes the value to a cgroup file
+func writeFile(path string, value string) error {
+	return ioutil.WriteFile(path, []byte(value), 0644)
+}
+
+// cpuQuota returns the cpu time in microseconds the processes can use per period
+func cpuQuota(percent int) int {
+	return percent * cpuPeriodMicroseconds / 100
+}
+
+// memoryBytes converts megabytes to bytes
+func memoryBytes(megabytes int) int64 {
+	return int64(megabytes) * 1024 * 1024
+}
+
+// blkioWeight converts an io weight of cgroup v2 to a blkio weight of cgroup v1, keeping the same ratio to the default weight
+func blkioWeight(ioWeight int) int {
+	weight := ioWeight * blkioWeightDefault / ioWeightDefault
+	if weight < blkioWeightMin {
+		return blkioWeightMin
+	}
+	if weight > blkioWeightMax {
+		return blkioWeightMax
+	}
+	return weight
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+// Package cgroup places the processes running a document in a control group limiting their CPU, memory and IO.
+// Control groups are supported on Linux only, for both the v1 and the v2 (unified) hierarchies.
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDocumentID  = "13e8e6ad-e195-4ccb-86ee-328153b0dafe"
+	testAgentCgroup = "/system.slice/amazon-ssm-agent.service"
+)
+
+var logger = log.NewMockLog()
+
+// setupMounts fakes the cgroup mounts with directories of a temporary directory
+func setupMounts(t *testing.T, mounts string) (root string, cleanup func()) {
+	root, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	mountsPath := filepath.Join(root, "mounts")
+	assert.NoError(t, ioutil.WriteFile(mountsPath, []byte(mounts), 0644))
+
+	selfCgroupPath := filepath.Join(root, "cgroup")
+	assert.NoError(t, ioutil.WriteFile(selfCgroupPath, []byte("12:blkio:"+testAgentCgroup+"\n"+
+		"4:cpu,cpuacct:"+testAgentCgroup+"\n"+
+		"3:memory:"+testAgentCgroup+"\n"+
+		"0::"+testAgentCgroup+"\n"), 0644))
+
+	origMountsFile, origSelfCgroupFile, origRemoveDirectory := mountsFile, selfCgroupFile, removeDirectory
+	mountsFile = mountsPath
+	selfCgroupFile = selfCgroupPath
+	// the files of a cgroup directory are removed along with it
+	removeDirectory = os.RemoveAll
+	return root, func() {
+		mountsFile, selfCgroupFile, removeDirectory = origMountsFile, origSelfCgroupFile, origRemoveDirectory
+		os.RemoveAll(root)
+	}
+}
+
+func readSetting(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestReadMounts(t *testing.T) {
+	_, cleanup := setupMounts(t, "sysfs /sys sysfs rw,nosuid 0 0\n"+
+		"cgroup /sys/fs/cgroup/cpu,cpuacct cgroup rw,nosuid,nodev,noexec,relatime,cpu,cpuacct 0 0\n"+
+		"cgroup /sys/fs/cgroup/memory cgroup rw,nosuid,nodev,noexec,relatime,memory 0 0\n"+
+		"cgroup /sys/fs/cgroup/pids cgroup rw,nosuid,nodev,noexec,relatime,pids 0 0\n"+
+		"cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime 0 0\n")
+	defer cleanup()
+
+	v1Mounts, v2Mount, err := readMounts()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cpu": "/sys/fs/cgroup/cpu,cpuacct", "memory": "/sys/fs/cgroup/memory"}, v1Mounts)
+	assert.Equal(t, "/sys/fs/cgroup/unified", v2Mount)
+}
+
+func TestReadSelfCgroups(t *testing.T) {
+	_, cleanup := setupMounts(t, "")
+	defer cleanup()
+
+	v1Paths, v2Path, err := readSelfCgroups()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cpu": testAgentCgroup, "memory": testAgentCgroup, "blkio": testAgentCgroup}, v1Paths)
+	assert.Equal(t, testAgentCgroup, v2Path)
+
+	ioutil.WriteFile(selfCgroupFile, []byte("3:memory:/\n"), 0644)
+	v1Paths, v2Path, err = readSelfCgroups()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"memory": "/"}, v1Paths)
+	assert.Equal(t, "/", v2Path)
+}
+
+func TestV1Cgroup(t *testing.T) {
+	root, cleanup := setupMounts(t, "")
+	defer cleanup()
+	ioutil.WriteFile(mountsFile, []byte("cgroup "+filepath.Join(root, "cpu")+" cgroup rw,cpu,cpuacct 0 0\n"+
+		"cgroup "+filepath.Join(root, "memory")+" cgroup rw,memory 0 0\n"+
+		"cgroup "+filepath.Join(root, "blkio")+" cgroup rw,blkio 0 0\n"), 0644)
+
+	c, err := New(logger, testDocumentID, contracts.ResourceLimits{CPUQuotaPercent: 50, MemoryLimitMB: 256, IOWeight: 1000})
+	assert.NoError(t, err)
+
+	cpuPath := filepath.Join(root, "cpu", testAgentCgroup, documentsCgroupName, testDocumentID)
+	memoryPath := filepath.Join(root, "memory", testAgentCgroup, documentsCgroupName, testDocumentID)
+	blkioPath := filepath.Join(root, "blkio", testAgentCgroup, documentsCgroupName, testDocumentID)
+	assert.Equal(t, "100000", readSetting(t, filepath.Join(cpuPath, "cpu.cfs_period_us")))
+	assert.Equal(t, "50000", readSetting(t, filepath.Join(cpuPath, "cpu.cfs_quota_us")))
+	assert.Equal(t, "268435456", readSetting(t, filepath.Join(memoryPath, "memory.limit_in_bytes")))
+	assert.Equal(t, "1000", readSetting(t, filepath.Join(blkioPath, "blkio.weight")))
+
+	assert.NoError(t, c.AddProcess(100))
+	assert.Equal(t, "100", readSetting(t, filepath.Join(memoryPath, procsFileName)))
+
+	ioutil.WriteFile(filepath.Join(memoryPath, "memory.oom_control"), []byte("oom_kill_disable 0\nunder_oom 0\noom_kill 2\n"), 0644)
+	count, err := c.OOMKillCount()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, c.Destroy())
+	for _, path := range []string{cpuPath, memoryPath, blkioPath} {
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestV1CgroupControllerNotMounted(t *testing.T) {
+	root, cleanup := setupMounts(t, "")
+	defer cleanup()
+	ioutil.WriteFile(mountsFile, []byte("cgroup "+filepath.Join(root, "memory")+" cgroup rw,memory 0 0\n"), 0644)
+
+	_, err := New(logger, testDocumentID, contracts.ResourceLimits{MemoryLimitMB: 256, IOWeight: 100})
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(root, "memory", testAgentCgroup, documentsCgroupName, testDocumentID))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestV2Cgroup(t *testing.T) {
+	root, cleanup := setupMounts(t, "")
+	defer cleanup()
+	ioutil.WriteFile(mountsFile, []byte("cgroup2 "+root+" cgroup2 rw,nosuid,nodev,noexec,relatime 0 0\n"), 0644)
+
+	// the processes of the agent cgroup are moved to a leaf cgroup before enabling the controllers
+	self := filepath.Join(root, testAgentCgroup)
+	assert.NoError(t, os.MkdirAll(self, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(self, procsFileName), []byte("1234\n"), 0644))
+
+	c, err := New(logger, testDocumentID, contracts.ResourceLimits{CPUQuotaPercent: 150, MemoryLimitMB: 1024, IOWeight: 50})
+	assert.NoError(t, err)
+
+	path := filepath.Join(self, documentsCgroupName, testDocumentID)
+	assert.Equal(t, "150000 100000", readSetting(t, filepath.Join(path, "cpu.max")))
+	assert.Equal(t, "1073741824", readSetting(t, filepath.Join(path, "memory.max")))
+	assert.Equal(t, "default 50", readSetting(t, filepath.Join(path, "io.weight")))
+	assert.Equal(t, "1234", readSetting(t, filepath.Join(self, agentLeafCgroupName, procsFileName)))
+	for _, ancestor := range []string{root, filepath.Join(root, "system.slice"), self, filepath.Join(self, documentsCgroupName)} {
+		assert.Equal(t, "+io", readSetting(t, filepath.Join(ancestor, "cgroup.subtree_control")))
+	}
+
+	count, err := c.OOMKillCount()
+	assert.Error(t, err)
+	ioutil.WriteFile(filepath.Join(path, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644)
+	count, err = c.OOMKillCount()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// the processes left in the cgroup are killed
+	cmd := exec.Command("sleep", "60")
+	assert.NoError(t, cmd.Start())
+	assert.NoError(t, c.AddProcess(cmd.Process.Pid))
+	assert.Equal(t, strconv.Itoa(cmd.Process.Pid), readSetting(t, filepath.Join(path, procsFileName)))
+	assert.NoError(t, c.Destroy())
+	assert.Error(t, cmd.Wait())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCommand(t *testing.T) {
+	root, cleanup := setupMounts(t, "")
+	defer cleanup()
+	ioutil.WriteFile(mountsFile, []byte("cgroup "+filepath.Join(root, "cpu")+" cgroup rw,cpu,cpuacct 0 0\n"+
+		"cgroup "+filepath.Join(root, "memory")+" cgroup rw,memory 0 0\n"), 0644)
+
+	c, err := New(logger, testDocumentID, contracts.ResourceLimits{CPUQuotaPercent: 50, MemoryLimitMB: 256})
+	assert.NoError(t, err)
+	defer c.Destroy()
+
+	// the command is executed by the shell added to the cgroup, keeping its pid
+	name, argv := c.Command("true", []string{"--version"})
+	cmd := exec.Command(name, argv...)
+	assert.NoError(t, cmd.Run())
+	for _, controller := range []string{"cpu", "memory"} {
+		procsPath := filepath.Join(root, controller, testAgentCgroup, documentsCgroupName, testDocumentID, procsFileName)
+		assert.Equal(t, strconv.Itoa(cmd.Process.Pid)+"\n", readSetting(t, procsPath))
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	defaults := contracts.ResourceLimits{CPUQuotaPercent: 100, MemoryLimitMB: 512}
+	assert.Equal(t, contracts.ResourceLimits{CPUQuotaPercent: 100, MemoryLimitMB: 1024, IOWeight: 10},
+		MergeLimits(defaults, contracts.ResourceLimits{MemoryLimitMB: 1024, IOWeight: 10}))
+	assert.Equal(t, defaults, MergeLimits(defaults, contracts.ResourceLimits{}))
+}
+
+func TestBlkioWeight(t *testing.T) {
+	assert.Equal(t, 10, blkioWeight(1))
+	assert.Equal(t, 500, blkioWeight(100))
+	assert.Equal(t, 1000, blkioWeight(10000))
+}
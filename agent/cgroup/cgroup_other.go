@@ -0,0 +1,29 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+// Package cgroup places the processes running a document in a control group limiting their CPU, memory and IO.
+// Control groups are supported on Linux only, for both the v1 and the v2 (unified) hierarchies.
+package cgroup
+
+import (
+	"errors"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+func newCgroup(log log.T, name string, limits contracts.ResourceLimits) (Cgroup, error) {
+	return nil, errors.New("resource limits are not supported on this platform")
+}
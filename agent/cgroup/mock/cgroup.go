@@ -0,0 +1,43 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cgroupmock implements the mock of the Cgroup interface
+package cgroupmock
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedCgroup struct {
+	mock.Mock
+}
+
+func (m *MockedCgroup) Command(name string, argv []string) (string, []string) {
+	args := m.Called(name, argv)
+	return args.String(0), args.Get(1).([]string)
+}
+
+func (m *MockedCgroup) AddProcess(pid int) error {
+	args := m.Called(pid)
+	return args.Error(0)
+}
+
+func (m *MockedCgroup) OOMKillCount() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockedCgroup) Destroy() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -25,56 +25,84 @@ import (
 )
 
 // TODO:MF: make errors more like ssm-cli: error: <arg type>: <error>?
-// RunCommand parses and executes a single command line
-func RunCommand(args []string, out io.Writer) {
+// RunCommand parses and executes a single command line, and returns the exit code of the cli
+func RunCommand(args []string, out io.Writer) int {
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	if len(args) < 2 {
 		displayUsage(out)
-		return
+		return cliutil.ExitCodeUsage
 	}
-	err, _, command, subcommands, parameters := parseCommand(args)
+	err, options, command, subcommands, parameters := parseCommand(args)
+	output := options[cliutil.OutputFlag]
 	if err != nil {
-		displayUsage(out)
-		fmt.Fprintln(out, err.Error())
-		return
+		return displayError(out, output, &cliutil.CliError{ExitCode: cliutil.ExitCodeUsage, Message: err.Error()})
 	}
 	if cmd, exists := cliutil.CliCommands[command]; exists {
 		if cliutil.IsHelp(subcommands, parameters) {
 			fmt.Fprint(out, cmd.Help())
-		} else {
-			cmdErr, result := cmd.Execute(subcommands, parameters)
-			if cmdErr != nil {
-				displayUsage(out)
-				fmt.Fprintln(out, cmdErr.Error())
-			} else {
-				fmt.Fprintln(out, result)
-			}
+			return cliutil.ExitCodeSuccess
+		}
+		cmdErr, result := cmd.Execute(subcommands, parameters)
+		if cmdErr != nil {
+			return displayError(out, output, cmdErr)
 		}
+		formatted, err := cliutil.FormatResult(output, result)
+		if err != nil {
+			return displayError(out, output, err)
+		}
+		fmt.Fprintln(out, formatted)
+		return cliutil.ExitCodeSuccess
 	} else if command == cliutil.HelpFlag {
 		displayHelp(out)
+		return cliutil.ExitCodeSuccess
+	} else if output == cliutil.OutputJson {
+		return displayError(out, output, &cliutil.CliError{ExitCode: cliutil.ExitCodeUsage, Message: fmt.Sprintf("Invalid command %v", command)})
 	} else {
 		displayUsage(out)
 		fmt.Fprintf(out, "\nInvalid command %v.  The following commands are supported:\n\n", command)
 		displayValidCommands(out)
+		return cliutil.ExitCodeUsage
+	}
+}
+
+// displayError prints the error in the output format, with the usage if the command line is invalid, and returns the exit code of the error
+func displayError(out io.Writer, output string, err error) int {
+	if output != cliutil.OutputJson && cliutil.IsUsage(err) {
+		displayUsage(out)
 	}
+	fmt.Fprintln(out, cliutil.FormatError(output, err))
+	return cliutil.ExitCode(err)
 }
 
 // parseCommand turns the command line arguments into a command name and a map of flag names and values
 // args format should be ssm-cli [options] <command> <subcommand> [<subcommand> ...] [parameters]
-func parseCommand(args []string) (err error, options []string, command string, subcommands []string, parameters map[string][]string) {
+// the global options are also accepted among the parameters, the output option defaults to text
+func parseCommand(args []string) (err error, options map[string]string, command string, subcommands []string, parameters map[string][]string) {
 	// TODO:MF: aws cli is case-sensitive on things other than parameter value, I propose we be case-insensitive on all non-values
 
 	argCount := len(args)
 	pos := 1
 
 	// Options
-	options = make([]string, 0)
-	for _, val := range args[pos:] {
-		if !cliutil.IsFlag(val) {
-			break
+	options = map[string]string{cliutil.OutputFlag: cliutil.DefaultOutput}
+	for pos < argCount && cliutil.IsFlag(args[pos]) {
+		option := cliutil.GetFlag(args[pos])
+		if option != cliutil.OutputFlag {
+			err = fmt.Errorf("unknown option %v", args[pos])
+			return
 		}
-		options = append(options, cliutil.GetFlag(val))
-		pos++
+		if pos+1 >= argCount {
+			err = fmt.Errorf("expected 1 value for option %v", cliutil.FormatFlag(option))
+			return
+		}
+		options[option] = strings.ToLower(args[pos+1])
+		pos += 2
+	}
+	if !cliutil.IsOutputFormat(options[cliutil.OutputFlag]) {
+		err = fmt.Errorf("invalid value %v for option %v, expected one of %v",
+			options[cliutil.OutputFlag], cliutil.FormatFlag(cliutil.OutputFlag), strings.Join(cliutil.OutputFormats, ", "))
+		options[cliutil.OutputFlag] = cliutil.DefaultOutput
+		return
 	}
 
 	// Command
@@ -94,7 +122,7 @@ func parseCommand(args []string) (err error, options []string, command string, s
 		if cliutil.IsFlag(val) {
 			break
 		}
-		subcommands = append(options, strings.ToLower(val))
+		subcommands = append(subcommands, strings.ToLower(val))
 		pos++
 	}
 
@@ -104,7 +132,7 @@ func parseCommand(args []string) (err error, options []string, command string, s
 	}
 	parameters = make(map[string][]string)
 	var parameterName string
-	for _, val := range args[2:] {
+	for _, val := range args[pos:] {
 		if cliutil.IsFlag(val) {
 			parameterName = cliutil.GetFlag(val)
 			if parameterName == "" {
@@ -122,5 +150,16 @@ func parseCommand(args []string) (err error, options []string, command string, s
 			parameters[parameterName] = append(parameters[parameterName], val)
 		}
 	}
+
+	// the output option placed after the command
+	if values, exists := parameters[cliutil.OutputFlag]; exists {
+		delete(parameters, cliutil.OutputFlag)
+		if len(values) != 1 || !cliutil.IsOutputFormat(strings.ToLower(values[0])) {
+			err = fmt.Errorf("invalid value %v for option %v, expected one of %v",
+				strings.Join(values, " "), cliutil.FormatFlag(cliutil.OutputFlag), strings.Join(cliutil.OutputFormats, ", "))
+			return
+		}
+		options[cliutil.OutputFlag] = strings.ToLower(values[0])
+	}
 	return
 }
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
 )
@@ -49,6 +50,14 @@ func displayHelp(out io.Writer) {
 	fmt.Fprintf(out, "%v\n", cliutil.SsmCliName)
 	fmt.Fprintf(out, "Submit commands directly to the local amazon-ssm-agent service.\n")
 	fmt.Fprintf(out, "You will need to have admin rights to run most commands.\n\n")
+	fmt.Fprintf(out, "Options:\n")
+	fmt.Fprintf(out, "  %v %v  format of the result, %v by default\n\n",
+		cliutil.FormatFlag(cliutil.OutputFlag), strings.Join(cliutil.OutputFormats, "|"), cliutil.DefaultOutput)
+	fmt.Fprintf(out, "Exit codes:\n")
+	fmt.Fprintf(out, "  %v  success\n", cliutil.ExitCodeSuccess)
+	fmt.Fprintf(out, "  %v  the command failed\n", cliutil.ExitCodeFailure)
+	fmt.Fprintf(out, "  %v  the command line is invalid\n", cliutil.ExitCodeUsage)
+	fmt.Fprintf(out, "  %v  the command, association or document was not found\n\n", cliutil.ExitCodeNotFound)
 	fmt.Fprintf(out, "Available commands:\n")
 	displayValidCommands(out)
 }
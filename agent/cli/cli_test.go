@@ -16,14 +16,121 @@ package cli
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
 	"github.com/stretchr/testify/assert"
 )
 
+type testResult struct {
+	Names []string
+}
+
+func (r testResult) Text() string {
+	return cliutil.FormatRows(r.rows())
+}
+
+func (r testResult) Table() ([]string, [][]string) {
+	return []string{"NAME"}, r.rows()
+}
+
+func (r testResult) rows() (rows [][]string) {
+	for _, name := range r.Names {
+		rows = append(rows, []string{name})
+	}
+	return
+}
+
+// testCommand returns its parameter values as names, fails with the error parameter, or is not found
+type testCommand struct{}
+
+func (testCommand) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	if values, exists := parameters["error"]; exists {
+		return errors.New(values[0]), nil
+	}
+	if _, exists := parameters["missing"]; exists {
+		return cliutil.NewNotFoundError("not found"), nil
+	}
+	if _, exists := parameters["invalid"]; exists {
+		return cliutil.NewUsageError([]string{"invalid"}), nil
+	}
+	return nil, testResult{Names: parameters["name"]}
+}
+
+func (testCommand) Help() string { return "test help" }
+
+func (testCommand) Name() string { return "test-command" }
+
+func init() {
+	cliutil.Register(testCommand{})
+}
+
 func TestCliUsage(t *testing.T) {
 	var buffer bytes.Buffer
 	args := []string{"ssm-cli"}
 	RunCommand(args, &buffer)
 	assert.Contains(t, buffer.String(), "usage")
 }
+
+func TestParseCommand(t *testing.T) {
+	err, options, command, subcommands, parameters := parseCommand([]string{"ssm-cli", "--output", "JSON", "Get-Thing", "sub", "--id", "1", "--flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"output": "json"}, options)
+	assert.Equal(t, "get-thing", command)
+	assert.Equal(t, []string{"sub"}, subcommands)
+	assert.Equal(t, map[string][]string{"id": {"1"}, "flag": {}}, parameters)
+
+	// the output option is also accepted after the command
+	err, options, _, _, parameters = parseCommand([]string{"ssm-cli", "get-thing", "--id", "1", "--output", "table"})
+	assert.NoError(t, err)
+	assert.Equal(t, "table", options[cliutil.OutputFlag])
+	assert.Equal(t, map[string][]string{"id": {"1"}}, parameters)
+
+	err, options, _, _, _ = parseCommand([]string{"ssm-cli", "get-thing"})
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.OutputText, options[cliutil.OutputFlag])
+
+	err, _, _, _, _ = parseCommand([]string{"ssm-cli", "--output", "xml", "get-thing"})
+	assert.Error(t, err)
+	err, _, _, _, _ = parseCommand([]string{"ssm-cli", "--unknown", "value", "get-thing"})
+	assert.Error(t, err)
+	err, _, _, _, _ = parseCommand([]string{"ssm-cli", "get-thing", "--output"})
+	assert.Error(t, err)
+}
+
+func TestRunCommandOutput(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.Equal(t, cliutil.ExitCodeSuccess, RunCommand([]string{"ssm-cli", "test-command", "--name", "a", "b"}, &buffer))
+	assert.Equal(t, "a\nb\n", buffer.String())
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeSuccess, RunCommand([]string{"ssm-cli", "--output", "table", "test-command", "--name", "a"}, &buffer))
+	assert.Equal(t, "NAME\na\n", buffer.String())
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeSuccess, RunCommand([]string{"ssm-cli", "--output", "json", "test-command", "--name", "a"}, &buffer))
+	assert.JSONEq(t, `{"Names": ["a"]}`, buffer.String())
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.Equal(t, cliutil.ExitCodeFailure, RunCommand([]string{"ssm-cli", "test-command", "--error", "boom"}, &buffer))
+	assert.Equal(t, "boom\n", buffer.String())
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeNotFound, RunCommand([]string{"ssm-cli", "--output", "json", "test-command", "--missing"}, &buffer))
+	assert.JSONEq(t, `{"Error": "not found", "ExitCode": 3}`, buffer.String())
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeUsage, RunCommand([]string{"ssm-cli", "test-command", "--invalid"}, &buffer))
+	assert.Contains(t, buffer.String(), "usage")
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeUsage, RunCommand([]string{"ssm-cli", "--output", "json", "unknown-command"}, &buffer))
+	assert.JSONEq(t, `{"Error": "Invalid command unknown-command", "ExitCode": 2}`, buffer.String())
+
+	buffer.Reset()
+	assert.Equal(t, cliutil.ExitCodeSuccess, RunCommand([]string{"ssm-cli", "test-command", "help"}, &buffer))
+	assert.Equal(t, "test help", buffer.String())
+}
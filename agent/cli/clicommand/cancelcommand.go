@@ -0,0 +1,199 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/twinj/uuid"
+)
+
+const (
+	cancelCommand          = "cancel-command"
+	cancelCommandCommandID = "command-id"
+)
+
+const cancelCommandHelp = `NAME:
+    {{.CancelCommandName}}
+
+DESCRIPTION
+    Cancels a command submitted with {{.SendCommandName}}, the commands sent by the Systems Manager service are cancelled through the service.
+
+SYNOPSIS
+    {{.CancelCommandName}}
+    {{.CommandIdFlag}} <value>
+
+PARAMETERS
+    {{.CommandIdFlag}} (string) Command ID from {{.SendCommandName}}.
+
+EXAMPLES
+    This example cancels a command run by the local amazon-ssm-agent service.
+
+    Command:
+
+      {{.SsmCliName}} {{.CancelCommandName}} {{.CommandIdFlag}} 01234567-890a-bcde-f012-34567890abcd
+
+    Output:
+
+      successfully submitted cancel request with command id: 12345678-90ab-cdef-0123-4567890abcde
+
+OUTPUT
+    Success message with the command id of the cancel request or failure message, the result of the cancel request is
+    reported by {{.GetCommandName}} for the cancelled command
+`
+
+type cancelCommandHelpParams struct {
+	SsmCliName        string
+	CancelCommandName string
+	SendCommandName   string
+	GetCommandName    string
+	CommandIdFlag     string
+}
+
+func init() {
+	cliutil.Register(&CancelCommand{})
+}
+
+type CancelCommand struct {
+	helpText string
+}
+
+// SubmittedCancel is the result of the cancel-command cli command
+type SubmittedCancel struct {
+	CommandId          string
+	CancelledCommandId string
+}
+
+// Text returns the success message with the command id of the cancel request
+func (r SubmittedCancel) Text() string {
+	return fmt.Sprintf("successfully submitted cancel request with command id: %v", r.CommandId)
+}
+
+// Execute validates and executes the cancel-command cli command
+func (c *CancelCommand) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	validation, commandID := c.validateCancelCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return cliutil.NewUsageError(validation), nil
+	}
+
+	record, found := findCommandInvocation(commandID)
+	if !found {
+		return cliutil.NewNotFoundError("No command found for command ID %v", commandID), nil
+	}
+	if record.DocumentType != contracts.SendCommandOffline {
+		return fmt.Errorf("command %v was not submitted with %v and can only be cancelled through the service", commandID, sendCommand), nil
+	}
+	if !record.EndDateTime.IsZero() {
+		return fmt.Errorf("command %v already ended with status %v", commandID, record.Status), nil
+	}
+
+	if err, requestName := c.submitCancelRequest(commandID); err != nil {
+		return err, nil
+	} else if err, cancelCommandID := c.waitForCancelStatus(requestName); err != nil {
+		return err, nil
+	} else {
+		return nil, SubmittedCancel{cancelCommandID, commandID}
+	}
+}
+
+// Help prints help for the cancel-command cli command
+func (c *CancelCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("CancelCommandHelp").Parse(cancelCommandHelp)
+		params := cancelCommandHelpParams{cliutil.SsmCliName, cancelCommand, sendCommand, getInvocation, cliutil.FormatFlag(cancelCommandCommandID)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (CancelCommand) Name() string {
+	return cancelCommand
+}
+
+// validateCancelCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (CancelCommand) validateCancelCommandInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", cancelCommand, subcommands), "")
+		return validation, ""
+	}
+
+	if _, exists := parameters[cancelCommandCommandID]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(cancelCommandCommandID)))
+	} else if len(parameters[cancelCommandCommandID]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(cancelCommandCommandID)))
+	} else {
+		// must be a 36 character UUID
+		commandID = parameters[cancelCommandCommandID][0]
+		if commandIdLen := len(commandID); commandIdLen != 36 {
+			validation = append(validation,
+				fmt.Sprintf("Invalid length for parameter %v.  Length was %v should be 36",
+					cliutil.FormatFlag(cancelCommandCommandID), commandIdLen))
+		}
+	}
+
+	for key := range parameters {
+		if key != cancelCommandCommandID {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, commandID
+}
+
+// submitCancelRequest writes the cancel request to the folder watched by the offline service
+func (CancelCommand) submitCancelRequest(commandID string) (error, string) {
+	requestName := uuid.NewV4().String()
+	requestPath := filepath.Join(appconfig.LocalCommandRootCancel, requestName)
+
+	content, err := jsonutil.Marshal(messageContracts.OfflineCancelRequest{CommandID: commandID})
+	if err != nil {
+		return err, ""
+	}
+	if err := fileutil.MakeDirs(appconfig.LocalCommandRootCancel); err != nil {
+		return errors.New("failed to submit cancel request"), ""
+	} else if err := fileutil.WriteAllText(requestPath, content); err != nil {
+		return err, ""
+	}
+	return nil, requestName
+}
+
+// waitForCancelStatus waits for the offline service to pick up the cancel request, and returns the id it gave to the cancel command
+func (CancelCommand) waitForCancelStatus(requestName string) (error, string) {
+	processed, valid, commandId := waitForDocumentProcessed(appconfig.LocalCommandRootCancel, requestName)
+	if !processed {
+		return errors.New("failed to submit cancel request: timed out"), ""
+	}
+	if !valid {
+		if reason := invalidDocumentReason(requestName); reason != "" {
+			return fmt.Errorf("failed to submit cancel request: request was invalid: %v", reason), ""
+		}
+		return errors.New("failed to submit cancel request: request was invalid"), ""
+	}
+	return nil, commandId
+}
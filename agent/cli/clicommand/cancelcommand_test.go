@@ -0,0 +1,81 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCommandID = "01234567-890a-bcde-f012-34567890abcd"
+
+func TestValidateCancelCommandInput(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subcommands []string
+		parameters  map[string][]string
+		validation  []string
+		commandID   string
+	}{
+		{
+			name:       "valid command id",
+			parameters: map[string][]string{"command-id": {testCommandID}},
+			validation: []string{},
+			commandID:  testCommandID,
+		},
+		{
+			name:        "subcommand",
+			subcommands: []string{"foo"},
+			parameters:  map[string][]string{"command-id": {testCommandID}},
+			validation:  []string{"cancel-command does not support subcommand [foo]", ""},
+		},
+		{
+			name:       "missing command id",
+			parameters: map[string][]string{},
+			validation: []string{"--command-id is required"},
+		},
+		{
+			name:       "several command ids",
+			parameters: map[string][]string{"command-id": {testCommandID, testCommandID}},
+			validation: []string{"expected 1 value for parameter --command-id"},
+		},
+		{
+			name:       "short command id",
+			parameters: map[string][]string{"command-id": {"01234567"}},
+			validation: []string{"Invalid length for parameter --command-id.  Length was 8 should be 36"},
+			commandID:  "01234567",
+		},
+		{
+			name:       "long command id",
+			parameters: map[string][]string{"command-id": {testCommandID + "0"}},
+			validation: []string{"Invalid length for parameter --command-id.  Length was 37 should be 36"},
+			commandID:  testCommandID + "0",
+		},
+		{
+			name:       "unknown parameter",
+			parameters: map[string][]string{"command-id": {testCommandID}, "foo": {"bar"}},
+			validation: []string{"unknown parameter --foo"},
+			commandID:  testCommandID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validation, commandID := CancelCommand{}.validateCancelCommandInput(tc.subcommands, tc.parameters)
+			assert.Equal(t, tc.validation, validation)
+			assert.Equal(t, tc.commandID, commandID)
+		})
+	}
+}
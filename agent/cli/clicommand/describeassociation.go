@@ -0,0 +1,188 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+)
+
+const (
+	describeAssociation              = "describe-association"
+	describeAssociationAssociationID = "association-id"
+	// describeAssociationExecutions is the number of recent executions shown
+	describeAssociationExecutions = 5
+)
+
+const describeAssociationHelp = `NAME:
+    {{.DescribeAssociationName}}
+
+DESCRIPTION
+    Shows the schedule of an association of the local amazon-ssm-agent service, and its recent executions.
+
+SYNOPSIS
+    {{.DescribeAssociationName}}
+    {{.AssociationIdFlag}} <value>
+
+PARAMETERS
+    {{.AssociationIdFlag}} (string) Association ID, as listed by {{.ListAssociationsName}}.
+
+EXAMPLES
+    This example describes an association.
+
+    Command:
+
+      {{.SsmCliName}} {{.DescribeAssociationName}} {{.AssociationIdFlag}} 01234567-890a-bcde-f012-34567890abcd
+
+    Output:
+
+      AssociationId: 01234567-890a-bcde-f012-34567890abcd
+      Name: AWS-UpdateSSMAgent
+      DocumentVersion: 1
+      ScheduleExpression: rate(30 minutes)
+      Status: Success
+      LastExecutionDate: 2018-03-01T10:00:00.000Z
+      NextScheduledDate: 2018-03-01T10:30:00.000Z
+
+      Recent executions:
+        2018-03-01T10:00:00.000Z  Success
+
+OUTPUT
+    Schedule and last status of the association as last refreshed by the agent, and the status of its recent executions
+`
+
+type describeAssociationHelpParams struct {
+	SsmCliName              string
+	DescribeAssociationName string
+	ListAssociationsName    string
+	AssociationIdFlag       string
+}
+
+func init() {
+	cliutil.Register(&DescribeAssociation{})
+}
+
+type DescribeAssociation struct {
+	helpText string
+}
+
+// AssociationDescription is the result of the describe-association cli command
+type AssociationDescription struct {
+	AssociationSummary
+	ScheduleExpression string
+	RecentExecutions   []AssociationExecution
+}
+
+// AssociationExecution is the status of an execution of the association
+type AssociationExecution struct {
+	StartDateTime *time.Time `json:",omitempty"`
+	EndDateTime   *time.Time `json:",omitempty"`
+	Status        string
+}
+
+// Text returns the schedule of the association and its recent executions
+func (r AssociationDescription) Text() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "AssociationId: %v\n", r.AssociationId)
+	fmt.Fprintf(buf, "Name: %v\n", r.Name)
+	fmt.Fprintf(buf, "DocumentVersion: %v\n", r.DocumentVersion)
+	fmt.Fprintf(buf, "ScheduleExpression: %v\n", r.ScheduleExpression)
+	fmt.Fprintf(buf, "Status: %v\n", r.Status)
+	fmt.Fprintf(buf, "LastExecutionDate: %v\n", formatOptionalTime(r.LastExecutionDate))
+	fmt.Fprintf(buf, "NextScheduledDate: %v\n", formatOptionalTime(r.NextScheduledDate))
+	if len(r.RecentExecutions) > 0 {
+		fmt.Fprintf(buf, "\nRecent executions:\n")
+		for _, execution := range r.RecentExecutions {
+			fmt.Fprintf(buf, "  %v  %v\n", formatOptionalTime(execution.StartDateTime), execution.Status)
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Execute validates and executes the describe-association cli command
+func (c *DescribeAssociation) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	validation, associationID := c.validateDescribeAssociationInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return cliutil.NewUsageError(validation), nil
+	}
+
+	for _, schedule := range loadSchedules() {
+		if schedule.AssociationID == associationID {
+			result := AssociationDescription{
+				AssociationSummary: newAssociationSummary(schedule),
+				ScheduleExpression: schedule.ScheduleExpression,
+				RecentExecutions:   []AssociationExecution{},
+			}
+			for _, record := range loadInvocations(history.Filter{AssociationID: associationID, Limit: describeAssociationExecutions}, contracts.Association) {
+				result.RecentExecutions = append(result.RecentExecutions, AssociationExecution{
+					StartDateTime: optionalTime(record.StartDateTime),
+					EndDateTime:   optionalTime(record.EndDateTime),
+					Status:        string(record.Status),
+				})
+			}
+			return nil, result
+		}
+	}
+	return cliutil.NewNotFoundError("No association found for association ID %v", associationID), nil
+}
+
+// Help prints help for the describe-association cli command
+func (c *DescribeAssociation) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("DescribeAssociationHelp").Parse(describeAssociationHelp)
+		params := describeAssociationHelpParams{cliutil.SsmCliName, describeAssociation, listAssociations, cliutil.FormatFlag(describeAssociationAssociationID)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (DescribeAssociation) Name() string {
+	return describeAssociation
+}
+
+// validateDescribeAssociationInput checks the subcommands and parameters for required values and unsupported values
+func (DescribeAssociation) validateDescribeAssociationInput(subcommands []string, parameters map[string][]string) (validation []string, associationID string) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", describeAssociation, subcommands), "")
+		return validation, ""
+	}
+
+	if _, exists := parameters[describeAssociationAssociationID]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(describeAssociationAssociationID)))
+	} else if len(parameters[describeAssociationAssociationID]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(describeAssociationAssociationID)))
+	} else {
+		associationID = parameters[describeAssociationAssociationID][0]
+	}
+
+	for key := range parameters {
+		if key != describeAssociationAssociationID {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, associationID
+}
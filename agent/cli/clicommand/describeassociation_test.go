@@ -0,0 +1,67 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDescribeAssociationInput(t *testing.T) {
+	testCases := []struct {
+		name          string
+		subcommands   []string
+		parameters    map[string][]string
+		validation    []string
+		associationID string
+	}{
+		{
+			name:          "association id",
+			parameters:    map[string][]string{"association-id": {"association"}},
+			validation:    []string{},
+			associationID: "association",
+		},
+		{
+			name:        "subcommand",
+			subcommands: []string{"foo"},
+			parameters:  map[string][]string{"association-id": {"association"}},
+			validation:  []string{"describe-association does not support subcommand [foo]", ""},
+		},
+		{
+			name:       "missing association id",
+			parameters: map[string][]string{},
+			validation: []string{"--association-id is required"},
+		},
+		{
+			name:       "several association ids",
+			parameters: map[string][]string{"association-id": {"association", "other"}},
+			validation: []string{"expected 1 value for parameter --association-id"},
+		},
+		{
+			name:          "unknown parameter",
+			parameters:    map[string][]string{"association-id": {"association"}, "foo": {"bar"}},
+			validation:    []string{"unknown parameter --foo"},
+			associationID: "association",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validation, associationID := DescribeAssociation{}.validateDescribeAssociationInput(tc.subcommands, tc.parameters)
+			assert.Equal(t, tc.validation, validation)
+			assert.Equal(t, tc.associationID, associationID)
+		})
+	}
+}
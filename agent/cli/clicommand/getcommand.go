@@ -16,10 +16,8 @@ package clicommand
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"path"
-	"strings"
 	"text/template"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -78,12 +76,23 @@ type GetOfflineCommand struct {
 	helpText string
 }
 
+// OfflineCommandStatus is the result of the get-offline-command-invocation cli command
+type OfflineCommandStatus struct {
+	CommandId string
+	Status    string
+}
+
+// Text returns the status of the command
+func (r OfflineCommandStatus) Text() string {
+	return r.Status
+}
+
 // Execute validates and executes the get-offline-command-invocation cli command
-func (c *GetOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+func (c *GetOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
 	validation, commandID, showDetails := c.validateGetCommandInput(subcommands, parameters)
 	// return validation errors if any were found
 	if len(validation) > 0 {
-		return errors.New(strings.Join(validation, "\n")), ""
+		return cliutil.NewUsageError(validation), nil
 	}
 
 	return c.getCommandStatus(commandID, showDetails)
@@ -145,24 +154,24 @@ func (GetOfflineCommand) validateGetCommandInput(subcommands []string, parameter
 }
 
 // getCommandStatus looks for the command in the local orchestration folders and returns status and optionally details
-func (c *GetOfflineCommand) getCommandStatus(commandID string, showDetails bool) (error, string) {
+func (c *GetOfflineCommand) getCommandStatus(commandID string, showDetails bool) (error, cliutil.Result) {
 	// Look for file with commandID as name in each orchestration folder
 	// If found, return status (or lots of details if showDetails is set)
 	if c.isCommandCompleted(commandID) {
-		return nil, "Complete"
+		return nil, OfflineCommandStatus{commandID, "Complete"}
 	}
 	if c.isCommandInState(appconfig.DefaultLocationOfPending, commandID) {
-		return nil, "Pending"
+		return nil, OfflineCommandStatus{commandID, "Pending"}
 	}
 	if c.isCommandInState(appconfig.DefaultLocationOfCurrent, commandID) {
-		return nil, "In Progress"
+		return nil, OfflineCommandStatus{commandID, "In Progress"}
 	}
 	if c.isCommandInState(appconfig.DefaultLocationOfCorrupt, commandID) {
-		return nil, "Corrupt"
+		return nil, OfflineCommandStatus{commandID, "Corrupt"}
 	}
 
 	// If not found, return error
-	return fmt.Errorf("No status found for command ID %v", commandID), ""
+	return cliutil.NewNotFoundError("No status found for command ID %v", commandID), nil
 }
 
 func (c *GetOfflineCommand) isCommandCompleted(commandID string) bool {
@@ -0,0 +1,274 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+)
+
+const (
+	getInvocation          = "get-command-invocation"
+	getInvocationCommandID = "command-id"
+	getInvocationDetails   = "details"
+)
+
+const getInvocationHelp = `NAME:
+    {{.GetInvocationName}}
+
+DESCRIPTION
+    Shows the status of a command executed by the local amazon-ssm-agent service, and optionally the status and output of each step.
+
+SYNOPSIS
+    {{.GetInvocationName}}
+    {{.CommandIdFlag}} <value>
+    [{{.DetailsFlag}}]
+
+PARAMETERS
+    {{.CommandIdFlag}} (string) Command ID of the command, as listed by {{.ListCommandsName}}.
+
+    {{.DetailsFlag}} (boolean) Shows the status, standard output and standard error of each step.
+
+EXAMPLES
+    This example shows the steps of a command.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetInvocationName}} {{.CommandIdFlag}} 01234567-890a-bcde-f012-34567890abcd {{.DetailsFlag}}
+
+    Output:
+
+      CommandId: 01234567-890a-bcde-f012-34567890abcd
+      DocumentName: AWS-RunShellScript
+      Status: Success
+      StartDateTime: 2018-03-01T10:00:00.000Z
+      EndDateTime: 2018-03-01T10:00:05.000Z
+      OrchestrationDirectory: /var/lib/amazon/ssm/i-01234567/document/orchestration/01234567-890a-bcde-f012-34567890abcd
+
+      Step: runShellScript
+        Status: Success
+        ResponseCode: 0
+        StartDateTime: 2018-03-01T10:00:00.000Z
+        EndDateTime: 2018-03-01T10:00:05.000Z
+        StandardOutput:
+          hello
+        StandardError:
+
+OUTPUT
+    Status of the command, and with {{.DetailsFlag}} the status and output of each step
+`
+
+type getInvocationHelpParams struct {
+	SsmCliName        string
+	GetInvocationName string
+	ListCommandsName  string
+	CommandIdFlag     string
+	DetailsFlag       string
+}
+
+func init() {
+	cliutil.Register(&GetCommandInvocation{})
+}
+
+type GetCommandInvocation struct {
+	helpText string
+}
+
+// CommandInvocation is the result of the get-command-invocation cli command
+type CommandInvocation struct {
+	CommandId              string
+	DocumentName           string
+	Status                 string
+	StartDateTime          *time.Time `json:",omitempty"`
+	EndDateTime            *time.Time `json:",omitempty"`
+	OrchestrationDirectory string
+	// Steps are only set with the details flag
+	Steps []CommandInvocationStep `json:",omitempty"`
+}
+
+// CommandInvocationStep is the status and output of a step of the command
+type CommandInvocationStep struct {
+	Name           string
+	Action         string
+	Status         string
+	ResponseCode   int
+	StartDateTime  *time.Time `json:",omitempty"`
+	EndDateTime    *time.Time `json:",omitempty"`
+	StandardOutput string
+	StandardError  string
+}
+
+// newCommandInvocation builds the result from the record of the command, with its steps if showDetails is set
+func newCommandInvocation(record history.Record, showDetails bool) CommandInvocation {
+	invocation := CommandInvocation{
+		CommandId:              record.CommandID,
+		DocumentName:           record.DocumentName,
+		Status:                 string(record.Status),
+		StartDateTime:          optionalTime(record.StartDateTime),
+		EndDateTime:            optionalTime(record.EndDateTime),
+		OrchestrationDirectory: record.OrchestrationDirectory,
+	}
+	if !showDetails {
+		return invocation
+	}
+	// the final state of the document keeps the step output truncated like the output sent to the service,
+	// the full output is read from the files of the orchestration directory
+	outputConfig := iohandler.DefaultOutputConfig()
+	invocation.Steps = make([]CommandInvocationStep, 0, len(record.DocumentState.InstancePluginsInformation))
+	for _, plugin := range record.DocumentState.InstancePluginsInformation {
+		result := plugin.Result
+		invocation.Steps = append(invocation.Steps, CommandInvocationStep{
+			Name:           plugin.Id,
+			Action:         plugin.Name,
+			Status:         string(result.Status),
+			ResponseCode:   result.Code,
+			StartDateTime:  optionalTime(result.StartDateTime),
+			EndDateTime:    optionalTime(result.EndDateTime),
+			StandardOutput: readStepOutput(record.OrchestrationDirectory, plugin, outputConfig.StdoutFileName, result.StandardOutput),
+			StandardError:  readStepOutput(record.OrchestrationDirectory, plugin, outputConfig.StderrFileName, result.StandardError),
+		})
+	}
+	return invocation
+}
+
+// readStepOutput reads the output file of the step from the orchestration directory, the steps of the 1.x documents write one file per property.
+// It returns the truncated output of the document state if no file is found, e.g. once the orchestration directory was cleaned up.
+func readStepOutput(orchestrationDirectory string, plugin contracts.PluginState, fileName string, truncatedOutput string) string {
+	if orchestrationDirectory == "" {
+		return truncatedOutput
+	}
+	pluginDirectory := fileutil.BuildPath(orchestrationDirectory, plugin.Name)
+	var paths []string
+	if plugin.Configuration.PluginID != plugin.Configuration.PluginName {
+		paths = []string{filepath.Join(fileutil.BuildPath(pluginDirectory, plugin.Id), fileName)}
+	} else {
+		paths, _ = filepath.Glob(filepath.Join(pluginDirectory, "*", fileName))
+		sort.Strings(paths)
+	}
+
+	var outputs []string
+	for _, path := range paths {
+		if content, err := ioutil.ReadFile(path); err == nil {
+			outputs = append(outputs, string(content))
+		}
+	}
+	if len(outputs) == 0 {
+		return truncatedOutput
+	}
+	return strings.Join(outputs, "\n")
+}
+
+// Text returns the status of the command, and the status and output of its steps
+func (r CommandInvocation) Text() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "CommandId: %v\n", r.CommandId)
+	fmt.Fprintf(buf, "DocumentName: %v\n", r.DocumentName)
+	fmt.Fprintf(buf, "Status: %v\n", r.Status)
+	fmt.Fprintf(buf, "StartDateTime: %v\n", formatOptionalTime(r.StartDateTime))
+	fmt.Fprintf(buf, "EndDateTime: %v\n", formatOptionalTime(r.EndDateTime))
+	fmt.Fprintf(buf, "OrchestrationDirectory: %v\n", r.OrchestrationDirectory)
+	for _, step := range r.Steps {
+		fmt.Fprintf(buf, "\nStep: %v\n", step.Name)
+		fmt.Fprintf(buf, "  Status: %v\n", step.Status)
+		fmt.Fprintf(buf, "  ResponseCode: %v\n", step.ResponseCode)
+		fmt.Fprintf(buf, "  StartDateTime: %v\n", formatOptionalTime(step.StartDateTime))
+		fmt.Fprintf(buf, "  EndDateTime: %v\n", formatOptionalTime(step.EndDateTime))
+		fmt.Fprintf(buf, "  StandardOutput:\n%v", indent(step.StandardOutput))
+		fmt.Fprintf(buf, "  StandardError:\n%v", indent(step.StandardError))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Execute validates and executes the get-command-invocation cli command
+func (c *GetCommandInvocation) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	validation, commandID, showDetails := c.validateGetInvocationInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return cliutil.NewUsageError(validation), nil
+	}
+
+	record, found := findCommandInvocation(commandID)
+	if !found {
+		return cliutil.NewNotFoundError("No command found for command ID %v", commandID), nil
+	}
+	return nil, newCommandInvocation(record, showDetails)
+}
+
+// Help prints help for the get-command-invocation cli command
+func (c *GetCommandInvocation) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetCommandInvocationHelp").Parse(getInvocationHelp)
+		params := getInvocationHelpParams{cliutil.SsmCliName, getInvocation, listCommands,
+			cliutil.FormatFlag(getInvocationCommandID), cliutil.FormatFlag(getInvocationDetails)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetCommandInvocation) Name() string {
+	return getInvocation
+}
+
+// validateGetInvocationInput checks the subcommands and parameters for required values, format, and unsupported values
+func (GetCommandInvocation) validateGetInvocationInput(subcommands []string, parameters map[string][]string) (validation []string, commandID string, showDetails bool) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getInvocation, subcommands), "")
+		return validation, "", false
+	}
+
+	if _, exists := parameters[getInvocationCommandID]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(getInvocationCommandID)))
+	} else if len(parameters[getInvocationCommandID]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(getInvocationCommandID)))
+	} else {
+		commandID = parameters[getInvocationCommandID][0]
+	}
+	_, showDetails = parameters[getInvocationDetails]
+	if showDetails && len(parameters[getInvocationDetails]) > 0 {
+		validation = append(validation, fmt.Sprintf("flag %v should not have any values", cliutil.FormatFlag(getInvocationDetails)))
+	}
+
+	for key := range parameters {
+		if key != getInvocationCommandID && key != getInvocationDetails {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	return validation, commandID, showDetails
+}
+
+// indent indents every line of the output of a step
+func indent(output string) string {
+	if output == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	return "    " + strings.Join(lines, "\n    ") + "\n"
+}
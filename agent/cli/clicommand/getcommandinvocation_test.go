@@ -0,0 +1,84 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGetInvocationInput(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subcommands []string
+		parameters  map[string][]string
+		validation  []string
+		commandID   string
+		showDetails bool
+	}{
+		{
+			name:       "command id",
+			parameters: map[string][]string{"command-id": {testCommandID}},
+			validation: []string{},
+			commandID:  testCommandID,
+		},
+		{
+			name:        "command id with details",
+			parameters:  map[string][]string{"command-id": {testCommandID}, "details": {}},
+			validation:  []string{},
+			commandID:   testCommandID,
+			showDetails: true,
+		},
+		{
+			name:        "subcommand",
+			subcommands: []string{"foo"},
+			parameters:  map[string][]string{"command-id": {testCommandID}},
+			validation:  []string{"get-command-invocation does not support subcommand [foo]", ""},
+		},
+		{
+			name:        "missing command id",
+			parameters:  map[string][]string{"details": {}},
+			validation:  []string{"--command-id is required"},
+			showDetails: true,
+		},
+		{
+			name:       "several command ids",
+			parameters: map[string][]string{"command-id": {testCommandID, testCommandID}},
+			validation: []string{"expected 1 value for parameter --command-id"},
+		},
+		{
+			name:        "details with a value",
+			parameters:  map[string][]string{"command-id": {testCommandID}, "details": {"true"}},
+			validation:  []string{"flag --details should not have any values"},
+			commandID:   testCommandID,
+			showDetails: true,
+		},
+		{
+			name:       "unknown parameter",
+			parameters: map[string][]string{"command-id": {testCommandID}, "foo": {"bar"}},
+			validation: []string{"unknown parameter --foo"},
+			commandID:  testCommandID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validation, commandID, showDetails := GetCommandInvocation{}.validateGetInvocationInput(tc.subcommands, tc.parameters)
+			assert.Equal(t, tc.validation, validation)
+			assert.Equal(t, tc.commandID, commandID)
+			assert.Equal(t, tc.showDetails, showDetails)
+		})
+	}
+}
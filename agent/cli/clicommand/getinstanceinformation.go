@@ -16,9 +16,7 @@ package clicommand
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
-	"strings"
 	"text/template"
 
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
@@ -69,31 +67,43 @@ type GetInstanceInformationCommand struct {
 	helpText string
 }
 
+// InstanceInformation is the result of the get-instance-information cli command
+type InstanceInformation struct {
+	InstanceId     string `json:"instance-id"`
+	Region         string `json:"region"`
+	ReleaseVersion string `json:"release-version"`
+}
+
+// Text returns the instance information in JSON format
+func (r InstanceInformation) Text() string {
+	result, _ := jsonutil.Marshal(r)
+	return result
+}
+
 // Execute validates and executes the get-instance-information cli command
-func (c *GetInstanceInformationCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+func (c *GetInstanceInformationCommand) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
 	validation := c.validateGetInstanceInformationCommandInput(subcommands, parameters)
 	// return validation errors if any were found
 	if len(validation) > 0 {
-		return errors.New(strings.Join(validation, "\n")), ""
+		return cliutil.NewUsageError(validation), nil
 	}
 
-	information := make(map[string]string)
+	information := InstanceInformation{}
 	if region, err := platform.Region(); err != nil {
-		return err, ""
+		return err, nil
 	} else {
-		information["region"] = region
+		information.Region = region
 	}
 
 	if instanceId, err := platform.InstanceID(); err != nil {
-		return err, ""
+		return err, nil
 	} else {
-		information["instance-id"] = instanceId
+		information.InstanceId = instanceId
 	}
 
-	information["release-version"] = version.Version
+	information.ReleaseVersion = version.Version
 
-	result, _ := jsonutil.Marshal(information)
-	return nil, result
+	return nil, information
 }
 
 // Help prints help for the get-instance-information cli command
@@ -0,0 +1,156 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// statusPending is the status shown for the documents not picked up by the processor yet
+const statusPending = "Pending"
+
+// dataStorePath is the root folder of the instance folders, a variable to allow unittest to override
+var dataStorePath = appconfig.DefaultDataStorePath
+
+// instanceIDs returns the candidate instance folders of the data store
+// TODO:MF: Find a way to get the current instanceID instead of trying all possible folders
+func instanceIDs() []string {
+	dirs, _ := fileutil.GetDirectoryNames(dataStorePath)
+	return dirs
+}
+
+// loadInvocations returns the documents of the given types, the unfinished documents first then the history of the finished ones, the most recent first.
+// Since and Until bound the start time of the unfinished documents and the end time of the finished ones
+func loadInvocations(filter history.Filter, documentTypes ...contracts.DocumentType) (records []history.Record) {
+	store := history.NewFileStore(dataStorePath, appconfig.SsmCfg{})
+	logger := log.NewMockLog()
+	for _, instanceID := range instanceIDs() {
+		records = append(records, loadUnfinishedInvocations(instanceID, filter)...)
+		finished, _ := store.Query(logger, instanceID, history.Filter{
+			DocumentID:    filter.DocumentID,
+			CommandID:     filter.CommandID,
+			AssociationID: filter.AssociationID,
+			DocumentType:  filter.DocumentType,
+			Since:         filter.Since,
+			Until:         filter.Until,
+		})
+		records = append(records, finished...)
+	}
+
+	selected := make([]history.Record, 0, len(records))
+	for _, record := range records {
+		if isDocumentTypeOf(record.DocumentType, documentTypes) && (filter.Status == "" || strings.EqualFold(string(filter.Status), string(record.Status))) {
+			selected = append(selected, record)
+		}
+	}
+	if filter.Limit > 0 && len(selected) > filter.Limit {
+		selected = selected[:filter.Limit]
+	}
+	return selected
+}
+
+// loadUnfinishedInvocations returns the documents of the pending and current state folders of the instance, the most recent first
+func loadUnfinishedInvocations(instanceID string, filter history.Filter) (records []history.Record) {
+	for _, stateFolder := range []string{appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent} {
+		dir := filepath.Join(dataStorePath,
+			instanceID,
+			appconfig.DefaultDocumentRootDirName,
+			appconfig.DefaultLocationOfState,
+			stateFolder)
+		fileNames, _ := fileutil.GetFileNames(dir)
+		for _, fileName := range fileNames {
+			var docState contracts.DocumentState
+			if err := jsonutil.UnmarshalFile(filepath.Join(dir, fileName), &docState); err != nil {
+				continue
+			}
+			record := history.NewRecord(docState, time.Now())
+			// the document has not ended yet
+			record.EndDateTime = time.Time{}
+			if stateFolder == appconfig.DefaultLocationOfPending {
+				record.Status = statusPending
+			}
+			if (filter.DocumentID == "" || filter.DocumentID == record.DocumentID) &&
+				(filter.CommandID == "" || filter.CommandID == record.CommandID) &&
+				(filter.AssociationID == "" || filter.AssociationID == record.AssociationID) &&
+				(filter.DocumentType == "" || filter.DocumentType == record.DocumentType) &&
+				(filter.Since.IsZero() || !record.StartDateTime.Before(filter.Since)) &&
+				(filter.Until.IsZero() || !record.StartDateTime.After(filter.Until)) {
+				records = append(records, record)
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].StartDateTime.After(records[j].StartDateTime) })
+	return records
+}
+
+// findCommandInvocation returns the document of the command, unfinished or from the history
+func findCommandInvocation(commandID string) (history.Record, bool) {
+	records := loadInvocations(history.Filter{CommandID: commandID, Limit: 1}, commandDocumentTypes...)
+	if len(records) == 0 {
+		return history.Record{}, false
+	}
+	return records[0], true
+}
+
+// commandDocumentTypes are the document types of the commands, sent by the service or submitted locally
+var commandDocumentTypes = []contracts.DocumentType{contracts.SendCommand, contracts.SendCommandOffline}
+
+func isDocumentTypeOf(documentType contracts.DocumentType, documentTypes []contracts.DocumentType) bool {
+	if len(documentTypes) == 0 {
+		return true
+	}
+	for _, t := range documentTypes {
+		if t == documentType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime parses the time of a parameter, either an ISO 8601 date time or a date
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// optionalTime returns the time of a result, nil for the zero time
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// formatOptionalTime formats a time of the text output, an unset time is shown as a dash
+func formatOptionalTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return times.ToIso8601UTC(*t)
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package clicommand
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceID = "i-1234567890"
+
+// newTestDocumentState returns the state of a document with one step, the step did not start if start is zero
+func newTestDocumentState(documentID string, documentType contracts.DocumentType, status contracts.ResultStatus, start, end time.Time) contracts.DocumentState {
+	docState := contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{
+			DocumentID:     documentID,
+			InstanceID:     testInstanceID,
+			DocumentName:   "AWS-RunShellScript",
+			DocumentStatus: status,
+		},
+		DocumentType: documentType,
+		InstancePluginsInformation: []contracts.PluginState{
+			{
+				Name: "aws:runShellScript",
+				Id:   "runShellScript",
+				Result: contracts.PluginResult{
+					Status:        status,
+					StartDateTime: start,
+					EndDateTime:   end,
+				},
+			},
+		},
+	}
+	if documentType == contracts.Association {
+		docState.DocumentInformation.AssociationID = documentID
+	} else {
+		docState.DocumentInformation.CommandID = documentID
+	}
+	return docState
+}
+
+// writeTestDocumentState writes the document to the pending or current state folder of the test instance
+func writeTestDocumentState(t *testing.T, stateFolder string, docState contracts.DocumentState) {
+	dir := filepath.Join(dataStorePath, testInstanceID, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState, stateFolder)
+	assert.NoError(t, fileutil.MakeDirs(dir))
+	content, err := jsonutil.Marshal(docState)
+	assert.NoError(t, err)
+	assert.NoError(t, fileutil.WriteAllText(filepath.Join(dir, docState.DocumentInformation.DocumentID), content))
+}
+
+func TestLoadInvocations(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "clicommand")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	defer func(path string) { dataStorePath = path }(dataStorePath)
+	dataStorePath = tmpDir
+
+	base := time.Now().Add(-time.Hour).UTC()
+	writeTestDocumentState(t, appconfig.DefaultLocationOfPending,
+		newTestDocumentState("pending", contracts.SendCommandOffline, "", time.Time{}, time.Time{}))
+	writeTestDocumentState(t, appconfig.DefaultLocationOfCurrent,
+		newTestDocumentState("current", contracts.SendCommand, contracts.ResultStatusInProgress, base, time.Time{}))
+	store := history.NewFileStore(tmpDir, appconfig.SsmCfg{RunCommandLogsRetentionDurationHours: 24, AssociationLogsRetentionDurationHours: 24})
+	logger := log.NewMockLog()
+	store.Record(logger, newTestDocumentState("succeeded", contracts.SendCommandOffline, contracts.ResultStatusSuccess, base.Add(-3*time.Hour), base.Add(-2*time.Hour)))
+	store.Record(logger, newTestDocumentState("failed", contracts.SendCommand, contracts.ResultStatusFailed, base.Add(-time.Hour), base.Add(-30*time.Minute)))
+	store.Record(logger, newTestDocumentState("association", contracts.Association, contracts.ResultStatusSuccess, base.Add(-20*time.Minute), base.Add(-10*time.Minute)))
+
+	testCases := []struct {
+		name          string
+		filter        history.Filter
+		documentTypes []contracts.DocumentType
+		documentIDs   []string
+	}{
+		{
+			name:          "commands, unfinished first then most recent first",
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"pending", "current", "failed", "succeeded"},
+		},
+		{
+			name:        "every document type",
+			documentIDs: []string{"pending", "current", "association", "failed", "succeeded"},
+		},
+		{
+			name:          "associations",
+			documentTypes: []contracts.DocumentType{contracts.Association},
+			documentIDs:   []string{"association"},
+		},
+		{
+			name:          "pending status",
+			filter:        history.Filter{Status: "pending"},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"pending"},
+		},
+		{
+			name:          "finished status",
+			filter:        history.Filter{Status: "success"},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"succeeded"},
+		},
+		{
+			name:          "unfinished command id",
+			filter:        history.Filter{CommandID: "current"},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"current"},
+		},
+		{
+			name:          "finished command id",
+			filter:        history.Filter{CommandID: "failed"},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"failed"},
+		},
+		{
+			name:          "since, start time of the unfinished and end time of the finished",
+			filter:        history.Filter{Since: base.Add(-45 * time.Minute)},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"pending", "current", "failed"},
+		},
+		{
+			name:          "until, start time of the unfinished and end time of the finished",
+			filter:        history.Filter{Until: base.Add(-time.Hour)},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"succeeded"},
+		},
+		{
+			name:          "limit",
+			filter:        history.Filter{Limit: 3},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{"pending", "current", "failed"},
+		},
+		{
+			name:          "unknown command id",
+			filter:        history.Filter{CommandID: "unknown"},
+			documentTypes: commandDocumentTypes,
+			documentIDs:   []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			documentIDs := []string{}
+			for _, record := range loadInvocations(tc.filter, tc.documentTypes...) {
+				documentIDs = append(documentIDs, record.DocumentID)
+			}
+			assert.Equal(t, tc.documentIDs, documentIDs)
+		})
+	}
+
+	records := loadInvocations(history.Filter{DocumentID: "pending"})
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, statusPending, string(records[0].Status))
+	assert.True(t, records[0].EndDateTime.IsZero())
+
+	record, found := findCommandInvocation("current")
+	assert.True(t, found)
+	assert.Equal(t, contracts.ResultStatusInProgress, record.Status)
+	assert.Equal(t, base, record.StartDateTime.UTC())
+	assert.True(t, record.EndDateTime.IsZero())
+
+	_, found = findCommandInvocation("association")
+	assert.False(t, found)
+}
+
+func TestLoadInvocationsWithoutDataStore(t *testing.T) {
+	defer func(path string) { dataStorePath = path }(dataStorePath)
+	dataStorePath = filepath.Join(os.TempDir(), "clicommand-missing")
+
+	assert.Empty(t, loadInvocations(history.Filter{}))
+	_, found := findCommandInvocation(testCommandID)
+	assert.False(t, found)
+}
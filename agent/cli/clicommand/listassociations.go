@@ -0,0 +1,159 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/association/schedulemanager"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+)
+
+const listAssociations = "list-associations"
+
+const listAssociationsHelp = `NAME:
+    {{.ListAssociationsName}}
+
+DESCRIPTION
+    Lists the associations scheduled by the local amazon-ssm-agent service, with their next scheduled date and last status.
+
+SYNOPSIS
+    {{.ListAssociationsName}}
+
+EXAMPLES
+    This example lists the associations of the instance.
+
+    Command:
+
+      {{.SsmCliName}} {{.OutputFlag}} table {{.ListAssociationsName}}
+
+    Output:
+
+      ASSOCIATION ID                        NAME                    STATUS   LAST EXECUTION            NEXT SCHEDULED
+      01234567-890a-bcde-f012-34567890abcd  AWS-UpdateSSMAgent      Success  2018-03-01T10:00:00.000Z  2018-03-01T10:30:00.000Z
+
+OUTPUT
+    Association ID, document name, last status, last execution and next scheduled date of each association,
+    as last refreshed by the agent, tab separated unless {{.OutputFlag}} is table or json
+`
+
+type listAssociationsHelpParams struct {
+	SsmCliName           string
+	ListAssociationsName string
+	OutputFlag           string
+}
+
+func init() {
+	cliutil.Register(&ListAssociations{})
+}
+
+type ListAssociations struct {
+	helpText string
+}
+
+// AssociationSummary is the schedule and last status of an association
+type AssociationSummary struct {
+	AssociationId     string
+	Name              string
+	DocumentVersion   string
+	Status            string
+	LastExecutionDate *time.Time `json:",omitempty"`
+	NextScheduledDate *time.Time `json:",omitempty"`
+}
+
+// AssociationList is the result of the list-associations cli command
+type AssociationList struct {
+	Associations []AssociationSummary
+}
+
+// newAssociationSummary builds the summary of the association from its persisted schedule
+func newAssociationSummary(schedule schedulemanager.Schedule) AssociationSummary {
+	return AssociationSummary{
+		AssociationId:     schedule.AssociationID,
+		Name:              schedule.Name,
+		DocumentVersion:   schedule.DocumentVersion,
+		Status:            schedule.DetailedStatus,
+		LastExecutionDate: schedule.LastExecutionDate,
+		NextScheduledDate: schedule.NextScheduledDate,
+	}
+}
+
+// Table returns a row per association
+func (r AssociationList) Table() (header []string, rows [][]string) {
+	header = []string{"ASSOCIATION ID", "NAME", "STATUS", "LAST EXECUTION", "NEXT SCHEDULED"}
+	rows = make([][]string, 0, len(r.Associations))
+	for _, association := range r.Associations {
+		rows = append(rows, []string{association.AssociationId, association.Name, association.Status,
+			formatOptionalTime(association.LastExecutionDate), formatOptionalTime(association.NextScheduledDate)})
+	}
+	return header, rows
+}
+
+// Text returns a tab separated row per association
+func (r AssociationList) Text() string {
+	_, rows := r.Table()
+	return cliutil.FormatRows(rows)
+}
+
+// Execute validates and executes the list-associations cli command
+func (c *ListAssociations) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", listAssociations, subcommands), "")
+	}
+	for key := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return cliutil.NewUsageError(validation), nil
+	}
+
+	schedules := loadSchedules()
+	result := AssociationList{Associations: make([]AssociationSummary, 0, len(schedules))}
+	for _, schedule := range schedules {
+		result.Associations = append(result.Associations, newAssociationSummary(schedule))
+	}
+	return nil, result
+}
+
+// Help prints help for the list-associations cli command
+func (c *ListAssociations) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ListAssociationsHelp").Parse(listAssociationsHelp)
+		params := listAssociationsHelpParams{cliutil.SsmCliName, listAssociations, cliutil.FormatFlag(cliutil.OutputFlag)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ListAssociations) Name() string {
+	return listAssociations
+}
+
+// loadSchedules returns the association schedules the agent persisted
+func loadSchedules() (schedules []schedulemanager.Schedule) {
+	for _, instanceID := range instanceIDs() {
+		if instanceSchedules, err := schedulemanager.LoadSchedules(instanceID); err == nil {
+			schedules = append(schedules, instanceSchedules...)
+		}
+	}
+	return schedules
+}
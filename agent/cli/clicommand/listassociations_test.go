@@ -0,0 +1,51 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAssociationsInvalidInput(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subcommands []string
+		parameters  map[string][]string
+		message     string
+	}{
+		{
+			name:        "subcommand",
+			subcommands: []string{"foo"},
+			parameters:  map[string][]string{},
+			message:     "list-associations does not support subcommand [foo]\n",
+		},
+		{
+			name:       "unknown parameter",
+			parameters: map[string][]string{"foo": {"bar"}},
+			message:    "unknown parameter --foo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, result := (&ListAssociations{}).Execute(tc.subcommands, tc.parameters)
+			assert.True(t, cliutil.IsUsage(err))
+			assert.Equal(t, tc.message, err.Error())
+			assert.Nil(t, result)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+)
+
+const (
+	listCommands           = "list-command-invocations"
+	listCommandsStatus     = "status"
+	listCommandsSince      = "since"
+	listCommandsUntil      = "until"
+	listCommandsMaxResults = "max-results"
+)
+
+const listCommandsHelp = `NAME:
+    {{.ListCommandsName}}
+
+DESCRIPTION
+    Lists the commands executed by the local amazon-ssm-agent service, the pending and in progress commands first,
+    then the finished commands kept in the local history, the most recent first.
+
+SYNOPSIS
+    {{.ListCommandsName}}
+    [{{.StatusFlag}} <value>]
+    [{{.SinceFlag}} <value>]
+    [{{.UntilFlag}} <value>]
+    [{{.MaxResultsFlag}} <value>]
+
+PARAMETERS
+    {{.StatusFlag}} (string) Only lists the commands with the status - Pending, InProgress, Success, Failed, Cancelled or TimedOut.
+
+    {{.SinceFlag}} (string) Only lists the commands that ended, or started if not finished, at or after the time (2018-03-01T10:00:00Z or 2018-03-01).
+
+    {{.UntilFlag}} (string) Only lists the commands that ended, or started if not finished, at or before the time (2018-03-01T10:00:00Z or 2018-03-01).
+
+    {{.MaxResultsFlag}} (integer) Maximum number of commands listed.
+
+EXAMPLES
+    This example lists the last two failed commands.
+
+    Command:
+
+      {{.SsmCliName}} {{.OutputFlag}} table {{.ListCommandsName}} {{.StatusFlag}} Failed {{.MaxResultsFlag}} 2
+
+    Output:
+
+      COMMAND ID                            DOCUMENT            STATUS  START                     END
+      01234567-890a-bcde-f012-34567890abcd  AWS-RunShellScript  Failed  2018-03-01T10:00:00.000Z  2018-03-01T10:00:05.000Z
+
+OUTPUT
+    Command ID, document name, status, start and end time of each command, tab separated unless {{.OutputFlag}} is table or json
+`
+
+type listCommandsHelpParams struct {
+	SsmCliName       string
+	ListCommandsName string
+	StatusFlag       string
+	SinceFlag        string
+	UntilFlag        string
+	MaxResultsFlag   string
+	OutputFlag       string
+}
+
+func init() {
+	cliutil.Register(&ListCommandInvocations{})
+}
+
+type ListCommandInvocations struct {
+	helpText string
+}
+
+// CommandInvocationList is the result of the list-command-invocations cli command
+type CommandInvocationList struct {
+	CommandInvocations []CommandInvocation
+}
+
+// Table returns a row per command
+func (r CommandInvocationList) Table() (header []string, rows [][]string) {
+	header = []string{"COMMAND ID", "DOCUMENT", "STATUS", "START", "END"}
+	rows = make([][]string, 0, len(r.CommandInvocations))
+	for _, invocation := range r.CommandInvocations {
+		rows = append(rows, []string{invocation.CommandId, invocation.DocumentName, invocation.Status,
+			formatOptionalTime(invocation.StartDateTime), formatOptionalTime(invocation.EndDateTime)})
+	}
+	return header, rows
+}
+
+// Text returns a tab separated row per command
+func (r CommandInvocationList) Text() string {
+	_, rows := r.Table()
+	return cliutil.FormatRows(rows)
+}
+
+// Execute validates and executes the list-command-invocations cli command
+func (c *ListCommandInvocations) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
+	validation, filter := c.validateListCommandsInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return cliutil.NewUsageError(validation), nil
+	}
+
+	records := loadInvocations(filter, commandDocumentTypes...)
+	result := CommandInvocationList{CommandInvocations: make([]CommandInvocation, 0, len(records))}
+	for _, record := range records {
+		result.CommandInvocations = append(result.CommandInvocations, newCommandInvocation(record, false))
+	}
+	return nil, result
+}
+
+// Help prints help for the list-command-invocations cli command
+func (c *ListCommandInvocations) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ListCommandInvocationsHelp").Parse(listCommandsHelp)
+		params := listCommandsHelpParams{cliutil.SsmCliName, listCommands, cliutil.FormatFlag(listCommandsStatus),
+			cliutil.FormatFlag(listCommandsSince), cliutil.FormatFlag(listCommandsUntil), cliutil.FormatFlag(listCommandsMaxResults),
+			cliutil.FormatFlag(cliutil.OutputFlag)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ListCommandInvocations) Name() string {
+	return listCommands
+}
+
+// validateListCommandsInput checks the subcommands and parameters for format and unsupported values, and builds the filter of the commands
+func (ListCommandInvocations) validateListCommandsInput(subcommands []string, parameters map[string][]string) (validation []string, filter history.Filter) {
+	validation = make([]string, 0)
+
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", listCommands, subcommands), "")
+		return validation, filter
+	}
+
+	for key, values := range parameters {
+		if key != listCommandsStatus && key != listCommandsSince && key != listCommandsUntil && key != listCommandsMaxResults {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+			continue
+		}
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+			continue
+		}
+		value := values[0]
+		switch key {
+		case listCommandsStatus:
+			filter.Status = contracts.ResultStatus(value)
+		case listCommandsSince, listCommandsUntil:
+			t, ok := parseTime(value)
+			if !ok {
+				validation = append(validation, fmt.Sprintf("invalid time %v for parameter %v", value, cliutil.FormatFlag(key)))
+			} else if key == listCommandsSince {
+				filter.Since = t
+			} else {
+				filter.Until = t
+			}
+		case listCommandsMaxResults:
+			if limit, err := strconv.Atoi(value); err != nil || limit < 1 {
+				validation = append(validation, fmt.Sprintf("%v should be a positive integer", cliutil.FormatFlag(key)))
+			} else {
+				filter.Limit = limit
+			}
+		}
+	}
+	return validation, filter
+}
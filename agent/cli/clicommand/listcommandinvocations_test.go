@@ -0,0 +1,94 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clicommand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateListCommandsInput(t *testing.T) {
+	testCases := []struct {
+		name        string
+		subcommands []string
+		parameters  map[string][]string
+		validation  []string
+		filter      history.Filter
+	}{
+		{
+			name:       "no parameter",
+			parameters: map[string][]string{},
+			validation: []string{},
+		},
+		{
+			name: "every parameter",
+			parameters: map[string][]string{
+				"status":      {"Failed"},
+				"since":       {"2018-03-01"},
+				"until":       {"2018-03-02T10:00:00Z"},
+				"max-results": {"10"},
+			},
+			validation: []string{},
+			filter: history.Filter{
+				Status: contracts.ResultStatusFailed,
+				Since:  time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC),
+				Until:  time.Date(2018, 3, 2, 10, 0, 0, 0, time.UTC),
+				Limit:  10,
+			},
+		},
+		{
+			name:        "subcommand",
+			subcommands: []string{"foo"},
+			parameters:  map[string][]string{},
+			validation:  []string{"list-command-invocations does not support subcommand [foo]", ""},
+		},
+		{
+			name:       "several values",
+			parameters: map[string][]string{"status": {"Failed", "Success"}},
+			validation: []string{"expected 1 value for parameter --status"},
+		},
+		{
+			name:       "invalid time",
+			parameters: map[string][]string{"since": {"yesterday"}},
+			validation: []string{"invalid time yesterday for parameter --since"},
+		},
+		{
+			name:       "zero max results",
+			parameters: map[string][]string{"max-results": {"0"}},
+			validation: []string{"--max-results should be a positive integer"},
+		},
+		{
+			name:       "non-numeric max results",
+			parameters: map[string][]string{"max-results": {"ten"}},
+			validation: []string{"--max-results should be a positive integer"},
+		},
+		{
+			name:       "unknown parameter",
+			parameters: map[string][]string{"foo": {"bar"}},
+			validation: []string{"unknown parameter --foo"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validation, filter := ListCommandInvocations{}.validateListCommandsInput(tc.subcommands, tc.parameters)
+			assert.Equal(t, tc.validation, validation)
+			assert.Equal(t, tc.filter, filter)
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -27,31 +28,54 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/twinj/uuid"
 )
 
 const (
-	sendCommand        = "send-offline-command"
-	sendCommandContent = "content"
+	sendCommand                   = "send-offline-command"
+	sendCommandContent            = "content"
+	sendCommandDocumentPath       = "document-path"
+	sendCommandParameters         = "parameters"
+	sendCommandOutputS3BucketName = "output-s3-bucket-name"
+	sendCommandStepTimeoutSeconds = "step-timeout-seconds"
 )
 
+// sendCommandParameterNames are the parameters supported by the send-offline-command cli command
+var sendCommandParameterNames = []string{sendCommandContent, sendCommandDocumentPath, sendCommandParameters, sendCommandOutputS3BucketName, sendCommandStepTimeoutSeconds}
+
 const sendCommandHelp = `NAME:
     {{.SendCommandName}}
 
 DESCRIPTION
 SYNOPSIS
     {{.SendCommandName}}
-    {{.ContentFlag}}
+    {{.ContentFlag}} <value> | {{.DocumentPathFlag}} <value>
+    [{{.ParametersFlag}} <value> [<value> ...]]
+    [{{.OutputS3BucketNameFlag}} <value>]
+    [{{.StepTimeoutSecondsFlag}} <value>]
 
 PARAMETERS
-    {{.ContentFlag}} (string) JSON or URL to command document.
-    A valid command document is a configuration document with all parameters filled in.
+    {{.ContentFlag}} (string) JSON or URL to a JSON or YAML command document.
+    A valid command document is a configuration document, its parameters are filled in by {{.ParametersFlag}} or their default values.
     For information about writing a configuration document, see Configuration Document in the SSM API Reference.
 
+    {{.DocumentPathFlag}} (string) Path to a JSON or YAML command document on the instance, such as a document also used with the
+    Systems Manager service. Either {{.ContentFlag}} or {{.DocumentPathFlag}} is required.
+
+    {{.ParametersFlag}} (string) Values of the document parameters, either a JSON object such as {"commands":["echo hello"]}
+    or name=value pairs. A name repeated in several pairs gives a list of values.
+
+    {{.OutputS3BucketNameFlag}} (string) Name of the S3 bucket where the output of the command is uploaded.
+
+    {{.StepTimeoutSecondsFlag}} (integer) Maximum duration in seconds of each step of the command, the steps with a shorter
+    timeoutSeconds in the document keep theirs. The timeout applies to each step separately, not to the whole command.
+
 EXAMPLES
     This example runs a command in a document in S3.
 
@@ -63,14 +87,28 @@ EXAMPLES
 
       Successfully submitted with command id 01234567-890a-bcde-f012-34567890abcd
 
+    This example runs a local copy of a Systems Manager document with parameters.
+
+    Command:
+
+      {{.SsmCliName}} {{.SendCommandName}} {{.DocumentPathFlag}} /etc/amazon/ssm/documents/AWS-RunShellScript.json {{.ParametersFlag}} commands=uptime {{.StepTimeoutSecondsFlag}} 600
+
+    Output:
+
+      Successfully submitted with command id 01234567-890a-bcde-f012-34567890abcd
+
 OUTPUT
     Success message with command id or failure message - failure usually happens because you are not admin or provided invalid JSON
 `
 
 type sendCommandHelpParams struct {
-	SsmCliName      string
-	SendCommandName string
-	ContentFlag     string
+	SsmCliName             string
+	SendCommandName        string
+	ContentFlag            string
+	DocumentPathFlag       string
+	ParametersFlag         string
+	OutputS3BucketNameFlag string
+	StepTimeoutSecondsFlag string
 }
 
 func init() {
@@ -81,24 +119,64 @@ type SendOfflineCommand struct {
 	helpText string
 }
 
+// SubmittedCommand is the result of the send-offline-command cli command
+type SubmittedCommand struct {
+	CommandId string
+}
+
+// Text returns the success message with the command id
+func (r SubmittedCommand) Text() string {
+	return fmt.Sprintf("successfully submitted with command id: %v", r.CommandId)
+}
+
 // Execute validates and executes the send-offline-command cli command
-func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string][]string) (error, cliutil.Result) {
 	validation := c.validateSendCommandInput(subcommands, parameters)
 	// return validation errors if any were found
 	if len(validation) > 0 {
-		return errors.New(strings.Join(validation, "\n")), ""
+		return cliutil.NewUsageError(validation), nil
 	}
 
-	if err, content := c.loadContent(parameters[sendCommandContent][0]); err != nil {
-		return err, ""
-	} else if err := c.validateContent(content); err != nil {
-		return err, ""
-	} else if contentString, err := jsonutil.Marshal(content); err != nil {
-		return err, ""
-	} else if err, documentName := c.submitCommandDocument(contentString); err != nil {
-		return err, ""
+	command := messageContracts.OfflineCommand{}
+	var content contracts.DocumentContent
+	var err error
+	if values, exists := parameters[sendCommandContent]; exists {
+		if err, content = c.loadContent(values[0]); err != nil {
+			return err, nil
+		}
+		command.DocumentContent = &content
+	} else {
+		// the document is loaded by the agent, it is only checked here to report invalid documents right away
+		if command.DocumentPath, err = filepath.Abs(parameters[sendCommandDocumentPath][0]); err != nil {
+			return err, nil
+		}
+		if content, err = docparser.LoadDocumentContent(command.DocumentPath); err != nil {
+			return fmt.Errorf("failed to load document %v: %v", command.DocumentPath, err), nil
+		}
+	}
+	if err := c.validateContent(content); err != nil {
+		return err, nil
+	}
+	if values, exists := parameters[sendCommandParameters]; exists {
+		if command.Parameters, err = parseDocumentParameters(values, content); err != nil {
+			return cliutil.NewUsageError([]string{err.Error()}), nil
+		}
+	}
+	if values, exists := parameters[sendCommandOutputS3BucketName]; exists {
+		command.OutputS3BucketName = values[0]
+	}
+	if values, exists := parameters[sendCommandStepTimeoutSeconds]; exists {
+		command.StepTimeoutSeconds, _ = strconv.Atoi(values[0])
+	}
+
+	if commandString, err := jsonutil.Marshal(command); err != nil {
+		return err, nil
+	} else if err, documentName := c.submitCommandDocument(commandString); err != nil {
+		return err, nil
+	} else if err, commandId := c.waitForSubmitStatus(documentName); err != nil {
+		return err, nil
 	} else {
-		return nil, c.waitForSubmitStatus(documentName)
+		return nil, SubmittedCommand{commandId}
 	}
 }
 
@@ -106,7 +184,9 @@ func (c *SendOfflineCommand) Execute(subcommands []string, parameters map[string
 func (c *SendOfflineCommand) Help() string {
 	if len(c.helpText) == 0 {
 		t, _ := template.New("SendOfflineCommandHelp").Parse(sendCommandHelp)
-		params := sendCommandHelpParams{cliutil.SsmCliName, sendCommand, cliutil.FormatFlag(sendCommandContent)}
+		params := sendCommandHelpParams{cliutil.SsmCliName, sendCommand, cliutil.FormatFlag(sendCommandContent),
+			cliutil.FormatFlag(sendCommandDocumentPath), cliutil.FormatFlag(sendCommandParameters),
+			cliutil.FormatFlag(sendCommandOutputS3BucketName), cliutil.FormatFlag(sendCommandStepTimeoutSeconds)}
 		buf := new(bytes.Buffer)
 		t.Execute(buf, params)
 		c.helpText = buf.String()
@@ -127,29 +207,95 @@ func (SendOfflineCommand) validateSendCommandInput(subcommands []string, paramet
 		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
 	}
 
-	// look for required parameters
-	if _, exists := parameters[sendCommandContent]; !exists {
-		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(sendCommandContent)))
-	} else if len(parameters[sendCommandContent]) != 1 {
-		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(sendCommandContent)))
-	} else {
-		// must be valid json or a valid URI
-		val := parameters[sendCommandContent][0]
-		if !cliutil.ValidJson(val) && !cliutil.ValidUrl(val) {
-			validation = append(validation, fmt.Sprintf("%v value must be valid json or a URL", cliutil.FormatFlag(sendCommandContent)))
-		}
+	// look for required parameters, the document is either inline or on disk
+	_, hasContent := parameters[sendCommandContent]
+	_, hasDocumentPath := parameters[sendCommandDocumentPath]
+	if !hasContent && !hasDocumentPath {
+		validation = append(validation, fmt.Sprintf("%v or %v is required", cliutil.FormatFlag(sendCommandContent), cliutil.FormatFlag(sendCommandDocumentPath)))
+	} else if hasContent && hasDocumentPath {
+		validation = append(validation, fmt.Sprintf("%v and %v cannot be used together", cliutil.FormatFlag(sendCommandContent), cliutil.FormatFlag(sendCommandDocumentPath)))
 	}
 
-	// look for unsupported parameters
-	for key := range parameters {
-		if key != sendCommandContent {
+	for key, values := range parameters {
+		if !isParameterNameOf(key, sendCommandParameterNames) {
+			// look for unsupported parameters
 			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+			continue
+		}
+		if key == sendCommandParameters {
+			if len(values) == 0 {
+				validation = append(validation, fmt.Sprintf("expected at least 1 value for parameter %v", cliutil.FormatFlag(key)))
+			}
+			continue
+		}
+		if len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+			continue
+		}
+		val := values[0]
+		switch key {
+		case sendCommandContent:
+			// must be valid json or a valid URI
+			if !cliutil.ValidJson(val) && !cliutil.ValidUrl(val) {
+				validation = append(validation, fmt.Sprintf("%v value must be valid json or a URL", cliutil.FormatFlag(key)))
+			}
+		case sendCommandDocumentPath:
+			if !fileutil.Exists(val) {
+				validation = append(validation, fmt.Sprintf("%v file %v does not exist", cliutil.FormatFlag(key), val))
+			}
+		case sendCommandStepTimeoutSeconds:
+			if timeout, err := strconv.Atoi(val); err != nil || timeout < 1 {
+				validation = append(validation, fmt.Sprintf("%v should be a positive integer", cliutil.FormatFlag(key)))
+			}
 		}
 	}
 	return validation
 }
 
-// loadContent loads raw json or json obtained from a URL into DocumentContent
+// isParameterNameOf checks whether the parameter is one of the names
+func isParameterNameOf(key string, names []string) bool {
+	for _, name := range names {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDocumentParameters parses the values of the document parameters, either a JSON object or name=value pairs.
+// The value of a pair is a list for the StringList parameters of the document, or when the name is repeated.
+func parseDocumentParameters(values []string, content contracts.DocumentContent) (map[string]interface{}, error) {
+	documentParameters := make(map[string]interface{})
+	if len(values) == 1 && cliutil.ValidJson(values[0]) {
+		err := json.Unmarshal([]byte(values[0]), &documentParameters)
+		return documentParameters, err
+	}
+
+	pairs := make(map[string][]string)
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		separator := strings.Index(value, "=")
+		if separator < 1 {
+			return nil, fmt.Errorf("%v value %v must be a JSON object or a name=value pair", cliutil.FormatFlag(sendCommandParameters), value)
+		}
+		name := value[:separator]
+		if _, exists := pairs[name]; !exists {
+			names = append(names, name)
+		}
+		pairs[name] = append(pairs[name], value[separator+1:])
+	}
+	for _, name := range names {
+		definition, declared := content.Parameters[name]
+		if len(pairs[name]) > 1 || (declared && definition.ParamType == contracts.ParamTypeStringList) {
+			documentParameters[name] = pairs[name]
+		} else {
+			documentParameters[name] = pairs[name][0]
+		}
+	}
+	return documentParameters, nil
+}
+
+// loadContent loads raw json or the json or yaml document obtained from a URL into DocumentContent
 func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.DocumentContent) {
 	var content contracts.DocumentContent
 	if cliutil.ValidJson(rawContent) {
@@ -166,20 +312,20 @@ func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.Docum
 	if output, err := artifact.Download(log.NewMockLog(), *input); err != nil {
 		return err, content
 	} else {
-		err = jsonutil.UnmarshalFile(output.LocalFilePath, &content)
+		content, err = docparser.LoadDocumentContent(output.LocalFilePath)
 		// TODO:MF: ideally we'd delete the file if we downloaded it - but it might've been a local file and we don't have a good way to tell
 		return err, content
 	}
 }
 
-//validateContent checks to see that content has at least one runtimeConfig for 1.2 or mainSteps for 2.0 and no unbound parameters
+//validateContent checks to see that content has at least one runtimeConfig for 1.2 or mainSteps for 2.0 and 2.2 and no unbound parameters
 func (SendOfflineCommand) validateContent(content contracts.DocumentContent) error {
 	// TODO:MF: also check for unbound parameters
 	if content.SchemaVersion == "1.2" {
 		if len(content.RuntimeConfig) == 0 {
 			return fmt.Errorf("runtimeConfig cannot be empty")
 		}
-	} else if content.SchemaVersion == "2.0" {
+	} else if content.SchemaVersion == "2.0" || content.SchemaVersion == "2.2" {
 		if len(content.MainSteps) == 0 {
 			return fmt.Errorf("mainSteps cannot be empty")
 		}
@@ -203,29 +349,57 @@ func (SendOfflineCommand) submitCommandDocument(content string) (error, string)
 }
 
 // waitForSubmitStatus
-func (c *SendOfflineCommand) waitForSubmitStatus(documentName string) string {
+func (c *SendOfflineCommand) waitForSubmitStatus(documentName string) (error, string) {
+	processed, valid, commandId := waitForDocumentProcessed(appconfig.LocalCommandRoot, documentName)
+	if !processed {
+		return errors.New("failed to submit document: timed out"), ""
+	}
+	if !valid {
+		if reason := invalidDocumentReason(documentName); reason != "" {
+			return fmt.Errorf("failed to submit document: document was invalid: %v", reason), ""
+		}
+		return errors.New("failed to submit document: document was invalid"), ""
+	}
+	return nil, commandId
+}
+
+// waitForDocumentProcessed waits for the offline service to pick up the document submitted to the folder, and returns the command id it was given.
+// The document is removed if it was not picked up in time.
+func waitForDocumentProcessed(folder string, documentName string) (processed bool, valid bool, commandId string) {
 	for i := 0; i < 10; i++ {
-		if processed, commandId := c.isDocumentProcessed(documentName, appconfig.LocalCommandRootSubmitted); processed {
-			return fmt.Sprintf("successfully submitted with command id: %v", commandId)
+		if processed, commandId = isDocumentProcessed(documentName, appconfig.LocalCommandRootSubmitted); processed {
+			return true, true, commandId
 		}
-		if processed, _ := c.isDocumentProcessed(documentName, appconfig.LocalCommandRootInvalid); processed {
-			return "failed to submit document: document was invalid"
+		if processed, _ = isDocumentProcessed(documentName, appconfig.LocalCommandRootInvalid); processed {
+			return true, false, ""
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
-	documentPath := filepath.Join(appconfig.LocalCommandRoot, documentName)
+	documentPath := filepath.Join(folder, documentName)
 	fileutil.DeleteFile(documentPath)
-	if processed, commandId := c.isDocumentProcessed(documentName, appconfig.LocalCommandRootSubmitted); processed {
-		return fmt.Sprintf("successfully submitted with command id: %v", commandId)
+	if processed, commandId = isDocumentProcessed(documentName, appconfig.LocalCommandRootSubmitted); processed {
+		return true, true, commandId
 	}
-	if processed, _ := c.isDocumentProcessed(documentName, appconfig.LocalCommandRootInvalid); processed {
-		return "failed to submit document: document was invalid"
+	if processed, _ = isDocumentProcessed(documentName, appconfig.LocalCommandRootInvalid); processed {
+		return true, false, ""
+	}
+	return false, false, ""
+}
+
+// invalidDocumentReason returns the reason the agent found for an invalid document, if any
+func invalidDocumentReason(documentName string) string {
+	files, _ := fileutil.GetFileNames(appconfig.LocalCommandRootInvalid)
+	for _, file := range files {
+		if strings.HasPrefix(file, documentName) && strings.HasSuffix(file, messageContracts.OfflineCommandErrorExtension) {
+			reason, _ := fileutil.ReadAllText(filepath.Join(appconfig.LocalCommandRootInvalid, file))
+			return reason
+		}
 	}
-	return "failed to submit document: timed out"
+	return ""
 }
 
 // isDocumentProcessed checks for a document in the processed folder and returns the command id suffix
-func (SendOfflineCommand) isDocumentProcessed(documentName string, folder string) (bool, string) {
+func isDocumentProcessed(documentName string, folder string) (bool, string) {
 	files, _ := fileutil.GetFileNames(folder)
 	for _, file := range files {
 		if strings.HasPrefix(file, documentName) && strings.Contains(file, ".") {
@@ -33,9 +33,10 @@ const (
 // CliCommands is the set of support commands
 var CliCommands map[string]CliCommand
 
-// CliCommand defines the interface for all commands the cli can execute
+// CliCommand defines the interface for all commands the cli can execute,
+// the result is rendered in the output format selected on the command line
 type CliCommand interface {
-	Execute(subcommands []string, parameters map[string][]string) (error, string)
+	Execute(subcommands []string, parameters map[string][]string) (error, Result)
 	Help() string
 	Name() string
 }
@@ -0,0 +1,159 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cliutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	// OutputFlag is the global option selecting the format of the result of the command
+	OutputFlag = "output"
+
+	// OutputText is the human readable output, lists are rendered as tab separated rows
+	OutputText = "text"
+	// OutputTable renders the lists as aligned columns with a header
+	OutputTable = "table"
+	// OutputJson renders the result as a JSON document
+	OutputJson = "json"
+
+	// DefaultOutput is the output used when the option is not given
+	DefaultOutput = OutputText
+)
+
+// Exit codes of the cli, they are part of its interface and must not change
+const (
+	// ExitCodeSuccess is returned when the command succeeded
+	ExitCodeSuccess = 0
+	// ExitCodeFailure is returned when the command failed
+	ExitCodeFailure = 1
+	// ExitCodeUsage is returned when the command line is invalid
+	ExitCodeUsage = 2
+	// ExitCodeNotFound is returned when the resource the command refers to does not exist
+	ExitCodeNotFound = 3
+)
+
+// OutputFormats are the supported values of the output option
+var OutputFormats = []string{OutputText, OutputTable, OutputJson}
+
+// Result is the structured result of a command, its exported fields are rendered by the json output
+type Result interface {
+	// Text returns the human readable result
+	Text() string
+}
+
+// TableResult is a result holding a list, rendered as columns by the table output
+type TableResult interface {
+	Result
+	// Table returns the names of the columns and the values of each row
+	Table() (header []string, rows [][]string)
+}
+
+// CliError is an error of a command, with the exit code of the cli
+type CliError struct {
+	ExitCode int
+	Message  string
+}
+
+// Error returns the message of the error
+func (e *CliError) Error() string {
+	return e.Message
+}
+
+// NewUsageError returns the error of an invalid command line, with its validation messages
+func NewUsageError(validation []string) error {
+	return &CliError{ExitCode: ExitCodeUsage, Message: strings.Join(validation, "\n")}
+}
+
+// NewNotFoundError returns the error of a resource that does not exist
+func NewNotFoundError(format string, a ...interface{}) error {
+	return &CliError{ExitCode: ExitCodeNotFound, Message: fmt.Sprintf(format, a...)}
+}
+
+// ExitCode returns the exit code of the error, errors of unknown type are failures
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+	if cliErr, ok := err.(*CliError); ok {
+		return cliErr.ExitCode
+	}
+	return ExitCodeFailure
+}
+
+// IsUsage returns true if the error is caused by an invalid command line
+func IsUsage(err error) bool {
+	return ExitCode(err) == ExitCodeUsage
+}
+
+// IsOutputFormat returns true if the value is a supported output format
+func IsOutputFormat(format string) bool {
+	for _, f := range OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatResult renders the result in the output format, the table output falls back to text for results that are not lists
+func FormatResult(format string, result Result) (string, error) {
+	switch format {
+	case OutputJson:
+		return jsonutil.MarshalIndent(result)
+	case OutputTable:
+		if table, ok := result.(TableResult); ok {
+			return FormatTable(table.Table()), nil
+		}
+	}
+	return result.Text(), nil
+}
+
+// FormatError renders the error in the output format
+func FormatError(format string, err error) string {
+	if format == OutputJson {
+		output, _ := jsonutil.MarshalIndent(struct {
+			Error    string
+			ExitCode int
+		}{err.Error(), ExitCode(err)})
+		return output
+	}
+	return err.Error()
+}
+
+// FormatTable renders the rows as aligned columns under the header
+func FormatTable(header []string, rows [][]string) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// FormatRows renders the rows as tab separated values, one row per line
+func FormatRows(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, "\t"))
+	}
+	return strings.Join(lines, "\n")
+}
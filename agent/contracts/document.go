@@ -68,6 +68,7 @@ type DocumentInfo struct {
 	DocumentStatus  ResultStatus
 	RunCount        int
 	ProcInfo        OSProcInfo
+	ResourceLimits  ResourceLimits
 }
 
 //CloudWatchConfiguration represents information relevant to command output in cloudWatch
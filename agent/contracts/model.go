@@ -146,6 +146,21 @@ const (
 	ParamTypeStringList = "StringList"
 	// ParamTypeStringMap represents the param type is StringMap
 	ParamTypeStringMap = "StringMap"
+	// ParamTypeInteger represents the param type is Integer
+	ParamTypeInteger = "Integer"
+	// ParamTypeBoolean represents the param type is Boolean
+	ParamTypeBoolean = "Boolean"
+	// ParamTypeMapList represents the param type is MapList
+	ParamTypeMapList = "MapList"
+)
+
+const (
+	// OnFailureExit stops the document execution when the step fails
+	OnFailureExit = "exit"
+	// OnFailureSuccessAndExit stops the document execution when the step fails and reports the step as successful
+	OnFailureSuccessAndExit = "successAndExit"
+	// OnFailureContinue keeps running the remaining steps when the step fails
+	OnFailureContinue = "continue"
 )
 
 type StopType string
@@ -162,6 +177,10 @@ type Parameter struct {
 	ParamType      string      `json:"type" yaml:"type"`
 	AllowedVal     []string    `json:"allowedValues" yaml:"allowedValues"`
 	AllowedPattern string      `json:"allowedPattern" yaml:"allowedPattern"`
+	MinItems       *int        `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems       *int        `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	MinChars       *int        `json:"minChars,omitempty" yaml:"minChars,omitempty"`
+	MaxChars       *int        `json:"maxChars,omitempty" yaml:"maxChars,omitempty"`
 }
 
 // PluginConfig stores plugin configuration
@@ -173,23 +192,43 @@ type PluginConfig struct {
 
 // InstancePluginConfig stores plugin configuration
 type InstancePluginConfig struct {
-	Action        string              `json:"action" yaml:"action"` // plugin name
-	Inputs        interface{}         `json:"inputs" yaml:"inputs"` // Properties
-	MaxAttempts   int                 `json:"maxAttempts" yaml:"maxAttempts"`
-	Name          string              `json:"name" yaml:"name"` // unique identifier
-	OnFailure     string              `json:"onFailure" yaml:"onFailure"`
-	Settings      interface{}         `json:"settings" yaml:"settings"`
-	Timeout       int                 `json:"timeoutSeconds" yaml:"timeoutSeconds"`
-	Preconditions map[string][]string `json:"precondition" yaml:"precondition"`
+	Action         string                 `json:"action" yaml:"action"` // plugin name
+	Inputs         interface{}            `json:"inputs" yaml:"inputs"` // Properties
+	MaxAttempts    int                    `json:"maxAttempts" yaml:"maxAttempts"`
+	Name           string                 `json:"name" yaml:"name"` // unique identifier
+	OnFailure      string                 `json:"onFailure" yaml:"onFailure"`
+	Settings       interface{}            `json:"settings" yaml:"settings"`
+	Timeout        int                    `json:"timeoutSeconds" yaml:"timeoutSeconds"`
+	Preconditions  map[string]interface{} `json:"precondition" yaml:"precondition"`
+	NextStep       string                 `json:"nextStep" yaml:"nextStep"`
+	IsEnd          bool                   `json:"isEnd" yaml:"isEnd"`
+	ParallelGroup  string                 `json:"parallelGroup" yaml:"parallelGroup"`
+	MaxConcurrency int                    `json:"maxConcurrency" yaml:"maxConcurrency"`
 }
 
 // DocumentContent object which represents ssm document content.
 type DocumentContent struct {
-	SchemaVersion string                   `json:"schemaVersion" yaml:"schemaVersion"`
-	Description   string                   `json:"description" yaml:"description"`
-	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
-	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
-	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	SchemaVersion  string                   `json:"schemaVersion" yaml:"schemaVersion"`
+	Description    string                   `json:"description" yaml:"description"`
+	RuntimeConfig  map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
+	MainSteps      []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
+	Parameters     map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	RunAsUser      string                   `json:"runAsUser" yaml:"runAsUser"`           // default user of the steps running commands
+	RunAsGroup     string                   `json:"runAsGroup" yaml:"runAsGroup"`         // default group of the steps running commands
+	ResourceLimits *ResourceLimits          `json:"resourceLimits" yaml:"resourceLimits"` // overrides the agent resource limits
+}
+
+// ResourceLimits represents the resource limits of the processes running a document, zero means the agent default.
+// CPUQuotaPercent is a percentage of one CPU, MemoryLimitMB is in megabytes and IOWeight is a relative weight between 1 and 10000.
+type ResourceLimits struct {
+	CPUQuotaPercent int `json:"cpuQuotaPercent" yaml:"cpuQuotaPercent"`
+	MemoryLimitMB   int `json:"memoryLimitMB" yaml:"memoryLimitMB"`
+	IOWeight        int `json:"ioWeight" yaml:"ioWeight"`
+}
+
+// IsEmpty returns true when no limit is set
+func (l ResourceLimits) IsEmpty() bool {
+	return l.CPUQuotaPercent == 0 && l.MemoryLimitMB == 0 && l.IOWeight == 0
 }
 
 // AdditionalInfo section in agent response
@@ -28,18 +28,20 @@ const (
 
 // PluginResult represents a plugin execution result.
 type PluginResult struct {
-	PluginID           string       `json:"pluginID"`
-	PluginName         string       `json:"pluginName"`
-	Status             ResultStatus `json:"status"`
-	Code               int          `json:"code"`
-	Output             interface{}  `json:"output"`
-	StartDateTime      time.Time    `json:"startDateTime"`
-	EndDateTime        time.Time    `json:"endDateTime"`
-	OutputS3BucketName string       `json:"outputS3BucketName"`
-	OutputS3KeyPrefix  string       `json:"outputS3KeyPrefix"`
-	Error              error        `json:"-"`
-	StandardOutput     string       `json:"standardOutput"`
-	StandardError      string       `json:"standardError"`
+	PluginID           string            `json:"pluginID"`
+	PluginName         string            `json:"pluginName"`
+	Status             ResultStatus      `json:"status"`
+	Code               int               `json:"code"`
+	Output             interface{}       `json:"output"`
+	StartDateTime      time.Time         `json:"startDateTime"`
+	EndDateTime        time.Time         `json:"endDateTime"`
+	OutputS3BucketName string            `json:"outputS3BucketName"`
+	OutputS3KeyPrefix  string            `json:"outputS3KeyPrefix"`
+	Error              error             `json:"-"`
+	StandardOutput     string            `json:"standardOutput"`
+	StandardError      string            `json:"standardError"`
+	StepOutputs        map[string]string `json:"stepOutputs,omitempty"`
+	OOMKilled          bool              `json:"oomKilled,omitempty"`
 }
 
 // IPlugin is interface for authoring a functionality of work.
@@ -75,9 +77,20 @@ type Configuration struct {
 	PluginName              string
 	PluginID                string
 	DefaultWorkingDirectory string
-	Preconditions           map[string][]string
+	Preconditions           map[string]interface{}
 	IsPreconditionEnabled   bool
 	CurrentAssociations     []string
+	OnFailure               string
+	MaxAttempts             int
+	TimeoutSeconds          int
+	NextStep                string
+	IsEnd                   bool
+	ParallelGroup           string
+	MaxConcurrency          int
+	RunAsUser               string
+	RunAsGroup              string
+	CommandID               string
+	DocumentName            string
 }
 
 // Plugin wraps the plugin configuration and plugin result.
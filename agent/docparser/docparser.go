@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameters"
 	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 
 	"fmt"
@@ -33,6 +34,10 @@ const (
 	preconditionSchemaVersion string = "2.2"
 )
 
+// nonSecureResolveOptions resolves the parameters of the providers while parsing the document, the secure ones
+// are left to the plugins which keep them out of the files written on disk
+var nonSecureResolveOptions = ssmparameterresolver.ResolveOptions{IgnoreSecureParameters: true}
+
 // DocumentParserInfo represents the parsed information from the request
 type DocumentParserInfo struct {
 	OrchestrationDir  string
@@ -63,10 +68,21 @@ func InitializeDocState(log log.T,
 		CloudWatchConfig:       parserInfo.CloudWatchConfig,
 	}
 
+	if docContent.ResourceLimits != nil {
+		if err = validateResourceLimits(*docContent.ResourceLimits); err != nil {
+			return
+		}
+		docState.DocumentInformation.ResourceLimits = *docContent.ResourceLimits
+	}
+
 	pluginInfo, err := ParseDocument(log, docContent, parserInfo, params)
 	if err != nil {
 		return
 	}
+	for i := range pluginInfo {
+		pluginInfo[i].Configuration.CommandID = docInfo.CommandID
+		pluginInfo[i].Configuration.DocumentName = docInfo.DocumentName
+	}
 	docState.InstancePluginsInformation = pluginInfo
 	return docState, nil
 }
@@ -95,16 +111,15 @@ func ParseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 
 		if definition, ok := paramsDef[name]; ok {
 			switch definition.ParamType {
-			case contracts.ParamTypeString:
+			// the values are converted to their types when the parameters are validated
+			case contracts.ParamTypeString, contracts.ParamTypeStringMap, contracts.ParamTypeInteger, contracts.ParamTypeBoolean:
 				result[name] = *(param[0])
-			case contracts.ParamTypeStringList:
+			case contracts.ParamTypeStringList, contracts.ParamTypeMapList:
 				newParam := []string{}
 				for _, value := range param {
 					newParam = append(newParam, *value)
 				}
 				result[name] = newParam
-			case contracts.ParamTypeStringMap:
-				result[name] = *(param[0])
 			default:
 				log.Debug("unknown parameter type ", definition.ParamType)
 			}
@@ -154,6 +169,8 @@ func parsePluginStateForV10Schema(
 			PluginName:              pluginName,
 			PluginID:                pluginName,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			RunAsUser:               docContent.RunAsUser,
+			RunAsGroup:              docContent.RunAsGroup,
 		}
 		pluginConfigurations = append(pluginConfigurations, &config)
 	}
@@ -185,6 +202,9 @@ func parsePluginStateForV20Schema(
 	// getPluginConfigurations converts from PluginConfig (structure from the MDS message) to plugin.Configuration (structure expected by the plugin)
 	for _, instancePluginConfig := range docContent.MainSteps {
 		pluginName := instancePluginConfig.Action
+		if err = validateOnFailure(instancePluginConfig.OnFailure); err != nil {
+			return pluginsInfo, fmt.Errorf("%v. Step name: %s", err, instancePluginConfig.Name)
+		}
 		config := contracts.Configuration{
 			Settings:                instancePluginConfig.Settings,
 			Properties:              instancePluginConfig.Inputs,
@@ -198,6 +218,15 @@ func parsePluginStateForV20Schema(
 			Preconditions:           instancePluginConfig.Preconditions,
 			IsPreconditionEnabled:   isPreconditionEnabled,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			OnFailure:               instancePluginConfig.OnFailure,
+			MaxAttempts:             instancePluginConfig.MaxAttempts,
+			TimeoutSeconds:          instancePluginConfig.Timeout,
+			NextStep:                instancePluginConfig.NextStep,
+			IsEnd:                   instancePluginConfig.IsEnd,
+			ParallelGroup:           instancePluginConfig.ParallelGroup,
+			MaxConcurrency:          instancePluginConfig.MaxConcurrency,
+			RunAsUser:               docContent.RunAsUser,
+			RunAsGroup:              docContent.RunAsGroup,
 		}
 
 		var plugin contracts.PluginState
@@ -206,9 +235,89 @@ func parsePluginStateForV20Schema(
 		plugin.Name = config.PluginName
 		pluginsInfo = append(pluginsInfo, plugin)
 	}
+
+	if err = validateNextSteps(docContent.MainSteps); err != nil {
+		return pluginsInfo, err
+	}
+	if err = validateParallelGroups(docContent.MainSteps); err != nil {
+		return pluginsInfo, err
+	}
 	return
 }
 
+// validateResourceLimits checks if the resource limits of the document are within their range.
+func validateResourceLimits(limits contracts.ResourceLimits) error {
+	if limits.CPUQuotaPercent < 0 {
+		return fmt.Errorf("cpuQuotaPercent must not be negative")
+	}
+	if limits.MemoryLimitMB < 0 {
+		return fmt.Errorf("memoryLimitMB must not be negative")
+	}
+	if limits.IOWeight < 0 || limits.IOWeight > appconfig.ResourceLimitsIOWeightMax {
+		return fmt.Errorf("ioWeight must be between 1 and %v", appconfig.ResourceLimitsIOWeightMax)
+	}
+	return nil
+}
+
+// validateParallelGroups checks if the steps of every parallel group are consecutive and can run concurrently.
+// Steps of a parallel group cannot change the flow of the document.
+func validateParallelGroups(mainSteps []*contracts.InstancePluginConfig) error {
+	closedGroups := make(map[string]bool)
+	previousGroup := ""
+	for _, step := range mainSteps {
+		if step.MaxConcurrency < 0 {
+			return fmt.Errorf("maxConcurrency must not be negative. Step name: %s", step.Name)
+		}
+		if step.ParallelGroup != previousGroup {
+			if step.ParallelGroup != "" && closedGroups[step.ParallelGroup] {
+				return fmt.Errorf("Steps of parallel group %s must be consecutive. Step name: %s", step.ParallelGroup, step.Name)
+			}
+			if previousGroup != "" {
+				closedGroups[previousGroup] = true
+			}
+			previousGroup = step.ParallelGroup
+		}
+		if step.ParallelGroup == "" {
+			continue
+		}
+		if step.NextStep != "" || step.IsEnd || step.Action == appconfig.PluginNameAwsBranch {
+			return fmt.Errorf("Steps of parallel group %s cannot change the flow of the document. Step name: %s", step.ParallelGroup, step.Name)
+		}
+	}
+	return nil
+}
+
+// validateNextSteps checks if the nextStep of every step refers to a step that comes after it.
+// Steps can only be skipped, going back to a previous step is not supported.
+func validateNextSteps(mainSteps []*contracts.InstancePluginConfig) error {
+	for index, step := range mainSteps {
+		if step.NextStep == "" {
+			continue
+		}
+		found := false
+		for _, nextStep := range mainSteps[index+1:] {
+			if nextStep.Name == step.NextStep {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Next step %s must be a step after step %s", step.NextStep, step.Name)
+		}
+	}
+	return nil
+}
+
+// validateOnFailure checks if the onFailure value of a step is supported by this agent version
+func validateOnFailure(onFailure string) error {
+	switch onFailure {
+	case "", contracts.OnFailureExit, contracts.OnFailureSuccessAndExit, contracts.OnFailureContinue:
+		return nil
+	default:
+		return fmt.Errorf("Unsupported onFailure value %s", onFailure)
+	}
+}
+
 // validateSchema checks if the document schema version is supported by this agent version
 func validateSchema(documentSchemaVersion string) error {
 	// Check if the document version is supported by this agent version
@@ -240,8 +349,13 @@ func getValidatedParameters(log log.T, params map[string]interface{}, docContent
 		return err
 	}
 
-	err := replaceValidatedPluginParameters(docContent, validParameters, log)
-	return err
+	// Validates the values against the parameter definitions and converts them to their types
+	validParameters, err := parameters.ValidateParameters(log, docContent.Parameters, validParameters)
+	if err != nil {
+		return err
+	}
+
+	return replaceValidatedPluginParameters(docContent, validParameters, log)
 }
 
 // replaceValidatedPluginParameters replaces parameters with their values, within the plugin Properties.
@@ -251,6 +365,14 @@ func replaceValidatedPluginParameters(
 	logger log.T) error {
 	var err error
 
+	// the default user and group of the document can reference parameters as well
+	if runAsUser, ok := parameters.ReplaceParameters(docContent.RunAsUser, params, logger).(string); ok {
+		docContent.RunAsUser = runAsUser
+	}
+	if runAsGroup, ok := parameters.ReplaceParameters(docContent.RunAsGroup, params, logger).(string); ok {
+		docContent.RunAsGroup = runAsGroup
+	}
+
 	//TODO: Refactor this to not not reparse the docContent
 	runtimeConfig := docContent.RuntimeConfig
 	// we assume that one of the runtimeConfig and mainSteps should be nil
@@ -267,10 +389,20 @@ func replaceValidatedPluginParameters(
 				return err
 			}
 
+			// Resolves the non secure parameters of the other parameter providers
+			if updatedRuntimeConfig[pluginName].Settings, err = ssmparameterresolver.ResolveProviderParameters(logger, updatedRuntimeConfig[pluginName].Settings, nonSecureResolveOptions); err != nil {
+				return err
+			}
+
 			// Resolves SSM parameters
 			if updatedRuntimeConfig[pluginName].Properties, err = parameterstore.Resolve(logger, updatedRuntimeConfig[pluginName].Properties); err != nil {
 				return err
 			}
+
+			// Resolves the non secure parameters of the other parameter providers
+			if updatedRuntimeConfig[pluginName].Properties, err = ssmparameterresolver.ResolveProviderParameters(logger, updatedRuntimeConfig[pluginName].Properties, nonSecureResolveOptions); err != nil {
+				return err
+			}
 		}
 		docContent.RuntimeConfig = updatedRuntimeConfig
 		return nil
@@ -290,10 +422,20 @@ func replaceValidatedPluginParameters(
 				return err
 			}
 
+			// Resolves the non secure parameters of the other parameter providers
+			if updatedMainSteps[index].Settings, err = ssmparameterresolver.ResolveProviderParameters(logger, updatedMainSteps[index].Settings, nonSecureResolveOptions); err != nil {
+				return err
+			}
+
 			// Resolves SSM parameters
 			if updatedMainSteps[index].Inputs, err = parameterstore.Resolve(logger, updatedMainSteps[index].Inputs); err != nil {
 				return err
 			}
+
+			// Resolves the non secure parameters of the other parameter providers
+			if updatedMainSteps[index].Inputs, err = ssmparameterresolver.ResolveProviderParameters(logger, updatedMainSteps[index].Inputs, nonSecureResolveOptions); err != nil {
+				return err
+			}
 		}
 		docContent.MainSteps = updatedMainSteps
 		return nil
@@ -40,6 +40,14 @@ const (
 const parameterdocument = `{"schemaVersion":"1.2","description":"","parameters":{"commands":{"type":"StringList"}},"runtimeConfig":{"aws:runPowerShellScript":{"properties":[{"id":"0.aws:runPowerShellScript","runCommand":"{{ commands }}"}]}}}`
 const invaliddocument = `{"schemaVersion":"1.2","description":"PowerShell.","FOO":"bar"}`
 const testparameters = `{"commands":["date"]}`
+const stepoptionsdocument = `{"schemaVersion":"2.2","description":"","mainSteps":[{"action":"aws:runShellScript","name":"install","onFailure":"exit","maxAttempts":3,"timeoutSeconds":60,"inputs":{"runCommand":["date"]}}]}`
+const invalidonfailuredocument = `{"schemaVersion":"2.2","description":"","mainSteps":[{"action":"aws:runShellScript","name":"install","onFailure":"abort","inputs":{"runCommand":["date"]}}]}`
+
+const invalidnextstepdocument = `{"schemaVersion":"2.2","description":"","mainSteps":[{"action":"aws:runShellScript","name":"install","inputs":{"runCommand":["date"]}},{"action":"aws:runShellScript","name":"verify","nextStep":"install","inputs":{"runCommand":["date"]}}]}`
+
+const invalidparallelgroupdocument = `{"schemaVersion":"2.2","description":"","mainSteps":[{"action":"aws:downloadContent","name":"fetchAgent","parallelGroup":"fetch","inputs":{"sourceType":"S3"}},{"action":"aws:runShellScript","name":"install","inputs":{"runCommand":["date"]}},{"action":"aws:downloadContent","name":"fetchConfig","parallelGroup":"fetch","inputs":{"sourceType":"S3"}}]}`
+
+const typedparameterdocument = `{"schemaVersion":"2.2","description":"","parameters":{"count":{"type":"Integer","default":3},"verbose":{"type":"Boolean","default":false},"level":{"type":"String","allowedValues":["info","debug"],"default":"info"}},"mainSteps":[{"action":"aws:runShellScript","name":"run","inputs":{"runCommand":["run --count {{ count }} --verbose={{ verbose }} --level {{ level }}"],"timeoutSeconds":"{{ count }}"}}]}`
 
 var sampleMessageFiles = []string{
 	"testdata/sampleMessageVersion2_0.json",
@@ -113,6 +121,116 @@ func TestParseDocument_ValidMainSteps(t *testing.T) {
 	assert.Equal(t, testWorkingDir, pluginInfoTest.Configuration.DefaultWorkingDirectory)
 }
 
+func TestParseDocument_StepOptions(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(stepoptionsdocument), &testDocContent)
+	assert.NoError(t, err)
+	pluginsInfo, err := ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pluginsInfo))
+	assert.Equal(t, contracts.OnFailureExit, pluginsInfo[0].Configuration.OnFailure)
+	assert.Equal(t, 3, pluginsInfo[0].Configuration.MaxAttempts)
+	assert.Equal(t, 60, pluginsInfo[0].Configuration.TimeoutSeconds)
+}
+
+func TestParseDocument_InvalidOnFailure(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(invalidonfailuredocument), &testDocContent)
+	assert.NoError(t, err)
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unsupported onFailure value abort")
+}
+
+func TestParseDocument_InvalidNextStep(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(invalidnextstepdocument), &testDocContent)
+	assert.NoError(t, err)
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Next step install must be a step after step verify")
+}
+
+func TestParseDocument_InvalidParallelGroup(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(invalidparallelgroupdocument), &testDocContent)
+	assert.NoError(t, err)
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Steps of parallel group fetch must be consecutive. Step name: fetchConfig")
+}
+
+func TestParseDocument_TypedParameters(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(typedparameterdocument), &testDocContent)
+	assert.NoError(t, err)
+	pluginsInfo, err := ParseDocument(mockLog, &testDocContent, testParserInfo, map[string]interface{}{"count": "5", "verbose": "true"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pluginsInfo))
+	assert.Equal(t, map[string]interface{}{
+		"runCommand":     []interface{}{"run --count 5 --verbose=true --level info"},
+		"timeoutSeconds": 5,
+	}, pluginsInfo[0].Configuration.Properties)
+}
+
+func TestParseDocument_InvalidParameters(t *testing.T) {
+	mockLog := log.NewMockLog()
+	testParserInfo := DocumentParserInfo{
+		OrchestrationDir: testOrchDir,
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+	}
+
+	var testDocContent contracts.DocumentContent
+	err := json.Unmarshal([]byte(typedparameterdocument), &testDocContent)
+	assert.NoError(t, err)
+	_, err = ParseDocument(mockLog, &testDocContent, testParserInfo, map[string]interface{}{"count": "five", "level": "trace"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Parameter count: value five must be an Integer")
+	assert.Contains(t, err.Error(), `Parameter level: value "trace" is not one of the allowed values [info, debug]`)
+}
+
 func TestInitializeDocState_Valid(t *testing.T) {
 	mockLog := log.NewMockLog()
 
@@ -156,6 +274,24 @@ func TestInitializeDocState_Valid(t *testing.T) {
 	assert.Equal(t, testLogStreamPrefix, docState.IOConfig.CloudWatchConfig.LogStreamPrefix)
 }
 
+func TestInitializeDocState_ResourceLimits(t *testing.T) {
+	mockLog := log.NewMockLog()
+
+	var testDocContent contracts.DocumentContent
+	validdocumentruntimeconfig := loadFile(t, "../runcommand/mds/testdata/validcommand12.json")
+	err := json.Unmarshal(validdocumentruntimeconfig, &testDocContent)
+	assert.NoError(t, err)
+
+	testDocContent.ResourceLimits = &contracts.ResourceLimits{CPUQuotaPercent: 50, MemoryLimitMB: 512}
+	docState, err := InitializeDocState(mockLog, contracts.SendCommand, &testDocContent, contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, contracts.ResourceLimits{CPUQuotaPercent: 50, MemoryLimitMB: 512}, docState.DocumentInformation.ResourceLimits)
+
+	testDocContent.ResourceLimits = &contracts.ResourceLimits{IOWeight: 20000}
+	_, err = InitializeDocState(mockLog, contracts.SendCommand, &testDocContent, contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+	assert.Error(t, err)
+}
+
 func TestParseDocument_EmptyDocContent(t *testing.T) {
 	mockLog := log.NewMockLog()
 	testParserInfo := DocumentParserInfo{
@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/go-yaml/yaml"
+)
+
+// DocumentFormat is the format of a document file
+type DocumentFormat string
+
+const (
+	// DocumentFormatJson is the format of the JSON documents
+	DocumentFormatJson DocumentFormat = "JSON"
+	// DocumentFormatYaml is the format of the YAML documents
+	DocumentFormatYaml DocumentFormat = "YAML"
+
+	// JsonExtension is the file extension of the JSON documents
+	JsonExtension = ".json"
+	// YamlExtension is the file extension of the YAML documents
+	YamlExtension = ".yaml"
+)
+
+// yamlExtensions are the file extensions of the YAML documents
+var yamlExtensions = []string{YamlExtension, ".yml"}
+
+// DocumentFormatError is a parse error of a document, with its position in the document when known
+type DocumentFormatError struct {
+	Format DocumentFormat
+	Line   int
+	Column int
+	Err    error
+}
+
+// Error returns the parse error with its position
+func (e *DocumentFormatError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("invalid %v document at line %v, column %v: %v", e.Format, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("invalid %v document: %v", e.Format, e.Err)
+}
+
+// FileExtension returns the file extension of the documents of the format, JSON for an unknown format
+func FileExtension(format string) string {
+	if strings.EqualFold(format, string(DocumentFormatYaml)) {
+		return YamlExtension
+	}
+	return JsonExtension
+}
+
+// LoadDocumentContent loads the JSON or YAML document of the file, the format is detected by the file extension or the content
+func LoadDocumentContent(filePath string) (content contracts.DocumentContent, err error) {
+	err = UnmarshalFile(filePath, &content)
+	return content, err
+}
+
+// ParseDocumentContent parses a JSON or YAML document, the format is detected by the content
+func ParseDocumentContent(documentRaw []byte) (content contracts.DocumentContent, err error) {
+	err = unmarshal(documentRaw, DetectDocumentFormat(documentRaw), &content)
+	return content, err
+}
+
+// UnmarshalFile loads a JSON or YAML file into the value, the format is detected by the file extension or the content
+func UnmarshalFile(filePath string, v interface{}) (err error) {
+	var documentRaw []byte
+	if documentRaw, err = ioutil.ReadFile(filePath); err != nil {
+		return err
+	}
+	extension := strings.ToLower(filepath.Ext(filePath))
+	if extension == JsonExtension {
+		return unmarshal(documentRaw, DocumentFormatJson, v)
+	}
+	for _, yamlExtension := range yamlExtensions {
+		if extension == yamlExtension {
+			return unmarshal(documentRaw, DocumentFormatYaml, v)
+		}
+	}
+	return unmarshal(documentRaw, DetectDocumentFormat(documentRaw), v)
+}
+
+// DetectDocumentFormat returns JSON for the documents that are a JSON object, YAML otherwise
+func DetectDocumentFormat(documentRaw []byte) DocumentFormat {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(documentRaw, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return DocumentFormatJson
+	}
+	return DocumentFormatYaml
+}
+
+// unmarshal parses the document of the given format into the value
+func unmarshal(documentRaw []byte, format DocumentFormat, v interface{}) (err error) {
+	if format == DocumentFormatJson {
+		if err = json.Unmarshal(documentRaw, v); err != nil {
+			return newJsonFormatError(documentRaw, err)
+		}
+		return nil
+	}
+
+	// the yaml maps are keyed by interface{}, convert them before filling the value to keep it serializable to json
+	var document interface{}
+	if err = yaml.Unmarshal(documentRaw, &document); err != nil {
+		return &DocumentFormatError{Format: DocumentFormatYaml, Err: err}
+	}
+	if _, isMap := document.(map[interface{}]interface{}); !isMap {
+		return &DocumentFormatError{Format: DocumentFormatYaml, Err: fmt.Errorf("document must be a mapping")}
+	}
+	if err = jsonutil.Remarshal(convertYamlValue(document), v); err != nil {
+		return &DocumentFormatError{Format: DocumentFormatYaml, Err: err}
+	}
+	return nil
+}
+
+// newJsonFormatError locates the json error in the document
+func newJsonFormatError(documentRaw []byte, err error) error {
+	var offset int64
+	switch jsonErr := err.(type) {
+	case *json.SyntaxError:
+		// the offset of a syntax error is past the invalid character
+		offset = jsonErr.Offset - 1
+	case *json.UnmarshalTypeError:
+		offset = jsonErr.Offset
+	default:
+		return &DocumentFormatError{Format: DocumentFormatJson, Err: err}
+	}
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(documentRaw)) {
+		offset = int64(len(documentRaw))
+	}
+	preceding := documentRaw[:offset]
+	line := bytes.Count(preceding, []byte("\n")) + 1
+	column := len(preceding) - bytes.LastIndex(preceding, []byte("\n"))
+	return &DocumentFormatError{Format: DocumentFormatJson, Line: line, Column: column, Err: err}
+}
+
+// convertYamlValue replaces the yaml maps keyed by interface{} by maps keyed by string
+func convertYamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprintf("%v", key)] = convertYamlValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertYamlValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
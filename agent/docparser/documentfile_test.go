@@ -0,0 +1,81 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package docparser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDocumentContent_Yaml(t *testing.T) {
+	content, err := LoadDocumentContent(filepath.Join("testdata", "sampleDocumentVersion2_2.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "2.2", content.SchemaVersion)
+	assert.Equal(t, 1, len(content.MainSteps))
+	assert.Equal(t, "aws:runShellScript", content.MainSteps[0].Action)
+	assert.Equal(t, 60, content.MainSteps[0].Timeout)
+	assert.Equal(t, map[string]interface{}{"runCommand": "{{ commands }}"}, content.MainSteps[0].Inputs)
+
+	// the document loaded from yaml has to be serializable for the messages and the document states
+	_, err = jsonutil.Marshal(content)
+	assert.Nil(t, err)
+}
+
+func TestParseDocumentContent_Json(t *testing.T) {
+	content, err := ParseDocumentContent([]byte(parameterdocument))
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2", content.SchemaVersion)
+	assert.Equal(t, 1, len(content.RuntimeConfig))
+}
+
+func TestParseDocumentContent_YamlContent(t *testing.T) {
+	content, err := ParseDocumentContent([]byte("schemaVersion: '2.2'\nmainSteps:\n- action: aws:runShellScript\n  name: run\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "2.2", content.SchemaVersion)
+	assert.Equal(t, "run", content.MainSteps[0].Name)
+}
+
+func TestParseDocumentContent_InvalidJson(t *testing.T) {
+	_, err := ParseDocumentContent([]byte("{\n  \"schemaVersion\": \"2.2\",\n  \"mainSteps\": [,]\n}"))
+	assert.NotNil(t, err)
+	formatErr, ok := err.(*DocumentFormatError)
+	assert.True(t, ok)
+	assert.Equal(t, DocumentFormatJson, formatErr.Format)
+	assert.Equal(t, 3, formatErr.Line)
+	assert.Equal(t, 17, formatErr.Column)
+	assert.Contains(t, err.Error(), "line 3, column 17")
+}
+
+func TestParseDocumentContent_InvalidYaml(t *testing.T) {
+	_, err := ParseDocumentContent([]byte("schemaVersion: '2.2'\nmainSteps: [\n"))
+	assert.NotNil(t, err)
+	formatErr, ok := err.(*DocumentFormatError)
+	assert.True(t, ok)
+	assert.Equal(t, DocumentFormatYaml, formatErr.Format)
+	assert.Contains(t, err.Error(), "line")
+}
+
+func TestDetectDocumentFormat(t *testing.T) {
+	assert.Equal(t, DocumentFormatJson, DetectDocumentFormat([]byte("\xef\xbb\xbf  {\"schemaVersion\": \"2.2\"}")))
+	assert.Equal(t, DocumentFormatYaml, DetectDocumentFormat([]byte("---\nschemaVersion: '2.2'")))
+}
+
+func TestFileExtension(t *testing.T) {
+	assert.Equal(t, ".yaml", FileExtension("YAML"))
+	assert.Equal(t, ".json", FileExtension("JSON"))
+	assert.Equal(t, ".json", FileExtension(""))
+}
@@ -30,12 +30,24 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/twinj/uuid"
 )
 
 const (
 	// envVar* constants are names of environment variables set for processes executed by ssm agent and should start with AWS_SSM_
 	envVarInstanceID = "AWS_SSM_INSTANCE_ID"
 	envVarRegionName = "AWS_SSM_REGION_NAME"
+	// envVarProcessTreeID marks the processes started by a command, including the ones detached from its process group
+	envVarProcessTreeID = "AWS_SSM_PROCESS_TREE_ID"
+
+	// EnvVarCommandID is the name of the environment variable holding the id of the command running the process
+	EnvVarCommandID = "AWS_SSM_COMMAND_ID"
+	// EnvVarDocumentName is the name of the environment variable holding the name of the document running the process
+	EnvVarDocumentName = "AWS_SSM_DOCUMENT_NAME"
+	// EnvVarStepName is the name of the environment variable holding the name of the step running the process
+	EnvVarStepName = "AWS_SSM_STEP_NAME"
+	// EnvVarOrchestrationDirectory is the name of the environment variable holding the orchestration directory of the step
+	EnvVarOrchestrationDirectory = "AWS_SSM_ORCHESTRATION_DIRECTORY"
 )
 
 // T is the interface type for ShellCommandExecuter.
@@ -43,6 +55,7 @@ type T interface {
 	//TODO: Remove Execute and rename NewExecute to Execute.
 	Execute(log.T, string, string, string, task.CancelFlag, int, string, []string) (io.Reader, io.Reader, int, []error)
 	NewExecute(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string) (int, error)
+	ExecuteWithOptions(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string, ExecuteOptions) (int, error)
 	StartExe(log.T, string, io.Writer, io.Writer, task.CancelFlag, string, []string) (*os.Process, int, error)
 }
 
@@ -50,6 +63,46 @@ type T interface {
 type ShellCommandExecuter struct {
 }
 
+// ExecuteOptions holds the optional settings of the process executing the commands
+type ExecuteOptions struct {
+	// RunAsUser is the name or id of the user the process runs as, the process runs as the agent user if empty
+	RunAsUser string
+	// RunAsGroup is the name or id of the group the process runs as, the primary group of the user is used if empty
+	RunAsGroup string
+	// Environment holds the environment variables set for the process in addition to the ones of the agent
+	Environment map[string]string
+}
+
+// processTree identifies the processes started by a command, they inherit the marker of the tree in their environment
+type processTree struct {
+	marker string
+}
+
+// newProcessTree sets a new process tree marker in the environment of the command
+func newProcessTree(command *exec.Cmd) *processTree {
+	tree := &processTree{marker: uuid.NewV4().String()}
+	command.Env = setEnvVariable(command.Env, envVarProcessTreeID, tree.marker)
+	return tree
+}
+
+// ProcessTree tracks the processes started by a command, including the ones detached from its process group
+type ProcessTree struct {
+	command *exec.Cmd
+	tree    *processTree
+}
+
+// TrackProcessTree marks the processes the command starts, it must be called once the environment of the command is set
+// and before the command is started.
+func TrackProcessTree(command *exec.Cmd) *ProcessTree {
+	return &ProcessTree{command: command, tree: newProcessTree(command)}
+}
+
+// Kill kills the process of the command along with the processes it started, and returns the processes it left behind.
+// It blocks until the processes exited, up to killGracePeriod plus killWaitPeriod when they ignore SIGTERM.
+func (t *ProcessTree) Kill(log log.T) (leftovers []string, err error) {
+	return killProcessTree(log, t.command.Process, t.tree, &timeoutSignal{})
+}
+
 type timeoutSignal struct {
 	// process kill doesn't send proper signal to the process status
 	// Setting the execInterruptedOnWindows to indicate execution was interrupted
@@ -173,6 +226,23 @@ func (ShellCommandExecuter) NewExecute(
 	return
 }
 
+// ExecuteWithOptions executes a list of shell commands in the given working directory with the given process options
+// and provides the stdout and stderr writers.
+func (ShellCommandExecuter) ExecuteWithOptions(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
+	exitCode, err = ExecuteCommandWithOptions(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, options)
+	return
+}
+
 // StartExe starts a list of shell commands in the given working directory.
 // Returns process started, an exit code (0 if successfully launch, 1 if error launching process), and a set of errors.
 // The errors need not be fatal - the output streams may still have data
@@ -254,6 +324,21 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return ExecuteCommandWithOptions(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, ExecuteOptions{})
+}
+
+// ExecuteCommandWithOptions executes the given commands using the given working directory and process options.
+// Standard output and standard error are sent to the given writers.
+func ExecuteCommandWithOptions(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	options ExecuteOptions,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -284,6 +369,17 @@ func ExecuteCommand(log log.T,
 
 	// configure environment variables
 	prepareEnvironment(command)
+	for name, value := range options.Environment {
+		command.Env = setEnvVariable(command.Env, name, value)
+	}
+	tree := newProcessTree(command)
+
+	// configure the user and group running the process
+	if err = prepareRunAs(command, options); err != nil {
+		log.Errorf("failed to run the command as user %v group %v: %v", options.RunAsUser, options.RunAsGroup, err)
+		exitCode = 1
+		return
+	}
 
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
@@ -315,7 +411,7 @@ func ExecuteCommand(log log.T,
 	case <-time.After(time.Duration(executionTimeout) * time.Second):
 		stopStdout <- true
 		stopStderr <- true
-		if err = killProcess(command.Process, &signal); err != nil {
+		if err = stopProcessTree(log, command.Process, tree, &signal, stderrWriter); err != nil {
 			exitCode = 1
 			log.Error(err)
 		} else {
@@ -329,7 +425,7 @@ func ExecuteCommand(log log.T,
 		log.Debug("Process cancelled. Attempting to stop process.")
 		stopStdout <- true
 		stopStderr <- true
-		if err = killProcess(command.Process, &signal); err != nil {
+		if err = stopProcessTree(log, command.Process, tree, &signal, stderrWriter); err != nil {
 			exitCode = 1
 			log.Error(err)
 		} else {
@@ -401,6 +497,7 @@ func StartCommand(log log.T,
 
 	// configure environment variables
 	prepareEnvironment(command)
+	tree := newProcessTree(command)
 
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
@@ -417,16 +514,17 @@ func StartCommand(log log.T,
 	// the writer when it is a file handle and when the cancellable writer is assigned, it doesn't (by design) give
 	// a reference to the file handle to the process
 	cancelChannel := make(chan bool, 2)
-	go killProcessOnCancel(log, command, cancelChannel, cancelChannel, cancelFlag, &signal)
+	go killProcessOnCancel(log, command, tree, cancelChannel, cancelChannel, cancelFlag, &signal, stderrWriter)
 
 	return
 }
 
 // killProcessOnCancel waits for a cancel request.
 // If a cancel request is received, this method kills the underlying
-// process of the command. This will unblock the command.Wait() call.
+// process of the command along with the processes it started, and reports the processes left behind in the error output.
+// This will unblock the command.Wait() call.
 // If the task completed successfully this method returns with no action.
-func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, cancelStderr chan bool, cancelFlag task.CancelFlag, signal *timeoutSignal) {
+func killProcessOnCancel(log log.T, command *exec.Cmd, tree *processTree, cancelStdout chan bool, cancelStderr chan bool, cancelFlag task.CancelFlag, signal *timeoutSignal, stderrWriter io.Writer) {
 	cancelFlag.Wait()
 	if cancelFlag.Canceled() {
 		log.Debug("Process cancelled. Attempting to stop process.")
@@ -436,7 +534,7 @@ func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, c
 		runtime.Gosched()
 
 		// task has been asked to cancel, kill process
-		if err := killProcess(command.Process, signal); err != nil {
+		if err := stopProcessTree(log, command.Process, tree, signal, stderrWriter); err != nil {
 			log.Error(err)
 		} else {
 			log.Debug("Process stopped successfully.")
@@ -445,6 +543,27 @@ func killProcessOnCancel(log log.T, command *exec.Cmd, cancelStdout chan bool, c
 	}
 }
 
+// stopProcessTree kills the process of the command along with the processes it started,
+// and reports the processes left behind by the command in its error output.
+// It blocks until the processes exited, up to killGracePeriod plus killWaitPeriod (7 seconds) when they ignore SIGTERM.
+func stopProcessTree(log log.T, process *os.Process, tree *processTree, signal *timeoutSignal, stderrWriter io.Writer) error {
+	leftovers, err := killProcessTree(log, process, tree, signal)
+	if len(leftovers) > 0 {
+		message := fmt.Sprintf("Stopped processes left behind by the command: %v", strings.Join(leftovers, ", "))
+		log.Info(message)
+		fmt.Fprintln(stderrWriter, message)
+	}
+	if err != nil {
+		fmt.Fprintln(stderrWriter, err)
+	}
+	return err
+}
+
+// PrepareRunAs sets up the command to run as the user and group of the options, the command runs as the agent user if they are empty
+func PrepareRunAs(command *exec.Cmd, options ExecuteOptions) error {
+	return prepareRunAs(command, options)
+}
+
 // prepareEnvironment adds ssm agent standard environment variables to the command
 func prepareEnvironment(command *exec.Cmd) {
 	env := os.Environ()
@@ -460,6 +579,18 @@ func prepareEnvironment(command *exec.Cmd) {
 	validateEnvironmentVariables(command)
 }
 
+// setEnvVariable sets the value of the environment variable, replacing its current value if any
+func setEnvVariable(env []string, name string, val string) []string {
+	prefix := name + "="
+	for i, variable := range env {
+		if strings.HasPrefix(variable, prefix) {
+			env[i] = fmtEnvVariable(name, val)
+			return env
+		}
+	}
+	return append(env, fmtEnvVariable(name, val))
+}
+
 // fmtEnvVariable creates the string to append to the current set of environment variables.
 func fmtEnvVariable(name string, val string) string {
 	return fmt.Sprintf("%s=%s", name, val)
@@ -132,3 +132,12 @@ func TestQuotePsStringWithQuotes(t *testing.T) {
 	result = QuotePsString("`abc`")
 	assert.Equal(t, "\"``abc``\"", result)
 }
+
+func TestSetEnvVariable(t *testing.T) {
+	env := []string{"PATH=/usr/bin", "HOME=/root"}
+
+	env = setEnvVariable(env, "HOME", "/home/ec2-user")
+	env = setEnvVariable(env, "USER", "ec2-user")
+
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/home/ec2-user", "USER=ec2-user"}, env)
+}
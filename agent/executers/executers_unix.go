@@ -16,12 +16,23 @@
 package executers
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	osuser "os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/user"
+)
+
+const (
+	// runAsDirPrefix is the prefix of the directories of the steps running as another user
+	runAsDirPrefix = "ssm-runas-"
 )
 
 func prepareProcess(command *exec.Cmd) {
@@ -30,19 +41,6 @@ func prepareProcess(command *exec.Cmd) {
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
-func killProcess(process *os.Process, signal *timeoutSignal) error {
-	//   NOTE: go only kills the process but not its sub processes.
-	//   The consequence is that command.Wait() does not return, for some reason.
-	//   As a workaround we use some (platform specific) magic:
-	//     syscall.Kill(-pid, syscall.SIGKILL)
-	//   Here '-pid' means that the KILL signal is sent to all processes
-	//   in the process group whose id is 'pid'. 'prepareProcess' makes
-	//   the shell we spawn the leader of its own process group and so
-	//   the kill here not just kills the shell but all its descendant
-	//   processes. [See manpage for kill(2)]
-	return syscall.Kill(-process.Pid, syscall.SIGKILL) // note the minus sign
-}
-
 // Running powershell on linux erquired the HOME env variable to be set and to remove the TERM env variable
 func validateEnvironmentVariables(command *exec.Cmd) {
 
@@ -64,3 +62,178 @@ func validateEnvironmentVariables(command *exec.Cmd) {
 		command.Env = env
 	}
 }
+
+// prepareRunAs sets the credential of the process to the user and group of the options,
+// and points the user specific environment variables to that user.
+func prepareRunAs(command *exec.Cmd, options ExecuteOptions) error {
+	if options.RunAsUser == "" && options.RunAsGroup == "" {
+		return nil
+	}
+
+	credential, runAsUser, err := runAsCredential(options)
+	if err != nil {
+		return err
+	}
+
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Credential = credential
+
+	if options.RunAsUser != "" {
+		command.Env = setEnvVariable(command.Env, "HOME", runAsUser.HomeDir)
+		command.Env = setEnvVariable(command.Env, "USER", runAsUser.Username)
+		command.Env = setEnvVariable(command.Env, "LOGNAME", runAsUser.Username)
+	}
+	return nil
+}
+
+// runAsRootDir is where the directories of the steps running as another user are created
+var runAsRootDir = os.TempDir()
+
+// GrantRunAsAccess copies the script into a new directory owned by the user and group of the options,
+// so that they can run it while the directories of the agent remain private to the agent.
+// The working directory is copied along with the script when it is inside the private directory of the agent,
+// e.g. the downloads directory of the document, the changes the commands make to the copy are discarded.
+// It returns the paths of the copies and a function removing the directory, to call once the step ends.
+func GrantRunAsAccess(scriptPath string, workingDir string, privateDir string, options ExecuteOptions) (runAsScriptPath string, runAsWorkingDir string, revoke func(), err error) {
+	if options.RunAsUser == "" && options.RunAsGroup == "" {
+		return scriptPath, workingDir, func() {}, nil
+	}
+
+	credential, _, err := runAsCredential(options)
+	if err != nil {
+		return "", "", nil, err
+	}
+	content, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	runAsDir, err := ioutil.TempDir(runAsRootDir, runAsDirPrefix)
+	if err != nil {
+		return "", "", nil, err
+	}
+	revoke = func() { os.RemoveAll(runAsDir) }
+	uid, gid := int(credential.Uid), int(credential.Gid)
+
+	runAsScriptPath = filepath.Join(runAsDir, filepath.Base(scriptPath))
+	if err = ioutil.WriteFile(runAsScriptPath, content, appconfig.ReadWriteExecuteAccess); err != nil {
+		revoke()
+		return "", "", nil, err
+	}
+	for _, path := range []string{runAsScriptPath, runAsDir} {
+		if err = os.Chown(path, uid, gid); err != nil {
+			revoke()
+			return "", "", nil, err
+		}
+	}
+
+	runAsWorkingDir = workingDir
+	if workingDir != "" && isUnderDir(workingDir, privateDir) {
+		runAsWorkingDir = filepath.Join(runAsDir, filepath.Base(workingDir))
+		if err = copyTree(workingDir, runAsWorkingDir, uid, gid); err != nil {
+			revoke()
+			return "", "", nil, fmt.Errorf("failed to copy the working directory %v, %v", workingDir, err)
+		}
+	}
+	return runAsScriptPath, runAsWorkingDir, revoke, nil
+}
+
+// isUnderDir returns true if the path is the directory or a path inside it
+func isUnderDir(path string, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	relative, err := filepath.Rel(dir, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// copyTree copies the directory with its files, directories and symbolic links, the copies are owned by the user and group
+func copyTree(source string, destination string, uid int, gid int) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relative)
+		switch {
+		case info.IsDir():
+			if err = os.Mkdir(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err = os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(target, content, info.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			// devices, sockets and pipes are not copied
+			return nil
+		}
+		return os.Lchown(target, uid, gid)
+	})
+}
+
+// runAsCredential returns the credential of the user and group of the options
+func runAsCredential(options ExecuteOptions) (*syscall.Credential, *osuser.User, error) {
+	runAsUser, err := lookupUser(options.RunAsUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	uid, err := strconv.ParseUint(runAsUser.Uid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid uid %v of user %v", runAsUser.Uid, runAsUser.Username)
+	}
+
+	gidString := runAsUser.Gid
+	if options.RunAsGroup != "" {
+		group, err := user.LookupGroup(options.RunAsGroup)
+		if err != nil {
+			return nil, nil, err
+		}
+		gidString = group.Gid
+	}
+	gid, err := strconv.ParseUint(gidString, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gid %v of group %v", gidString, options.RunAsGroup)
+	}
+
+	// the supplementary groups are the ones of the user, as for a login, instead of the ones of the agent
+	groupIds, err := user.LookupGroupIds(runAsUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := make([]uint32, 0, len(groupIds))
+	for _, groupId := range groupIds {
+		groupGid, err := strconv.ParseUint(groupId, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid gid %v of a group of user %v", groupId, runAsUser.Username)
+		}
+		groups = append(groups, uint32(groupGid))
+	}
+
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}, runAsUser, nil
+}
+
+// lookupUser returns the user with the given name or id, or the agent user if no name is given
+func lookupUser(username string) (*osuser.User, error) {
+	if username == "" {
+		return user.Current()
+	}
+	return user.Lookup(username)
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+// Package executers contains general purpose (shell) command executing objects.
+package executers
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareRunAs(t *testing.T) {
+	command := exec.Command("id")
+	prepareProcess(command)
+	command.Env = []string{"HOME=/home/agent"}
+
+	err := prepareRunAs(command, ExecuteOptions{RunAsUser: "root", RunAsGroup: "0"})
+
+	assert.NoError(t, err)
+	assert.True(t, command.SysProcAttr.Setpgid)
+	assert.Equal(t, uint32(0), command.SysProcAttr.Credential.Uid)
+	assert.Equal(t, uint32(0), command.SysProcAttr.Credential.Gid)
+	assert.Contains(t, command.SysProcAttr.Credential.Groups, uint32(0))
+	assert.Equal(t, "root", getEnvVariableValue(command.Env, "USER"))
+	assert.NotEqual(t, "/home/agent", getEnvVariableValue(command.Env, "HOME"))
+}
+
+func TestPrepareRunAsWithoutUser(t *testing.T) {
+	command := exec.Command("id")
+	prepareProcess(command)
+
+	err := prepareRunAs(command, ExecuteOptions{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, command.SysProcAttr.Credential)
+}
+
+func TestPrepareRunAsWithUnknownUser(t *testing.T) {
+	command := exec.Command("id")
+	prepareProcess(command)
+
+	assert.Error(t, prepareRunAs(command, ExecuteOptions{RunAsUser: "no-such-user-for-ssm-agent"}))
+	assert.Error(t, prepareRunAs(command, ExecuteOptions{RunAsGroup: "no-such-group-for-ssm-agent"}))
+}
+
+func TestGrantRunAsAccess(t *testing.T) {
+	origRunAsRootDir := runAsRootDir
+	defer func() { runAsRootDir = origRunAsRootDir }()
+	orchestrationDir, err := ioutil.TempDir("", "orchestration")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	runAsRootDir, err = ioutil.TempDir("", "runas")
+	assert.NoError(t, err)
+	defer os.RemoveAll(runAsRootDir)
+
+	scriptPath := filepath.Join(orchestrationDir, "_script.sh")
+	assert.NoError(t, ioutil.WriteFile(scriptPath, []byte("id\n"), 0700))
+	assert.NoError(t, os.Chmod(orchestrationDir, 0700))
+
+	runAsScriptPath, runAsWorkingDir, revoke, err := GrantRunAsAccess(scriptPath, "/tmp", orchestrationDir, ExecuteOptions{RunAsUser: "root"})
+	assert.NoError(t, err)
+	assert.Equal(t, runAsRootDir, filepath.Dir(filepath.Dir(runAsScriptPath)))
+	// the working directories outside of the directories of the agent are used as is
+	assert.Equal(t, "/tmp", runAsWorkingDir)
+	content, err := ioutil.ReadFile(runAsScriptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\n", string(content))
+
+	// the directories of the agent are left private
+	info, err := os.Stat(orchestrationDir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	revoke()
+	_, err = os.Stat(filepath.Dir(runAsScriptPath))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGrantRunAsAccessToDownloadsDirectory(t *testing.T) {
+	origRunAsRootDir := runAsRootDir
+	defer func() { runAsRootDir = origRunAsRootDir }()
+	orchestrationDir, err := ioutil.TempDir("", "orchestration")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	runAsRootDir, err = ioutil.TempDir("", "runas")
+	assert.NoError(t, err)
+	defer os.RemoveAll(runAsRootDir)
+
+	// a relative working directory of a step is inside the downloads directory of the document
+	scriptPath := filepath.Join(orchestrationDir, "aws:runShellScript", "_script.sh")
+	workingDir := filepath.Join(orchestrationDir, "downloads", "scripts")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(scriptPath), 0700))
+	assert.NoError(t, os.MkdirAll(filepath.Join(workingDir, "lib"), 0700))
+	assert.NoError(t, ioutil.WriteFile(scriptPath, []byte("./install.sh\n"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "install.sh"), []byte("echo installed\n"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "lib", "common.sh"), []byte("true\n"), 0600))
+	assert.NoError(t, os.Symlink("lib/common.sh", filepath.Join(workingDir, "common.sh")))
+
+	runAsScriptPath, runAsWorkingDir, revoke, err := GrantRunAsAccess(scriptPath, workingDir, orchestrationDir, ExecuteOptions{RunAsUser: "root"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Dir(runAsScriptPath), filepath.Dir(runAsWorkingDir))
+	assert.Equal(t, "scripts", filepath.Base(runAsWorkingDir))
+	content, err := ioutil.ReadFile(filepath.Join(runAsWorkingDir, "install.sh"))
+	assert.NoError(t, err)
+	assert.Equal(t, "echo installed\n", string(content))
+	content, err = ioutil.ReadFile(filepath.Join(runAsWorkingDir, "common.sh"))
+	assert.NoError(t, err)
+	assert.Equal(t, "true\n", string(content))
+
+	revoke()
+	_, err = os.Stat(runAsWorkingDir)
+	assert.True(t, os.IsNotExist(err))
+	// the downloads directory of the agent is left in place
+	assert.True(t, fileutil.Exists(filepath.Join(workingDir, "install.sh")))
+}
+
+func TestGrantRunAsAccessWithoutUser(t *testing.T) {
+	runAsScriptPath, runAsWorkingDir, revoke, err := GrantRunAsAccess("/orchestration/_script.sh", "/orchestration/downloads", "/orchestration", ExecuteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/orchestration/_script.sh", runAsScriptPath)
+	assert.Equal(t, "/orchestration/downloads", runAsWorkingDir)
+	revoke()
+}
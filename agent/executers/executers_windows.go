@@ -16,8 +16,11 @@
 package executers
 
 import (
+	"errors"
 	"os"
 	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
 const (
@@ -35,6 +38,30 @@ func killProcess(process *os.Process, signal *timeoutSignal) error {
 	return process.Kill()
 }
 
+// killProcessTree kills the process, the processes it started are not tracked on windows
+func killProcessTree(log log.T, process *os.Process, tree *processTree, signal *timeoutSignal) (leftovers []string, err error) {
+	return nil, killProcess(process, signal)
+}
+
 // Running powershell on linux required the HOME env variable to be set and to remove the TERM env variable
 func validateEnvironmentVariables(command *exec.Cmd) {
 }
+
+func prepareRunAs(command *exec.Cmd, options ExecuteOptions) error {
+	return validateRunAs(options)
+}
+
+// GrantRunAsAccess is not needed on windows, where running commands as another user is not supported
+func GrantRunAsAccess(scriptPath string, workingDir string, privateDir string, options ExecuteOptions) (runAsScriptPath string, runAsWorkingDir string, revoke func(), err error) {
+	if err = validateRunAs(options); err != nil {
+		return "", "", nil, err
+	}
+	return scriptPath, workingDir, func() {}, nil
+}
+
+func validateRunAs(options ExecuteOptions) error {
+	if options.RunAsUser != "" || options.RunAsGroup != "" {
+		return errors.New("running commands as another user is not supported on windows")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd netbsd openbsd
+
+package executers
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Assign to global variables to allow unittest to override
+var ps = func() ([]byte, error) {
+	return exec.Command("ps", "-A", "-o", "pid=,ppid=,pgid=,stat=,comm=").CombinedOutput()
+}
+
+// listProcesses reads the processes from ps, the environment of the processes is not available
+// so the processes detached from the process tree cannot be found
+func listProcesses() ([]processInfo, error) {
+	output, err := ps()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []processInfo
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		var p processInfo
+		var err error
+		if p.pid, err = strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		if p.ppid, err = strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		if p.pgid, err = strconv.Atoi(fields[2]); err != nil {
+			continue
+		}
+		p.zombie = strings.HasPrefix(fields[3], "Z")
+		p.name = strings.Join(fields[4:], " ")
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Assign to global variables to allow unittest to override
+var procDirectory = "/proc"
+
+// listProcesses reads the processes from the proc file system, along with their process tree marker
+func listProcesses() ([]processInfo, error) {
+	entries, err := ioutil.ReadDir(procDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// the process may exit while being read
+		stat, err := ioutil.ReadFile(filepath.Join(procDirectory, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		p, err := parseProcStat(pid, string(stat))
+		if err != nil {
+			continue
+		}
+		if environ, err := ioutil.ReadFile(filepath.Join(procDirectory, entry.Name(), "environ")); err == nil {
+			p.marker = environVariable(environ, envVarProcessTreeID)
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+// parseProcStat parses the content of /proc/[pid]/stat, the name of the process is between parentheses and may contain spaces
+func parseProcStat(pid int, stat string) (p processInfo, err error) {
+	start, end := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+	if start < 0 || end < start {
+		return p, fmt.Errorf("invalid stat of process %v", pid)
+	}
+	// fields after the name: state ppid pgrp ...
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 3 {
+		return p, fmt.Errorf("invalid stat of process %v", pid)
+	}
+	p.pid = pid
+	p.name = stat[start+1 : end]
+	p.zombie = fields[0] == "Z"
+	if p.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return p, err
+	}
+	if p.pgid, err = strconv.Atoi(fields[2]); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// environVariable returns the value of the variable in the content of /proc/[pid]/environ
+func environVariable(environ []byte, name string) string {
+	prefix := []byte(name + "=")
+	for _, variable := range bytes.Split(environ, []byte{0}) {
+		if bytes.HasPrefix(variable, prefix) {
+			return string(variable[len(prefix):])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package executers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcStat(t *testing.T) {
+	p, err := parseProcStat(1234, "1234 (tail -f) S 1 1230 1230 0 -1 4194560 109 0 0 0 0 0 0 0 20 0 1 0")
+	assert.NoError(t, err)
+	assert.Equal(t, processInfo{pid: 1234, ppid: 1, pgid: 1230, name: "tail -f"}, p)
+
+	p, err = parseProcStat(1235, "1235 (sh (1)) Z 1234 1230 1230 0")
+	assert.NoError(t, err)
+	assert.Equal(t, "sh (1)", p.name)
+	assert.True(t, p.zombie)
+
+	_, err = parseProcStat(1236, "1236 sh")
+	assert.Error(t, err)
+}
+
+func TestEnvironVariable(t *testing.T) {
+	environ := []byte("PATH=/usr/bin\x00AWS_SSM_PROCESS_TREE_ID=marker\x00HOME=/root\x00")
+	assert.Equal(t, "marker", environVariable(environ, envVarProcessTreeID))
+	assert.Equal(t, "", environVariable(environ, "USER"))
+}
+
+func TestProcessTreeMembers(t *testing.T) {
+	command := exec.Command("sh", "-c", "sleep 60 & wait")
+	prepareProcess(command)
+	prepareEnvironment(command)
+	tree := newProcessTree(command)
+	assert.NoError(t, command.Start())
+	defer command.Wait()
+	defer killProcessTree(log.NewMockLog(), command.Process, tree, &timeoutSignal{})
+
+	// wait for the shell to start sleep
+	var members []processInfo
+	for i := 0; i < 50 && len(members) < 2; i++ {
+		time.Sleep(killPollInterval)
+		members = tree.members(log.NewMockLog(), command.Process.Pid)
+	}
+	assert.Len(t, members, 2)
+	assert.Equal(t, command.Process.Pid, members[0].pid)
+
+	// another tree does not include the processes
+	otherTree := &processTree{marker: "other"}
+	assert.Len(t, otherTree.members(log.NewMockLog(), 1<<30), 0)
+}
+
+func TestExecuteCommandCancelStopsDetachedProcesses(t *testing.T) {
+	origKillGracePeriod := killGracePeriod
+	defer func() { killGracePeriod = origKillGracePeriod }()
+	killGracePeriod = time.Second
+
+	// the first sleep leaves the process group of the shell, the second one ignores SIGTERM
+	script := "setsid sleep 61 & (trap '' TERM; sleep 62) & wait"
+	cancelFlag := task.NewChanneledCancelFlag()
+	go func() {
+		time.Sleep(time.Second)
+		cancelFlag.Set(task.Canceled)
+	}()
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := ExecuteCommand(log.NewMockLog(), cancelFlag, "", &stdout, &stderr, 60, "sh", []string{"-c", script})
+	assert.Error(t, err)
+	assert.Equal(t, appconfig.CommandStoppedPreemptivelyExitCode, exitCode)
+	assert.Contains(t, stderr.String(), "Stopped processes left behind by the command")
+	assert.Equal(t, 2, strings.Count(stderr.String(), "(sleep)"))
+
+	output, _ := exec.Command("ps", "-e", "-o", "args=").CombinedOutput()
+	assert.NotContains(t, string(output), "sleep 61")
+	assert.NotContains(t, string(output), "sleep 62")
+}
+
+func TestStartCommandCancelReportsLeftovers(t *testing.T) {
+	origKillGracePeriod := killGracePeriod
+	defer func() { killGracePeriod = origKillGracePeriod }()
+	killGracePeriod = time.Second
+
+	stderr, err := ioutil.TempFile("", "stderr")
+	assert.NoError(t, err)
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	process, exitCode, err := StartCommand(log.NewMockLog(), cancelFlag, "", ioutil.Discard, stderr, "sh", []string{"-c", "setsid sleep 63 & wait"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	time.Sleep(time.Second)
+	cancelFlag.Set(task.Canceled)
+	process.Wait()
+
+	// the leftovers are reported once the processes are stopped
+	var output []byte
+	for i := 0; i < 50 && !strings.Contains(string(output), "(sleep)"); i++ {
+		time.Sleep(killPollInterval)
+		output, _ = ioutil.ReadFile(stderr.Name())
+	}
+	assert.Contains(t, string(output), "Stopped processes left behind by the command")
+	assert.Contains(t, string(output), "(sleep)")
+}
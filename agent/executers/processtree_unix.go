@@ -0,0 +1,144 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package executers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// killPollInterval is the interval between two checks of the processes left in the tree
+	killPollInterval = 100 * time.Millisecond
+)
+
+// Assign to global variables to allow unittest to override
+// Stopping a process tree on timeout or cancel blocks the caller for up to killGracePeriod plus killWaitPeriod:
+// the processes get killGracePeriod to handle SIGTERM, then killWaitPeriod to exit after SIGKILL.
+var killGracePeriod = 5 * time.Second
+var killWaitPeriod = 2 * time.Second
+
+// processInfo describes a running process
+type processInfo struct {
+	pid    int
+	ppid   int
+	pgid   int
+	name   string
+	zombie bool
+	// marker is the process tree marker in the environment of the process, empty if unknown
+	marker string
+}
+
+func (p processInfo) String() string {
+	return fmt.Sprintf("%v (%v)", p.pid, p.name)
+}
+
+// members returns the running processes of the tree rooted at the given process:
+// the processes of its process group, its descendants and the processes inheriting the marker of the tree.
+func (t *processTree) members(log log.T, rootPid int) []processInfo {
+	processes, err := listProcesses()
+	if err != nil {
+		log.Debugf("failed to list the processes: %v", err)
+		return nil
+	}
+
+	children := make(map[int][]int)
+	for _, p := range processes {
+		children[p.ppid] = append(children[p.ppid], p.pid)
+	}
+	descendants := make(map[int]bool)
+	for queue := []int{rootPid}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			if !descendants[child] {
+				descendants[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var members []processInfo
+	agentPid := os.Getpid()
+	for _, p := range processes {
+		if p.zombie || p.pid == agentPid {
+			continue
+		}
+		if p.pid == rootPid || p.pgid == rootPid || descendants[p.pid] || (p.marker != "" && p.marker == t.marker) {
+			members = append(members, p)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].pid < members[j].pid })
+	return members
+}
+
+// killProcessTree asks the processes of the tree to terminate, and kills the ones still running after a grace period.
+// It returns the processes of the tree other than the given process, and an error listing the processes that could not be stopped.
+func killProcessTree(log log.T, process *os.Process, tree *processTree, signal *timeoutSignal) (leftovers []string, err error) {
+	//   NOTE: go only kills the process but not its sub processes.
+	//   The consequence is that command.Wait() does not return, for some reason.
+	//   'prepareProcess' makes the shell we spawn the leader of its own process group,
+	//   the signals are sent to the process group with the minus sign [See manpage for kill(2)]
+	//   as well as to the processes that left it, e.g. daemons calling setsid.
+	members := tree.members(log, process.Pid)
+	for _, p := range members {
+		if p.pid != process.Pid {
+			leftovers = append(leftovers, p.String())
+		}
+	}
+
+	signalProcesses(process.Pid, members, syscall.SIGTERM)
+	remaining := waitForProcessTree(log, process.Pid, tree, killGracePeriod)
+	if len(remaining) == 0 {
+		return leftovers, nil
+	}
+
+	log.Debugf("processes %v did not terminate within %v, killing them", remaining, killGracePeriod)
+	signalProcesses(process.Pid, remaining, syscall.SIGKILL)
+	remaining = waitForProcessTree(log, process.Pid, tree, killWaitPeriod)
+	if len(remaining) == 0 {
+		return leftovers, nil
+	}
+	survivors := make([]string, len(remaining))
+	for i, p := range remaining {
+		survivors[i] = p.String()
+	}
+	return leftovers, fmt.Errorf("failed to stop processes: %v", strings.Join(survivors, ", "))
+}
+
+// signalProcesses sends the signal to the process group and to every process
+func signalProcesses(pgid int, processes []processInfo, signal syscall.Signal) {
+	syscall.Kill(-pgid, signal) // note the minus sign
+	for _, p := range processes {
+		syscall.Kill(p.pid, signal)
+	}
+}
+
+// waitForProcessTree waits until the processes of the tree exit or the period elapses, and returns the processes still running
+func waitForProcessTree(log log.T, rootPid int, tree *processTree, period time.Duration) []processInfo {
+	deadline := time.Now().Add(period)
+	for {
+		members := tree.members(log, rootPid)
+		if len(members) == 0 || time.Now().After(deadline) {
+			return members
+		}
+		time.Sleep(killPollInterval)
+	}
+}
@@ -233,7 +233,9 @@ func initializeClientDiagnostics(log logger.T) *cloudwatchlogspublisher.CloudWat
 
 // Start executes the registered core modules while watching for reboot request
 func (c *CoreManager) Start() {
-	go c.watchForReboot()
+	// the coordinator removes the request of the completed reboot before the core modules start
+	coordinator := rebooter.NewCoordinator(c.context.Log(), c.context.AppConfig().Reboot)
+	go c.watchForReboot(coordinator)
 	c.executeCoreModules()
 }
 
@@ -298,20 +300,24 @@ func (c *CoreManager) stopCoreModules(stopType contracts.StopType) {
 	}
 }
 
-// watchForReboot watches for reboot events and request core modules to stop when necessary
-func (c *CoreManager) watchForReboot() {
+// watchForReboot reboots once the reboot coordinator lets the pending reboot proceed, the reboot pending before
+// the agent started is checked once, the following requests come through the reboot channel
+func (c *CoreManager) watchForReboot(coordinator *rebooter.Coordinator) {
 	log := c.context.Log()
 
-	ch := rebooter.GetChannel()
-	// blocking receive
-	val := <-ch
-	log.Info("A plugin has requested a reboot.")
-	if val == rebooter.RebootRequestTypeReboot {
-		log.Info("Processing reboot request...")
-		c.stopCoreModules(contracts.StopTypeSoftStop)
-		rebooter.RebootMachine(log)
-	} else {
-		log.Error("reboot type not supported yet")
+	if !coordinator.IsRebootPending() {
+		// blocking receive
+		val := <-rebooter.GetChannel()
+		log.Info("A plugin has requested a reboot.")
+		if val != rebooter.RebootRequestTypeReboot {
+			log.Error("reboot type not supported yet")
+			return
+		}
 	}
 
+	log.Info("Processing reboot request...")
+	coordinator.WaitForReboot()
+	coordinator.RunPreRebootHooks()
+	c.stopCoreModules(contracts.StopTypeSoftStop)
+	coordinator.Reboot()
 }
@@ -18,8 +18,10 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	hummingbird "github.com/aws/amazon-ssm-agent/agent/hummingbird/module"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand"
+	"github.com/aws/amazon-ssm-agent/agent/session"
 	"github.com/aws/amazon-ssm-agent/agent/startup"
 )
 
@@ -40,7 +42,20 @@ func RegisteredCoreModules(context context.T) *ModuleRegistry {
 // register core modules here
 func loadCoreModules(context context.T) {
 	registeredCoreModules = append(registeredCoreModules, health.NewHealthCheck(context))
-	registeredCoreModules = append(registeredCoreModules, runcommand.NewMDSService(context))
+	mdsService := runcommand.NewMDSService(context)
+	registeredCoreModules = append(registeredCoreModules, mdsService)
+
+	// the commands pushed by HummingBird are processed as the ones polled from MDS, the sessions it requests by the session manager
+	if mdsService != nil {
+		var startSession hummingbird.SessionHandler
+		if sessionManager := session.NewManager(context); sessionManager != nil {
+			registeredCoreModules = append(registeredCoreModules, sessionManager)
+			startSession = sessionManager.StartSession
+		}
+		if hummingBird := hummingbird.NewHummingBird(context, mdsService.ProcessPushedMessage, startSession); hummingBird != nil {
+			registeredCoreModules = append(registeredCoreModules, hummingBird)
+		}
+	}
 
 	if offlineProcessor, err := runcommand.NewOfflineService(context); err == nil {
 		registeredCoreModules = append(registeredCoreModules, offlineProcessor)
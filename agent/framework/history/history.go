@@ -0,0 +1,283 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package history keeps the local history of the documents executed on the instance
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const recordFileExtension = ".json"
+
+// Record is the history of a document execution
+type Record struct {
+	DocumentID      string
+	DocumentType    contracts.DocumentType
+	DocumentName    string
+	DocumentVersion string
+	CommandID       string
+	AssociationID   string
+	RunID           string
+	Status          contracts.ResultStatus
+	StartDateTime   time.Time
+	EndDateTime     time.Time
+	// OrchestrationDirectory and the S3 location hold the output of the document
+	OrchestrationDirectory string
+	OutputS3BucketName     string
+	OutputS3KeyPrefix      string
+	Plugins                []PluginRecord
+	// DocumentState is the final state of the document
+	DocumentState contracts.DocumentState
+}
+
+// PluginRecord is the history of a plugin of the document
+type PluginRecord struct {
+	ID                     string
+	Name                   string
+	Status                 contracts.ResultStatus
+	Code                   int
+	StartDateTime          time.Time
+	EndDateTime            time.Time
+	OrchestrationDirectory string
+	OutputS3BucketName     string
+	OutputS3KeyPrefix      string
+}
+
+// Filter selects the records of a query, the empty fields select every record
+type Filter struct {
+	DocumentID    string
+	CommandID     string
+	AssociationID string
+	DocumentType  contracts.DocumentType
+	Status        contracts.ResultStatus
+	// Since and Until bound the end time of the documents
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of records returned, the most recent first
+	Limit int
+}
+
+// Store records the documents executed on the instance and answers the queries on them
+type Store interface {
+	// Record saves the final state of the document
+	Record(log log.T, docState contracts.DocumentState)
+	// Query returns the records of the instance matching the filter, the most recent first
+	Query(log log.T, instanceID string, filter Filter) ([]Record, error)
+}
+
+// FileStore keeps a file per record, under a directory per document type
+type FileStore struct {
+	dataStorePath             string
+	runCommandRetentionHours  int
+	associationRetentionHours int
+	recordsLimit              int
+}
+
+// NewFileStore creates a store bounded by the Run Command and Association logs retention durations
+func NewFileStore(dataStorePath string, config appconfig.SsmCfg) *FileStore {
+	return &FileStore{
+		dataStorePath:             dataStorePath,
+		runCommandRetentionHours:  config.RunCommandLogsRetentionDurationHours,
+		associationRetentionHours: config.AssociationLogsRetentionDurationHours,
+		recordsLimit:              appconfig.DefaultHistoryRecordsLimit,
+	}
+}
+
+// historyDir returns the directory of the records of the instance
+func (s *FileStore) historyDir(instanceID string) string {
+	return filepath.Join(s.dataStorePath,
+		instanceID,
+		appconfig.DefaultDocumentRootDirName,
+		appconfig.DefaultLocationOfHistory)
+}
+
+// Record saves the final state of the document, and removes the expired records of its document type
+func (s *FileStore) Record(log log.T, docState contracts.DocumentState) {
+	record := NewRecord(docState, time.Now())
+	dir := filepath.Join(s.historyDir(docState.DocumentInformation.InstanceID), string(docState.DocumentType))
+	if err := fileutil.MakeDirs(dir); err != nil {
+		log.Errorf("failed to create history directory %v: %v", dir, err)
+		return
+	}
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		log.Errorf("encountered error with message %v while marshalling history record of %v", err, record.DocumentID)
+		return
+	}
+	fileName := filepath.Join(dir, record.DocumentID+recordFileExtension)
+	if ok, err := fileutil.WriteIntoFileWithPermissions(fileName, content, os.FileMode(int(appconfig.ReadWriteAccess))); !ok || err != nil {
+		log.Errorf("failed to write history record %v: %v", fileName, err)
+		return
+	}
+	log.Debugf("recorded document %v in history", record.DocumentID)
+	s.cleanup(log, dir, s.retentionHours(docState.DocumentType))
+}
+
+// retentionHours returns the retention duration of the records of the document type
+func (s *FileStore) retentionHours(documentType contracts.DocumentType) int {
+	if documentType == contracts.Association {
+		return s.associationRetentionHours
+	}
+	return s.runCommandRetentionHours
+}
+
+// cleanup removes the records older than the retention duration, and the oldest records above the limit
+func (s *FileStore) cleanup(log log.T, dir string, retentionHours int) {
+	files, err := recordFiles(dir)
+	if err != nil {
+		log.Debugf("failed to read history directory %v: %v", dir, err)
+		return
+	}
+	expiry := time.Now().Add(-time.Duration(retentionHours) * time.Hour)
+	for i, f := range files {
+		if i < s.recordsLimit && f.ModTime().After(expiry) {
+			continue
+		}
+		if err := fileutil.DeleteFile(filepath.Join(dir, f.Name())); err != nil {
+			log.Debugf("failed to delete history record %v: %v", f.Name(), err)
+		}
+	}
+}
+
+// Query returns the records of the instance matching the filter, the most recent first
+func (s *FileStore) Query(log log.T, instanceID string, filter Filter) ([]Record, error) {
+	root := s.historyDir(instanceID)
+	var dirs []string
+	if filter.DocumentType != "" {
+		dirs = []string{filepath.Join(root, string(filter.DocumentType))}
+	} else {
+		// no directory before the first record
+		names, err := fileutil.GetDirectoryNames(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			dirs = append(dirs, filepath.Join(root, name))
+		}
+	}
+
+	records := []Record{}
+	for _, dir := range dirs {
+		files, err := recordFiles(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, f := range files {
+			// the record is written when the document ends
+			if !filter.Since.IsZero() && f.ModTime().Before(filter.Since) {
+				break
+			}
+			var record Record
+			if err := jsonutil.UnmarshalFile(filepath.Join(dir, f.Name()), &record); err != nil {
+				log.Debugf("skipping corrupt history record %v: %v", f.Name(), err)
+				continue
+			}
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].EndDateTime.After(records[j].EndDateTime) })
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// matches checks the record against every field of the filter
+func (f Filter) matches(r Record) bool {
+	return (f.DocumentID == "" || f.DocumentID == r.DocumentID) &&
+		(f.CommandID == "" || f.CommandID == r.CommandID) &&
+		(f.AssociationID == "" || f.AssociationID == r.AssociationID) &&
+		(f.DocumentType == "" || f.DocumentType == r.DocumentType) &&
+		(f.Status == "" || f.Status == r.Status) &&
+		(f.Since.IsZero() || !r.EndDateTime.Before(f.Since)) &&
+		(f.Until.IsZero() || !r.EndDateTime.After(f.Until))
+}
+
+// NewRecord summarizes the final state of the document, the end time defaults to the given time if no plugin ran
+func NewRecord(docState contracts.DocumentState, now time.Time) Record {
+	info := docState.DocumentInformation
+	record := Record{
+		DocumentID:             info.DocumentID,
+		DocumentType:           docState.DocumentType,
+		DocumentName:           info.DocumentName,
+		DocumentVersion:        info.DocumentVersion,
+		CommandID:              info.CommandID,
+		AssociationID:          info.AssociationID,
+		RunID:                  info.RunID,
+		Status:                 info.DocumentStatus,
+		OrchestrationDirectory: docState.IOConfig.OrchestrationDirectory,
+		OutputS3BucketName:     docState.IOConfig.OutputS3BucketName,
+		OutputS3KeyPrefix:      docState.IOConfig.OutputS3KeyPrefix,
+		Plugins:                []PluginRecord{},
+		DocumentState:          docState,
+	}
+	for _, plugin := range docState.InstancePluginsInformation {
+		res := plugin.Result
+		record.Plugins = append(record.Plugins, PluginRecord{
+			ID:                     plugin.Id,
+			Name:                   plugin.Name,
+			Status:                 res.Status,
+			Code:                   res.Code,
+			StartDateTime:          res.StartDateTime,
+			EndDateTime:            res.EndDateTime,
+			OrchestrationDirectory: plugin.Configuration.OrchestrationDirectory,
+			OutputS3BucketName:     plugin.Configuration.OutputS3BucketName,
+			OutputS3KeyPrefix:      plugin.Configuration.OutputS3KeyPrefix,
+		})
+		if !res.StartDateTime.IsZero() && (record.StartDateTime.IsZero() || res.StartDateTime.Before(record.StartDateTime)) {
+			record.StartDateTime = res.StartDateTime
+		}
+		if res.EndDateTime.After(record.EndDateTime) {
+			record.EndDateTime = res.EndDateTime
+		}
+	}
+	if record.EndDateTime.IsZero() {
+		record.EndDateTime = now
+	}
+	if record.StartDateTime.IsZero() {
+		record.StartDateTime = record.EndDateTime
+	}
+	return record
+}
+
+// recordFiles returns the record files of the directory, the most recent first
+func recordFiles(dir string) ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == recordFileExtension {
+			files = append(files, entry)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+	return files, nil
+}
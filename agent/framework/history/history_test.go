@@ -0,0 +1,170 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceID = "i-400e1090"
+
+var logger = log.NewMockLog()
+
+var testStartDateTime = time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.NoError(t, err)
+	store := NewFileStore(dir, appconfig.SsmCfg{
+		RunCommandLogsRetentionDurationHours:  appconfig.DefaultRunCommandLogsRetentionDurationHours,
+		AssociationLogsRetentionDurationHours: appconfig.DefaultAssociationLogsRetentionDurationHours,
+	})
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func newTestDocState(documentType contracts.DocumentType, documentID string, status contracts.ResultStatus, end time.Time) contracts.DocumentState {
+	docState := contracts.DocumentState{
+		DocumentType: documentType,
+		DocumentInformation: contracts.DocumentInfo{
+			DocumentID:     documentID,
+			InstanceID:     testInstanceID,
+			DocumentName:   "AWS-RunShellScript",
+			DocumentStatus: status,
+		},
+		IOConfig: contracts.IOConfiguration{
+			OrchestrationDirectory: "/var/lib/amazon/ssm/" + documentID,
+			OutputS3BucketName:     "bucket",
+		},
+		InstancePluginsInformation: []contracts.PluginState{
+			{
+				Id:   "runScript",
+				Name: "aws:runShellScript",
+				Result: contracts.PluginResult{
+					Status:        status,
+					StartDateTime: testStartDateTime,
+					EndDateTime:   end,
+				},
+			},
+		},
+	}
+	docState.InstancePluginsInformation[0].Configuration.OrchestrationDirectory = "/var/lib/amazon/ssm/" + documentID + "/runScript"
+	if documentType == contracts.Association {
+		docState.DocumentInformation.AssociationID = documentID
+	} else {
+		docState.DocumentInformation.CommandID = documentID
+	}
+	return docState
+}
+
+func TestNewRecord(t *testing.T) {
+	end := testStartDateTime.Add(time.Minute)
+	docState := newTestDocState(contracts.SendCommand, "command1", contracts.ResultStatusSuccess, end)
+	record := NewRecord(docState, time.Now())
+	assert.Equal(t, "command1", record.DocumentID)
+	assert.Equal(t, "command1", record.CommandID)
+	assert.Equal(t, contracts.ResultStatusSuccess, record.Status)
+	assert.Equal(t, testStartDateTime, record.StartDateTime)
+	assert.Equal(t, end, record.EndDateTime)
+	assert.Equal(t, "/var/lib/amazon/ssm/command1", record.OrchestrationDirectory)
+	assert.Equal(t, "bucket", record.OutputS3BucketName)
+	assert.Equal(t, []PluginRecord{{
+		ID:                     "runScript",
+		Name:                   "aws:runShellScript",
+		Status:                 contracts.ResultStatusSuccess,
+		StartDateTime:          testStartDateTime,
+		EndDateTime:            end,
+		OrchestrationDirectory: "/var/lib/amazon/ssm/command1/runScript",
+	}}, record.Plugins)
+
+	// a document without plugins ends when it is recorded
+	now := time.Now()
+	record = NewRecord(contracts.DocumentState{DocumentType: contracts.CancelCommand}, now)
+	assert.Equal(t, now, record.StartDateTime)
+	assert.Equal(t, now, record.EndDateTime)
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	records, err := store.Query(logger, testInstanceID, Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	store.Record(logger, newTestDocState(contracts.SendCommand, "command1", contracts.ResultStatusSuccess, testStartDateTime.Add(time.Minute)))
+	store.Record(logger, newTestDocState(contracts.SendCommandOffline, "command2", contracts.ResultStatusFailed, testStartDateTime.Add(2*time.Minute)))
+	store.Record(logger, newTestDocState(contracts.Association, "association1", contracts.ResultStatusSuccess, testStartDateTime.Add(3*time.Minute)))
+
+	documentIDs := func(filter Filter) []string {
+		records, err := store.Query(logger, testInstanceID, filter)
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, record := range records {
+			ids = append(ids, record.DocumentID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"association1", "command2", "command1"}, documentIDs(Filter{}))
+	assert.Equal(t, []string{"association1", "command2"}, documentIDs(Filter{Limit: 2}))
+	assert.Equal(t, []string{"command2"}, documentIDs(Filter{CommandID: "command2"}))
+	assert.Equal(t, []string{"association1"}, documentIDs(Filter{AssociationID: "association1"}))
+	assert.Equal(t, []string{"command1"}, documentIDs(Filter{DocumentID: "command1"}))
+	assert.Equal(t, []string{"command2"}, documentIDs(Filter{DocumentType: contracts.SendCommandOffline}))
+	assert.Equal(t, []string{"association1", "command1"}, documentIDs(Filter{Status: contracts.ResultStatusSuccess}))
+	assert.Equal(t, []string{"command2", "command1"}, documentIDs(Filter{Until: testStartDateTime.Add(2 * time.Minute)}))
+	assert.Equal(t, []string{"command2"}, documentIDs(Filter{Since: testStartDateTime.Add(90 * time.Second), Until: testStartDateTime.Add(150 * time.Second)}))
+	assert.Empty(t, documentIDs(Filter{DocumentType: contracts.CancelCommand}))
+
+	// the record holds the final state of the document
+	records, err = store.Query(logger, testInstanceID, Filter{DocumentID: "association1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "AWS-RunShellScript", records[0].DocumentState.DocumentInformation.DocumentName)
+}
+
+func TestRecordCleanup(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+	store.recordsLimit = 2
+	dir := filepath.Join(store.historyDir(testInstanceID), string(contracts.Association))
+
+	store.Record(logger, newTestDocState(contracts.Association, "association1", contracts.ResultStatusSuccess, testStartDateTime))
+	store.Record(logger, newTestDocState(contracts.Association, "association2", contracts.ResultStatusSuccess, testStartDateTime))
+	// the first record expires
+	expired := time.Now().Add(-time.Duration(appconfig.DefaultAssociationLogsRetentionDurationHours+1) * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "association1.json"), expired, expired))
+	older := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "association2.json"), older, older))
+
+	store.Record(logger, newTestDocState(contracts.Association, "association3", contracts.ResultStatusSuccess, testStartDateTime))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 2)
+
+	// the oldest record goes above the limit
+	store.Record(logger, newTestDocState(contracts.Association, "association4", contracts.ResultStatusSuccess, testStartDateTime))
+	records, err := store.Query(logger, testInstanceID, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	for _, record := range records {
+		assert.NotEqual(t, "association2", record.DocumentID)
+	}
+}
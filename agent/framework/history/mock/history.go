@@ -0,0 +1,38 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package historymock implements the mock of the history store
+package historymock
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockedStore stands for a mocked history store
+type MockedStore struct {
+	mock.Mock
+}
+
+// Record mocks the method with the same name
+func (m *MockedStore) Record(log log.T, docState contracts.DocumentState) {
+	m.Called(log, docState)
+}
+
+// Query mocks the method with the same name
+func (m *MockedStore) Query(log log.T, instanceID string, filter history.Filter) ([]history.Record, error) {
+	args := m.Called(log, instanceID, filter)
+	return args.Get(0).([]history.Record), args.Error(1)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 )
 
 const (
@@ -281,12 +282,12 @@ func (out *DefaultIOHandler) SetStatus(status contracts.ResultStatus) {
 
 // SetStdout sets the stdout
 func (out *DefaultIOHandler) SetStdout(stdout string) {
-	out.stdout = stdout
+	out.stdout = redaction.Redact(stdout)
 }
 
 // SetStderr sets the stderr
 func (out *DefaultIOHandler) SetStderr(stderr string) {
-	out.stderr = stderr
+	out.stderr = redaction.Redact(stderr)
 }
 
 // SetExitCode sets the exit code
@@ -375,6 +376,7 @@ func (out *DefaultIOHandler) AppendInfo(message string) {
 		out.StdoutWriter.WriteString(message)
 	} else {
 		// Write to stdout if the writer is not defined.
+		message = redaction.Redact(message)
 		if len(out.stdout) > 0 {
 			out.stdout = fmt.Sprintf("%v\n%v", out.stdout, message)
 		} else {
@@ -400,6 +402,7 @@ func (out *DefaultIOHandler) AppendError(message string) {
 		out.StderrWriter.WriteString(message)
 	} else {
 		// Write to stderr if the writer is not defined.
+		message = redaction.Redact(message)
 		if len(out.stderr) > 0 {
 			out.stderr = fmt.Sprintf("%v\n%v", out.stderr, message)
 		} else {
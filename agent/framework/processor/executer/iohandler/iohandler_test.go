@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -148,6 +149,20 @@ func TestAppendInfo(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), "Second entry")
 }
 
+func TestRedactSecrets(t *testing.T) {
+	redaction.Register("Pa55w0rd!")
+	defer redaction.Reset()
+	output := DefaultIOHandler{}
+
+	output.AppendInfo("password is Pa55w0rd!")
+	output.AppendErrorf("failed to connect with %v", "Pa55w0rd!")
+	assert.Equal(t, "password is "+redaction.Mask, output.GetStdout())
+	assert.Equal(t, "failed to connect with "+redaction.Mask, output.GetStderr())
+
+	output.SetStdout("Pa55w0rd!")
+	assert.Equal(t, redaction.Mask, output.GetStdout())
+}
+
 func TestAppendSpecialChars(t *testing.T) {
 	output := DefaultIOHandler{}
 
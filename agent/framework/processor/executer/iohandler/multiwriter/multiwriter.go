@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"io"
 	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 )
 
 // DocumentIOMultiWriter is a multi-writer with support for close channel.
@@ -32,15 +34,17 @@ type DocumentIOMultiWriter interface {
 }
 
 // DefaultDocumentIOMultiWriter is the default implementation of multi-writer.
+// The secret values resolved for the document are masked before reaching the attached pipes.
 type DefaultDocumentIOMultiWriter struct {
-	writers []*io.PipeWriter
-	wg      *sync.WaitGroup
+	writers  []*io.PipeWriter
+	wg       *sync.WaitGroup
+	redactor *redaction.StreamRedactor
 }
 
 // NewDocumentIOMultiWriter creates a new document multi-writer
 func NewDocumentIOMultiWriter() (b *DefaultDocumentIOMultiWriter) {
 	var w []*io.PipeWriter
-	b = &DefaultDocumentIOMultiWriter{w, new(sync.WaitGroup)}
+	b = &DefaultDocumentIOMultiWriter{w, new(sync.WaitGroup), new(redaction.StreamRedactor)}
 	return
 }
 
@@ -61,19 +65,24 @@ func (b *DefaultDocumentIOMultiWriter) Write(p []byte) (n int, err error) {
 		return 0, fmt.Errorf("No writers present.")
 	}
 
+	b.writeToPipes(b.redactor.Redact(p))
+	return len(p), nil
+}
+
+// writeToPipes writes the redacted bytes to all the attached pipes.
+func (b *DefaultDocumentIOMultiWriter) writeToPipes(p []byte) {
+	if len(p) == 0 {
+		return
+	}
 	for i := 0; i < len(b.writers); i++ {
-		n, err = b.writers[i].Write(p)
+		_, err := b.writers[i].Write(p)
 		// TODO: Handler other error types and close the writers after a fixed number of retries
 		if err == io.ErrClosedPipe {
 			// remove the writer as the reader is closed
 			b.writers = append(b.writers[:i], b.writers[i+1:]...)
 			i--
 		}
-		if n != len(p) {
-			err = io.ErrShortWrite
-		}
 	}
-	return len(p), nil
 }
 
 // WriteString is responsible for writing a string to all the attached pipes.
@@ -82,15 +91,14 @@ func (b *DefaultDocumentIOMultiWriter) WriteString(message string) (n int, err e
 		return 0, fmt.Errorf("No writers present.")
 	}
 
-	for _, w := range b.writers {
-		p := []byte(message)
-		n, err = w.Write(p)
-	}
+	b.writeToPipes(b.redactor.Redact([]byte(message)))
 	return len(message), nil
 }
 
 // Close waits for all the writers to be closed.
 func (b *DefaultDocumentIOMultiWriter) Close() (err error) {
+	// release the end of the output held back by the redaction
+	b.writeToPipes(b.redactor.Flush())
 	for i := 0; i < len(b.writers); i++ {
 		err = b.writers[i].Close()
 	}
@@ -22,6 +22,7 @@ import (
 
 	"sync"
 
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -153,3 +154,27 @@ func TestCloseWriter(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+// TestWriteRedactsSecrets runs to check that the secret values are masked, even when written byte by byte.
+func TestWriteRedactsSecrets(t *testing.T) {
+	redaction.Register("Pa55w0rd!")
+	defer redaction.Reset()
+
+	sourceString := "connecting with password Pa55w0rd! to Pa55"
+	expectedString := "connecting with password " + redaction.Mask + " to Pa55"
+	for _, stream := range []bool{false, true} {
+		mw := NewDocumentIOMultiWriter()
+		r, w := io.Pipe()
+		mw.AddWriter(w)
+		go testReadBulk(t, r, expectedString, mw.wg)
+
+		if stream {
+			for i := 0; i < len(sourceString); i++ {
+				mw.Write([]byte{sourceString[i]})
+			}
+		} else {
+			mw.WriteString(sourceString)
+		}
+		mw.Close()
+	}
+}
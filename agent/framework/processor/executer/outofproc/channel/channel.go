@@ -58,3 +58,35 @@ func CreateFileChannel(log log.T, mode Mode, filename string) (Channel, error, b
 	f, err := NewFileWatcherChannel(log, mode, path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename))
 	return f, err, false
 }
+
+//find the folder named as "documentID" under the default root dir, and reopen the channel of the type it was created with
+//if not found, create a new channel of the given type under the default root dir, the worker always finds the folder created by the master
+//return the channel and the found flag
+func CreateChannel(log log.T, mode Mode, channelType string, filename string) (Channel, error, bool) {
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		log.Errorf("failed to load instance ID: %v", err)
+		return nil, err, false
+	}
+	channelPath := path.Join(appconfig.DefaultDataStorePath, instanceID, defaultFileChannelPath, filename)
+	found := fileutil.Exists(channelPath)
+	if found {
+		log.Infof("channel: %v found", filename)
+		if fileutil.Exists(path.Join(channelPath, socketAddressFileName)) {
+			channelType = appconfig.ChannelTypeSocket
+		} else {
+			channelType = appconfig.ChannelTypeFile
+		}
+	} else {
+		log.Infof("channel: %v not found, creating a new %v channel...", filename, channelType)
+	}
+	if channelType == appconfig.ChannelTypeSocket {
+		s, err := NewSocketChannel(log, mode, channelPath, socketAddress(channelPath))
+		if err != nil {
+			return nil, err, found
+		}
+		return s, nil, found
+	}
+	f, err := NewFileWatcherChannel(log, mode, channelPath)
+	return f, err, found
+}
@@ -0,0 +1,64 @@
+// +build darwin freebsd linux netbsd openbsd
+
+package channel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	defaultSocketPath       = "ipc"
+	defaultSocketDirMode    = 0700
+	defaultSocketFileMode   = 0600
+	socketFileNameHashBytes = 8
+)
+
+// Assign to global variables to allow unittest to override
+var socketRootDir = filepath.Join(appconfig.DefaultDataStorePath, defaultSocketPath)
+
+//socketAddress returns the socket of the channel directory, the path of a unix domain socket is limited to about 100 bytes,
+//so the socket is named after the hash of the channel directory
+func socketAddress(channelPath string) string {
+	hash := sha256.Sum256([]byte(channelPath))
+	return filepath.Join(socketRootDir, hex.EncodeToString(hash[:socketFileNameHashBytes])+".sock")
+}
+
+type unixListener struct {
+	net.Listener
+}
+
+func (l unixListener) Accept() (conn, error) {
+	return l.Listener.Accept()
+}
+
+//listen creates the socket, only accessible by the owner of the agent
+func listen(address string) (listener, error) {
+	if err := os.MkdirAll(filepath.Dir(address), defaultSocketDirMode); err != nil {
+		return nil, err
+	}
+	//remove the socket left behind by the previous master
+	removeAddress(address)
+	l, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(address, defaultSocketFileMode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return unixListener{l}, nil
+}
+
+func dial(address string) (conn, error) {
+	return net.Dial("unix", address)
+}
+
+func removeAddress(address string) {
+	os.Remove(address)
+}
@@ -0,0 +1,253 @@
+// +build windows
+
+package channel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	pipeNamePrefix        = `\\.\pipe\amazon-ssm-agent-`
+	pipeNameHashBytes     = 8
+	pipeInboundSuffix     = "-in"
+	pipeOutboundSuffix    = "-out"
+	pipeBufferSize        = 65536
+	pipeUnlimitedInstance = 255
+
+	pipeAccessInbound         = 0x1
+	pipeAccessOutbound        = 0x2
+	fileFlagFirstPipeInstance = 0x80000
+	pipeTypeByte              = 0x0
+	pipeRejectRemoteClients   = 0x8
+	sddlRevision1             = 1
+
+	errorPipeConnected syscall.Errno = 535
+
+	//only the local system and the administrators have access to the pipes
+	pipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+	procCancelIoEx       = kernel32.NewProc("CancelIoEx")
+	procLocalFree        = kernel32.NewProc("LocalFree")
+
+	advapi32                                                 = syscall.NewLazyDLL("advapi32.dll")
+	procConvertStringSecurityDescriptorToSecurityDescriptorW = advapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+//socketAddress returns the named pipe of the channel directory, named after the hash of the channel directory
+func socketAddress(channelPath string) string {
+	hash := sha256.Sum256([]byte(channelPath))
+	return pipeNamePrefix + hex.EncodeToString(hash[:pipeNameHashBytes])
+}
+
+//pipeConn is a duplex stream over two named pipes, one per direction
+type pipeConn struct {
+	reader *os.File
+	writer *os.File
+	once   sync.Once
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+//Close cancels the pending reads and writes, and closes the pipes
+func (c *pipeConn) Close() error {
+	c.once.Do(func() {
+		cancelIo(syscall.Handle(c.reader.Fd()))
+		cancelIo(syscall.Handle(c.writer.Fd()))
+		c.reader.Close()
+		c.writer.Close()
+	})
+	return nil
+}
+
+//pipeListener creates the instances of the pipes and waits for the worker to open them
+type pipeListener struct {
+	address string
+	mu      sync.Mutex
+	pending []syscall.Handle
+	first   bool
+	closed  bool
+}
+
+//listen makes sure no other process owns the pipes, the pipes are created by Accept
+func listen(address string) (listener, error) {
+	l := &pipeListener{address: address, first: true}
+	in, err := l.createPipe(address+pipeInboundSuffix, pipeAccessInbound)
+	if err != nil {
+		return nil, err
+	}
+	out, err := l.createPipe(address+pipeOutboundSuffix, pipeAccessOutbound)
+	if err != nil {
+		syscall.CloseHandle(in)
+		return nil, err
+	}
+	l.pending = []syscall.Handle{in, out}
+	return l, nil
+}
+
+func (l *pipeListener) createPipe(name string, access uint32) (syscall.Handle, error) {
+	if l.first {
+		access |= fileFlagFirstPipeInstance
+	}
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	sa, err := securityAttributes()
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	defer procLocalFree.Call(sa.SecurityDescriptor)
+	h, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(access),
+		uintptr(pipeTypeByte|pipeRejectRemoteClients),
+		uintptr(pipeUnlimitedInstance),
+		uintptr(pipeBufferSize),
+		uintptr(pipeBufferSize),
+		0,
+		uintptr(unsafe.Pointer(sa)))
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, err
+	}
+	return syscall.Handle(h), nil
+}
+
+func (l *pipeListener) Accept() (conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, syscall.EINVAL
+	}
+	var err error
+	if l.pending == nil {
+		l.first = false
+		var in, out syscall.Handle
+		if in, err = l.createPipe(l.address+pipeInboundSuffix, pipeAccessInbound); err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+		if out, err = l.createPipe(l.address+pipeOutboundSuffix, pipeAccessOutbound); err != nil {
+			syscall.CloseHandle(in)
+			l.mu.Unlock()
+			return nil, err
+		}
+		l.pending = []syscall.Handle{in, out}
+	}
+	handles := l.pending
+	l.mu.Unlock()
+
+	for _, h := range handles {
+		if err = connectPipe(h); err != nil {
+			break
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, syscall.EINVAL
+	}
+	l.pending = nil
+	if err != nil {
+		syscall.CloseHandle(handles[0])
+		syscall.CloseHandle(handles[1])
+		return nil, err
+	}
+	return &pipeConn{
+		reader: os.NewFile(uintptr(handles[0]), l.address+pipeInboundSuffix),
+		writer: os.NewFile(uintptr(handles[1]), l.address+pipeOutboundSuffix),
+	}, nil
+}
+
+//Close cancels the pending Accept and closes the pipes not connected yet
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	for _, h := range l.pending {
+		cancelIo(h)
+		syscall.CloseHandle(h)
+	}
+	l.pending = nil
+	return nil
+}
+
+//dial opens the pipes created by the master, the inbound pipe of the master is written by the worker
+func dial(address string) (conn, error) {
+	writer, err := openPipe(address+pipeInboundSuffix, syscall.GENERIC_WRITE)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := openPipe(address+pipeOutboundSuffix, syscall.GENERIC_READ)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	return &pipeConn{reader: reader, writer: writer}, nil
+}
+
+//named pipes are removed by the system when their last handle is closed
+func removeAddress(address string) {}
+
+func openPipe(name string, access uint32) (*os.File, error) {
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(namePtr, access, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), name), nil
+}
+
+func connectPipe(h syscall.Handle) error {
+	r, _, err := procConnectNamedPipe.Call(uintptr(h), 0)
+	if r == 0 && err != errorPipeConnected {
+		return err
+	}
+	return nil
+}
+
+func cancelIo(h syscall.Handle) {
+	procCancelIoEx.Call(uintptr(h), 0)
+}
+
+//securityAttributes returns the attributes of the pipes, the security descriptor must be freed with LocalFree
+func securityAttributes() (*syscall.SecurityAttributes, error) {
+	sddl, err := syscall.UTF16PtrFromString(pipeSecurityDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	var sd uintptr
+	r, _, err := procConvertStringSecurityDescriptorToSecurityDescriptorW.Call(
+		uintptr(unsafe.Pointer(sddl)),
+		sddlRevision1,
+		uintptr(unsafe.Pointer(&sd)),
+		0)
+	if r == 0 {
+		return nil, err
+	}
+	sa := &syscall.SecurityAttributes{SecurityDescriptor: sd}
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+	return sa, nil
+}
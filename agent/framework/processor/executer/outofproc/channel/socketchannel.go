@@ -0,0 +1,551 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/twinj/uuid"
+)
+
+const (
+	frameTypeData  = "data"
+	frameTypeAck   = "ack"
+	frameTypeHello = "hello"
+
+	//the file, under the channel directory, holding the address of the socket or named pipe
+	socketAddressFileName = "address"
+	//the files, under the channel directory, holding the messages sent and not acknowledged by the other end
+	journalFileSuffix = ".journal"
+
+	dialRetryIntervalMin = 100 * time.Millisecond
+	dialRetryIntervalMax = 2 * time.Second
+	//Close waits for the other end to acknowledge the messages in flight, before releasing the connection
+	closeAckTimeout   = 2 * time.Second
+	closePollInterval = 10 * time.Millisecond
+	//the pending frames are journaled as soon as that many are pending and were sent since the last write of the journal
+	journalMaxUnwritten = 100
+)
+
+var (
+	//the frames pending for this interval are journaled, the pending frames are also journaled when the connection breaks or the channel closes
+	journalInterval = time.Second
+
+	//Assign method to global variables to allow unittest to override
+	writeFile = ioutil.WriteFile
+)
+
+//frame is the unit transmitted over the socket, data frames are numbered by their sender within its epoch,
+//a restarted end starts a new epoch unless it resumes the frames of its journal.
+//The receiver acknowledges every data frame it received, and says hello with the epoch and the number of the next data frame it expects
+type frame struct {
+	Type    string `json:"type"`
+	Epoch   string `json:"epoch"`
+	Seq     int    `json:"seq"`
+	Payload string `json:"payload,omitempty"`
+}
+
+//conn is a duplex stream between the master and the worker
+type conn interface {
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Close() error
+}
+
+//listener accepts the connection of the worker
+type listener interface {
+	Accept() (conn, error)
+	Close() error
+}
+
+//connection writes the frames to the stream from a dedicated go-routine, so that reading the stream never blocks on writing
+type connection struct {
+	conn conn
+	out  chan frame
+	done chan bool
+	once sync.Once
+}
+
+func newConnection(c conn, bufferSize int) *connection {
+	return &connection{
+		conn: c,
+		out:  make(chan frame, bufferSize),
+		done: make(chan bool),
+	}
+}
+
+func (c *connection) write() {
+	encoder := json.NewEncoder(c.conn)
+	for {
+		select {
+		case f := <-c.out:
+			if err := encoder.Encode(f); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+//send queues the frame, returns false if the connection is closed
+func (c *connection) send(f frame) bool {
+	select {
+	case c.out <- f:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+//trySend queues the frame if the queue is not full, acks are cumulative so a dropped ack is covered by the next one
+func (c *connection) trySend(f frame) {
+	select {
+	case c.out <- f:
+	default:
+	}
+}
+
+func (c *connection) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+//socketChannel exchanges the datagrams over a unix domain socket, or a named pipe on windows.
+//The master listens and the worker connects, the messages not acknowledged by the other end are sent again when it reconnects.
+//The messages are kept in memory until they are acknowledged, the ones still pending are written to a journal under the channel
+//directory when the connection breaks, when the channel closes and periodically, so that a restarted end can read the messages
+//of the other end, and resume its own.
+type socketChannel struct {
+	logger        log.T
+	mode          Mode
+	path          string
+	address       string
+	onMessageChan chan string
+	listener      listener
+
+	mu sync.Mutex
+	//the current connection, nil if the other end is not connected
+	current *connection
+	//the epoch and the number of the next data frame to send
+	epoch   string
+	counter int
+	//the epoch and the number of the next data frame expected
+	recvEpoch   string
+	recvCounter int
+	//the data frames sent and not acknowledged yet
+	pending   []frame
+	journaled bool
+	closed    bool
+	//the journal does not match the pending frames, unwritten counts the frames sent since the last write of the journal
+	dirty     bool
+	unwritten int
+	//closed by Close to stop the periodic writes of the journal
+	stopJournal chan bool
+
+	//guards the delivery to onMessageChan against its closing
+	deliverMu sync.Mutex
+	delivered bool
+	//closed by Close to stop the delivery blocked on a full onMessageChan
+	stopDelivery chan bool
+}
+
+/*
+	Create a socket channel, identified by the directory holding its address and its journals
+	The master creates the socket at the given address and listens to it, the worker reads the address from the directory and connects to it
+	Only Master channel has the privilege to remove the dir at destroy time
+*/
+func NewSocketChannel(logger log.T, mode Mode, name string, address string) (*socketChannel, error) {
+	if err := createIfNotExist(name); err != nil {
+		logger.Errorf("failed to create directory: %v", err)
+		return nil, err
+	}
+	addressFile := path.Join(name, socketAddressFileName)
+	if mode == ModeMaster {
+		if err := ioutil.WriteFile(addressFile, []byte(address), defaultFileWriteMode); err != nil {
+			logger.Errorf("failed to write channel address: %v", err)
+			return nil, err
+		}
+	} else {
+		buf, err := ioutil.ReadFile(addressFile)
+		if err != nil {
+			logger.Errorf("failed to read channel address: %v", err)
+			return nil, err
+		}
+		address = string(buf)
+	}
+
+	ch := &socketChannel{
+		logger:        logger,
+		mode:          mode,
+		path:          name,
+		address:       address,
+		onMessageChan: make(chan string, defaultChannelBufferSize),
+		epoch:         uuid.NewV4().String(),
+		stopDelivery:  make(chan bool),
+		stopJournal:   make(chan bool),
+	}
+
+	//resume the messages the previous process of this end did not get acknowledged
+	ch.pending = ch.readJournal(ch.mode)
+	ch.journaled = len(ch.pending) > 0
+	if ch.journaled {
+		last := ch.pending[len(ch.pending)-1]
+		ch.epoch = last.Epoch
+		ch.counter = last.Seq + 1
+	}
+	//the messages the other end left in its journal, if it exited while this end was not connected
+	received := ch.readJournal(ch.peerMode())
+	go ch.writeJournalPeriodically(journalInterval)
+
+	if mode == ModeMaster {
+		l, err := listen(address)
+		if err != nil {
+			logger.Errorf("failed to listen to %v: %v", address, err)
+			return nil, err
+		}
+		ch.listener = l
+		go func() {
+			ch.deliverJournal(received)
+			ch.accept()
+		}()
+	} else {
+		go func() {
+			ch.deliverJournal(received)
+			ch.dial()
+		}()
+	}
+	return ch, nil
+}
+
+func (ch *socketChannel) peerMode() Mode {
+	if ch.mode == ModeMaster {
+		return ModeWorker
+	}
+	return ModeMaster
+}
+
+func (ch *socketChannel) journalPath(mode Mode) string {
+	return path.Join(ch.path, string(mode)+journalFileSuffix)
+}
+
+//readJournal reads the frames of the journal of the given end
+func (ch *socketChannel) readJournal(mode Mode) []frame {
+	buf, err := ioutil.ReadFile(ch.journalPath(mode))
+	if err != nil {
+		return nil
+	}
+	var frames []frame
+	if err = json.Unmarshal(buf, &frames); err != nil {
+		ch.logger.Errorf("failed to parse journal %v: %v", ch.journalPath(mode), err)
+		return nil
+	}
+	return frames
+}
+
+//writeJournal saves the pending frames of this end, the file is first written as tmp, then renamed to guarantee atomicity
+func (ch *socketChannel) writeJournal() error {
+	journalPath := ch.journalPath(ch.mode)
+	if len(ch.pending) == 0 {
+		if ch.journaled {
+			os.Remove(journalPath)
+			ch.journaled = false
+		}
+		ch.dirty = false
+		ch.unwritten = 0
+		return nil
+	}
+	buf, err := json.Marshal(ch.pending)
+	if err != nil {
+		return err
+	}
+	tmpPath := journalPath + ".tmp"
+	if err = writeFile(tmpPath, buf, defaultFileWriteMode); err != nil {
+		ch.logger.Errorf("write journal %v encountered error: %v", tmpPath, err)
+		return err
+	}
+	if err = os.Rename(tmpPath, journalPath); err != nil {
+		ch.logger.Errorf("renaming journal encountered error: %v", err)
+		return err
+	}
+	ch.journaled = true
+	ch.dirty = false
+	ch.unwritten = 0
+	return nil
+}
+
+//writeJournalPeriodically writes the journal when a frame is not acknowledged within an interval, or when the journal holds frames
+//acknowledged since its last write, until the channel closes. The frames acknowledged in time are never written.
+func (ch *socketChannel) writeJournalPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	//the number of the next data frame at the previous tick, the frames numbered below were sent an interval ago at least
+	sentBefore := 0
+	for {
+		select {
+		case <-ticker.C:
+			ch.mu.Lock()
+			overdue := len(ch.pending) > 0 && ch.pending[0].Seq < sentBefore
+			//Close writes the journal once closed
+			if ch.dirty && !ch.closed && (overdue || ch.journaled) {
+				ch.writeJournal()
+			}
+			sentBefore = ch.counter
+			ch.mu.Unlock()
+		case <-ch.stopJournal:
+			return
+		}
+	}
+}
+
+//deliverJournal delivers the frames the other end journaled, and removes its journal
+func (ch *socketChannel) deliverJournal(frames []frame) {
+	if len(frames) == 0 {
+		return
+	}
+	ch.logger.Infof("resuming %v messages from the journal of the %v", len(frames), ch.peerMode())
+	for _, f := range frames {
+		ch.receive(f)
+	}
+	os.Remove(ch.journalPath(ch.peerMode()))
+}
+
+//accept serves the connections of the worker, a new connection replaces the current one
+func (ch *socketChannel) accept() {
+	log := ch.logger
+	log.Debugf("%v listener started on: %v", ch.mode, ch.address)
+	for {
+		c, err := ch.listener.Accept()
+		if err != nil {
+			if ch.isClosed() {
+				return
+			}
+			log.Errorf("failed to accept connection: %v", err)
+			time.Sleep(dialRetryIntervalMin)
+			continue
+		}
+		go ch.serve(c)
+	}
+}
+
+//dial connects to the master, and reconnects until the channel is closed
+func (ch *socketChannel) dial() {
+	log := ch.logger
+	interval := dialRetryIntervalMin
+	for !ch.isClosed() {
+		c, err := dial(ch.address)
+		if err != nil {
+			log.Debugf("failed to connect to %v: %v", ch.address, err)
+			time.Sleep(interval)
+			if interval *= 2; interval > dialRetryIntervalMax {
+				interval = dialRetryIntervalMax
+			}
+			continue
+		}
+		interval = dialRetryIntervalMin
+		ch.serve(c)
+	}
+}
+
+//serve says hello, sends the pending frames again and reads the frames of the other end until the connection breaks
+func (ch *socketChannel) serve(c conn) {
+	log := ch.logger
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		c.Close()
+		return
+	}
+	if ch.current != nil {
+		ch.current.close()
+	}
+	current := newConnection(c, len(ch.pending)+defaultChannelBufferSize)
+	current.out <- frame{Type: frameTypeHello, Epoch: ch.recvEpoch, Seq: ch.recvCounter}
+	for _, f := range ch.pending {
+		current.out <- f
+	}
+	ch.current = current
+	ch.mu.Unlock()
+	log.Infof("channel %v connected", ch.path)
+
+	go current.write()
+	decoder := json.NewDecoder(c)
+	for {
+		var f frame
+		if err := decoder.Decode(&f); err != nil {
+			log.Debugf("channel %v connection closed: %v", ch.path, err)
+			break
+		}
+		switch f.Type {
+		case frameTypeData:
+			if ch.receive(f) {
+				current.trySend(frame{Type: frameTypeAck, Epoch: f.Epoch, Seq: f.Seq})
+			}
+		case frameTypeAck:
+			ch.acknowledge(f.Epoch, f.Seq+1)
+		case frameTypeHello:
+			ch.acknowledge(f.Epoch, f.Seq)
+		}
+	}
+
+	current.close()
+	ch.mu.Lock()
+	if ch.current == current {
+		ch.current = nil
+		//the other end may not come back, it reads the journal when it restarts
+		if ch.dirty {
+			ch.writeJournal()
+		}
+	}
+	ch.mu.Unlock()
+}
+
+//receive delivers the data frame if it was not delivered yet, returns false if the channel is closed and the frame is not delivered
+func (ch *socketChannel) receive(f frame) bool {
+	ch.deliverMu.Lock()
+	defer ch.deliverMu.Unlock()
+	if ch.delivered {
+		return false
+	}
+	ch.mu.Lock()
+	if f.Epoch != ch.recvEpoch {
+		//the other end restarted
+		ch.recvEpoch = f.Epoch
+		ch.recvCounter = 0
+	}
+	isNew := f.Seq >= ch.recvCounter
+	ch.mu.Unlock()
+	if !isNew {
+		ch.logger.Debugf("dropping duplicate message %v", f.Seq)
+		return true
+	}
+	//the frame is not acknowledged unless delivered, the other end keeps it in its journal
+	select {
+	case ch.onMessageChan <- f.Payload:
+	case <-ch.stopDelivery:
+		return false
+	}
+	ch.mu.Lock()
+	ch.recvCounter = f.Seq + 1
+	ch.mu.Unlock()
+	return true
+}
+
+//acknowledge removes the pending frames numbered below next, if the other end refers to the epoch of this end
+func (ch *socketChannel) acknowledge(epoch string, next int) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if epoch != ch.epoch {
+		return
+	}
+	i := 0
+	for i < len(ch.pending) && ch.pending[i].Seq < next {
+		i++
+	}
+	if i == 0 {
+		return
+	}
+	ch.pending = ch.pending[i:]
+	//the journal is only rewritten if it holds frames that are acknowledged now
+	ch.dirty = ch.dirty || ch.journaled
+}
+
+func (ch *socketChannel) isClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.closed
+}
+
+//Send keeps the datagram pending until it is acknowledged, and queues it to the other end if it is connected
+func (ch *socketChannel) Send(rawJson string) error {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return errors.New("channel already closed")
+	}
+	f := frame{Type: frameTypeData, Epoch: ch.epoch, Seq: ch.counter, Payload: rawJson}
+	ch.pending = append(ch.pending, f)
+	ch.counter++
+	ch.dirty = true
+	//the other end does not keep up, bound the frames lost if this end crashes
+	if ch.unwritten++; ch.unwritten >= journalMaxUnwritten && len(ch.pending) >= journalMaxUnwritten {
+		ch.writeJournal()
+	}
+	current := ch.current
+	ch.mu.Unlock()
+
+	//the frame stays pending until acknowledged, it is sent again if the connection breaks
+	if current != nil {
+		current.send(f)
+	}
+	return nil
+}
+
+func (ch *socketChannel) GetMessage() <-chan string {
+	return ch.onMessageChan
+}
+
+// Close the socket channel
+// wait for the in flight messages to be acknowledged, the ones that are not stay in the journal, and release the connection
+func (ch *socketChannel) Close() {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return
+	}
+	log := ch.logger
+	log.Infof("channel %v requested close", ch.path)
+	//block other threads to call Send()
+	ch.closed = true
+	ch.mu.Unlock()
+
+	for deadline := time.Now().Add(closeAckTimeout); time.Now().Before(deadline); time.Sleep(closePollInterval) {
+		ch.mu.Lock()
+		done := len(ch.pending) == 0 || ch.current == nil
+		ch.mu.Unlock()
+		if done {
+			break
+		}
+	}
+
+	if ch.listener != nil {
+		ch.listener.Close()
+	}
+	close(ch.stopJournal)
+	ch.mu.Lock()
+	if ch.current != nil {
+		ch.current.close()
+		ch.current = nil
+	}
+	if ch.dirty {
+		ch.writeJournal()
+	}
+	ch.mu.Unlock()
+
+	close(ch.stopDelivery)
+	ch.deliverMu.Lock()
+	ch.delivered = true
+	close(ch.onMessageChan)
+	ch.deliverMu.Unlock()
+	log.Infof("channel %v closed", ch.path)
+}
+
+func (ch *socketChannel) Destroy() {
+	ch.Close()
+	//only master can remove the dir at destroy
+	if ch.mode == ModeMaster {
+		ch.logger.Debug("master removing directory...")
+		if err := os.RemoveAll(ch.path); err != nil {
+			ch.logger.Errorf("failed to remove directory %v : %v", ch.path, err)
+		}
+		removeAddress(ch.address)
+	}
+}
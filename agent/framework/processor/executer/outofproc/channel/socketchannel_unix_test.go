@@ -0,0 +1,242 @@
+// +build darwin freebsd linux netbsd openbsd
+
+package channel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMessageTimeout = 5 * time.Second
+
+func receiveMessage(t *testing.T, ch Channel) string {
+	select {
+	case msg := <-ch.GetMessage():
+		return msg
+	case <-time.After(testMessageTimeout):
+		assert.Fail(t, "timed out waiting for message")
+		return ""
+	}
+}
+
+func TestSocketChannelDuplexTransmission(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, channelPath, "")
+	assert.NoError(t, err)
+	assert.Equal(t, address, worker.address)
+
+	for _, msg := range []string{"start", "cancel"} {
+		assert.NoError(t, master.Send(msg))
+	}
+	for _, msg := range []string{"reply1", "reply2", "complete"} {
+		assert.NoError(t, worker.Send(msg))
+	}
+	assert.Equal(t, "start", receiveMessage(t, worker))
+	assert.Equal(t, "cancel", receiveMessage(t, worker))
+	assert.Equal(t, "reply1", receiveMessage(t, master))
+	assert.Equal(t, "reply2", receiveMessage(t, master))
+	assert.Equal(t, "complete", receiveMessage(t, master))
+
+	// every message is acknowledged, nothing is journaled
+	worker.Close()
+	assert.Empty(t, worker.pending)
+	_, err = os.Stat(filepath.Join(channelPath, string(ModeWorker)+journalFileSuffix))
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, worker.Send("closed"))
+	_, more := <-worker.GetMessage()
+	assert.False(t, more)
+
+	master.Destroy()
+	_, err = os.Stat(channelPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSocketChannelResumeAfterMasterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, channelPath, "")
+	assert.NoError(t, err)
+	defer worker.Close()
+	assert.NoError(t, master.Send("start"))
+	assert.Equal(t, "start", receiveMessage(t, worker))
+
+	// the agent restarts while the worker keeps running
+	master.Close()
+	assert.NoError(t, worker.Send("reply"))
+
+	master, err = NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	defer master.Destroy()
+	assert.Equal(t, "reply", receiveMessage(t, master))
+
+	// the new master starts a new epoch, its messages are not taken for duplicates
+	assert.NoError(t, master.Send("cancel"))
+	assert.Equal(t, "cancel", receiveMessage(t, worker))
+	assert.NoError(t, worker.Send("complete"))
+	assert.Equal(t, "complete", receiveMessage(t, master))
+}
+
+func TestSocketChannelJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+
+	// the master sends before the worker starts, and exits
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	assert.NoError(t, master.Send("start"))
+	master.Close()
+	masterJournal := filepath.Join(channelPath, string(ModeMaster)+journalFileSuffix)
+	_, err = os.Stat(masterJournal)
+	assert.NoError(t, err)
+
+	// the worker reads the journal of the master, and journals its reply while the master is gone
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, channelPath, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "start", receiveMessage(t, worker))
+	assert.NoError(t, worker.Send("complete"))
+	worker.Close()
+	_, err = os.Stat(masterJournal)
+	assert.True(t, os.IsNotExist(err))
+
+	master, err = NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	defer master.Destroy()
+	assert.Equal(t, "complete", receiveMessage(t, master))
+	_, err = os.Stat(filepath.Join(channelPath, string(ModeWorker)+journalFileSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSocketChannelJournalsUnacknowledgedMessages(t *testing.T) {
+	defer func(interval time.Duration) { journalInterval = interval }(journalInterval)
+	journalInterval = 50 * time.Millisecond
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+	masterJournal := filepath.Join(channelPath, string(ModeMaster)+journalFileSuffix)
+	waitForJournal := func(exists bool) {
+		for i := 0; i < 50; i++ {
+			if _, err = os.Stat(masterJournal); err == nil == exists {
+				break
+			}
+			time.Sleep(closePollInterval)
+		}
+		assert.Equal(t, exists, err == nil)
+	}
+
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	defer master.Destroy()
+	// the worker end connects and does not acknowledge, the pending frame is journaled at the next interval
+	c, err := dial(address)
+	assert.NoError(t, err)
+	defer c.Close()
+	assert.NoError(t, master.Send("start"))
+	waitForJournal(true)
+	journal := master.readJournal(ModeMaster)
+	assert.Len(t, journal, 1)
+	assert.Equal(t, "start", journal[0].Payload)
+
+	decoder := json.NewDecoder(c)
+	var f frame
+	assert.NoError(t, decoder.Decode(&f))
+	assert.Equal(t, frameTypeHello, f.Type)
+	assert.NoError(t, decoder.Decode(&f))
+	assert.Equal(t, "start", f.Payload)
+
+	// the acknowledged frame is removed from the journal
+	assert.NoError(t, json.NewEncoder(c).Encode(frame{Type: frameTypeAck, Epoch: f.Epoch, Seq: f.Seq}))
+	waitForJournal(false)
+}
+
+func TestSocketChannelDoesNotJournalAcknowledgedMessages(t *testing.T) {
+	defer func(interval time.Duration, write func(string, []byte, os.FileMode) error) {
+		journalInterval, writeFile = interval, write
+	}(journalInterval, writeFile)
+	journalInterval = 10 * time.Millisecond
+	var writes int32
+	writeFile = func(filename string, data []byte, perm os.FileMode) error {
+		atomic.AddInt32(&writes, 1)
+		return ioutil.WriteFile(filename, data, perm)
+	}
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, channelPath, "")
+	assert.NoError(t, err)
+
+	// the other end is connected and acknowledges every message, the messages stay in memory only
+	for i := 0; i < 2*journalMaxUnwritten; i++ {
+		assert.NoError(t, master.Send("start"))
+		assert.Equal(t, "start", receiveMessage(t, worker))
+		assert.NoError(t, worker.Send("reply"))
+		assert.Equal(t, "reply", receiveMessage(t, master))
+	}
+	worker.Close()
+	master.Destroy()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&writes))
+}
+
+func TestSocketChannelCloseWithUndeliveredMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socketchannel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelPath := filepath.Join(dir, "channel")
+	address := filepath.Join(dir, "channel.sock")
+
+	master, err := NewSocketChannel(log.NewMockLog(), ModeMaster, channelPath, address)
+	assert.NoError(t, err)
+	worker, err := NewSocketChannel(log.NewMockLog(), ModeWorker, channelPath, "")
+	assert.NoError(t, err)
+	defer worker.Close()
+
+	// nobody reads the messages of the master, its delivery blocks once the buffer is full
+	for i := 0; i <= defaultChannelBufferSize; i++ {
+		assert.NoError(t, worker.Send("reply"))
+	}
+	for i := 0; i < 50 && len(master.onMessageChan) < defaultChannelBufferSize; i++ {
+		time.Sleep(closePollInterval)
+	}
+	assert.Len(t, master.onMessageChan, defaultChannelBufferSize)
+
+	closed := make(chan bool)
+	go func() {
+		master.Destroy()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(testMessageTimeout):
+		assert.Fail(t, "timed out closing the channel")
+	}
+}
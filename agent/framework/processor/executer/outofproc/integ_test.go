@@ -42,7 +42,7 @@ var fakeProcess *FakeProcess
 func setup(t *testing.T) *TestCase {
 	logger.Info("initializing dependencies for integration testing...")
 	testCase := CreateTestCase()
-	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
 		isFound := channelmock.IsExists(documentID)
 		assert.Equal(t, testDocumentID, documentID)
 		fakeChannel := channelmock.NewFakeChannel(logger, mode, documentID)
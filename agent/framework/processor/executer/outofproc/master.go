@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cgroup"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
@@ -25,6 +26,8 @@ const (
 	defaultZombieProcessTimeout = 3 * time.Second
 	//command maximum timeout
 	defaultOrphanProcessTimeout = 172800 * time.Second
+	//appended to the output of the plugins failing after processes of the document were killed by the oom killer
+	oomKilledMessage = "A process of the document was killed because the document exceeded its memory limit"
 )
 
 type OutOfProcExecuter struct {
@@ -32,10 +35,15 @@ type OutOfProcExecuter struct {
 	docState   *contracts.DocumentState
 	ctx        context.T
 	cancelFlag task.CancelFlag
+	//cgroup limiting the resources of the document worker, nil if the document has no resource limits
+	cgroup       cgroup.Cgroup
+	oomKillCount int
+	//whether the document worker was started before the agent restarted, its exit cannot be waited for then
+	resumed bool
 }
 
-var channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
-	return channel.CreateFileChannel(log, mode, documentID)
+var channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
+	return channel.CreateChannel(log, mode, channelType, documentID)
 }
 
 var processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
@@ -52,6 +60,10 @@ var processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 	return proc.StartProcess(name, argv)
 }
 
+var cgroupCreator = func(log log.T, name string, limits contracts.ResourceLimits) (cgroup.Cgroup, error) {
+	return cgroup.New(log, name, limits)
+}
+
 func NewOutOfProcExecuter(ctx context.T) *OutOfProcExecuter {
 	return &OutOfProcExecuter{
 		BasicExecuter: *basicexecuter.NewBasicExecuter(ctx),
@@ -95,6 +107,13 @@ func (e *OutOfProcExecuter) Run(
 				close(resChan)
 			}()
 			e.messaging(log, ipc, resChan, cancelFlag, stopTimer)
+			if e.resumed {
+				//the document is complete, give the resumed worker the zombie timeout to exit before removing its cgroup
+				go func() {
+					time.Sleep(defaultZombieProcessTimeout)
+					e.destroyCgroup(log)
+				}()
+			}
 		}(docStore)
 
 		return resChan
@@ -106,8 +125,23 @@ func (e *OutOfProcExecuter) Run(
 //Executer however does hold a timer to the worker to forcefully termniate both of them
 func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool) {
 
+	//check the oom kills of the results before replying
+	results := make(chan contracts.DocumentResult, cap(resChan))
+	forwarded := make(chan bool)
+	go func() {
+		for docResult := range results {
+			e.markOOMKilledPlugins(log, &docResult)
+			resChan <- docResult
+		}
+		close(forwarded)
+	}()
+	defer func() {
+		close(results)
+		<-forwarded
+	}()
+
 	//handoff reply functionalities to data backend.
-	backend := messaging.NewExecuterBackend(resChan, e.docState, cancelFlag)
+	backend := messaging.NewExecuterBackend(results, e.docState, cancelFlag)
 	//handoff the data backend to messaging worker
 	if err := messaging.Messaging(log, ipc, backend, stopTimer); err != nil {
 		//the messaging worker encountered error, either ipc run into error or data backend throws error
@@ -117,13 +151,44 @@ func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan ch
 			e.docState.DocumentInformation.DocumentStatus == contracts.ResultStatusNotStarted {
 			e.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
 			log.Info("document failed half way, sending fail message...")
-			resChan <- e.generateUnexpectedFailResult(fmt.Sprintf("document process failed unexpectedly: %s , check [ssm-document-worker] log for crash reason", err))
+			results <- e.generateUnexpectedFailResult(fmt.Sprintf("document process failed unexpectedly: %s , check [ssm-document-worker] log for crash reason", err))
 		}
 		//destroy the channel
 		ipc.Destroy()
 	}
 }
 
+//markOOMKilledPlugins marks the failed plugins of the result when processes of the document were killed by the oom killer since the previous result
+func (e *OutOfProcExecuter) markOOMKilledPlugins(log log.T, docResult *contracts.DocumentResult) {
+	if e.cgroup == nil {
+		return
+	}
+	count, err := e.cgroup.OOMKillCount()
+	if err != nil {
+		log.Debugf("failed to read the oom kills of the document: %v", err)
+		return
+	}
+	if count <= e.oomKillCount {
+		return
+	}
+	marked := false
+	for _, res := range docResult.PluginResults {
+		if res.Status != contracts.ResultStatusFailed {
+			continue
+		}
+		res.OOMKilled = true
+		if output, ok := res.Output.(string); ok {
+			res.Output = output + "\n" + oomKilledMessage
+		}
+		marked = true
+	}
+	//the kills are reported once, with the first failed plugins
+	if marked {
+		log.Errorf("%v processes of the document were killed by the oom killer", count-e.oomKillCount)
+		e.oomKillCount = count
+	}
+}
+
 func (e *OutOfProcExecuter) generateUnexpectedFailResult(errMsg string) contracts.DocumentResult {
 	var docResult contracts.DocumentResult
 	docResult.MessageID = e.docState.DocumentInformation.MessageID
@@ -146,7 +211,7 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 	log := e.ctx.Log()
 	var found bool
 	documentID := e.docState.DocumentInformation.DocumentID
-	ipc, err, found = channelCreator(log, channel.ModeMaster, documentID)
+	ipc, err, found = channelCreator(log, channel.ModeMaster, e.ctx.AppConfig().Agent.ChannelType, documentID)
 
 	if err != nil {
 		log.Errorf("failed to create ipc channel: %v", err)
@@ -156,9 +221,17 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 		log.Info("discovered old channel object, trying to find detached process...")
 		var stopTime time.Duration
 		procInfo := e.docState.DocumentInformation.ProcInfo
+		//reopen the cgroup of the document to keep detecting the oom kills, and to remove it once the document completes
+		e.resumed = true
+		e.cgroup = e.createCgroup(log)
 		if processFinder(log, procInfo) {
 			log.Infof("found orphan process: %v, start time: %v", procInfo.Pid, procInfo.StartTime)
 			stopTime = defaultOrphanProcessTimeout
+			if e.cgroup != nil {
+				if err := e.cgroup.AddProcess(procInfo.Pid); err != nil {
+					log.Errorf("failed to add process %v back to its cgroup: %v", procInfo.Pid, err)
+				}
+			}
 		} else {
 			log.Infof("process: %v not found, treat as exited", procInfo.Pid)
 			stopTime = defaultZombieProcessTimeout
@@ -167,8 +240,14 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 	} else {
 		log.Debug("channel not found, starting a new process...")
 		var process proc.OSProcess
-		if process, err = processCreator(appconfig.DefaultDocumentWorker, proc.FormArgv(documentID)); err != nil {
+		name, argv := appconfig.DefaultDocumentWorker, proc.FormArgv(documentID)
+		//the worker joins the cgroup before it starts, so that neither it nor its children run without the limits
+		if e.cgroup = e.createCgroup(log); e.cgroup != nil {
+			name, argv = e.cgroup.Command(name, argv)
+		}
+		if process, err = processCreator(name, argv); err != nil {
 			log.Errorf("start process: %v error: %v", appconfig.DefaultDocumentWorker, err)
+			e.destroyCgroup(log)
 			//make sure close the channel
 			ipc.Destroy()
 			return
@@ -187,6 +266,38 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 	return
 }
 
+//createCgroup creates the cgroup limiting the resources of the document worker, or reopens it if it already exists
+//it returns nil when the document has no resource limits, or when the cgroup cannot be created and the document runs without limits
+func (e *OutOfProcExecuter) createCgroup(log log.T) cgroup.Cgroup {
+	defaults := e.ctx.AppConfig().ResourceLimits
+	limits := cgroup.MergeLimits(contracts.ResourceLimits{
+		CPUQuotaPercent: defaults.CPUQuotaPercent,
+		MemoryLimitMB:   defaults.MemoryLimitMB,
+		IOWeight:        defaults.IOWeight,
+	}, e.docState.DocumentInformation.ResourceLimits)
+	if limits.IsEmpty() {
+		return nil
+	}
+
+	c, err := cgroupCreator(log, e.docState.DocumentInformation.DocumentID, limits)
+	if err != nil {
+		log.Errorf("failed to create cgroup, running the document without resource limits: %v", err)
+		return nil
+	}
+	log.Infof("limited the resources of the document to %+v", limits)
+	return c
+}
+
+//destroyCgroup removes the cgroup of the document along with the processes left behind
+func (e *OutOfProcExecuter) destroyCgroup(log log.T) {
+	if e.cgroup == nil {
+		return
+	}
+	if err := e.cgroup.Destroy(); err != nil {
+		log.Errorf("failed to destroy the cgroup of the document: %v", err)
+	}
+}
+
 func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSProcess) {
 	log := e.ctx.Log()
 	//TODO revisit this feature, it has done sides of killing the document worker too fast -- the worker might busy doing s3 upload
@@ -208,6 +319,8 @@ func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSP
 	}
 	//waitReturned = true
 	timeout(stopTimer, defaultZombieProcessTimeout, e.cancelFlag)
+	//the document is complete, remove the cgroup along with the processes left behind
+	e.destroyCgroup(log)
 }
 
 func timeout(stopTimer chan bool, duration time.Duration, cancelFlag task.CancelFlag) {
@@ -14,6 +14,8 @@ import (
 	"errors"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cgroup"
+	cgroupmock "github.com/aws/amazon-ssm-agent/agent/cgroup/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
@@ -101,7 +103,7 @@ func CreateTestCase() *TestCase {
 func TestInitializeNewProcess(t *testing.T) {
 	testCase := CreateTestCase()
 	channelMock := new(channelmock.MockedChannel)
-	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
 		assert.Equal(t, mode, channel.ModeMaster)
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
@@ -132,11 +134,80 @@ func TestInitializeNewProcess(t *testing.T) {
 	assert.Equal(t, testPid, exe.docState.DocumentInformation.ProcInfo.Pid)
 }
 
+func TestInitializeNewProcessWithResourceLimits(t *testing.T) {
+	testCase := CreateTestCase()
+	testCase.docState.DocumentInformation.ResourceLimits = contracts.ResourceLimits{MemoryLimitMB: 256}
+	channelMock := new(channelmock.MockedChannel)
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
+		return channelMock, nil, false
+	}
+	//the worker is started through the command joining the cgroup
+	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+		assert.Equal(t, "/bin/sh", name)
+		assert.Equal(t, []string{"-c", "join", appconfig.DefaultDocumentWorker, testDocumentID}, argv)
+		return testCase.processMock, nil
+	}
+	cgroupMock := new(cgroupmock.MockedCgroup)
+	cgroupCreator = func(log log.T, name string, limits contracts.ResourceLimits) (cgroup.Cgroup, error) {
+		assert.Equal(t, testDocumentID, name)
+		assert.Equal(t, contracts.ResourceLimits{MemoryLimitMB: 256}, limits)
+		return cgroupMock, nil
+	}
+	exe := &OutOfProcExecuter{
+		ctx:        testCase.context,
+		docState:   &testCase.docState,
+		cancelFlag: task.NewChanneledCancelFlag(),
+	}
+	stopTimer := make(chan bool)
+
+	testCase.processMock.On("Wait").Return(nil)
+	testCase.processMock.On("Pid").Return(testPid)
+	testCase.processMock.On("StartTime").Return(testStartDateTime)
+	cgroupMock.On("Command", appconfig.DefaultDocumentWorker, []string{testDocumentID}).Return("/bin/sh", []string{"-c", "join", appconfig.DefaultDocumentWorker, testDocumentID})
+	cgroupMock.On("Destroy").Return(nil)
+	_, err := exe.initialize(stopTimer)
+	assert.NoError(t, err)
+	<-stopTimer
+	//the cgroup is destroyed once the zombie timeout returns
+	time.Sleep(100 * time.Millisecond)
+	testCase.processMock.AssertExpectations(t)
+	cgroupMock.AssertExpectations(t)
+}
+
+func TestMarkOOMKilledPlugins(t *testing.T) {
+	testCase := CreateTestCase()
+	cgroupMock := new(cgroupmock.MockedCgroup)
+	exe := &OutOfProcExecuter{
+		ctx:      testCase.context,
+		docState: &testCase.docState,
+		cgroup:   cgroupMock,
+	}
+	failed := contracts.PluginResult{Status: contracts.ResultStatusFailed, Output: "Killed"}
+	docResult := contracts.DocumentResult{
+		PluginResults: map[string]*contracts.PluginResult{"plugin1": &failed},
+	}
+
+	cgroupMock.On("OOMKillCount").Return(0, nil).Once()
+	exe.markOOMKilledPlugins(logger, &docResult)
+	assert.False(t, failed.OOMKilled)
+
+	cgroupMock.On("OOMKillCount").Return(1, nil)
+	exe.markOOMKilledPlugins(logger, &docResult)
+	assert.True(t, failed.OOMKilled)
+	assert.Equal(t, "Killed\n"+oomKilledMessage, failed.Output)
+
+	//the same kill is not reported twice
+	other := contracts.PluginResult{Status: contracts.ResultStatusFailed}
+	docResult.PluginResults = map[string]*contracts.PluginResult{"plugin2": &other}
+	exe.markOOMKilledPlugins(logger, &docResult)
+	assert.False(t, other.OOMKilled)
+}
+
 func TestCreateProcessFailed(t *testing.T) {
 	testCase := CreateTestCase()
 	channelMock := new(channelmock.MockedChannel)
 	channelMock.On("Destroy").Return(nil)
-	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
 		assert.Equal(t, mode, channel.ModeMaster)
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
@@ -162,7 +233,7 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 	testCase := CreateTestCase()
 	channelMock := new(channelmock.MockedChannel)
 	channelMock.On("Destroy").Return(nil)
-	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
 		assert.Equal(t, mode, channel.ModeMaster)
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
@@ -223,7 +294,7 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 func TestInitializeConnectOldOrphan(t *testing.T) {
 	testCase := CreateTestCase()
 	channelMock := new(channelmock.MockedChannel)
-	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
 		assert.Equal(t, mode, channel.ModeMaster)
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, true
@@ -256,6 +327,40 @@ func TestInitializeConnectOldOrphan(t *testing.T) {
 	channelMock.AssertExpectations(t)
 }
 
+func TestInitializeConnectOldOrphanWithResourceLimits(t *testing.T) {
+	testCase := CreateTestCase()
+	testCase.docState.DocumentInformation.ResourceLimits = contracts.ResourceLimits{MemoryLimitMB: 256}
+	testCase.docState.DocumentInformation.ProcInfo = contracts.OSProcInfo{Pid: testPid}
+	channelMock := new(channelmock.MockedChannel)
+	channelCreator = func(log log.T, mode channel.Mode, channelType string, documentID string) (channel.Channel, error, bool) {
+		return channelMock, nil, true
+	}
+	processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
+		return true
+	}
+	cgroupMock := new(cgroupmock.MockedCgroup)
+	cgroupCreator = func(log log.T, name string, limits contracts.ResourceLimits) (cgroup.Cgroup, error) {
+		assert.Equal(t, testDocumentID, name)
+		return cgroupMock, nil
+	}
+	cancel := task.NewChanneledCancelFlag()
+	exe := &OutOfProcExecuter{
+		ctx:        testCase.context,
+		docState:   &testCase.docState,
+		cancelFlag: cancel,
+	}
+	stopTimer := make(chan bool)
+
+	//the orphan worker is added back to the reopened cgroup
+	cgroupMock.On("AddProcess", testPid).Return(nil)
+	_, err := exe.initialize(stopTimer)
+	cancel.Set(task.Completed)
+	assert.NoError(t, err)
+	assert.True(t, exe.resumed)
+	assert.Equal(t, cgroupMock, exe.cgroup)
+	cgroupMock.AssertExpectations(t)
+}
+
 //TODO add Run() unittest
 
 //this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
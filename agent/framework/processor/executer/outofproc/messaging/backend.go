@@ -2,12 +2,15 @@ package messaging
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"sync"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -16,6 +19,10 @@ const (
 	defaultBackendChannelSize = 10
 )
 
+// Assign to global variables to allow unittest to override
+// handshakeTimeout is how long the master waits for the handshake response, the workers older than the handshake never answer
+var handshakeTimeout = 5 * time.Second
+
 type PluginRunner func(
 	context context.T,
 	docState contracts.DocumentState,
@@ -23,7 +30,7 @@ type PluginRunner func(
 	cancelFlag task.CancelFlag,
 )
 
-//worker backend receives request messages from master, controls a pluginRunner based off the request and send reponses to Executer
+// worker backend receives request messages from master, controls a pluginRunner based off the request and send reponses to Executer
 type WorkerBackend struct {
 	ctx        context.T
 	input      chan string
@@ -31,9 +38,16 @@ type WorkerBackend struct {
 	cancelFlag task.CancelFlag
 	runner     PluginRunner
 	stopChan   chan int
+	//protocol version negotiated with the master
+	version    string
+	versionMu  sync.Mutex
+	inputMu    sync.RWMutex
+	inputClose bool
+	//closed once the worker stops, to release the datagrams still waiting to be sent
+	done chan bool
 }
 
-//Executer backend formulate the run request to the worker, and collect back the responses from worker
+// Executer backend formulate the run request to the worker, and collect back the responses from worker
 type ExecuterBackend struct {
 	//the shared state object that Executer hand off to data backend
 	docState   *contracts.DocumentState
@@ -41,28 +55,44 @@ type ExecuterBackend struct {
 	cancelFlag task.CancelFlag
 	output     chan contracts.DocumentResult
 	stopChan   chan int
+	//protocol version negotiated with the worker
+	version   string
+	versionMu sync.Mutex
+	//closed once the worker answered the handshake
+	handshakeDone chan bool
+	handshakeOnce sync.Once
+	failed        bool
 }
 
 func NewExecuterBackend(output chan contracts.DocumentResult, docState *contracts.DocumentState, cancelFlag task.CancelFlag) *ExecuterBackend {
 	stopChan := make(chan int, defaultBackendChannelSize)
 	inputChan := make(chan string, defaultBackendChannelSize)
 	p := ExecuterBackend{
-		output:     output,
-		docState:   docState,
-		input:      inputChan,
-		cancelFlag: cancelFlag,
-		stopChan:   stopChan,
+		output:        output,
+		docState:      docState,
+		input:         inputChan,
+		cancelFlag:    cancelFlag,
+		stopChan:      stopChan,
+		handshakeDone: make(chan bool),
 	}
 	go p.start(*docState)
 	return &p
 }
 
+// start says hello to the worker with the supported protocol versions, and waits for its answer before sending the plugin config.
+// Workers older than the handshake ignore it, the messages are sent in the oldest version if the worker does not answer in time.
 func (p *ExecuterBackend) start(docState contracts.DocumentState) {
-	startDatagram, _ := CreateDatagram(MessageTypePluginConfig, docState)
+	handshakeDatagram, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshake, HandshakeRequest{SupportedVersions: versions})
+	p.input <- handshakeDatagram
+	select {
+	case <-p.handshakeDone:
+	case <-time.After(handshakeTimeout):
+	}
+	startDatagram, _ := CreateVersionedDatagram(p.protocolVersion(), MessageTypePluginConfig, docState)
 	p.input <- startDatagram
 	p.cancelFlag.Wait()
 	if p.cancelFlag.Canceled() {
-		cancelDatagram, _ := CreateDatagram(MessageTypeCancel, "cancel")
+		cancelDatagram, _ := CreateVersionedDatagram(p.protocolVersion(), MessageTypeCancel, "cancel")
 		p.input <- cancelDatagram
 	} else if p.cancelFlag.ShutDown() {
 		p.stopChan <- stopTypeShutdown
@@ -79,26 +109,66 @@ func (p *ExecuterBackend) Stop() <-chan int {
 	return p.stopChan
 }
 
-//TODO handle error and logging, when err, ask messaging to stop
-//TODO version handling?
+// protocolVersion returns the protocol version negotiated with the worker, the oldest version until the worker answers the handshake
+func (p *ExecuterBackend) protocolVersion() string {
+	p.versionMu.Lock()
+	defer p.versionMu.Unlock()
+	if p.version == "" {
+		return versions[0]
+	}
+	return p.version
+}
+
+// Process the message of the worker, the document fails when the worker sends an invalid message or reports a fatal error,
+// so that the document is not left without an explanation
 func (p *ExecuterBackend) Process(datagram string) error {
-	t, content := ParseDatagram(datagram)
-	switch t {
+	if p.failed {
+		return errors.New("document already failed, dropping message")
+	}
+	message, err := ParseDatagram(datagram)
+	if err != nil {
+		p.fail(fmt.Sprintf("document worker sent an invalid message: %v", err))
+		return err
+	}
+	switch message.Type {
+	case MessageTypeHandshakeResponse:
+		var response HandshakeResponse
+		jsonutil.Unmarshal(message.Content, &response)
+		p.versionMu.Lock()
+		p.version = response.ProtocolVersion
+		p.versionMu.Unlock()
+		p.handshakeOnce.Do(func() { close(p.handshakeDone) })
+	case MessageTypeError:
+		var reply ErrorReply
+		jsonutil.Unmarshal(message.Content, &reply)
+		if reply.Fatal {
+			p.fail(fmt.Sprintf("document worker rejected the %v message: %v", reply.MessageType, reply.Message))
+		}
+		return fmt.Errorf("document worker rejected the %v message: %v", reply.MessageType, reply.Message)
 	case MessageTypeReply, MessageTypeComplete:
 		var docResult contracts.DocumentResult
-		jsonutil.Unmarshal(content, &docResult)
+		jsonutil.Unmarshal(message.Content, &docResult)
 		p.formatDocResult(&docResult)
 		p.output <- docResult
-		if t == MessageTypeComplete {
+		if message.Type == MessageTypeComplete {
 			//get document result, force termniate messaging worker
 			p.stopChan <- stopTypeTerminate
 		}
 	default:
-		return errors.New("unsupported message type")
+		return fmt.Errorf("unexpected %v message from the document worker", message.Type)
 	}
 	return nil
 }
 
+// fail the plugins not completed yet, and terminate the messaging worker
+func (p *ExecuterBackend) fail(errMsg string) {
+	p.failed = true
+	docResult := failedDocumentResult(*p.docState, errMsg)
+	p.formatDocResult(&docResult)
+	p.output <- docResult
+	p.stopChan <- stopTypeTerminate
+}
+
 func (p *ExecuterBackend) formatDocResult(docResult *contracts.DocumentResult) {
 	//fill doc level information that the sub-process wouldn't know
 	docResult.MessageID = p.docState.DocumentInformation.MessageID
@@ -118,22 +188,50 @@ func NewWorkerBackend(ctx context.T, runner PluginRunner) *WorkerBackend {
 		cancelFlag: task.NewChanneledCancelFlag(),
 		runner:     runner,
 		stopChan:   stopChan,
+		done:       make(chan bool),
 	}
 }
 
+// protocolVersion returns the protocol version negotiated with the master, the oldest version if the master did not say hello
+func (p *WorkerBackend) protocolVersion() string {
+	p.versionMu.Lock()
+	defer p.versionMu.Unlock()
+	if p.version == "" {
+		return versions[0]
+	}
+	return p.version
+}
+
 func (p *WorkerBackend) Process(datagram string) error {
-	t, content := ParseDatagram(datagram)
+	message, err := ParseDatagram(datagram)
 	log := p.ctx.Log()
-	switch t {
+	if err != nil {
+		log.Errorf("rejected message from the master: %v", err)
+		//the document cannot run without its plugin config
+		p.reject(message, err, message.Type == MessageTypePluginConfig)
+		return err
+	}
+	switch message.Type {
+	case MessageTypeHandshake:
+		var request HandshakeRequest
+		jsonutil.Unmarshal(message.Content, &request)
+		version, ok := negotiateVersion(request.SupportedVersions)
+		if !ok {
+			err = fmt.Errorf("no protocol version in common with the master versions %v", request.SupportedVersions)
+			p.reject(message, err, true)
+			return err
+		}
+		log.Infof("using protocol version %v", version)
+		p.versionMu.Lock()
+		p.version = version
+		p.versionMu.Unlock()
+		response, _ := CreateVersionedDatagram(version, MessageTypeHandshakeResponse, HandshakeResponse{ProtocolVersion: version})
+		go p.send(response)
 	case MessageTypePluginConfig:
 		log.Info("received plugin config message")
 		var docState contracts.DocumentState
-		log.Info(content)
-		if err := jsonutil.Unmarshal(content, &docState); err != nil {
-			log.Errorf("failed to unmarshal plugin config: %v", err)
-			//TODO request messaging to stop
-			return err
-		}
+		log.Info(message.Content)
+		jsonutil.Unmarshal(message.Content, &docState)
 		p.once.Do(func() {
 			statusChan := make(chan contracts.PluginResult)
 			go p.runner(p.ctx, docState, statusChan, p.cancelFlag)
@@ -145,11 +243,67 @@ func (p *WorkerBackend) Process(datagram string) error {
 		p.cancelFlag.Set(task.Canceled)
 	default:
 		//TODO add extra logic to check whether plugin has started, if not, stop IPC, or add timeout
-		return errors.New("unsupported message type")
+		err = fmt.Errorf("unexpected %v message from the master", message.Type)
+		p.reject(message, err, false)
+		return err
 	}
 	return nil
 }
 
+// reject replies an error to the master, a fatal error stops the worker.
+// The masters of protocol 1.0 do not understand error replies, a fatal error is reported to them as the failed result of the document.
+func (p *WorkerBackend) reject(message Message, err error, fatal bool) {
+	version := p.protocolVersion()
+	if !fatal {
+		if version == ProtocolVersion1_0 {
+			return
+		}
+		reply, _ := CreateVersionedDatagram(version, MessageTypeError, ErrorReply{MessageType: message.Type, Message: err.Error()})
+		go p.send(reply)
+		return
+	}
+	p.once.Do(func() {
+		var reply string
+		if version == ProtocolVersion1_0 {
+			var docState contracts.DocumentState
+			jsonutil.Unmarshal(message.Content, &docState)
+			reply, _ = CreateVersionedDatagram(version, MessageTypeComplete, failedDocumentResult(docState, err.Error()))
+		} else {
+			reply, _ = CreateVersionedDatagram(version, MessageTypeError, ErrorReply{MessageType: message.Type, Message: err.Error(), Fatal: true})
+		}
+		go p.stop(reply)
+	})
+}
+
+// send the datagram to the master, unless the worker is stopping
+func (p *WorkerBackend) send(datagram string) {
+	p.inputMu.RLock()
+	defer p.inputMu.RUnlock()
+	if p.inputClose {
+		return
+	}
+	select {
+	case p.input <- datagram:
+	case <-p.done:
+	}
+}
+
+// stop sends the last datagram to the master and stops the messaging worker
+func (p *WorkerBackend) stop(lastDatagram string) {
+	log := p.ctx.Log()
+	p.send(lastDatagram)
+	//the master expects nothing after the last datagram, release the senders still waiting
+	close(p.done)
+	p.inputMu.Lock()
+	p.inputClose = true
+	close(p.input)
+	p.inputMu.Unlock()
+	log.Info("stopping ipc worker...")
+	//sending stop signal
+	p.stopChan <- stopTypeShutdown
+	close(p.stopChan)
+}
+
 func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult) {
 	log := p.ctx.Log()
 	results := make(map[string]*contracts.PluginResult)
@@ -167,17 +321,14 @@ func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult) {
 			LastPlugin:    "",
 		}
 		log.Info("sending document complete response...")
-		completeMessage, _ := CreateDatagram(MessageTypeComplete, docResult)
-		p.input <- completeMessage
-		close(p.input)
-		log.Info("stopping ipc worker...")
-		//sending stop signal
-		p.stopChan <- stopTypeShutdown
-		close(p.stopChan)
+		completeMessage, _ := CreateVersionedDatagram(p.protocolVersion(), MessageTypeComplete, docResult)
+		//the results are masked, the secret values of the document are not needed anymore
+		redaction.Reset()
+		p.stop(completeMessage)
 	}()
 
 	for res := range statusChan {
-		var result = res
+		var result = redactResult(res)
 		results[res.PluginID] = &result
 		//TODO move the aggregator under executer package and protect it, there's global lock in this package
 		status, _, _ := contracts.DocumentResultAggregator(log, res.PluginID, results)
@@ -186,9 +337,9 @@ func (p *WorkerBackend) pluginListener(statusChan chan contracts.PluginResult) {
 			PluginResults: results,
 			LastPlugin:    res.PluginID,
 		}
-		replyMessage, _ := CreateDatagram(MessageTypeReply, docResult)
+		replyMessage, _ := CreateVersionedDatagram(p.protocolVersion(), MessageTypeReply, docResult)
 		log.Debugf("plugin: %v done, sending reply message...", res.PluginID)
-		p.input <- replyMessage
+		p.send(replyMessage)
 	}
 	log.Info("document execution complete")
 	finalStatus, _, _ = contracts.DocumentResultAggregator(log, "", results)
@@ -202,3 +353,36 @@ func (p *WorkerBackend) Accept() <-chan string {
 func (p *WorkerBackend) Stop() <-chan int {
 	return p.stopChan
 }
+
+// redactResult masks the secret values resolved for the document in the result of the plugin, before the result leaves the worker.
+// The secret values are only registered in the worker, the master cannot mask them.
+func redactResult(res contracts.PluginResult) contracts.PluginResult {
+	if !redaction.HasSecrets() {
+		return res
+	}
+	res.StandardOutput = redaction.Redact(res.StandardOutput)
+	res.StandardError = redaction.Redact(res.StandardError)
+	switch output := res.Output.(type) {
+	case string:
+		res.Output = redaction.Redact(output)
+	case nil:
+	default:
+		//structured outputs are masked in their json form
+		if content, err := jsonutil.Marshal(output); err == nil {
+			if redacted := redaction.Redact(content); redacted != content {
+				var redactedOutput interface{}
+				if err = jsonutil.Unmarshal(redacted, &redactedOutput); err == nil {
+					res.Output = redactedOutput
+				}
+			}
+		}
+	}
+	if len(res.StepOutputs) > 0 {
+		stepOutputs := make(map[string]string, len(res.StepOutputs))
+		for name, value := range res.StepOutputs {
+			stepOutputs[name] = redaction.Redact(value)
+		}
+		res.StepOutputs = stepOutputs
+	}
+	return res
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 
 	"time"
 
@@ -112,9 +114,18 @@ func TestExecuterBackendStart_Shutdown(t *testing.T) {
 		stopChan:   stopChan,
 		docState:   &testCase.docState,
 	}
+	origHandshakeTimeout := handshakeTimeout
+	defer func() { handshakeTimeout = origHandshakeTimeout }()
+	handshakeTimeout = 100 * time.Millisecond
 	closed := make(chan bool)
 	go func() {
-		<-inputChan
+		handshake, err := ParseDatagram(<-inputChan)
+		assert.NoError(t, err)
+		assert.Equal(t, MessageType(MessageTypeHandshake), handshake.Type)
+		start, err := ParseDatagram(<-inputChan)
+		assert.NoError(t, err)
+		assert.Equal(t, MessageType(MessageTypePluginConfig), start.Type)
+		assert.Equal(t, ProtocolVersion1_0, start.Version)
 		stopType := <-stopChan
 		assert.Equal(t, stopTypeShutdown, stopType)
 		closed <- true
@@ -214,6 +225,7 @@ func TestWorkerBackend_ProcessCancelV1(t *testing.T) {
 		cancelFlag: cancelFlag,
 		runner:     pluginRunner,
 		stopChan:   stopChan,
+		done:       make(chan bool),
 	}
 	backend.Process(testPluginsRawJSON)
 	backend.Process(testCancelRawJSON)
@@ -235,6 +247,7 @@ func TestWorkerBackendPluginListener(t *testing.T) {
 		ctx:      contextMock,
 		input:    inputChan,
 		stopChan: stopChan,
+		done:     make(chan bool),
 	}
 	go backend.pluginListener(statusChan)
 	statusChan <- *testCase.results["plugin1"]
@@ -256,6 +269,43 @@ func TestWorkerBackendPluginListener(t *testing.T) {
 
 }
 
+func TestWorkerBackendPluginListenerRedactsSecrets(t *testing.T) {
+	defer redaction.Reset()
+	redaction.Register("Pa55w0rd!")
+	statusChan := make(chan contracts.PluginResult)
+	inputChan := make(chan string)
+	stopChan := make(chan int)
+	backend := WorkerBackend{
+		ctx:      contextMock,
+		input:    inputChan,
+		stopChan: stopChan,
+		done:     make(chan bool),
+	}
+	go backend.pluginListener(statusChan)
+	statusChan <- contracts.PluginResult{
+		PluginID:       "plugin1",
+		PluginName:     "aws:runShellScript",
+		Status:         contracts.ResultStatusSuccess,
+		Output:         "connecting with Pa55w0rd!",
+		StandardOutput: "connecting with Pa55w0rd!",
+		StepOutputs:    map[string]string{"password": "Pa55w0rd!"},
+	}
+	message, err := ParseDatagram(<-inputChan)
+	assert.NoError(t, err)
+	var docResult contracts.DocumentResult
+	assert.NoError(t, jsonutil.Unmarshal(message.Content, &docResult))
+	result := docResult.PluginResults["plugin1"]
+	assert.Equal(t, "connecting with "+redaction.Mask, result.Output)
+	assert.Equal(t, "connecting with "+redaction.Mask, result.StandardOutput)
+	assert.Equal(t, redaction.Mask, result.StepOutputs["password"])
+
+	// the secrets are released once the document completes
+	close(statusChan)
+	<-inputChan
+	assert.Equal(t, stopTypeShutdown, <-stopChan)
+	assert.False(t, redaction.HasSecrets())
+}
+
 //this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
 func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[string]*contracts.PluginResult) {
 	assert.Equal(t, len(a), len(b))
@@ -263,3 +313,64 @@ func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[
 		assert.Equal(t, *val, *b[key])
 	}
 }
+
+func TestExecuterBackendStartWaitsForHandshake(t *testing.T) {
+	testCase := CreateTestCase()
+	inputChan := make(chan string, 10)
+	cancel := task.NewChanneledCancelFlag()
+	backend := ExecuterBackend{
+		output:        make(chan contracts.DocumentResult, 10),
+		input:         inputChan,
+		cancelFlag:    cancel,
+		stopChan:      make(chan int, 1),
+		docState:      &testCase.docState,
+		handshakeDone: make(chan bool),
+	}
+	go backend.start(testCase.docState)
+	handshake, err := ParseDatagram(<-inputChan)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeHandshake), handshake.Type)
+
+	// the plugin config waits for the answer of the worker
+	select {
+	case <-inputChan:
+		assert.Fail(t, "the plugin config was sent before the handshake response")
+	case <-time.After(100 * time.Millisecond):
+	}
+	response, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshakeResponse, HandshakeResponse{ProtocolVersion: ProtocolVersion1_1})
+	assert.NoError(t, backend.Process(response))
+	start, err := ParseDatagram(<-inputChan)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypePluginConfig), start.Type)
+	assert.Equal(t, ProtocolVersion1_1, start.Version)
+	cancel.Set(task.Completed)
+}
+
+func TestWorkerBackendStopReleasesPendingSends(t *testing.T) {
+	inputChan := make(chan string)
+	stopChan := make(chan int, 1)
+	backend := WorkerBackend{
+		ctx:      contextMock,
+		input:    inputChan,
+		stopChan: stopChan,
+		done:     make(chan bool),
+	}
+	// the reply is queued behind the last datagram, the master stops reading after the last one
+	go backend.stop("complete")
+	time.Sleep(100 * time.Millisecond)
+	sent := make(chan bool)
+	go func() {
+		backend.send("reply")
+		close(sent)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "complete", <-inputChan)
+	assert.Equal(t, stopTypeShutdown, <-stopChan)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the pending send was not released")
+	}
+	_, more := <-inputChan
+	assert.False(t, more)
+}
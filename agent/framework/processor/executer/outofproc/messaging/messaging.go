@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
@@ -21,9 +22,13 @@ const (
 	MessageTypeComplete     = "complete"
 	MessageTypeReply        = "reply"
 	MessageTypeCancel       = "cancel"
+
+	MessageTypeHandshake         = "handshake"
+	MessageTypeHandshakeResponse = "handshakeresponse"
+	MessageTypeError             = "error"
 )
 
-var versions = []string{"1.0"}
+var versions = []string{ProtocolVersion1_0, ProtocolVersion1_1}
 
 type Message struct {
 	Version string      `json:"version"`
@@ -46,14 +51,18 @@ func GetLatestVersion() string {
 
 //CreateDatagram marshals a given arbitrary object to raw json string
 //Message schema is determined by the current version, content struct is indicated by type field
-//TODO add version handling
 func CreateDatagram(t MessageType, content interface{}) (string, error) {
+	return CreateVersionedDatagram(GetLatestVersion(), t, content)
+}
+
+//CreateVersionedDatagram marshals a given arbitrary object to raw json string, in the given protocol version
+func CreateVersionedDatagram(version string, t MessageType, content interface{}) (string, error) {
 	contentStr, err := jsonutil.Marshal(content)
 	if err != nil {
 		return "", err
 	}
 	message := Message{
-		Version: GetLatestVersion(),
+		Version: version,
 		Type:    t,
 		Content: contentStr,
 	}
@@ -64,11 +73,13 @@ func CreateDatagram(t MessageType, content interface{}) (string, error) {
 	return datagram, nil
 }
 
-//TODO add version and error handling
-func ParseDatagram(datagram string) (MessageType, string) {
-	message := Message{}
-	jsonutil.Unmarshal(datagram, &message)
-	return message.Type, message.Content
+//ParseDatagram unmarshals the raw json string, and validates the message against its protocol version
+//the message is returned along with the validation error, as far as it could be read
+func ParseDatagram(datagram string) (message Message, err error) {
+	if err = jsonutil.Unmarshal(datagram, &message); err != nil {
+		return message, fmt.Errorf("malformed message: %v", err)
+	}
+	return message, validateMessage(message)
 }
 
 // Messaging implements the duplex transmission between master and worker, it send datagram it received to data backend,
@@ -0,0 +1,166 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+//Protocol versions, an agent supports every version up to its latest one
+const (
+	//1.0 pluginconfig, reply, complete and cancel messages
+	ProtocolVersion1_0 = "1.0"
+	//1.1 handshake and error messages
+	ProtocolVersion1_1 = "1.1"
+)
+
+//the protocol version each message type was introduced in
+var messageTypeVersions = map[MessageType]string{
+	MessageTypePluginConfig:      ProtocolVersion1_0,
+	MessageTypeComplete:          ProtocolVersion1_0,
+	MessageTypeReply:             ProtocolVersion1_0,
+	MessageTypeCancel:            ProtocolVersion1_0,
+	MessageTypeHandshake:         ProtocolVersion1_1,
+	MessageTypeHandshakeResponse: ProtocolVersion1_1,
+	MessageTypeError:             ProtocolVersion1_1,
+}
+
+//HandshakeRequest is sent by the master ahead of the plugin config, with the protocol versions it supports
+type HandshakeRequest struct {
+	SupportedVersions []string `json:"supportedVersions"`
+}
+
+//HandshakeResponse is the protocol version the worker chose among the versions of the master
+type HandshakeResponse struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+//ErrorReply reports a message the receiver rejected, the document cannot proceed after a fatal error
+type ErrorReply struct {
+	MessageType MessageType `json:"messageType"`
+	Message     string      `json:"message"`
+	Fatal       bool        `json:"fatal"`
+}
+
+//versionIndex returns the position of the version in the supported versions, -1 if the version is not supported
+func versionIndex(version string) int {
+	for i, v := range versions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
+
+//negotiateVersion returns the latest version supported by both ends
+func negotiateVersion(peerVersions []string) (string, bool) {
+	best := -1
+	for _, v := range peerVersions {
+		if i := versionIndex(v); i > best {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	return versions[best], true
+}
+
+//validateMessage checks the message is defined in its protocol version, and its content matches the schema of its type
+func validateMessage(message Message) error {
+	index := versionIndex(message.Version)
+	if index < 0 {
+		return fmt.Errorf("unsupported protocol version %q of %v message", message.Version, message.Type)
+	}
+	since, ok := messageTypeVersions[message.Type]
+	if !ok {
+		return fmt.Errorf("unsupported message type %q", message.Type)
+	}
+	if index < versionIndex(since) {
+		return fmt.Errorf("message type %v is not defined in protocol version %v", message.Type, message.Version)
+	}
+	if err := validateContent(message.Type, message.Content); err != nil {
+		return fmt.Errorf("invalid %v message: %v", message.Type, err)
+	}
+	return nil
+}
+
+func validateContent(t MessageType, content string) error {
+	switch t {
+	case MessageTypePluginConfig:
+		var docState contracts.DocumentState
+		if err := jsonutil.Unmarshal(content, &docState); err != nil {
+			return err
+		}
+		if len(docState.InstancePluginsInformation) == 0 {
+			return errors.New("no plugins to run")
+		}
+		for i, plugin := range docState.InstancePluginsInformation {
+			if plugin.Name == "" || plugin.Id == "" {
+				return fmt.Errorf("plugin %v has no name or id", i)
+			}
+		}
+	case MessageTypeReply, MessageTypeComplete:
+		var docResult contracts.DocumentResult
+		if err := jsonutil.Unmarshal(content, &docResult); err != nil {
+			return err
+		}
+		if t == MessageTypeReply {
+			if docResult.Status == "" {
+				return errors.New("no document status")
+			}
+			if res, ok := docResult.PluginResults[docResult.LastPlugin]; !ok || res == nil {
+				return fmt.Errorf("no result of plugin %q", docResult.LastPlugin)
+			}
+		}
+	case MessageTypeHandshake:
+		var request HandshakeRequest
+		if err := jsonutil.Unmarshal(content, &request); err != nil {
+			return err
+		}
+		if len(request.SupportedVersions) == 0 {
+			return errors.New("no supported versions")
+		}
+	case MessageTypeHandshakeResponse:
+		var response HandshakeResponse
+		if err := jsonutil.Unmarshal(content, &response); err != nil {
+			return err
+		}
+		if versionIndex(response.ProtocolVersion) < 0 {
+			return fmt.Errorf("unsupported protocol version %q", response.ProtocolVersion)
+		}
+	case MessageTypeError:
+		var reply ErrorReply
+		if err := jsonutil.Unmarshal(content, &reply); err != nil {
+			return err
+		}
+		if reply.Message == "" {
+			return errors.New("no error message")
+		}
+	}
+	return nil
+}
+
+//failedDocumentResult fails the plugins of the document that have not completed, with the given explanation
+func failedDocumentResult(docState contracts.DocumentState, errMsg string) contracts.DocumentResult {
+	docResult := contracts.DocumentResult{
+		Status:        contracts.ResultStatusFailed,
+		PluginResults: make(map[string]*contracts.PluginResult),
+	}
+	for _, plugin := range docState.InstancePluginsInformation {
+		status := plugin.Result.Status
+		if status != "" && status != contracts.ResultStatusNotStarted && status != contracts.ResultStatusInProgress {
+			continue
+		}
+		res := plugin.Result
+		res.PluginID = plugin.Id
+		res.PluginName = plugin.Name
+		res.Status = contracts.ResultStatusFailed
+		res.Code = 1
+		res.Output = errMsg
+		docResult.PluginResults[plugin.Id] = &res
+	}
+	return docResult
+}
@@ -0,0 +1,179 @@
+package messaging
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDatagram(t *testing.T) {
+	_ = CreateTestCase()
+	message, err := ParseDatagram(testPluginReplyRawJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeReply), message.Type)
+	assert.Equal(t, ProtocolVersion1_0, message.Version)
+
+	handshake, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshake, HandshakeRequest{SupportedVersions: []string{"1.0", "1.1"}})
+	_, err = ParseDatagram(handshake)
+	assert.NoError(t, err)
+}
+
+func TestParseDatagramErrors(t *testing.T) {
+	testCases := []struct {
+		version  string
+		t        MessageType
+		content  interface{}
+		expected string
+	}{
+		{"2.0", MessageTypeCancel, "cancel", "unsupported protocol version \"2.0\" of cancel message"},
+		{"", MessageTypeCancel, "cancel", "unsupported protocol version \"\" of cancel message"},
+		{ProtocolVersion1_1, "unknown", "", "unsupported message type \"unknown\""},
+		{ProtocolVersion1_0, MessageTypeHandshake, HandshakeRequest{SupportedVersions: []string{"1.0"}}, "message type handshake is not defined in protocol version 1.0"},
+		{ProtocolVersion1_1, MessageTypeHandshake, HandshakeRequest{}, "invalid handshake message: no supported versions"},
+		{ProtocolVersion1_1, MessageTypeHandshakeResponse, HandshakeResponse{ProtocolVersion: "0.9"}, "invalid handshakeresponse message: unsupported protocol version \"0.9\""},
+		{ProtocolVersion1_1, MessageTypeError, ErrorReply{}, "invalid error message: no error message"},
+		{ProtocolVersion1_0, MessageTypePluginConfig, contracts.DocumentState{}, "invalid pluginconfig message: no plugins to run"},
+		{ProtocolVersion1_0, MessageTypePluginConfig, contracts.DocumentState{InstancePluginsInformation: []contracts.PluginState{{Name: "aws:runScript"}}}, "invalid pluginconfig message: plugin 0 has no name or id"},
+		{ProtocolVersion1_0, MessageTypePluginConfig, []string{"plugin"}, "invalid pluginconfig message: json: cannot unmarshal array into Go value of type contracts.DocumentState"},
+		{ProtocolVersion1_0, MessageTypeReply, contracts.DocumentResult{LastPlugin: "plugin1"}, "invalid reply message: no document status"},
+		{ProtocolVersion1_0, MessageTypeReply, contracts.DocumentResult{Status: contracts.ResultStatusSuccess, LastPlugin: "plugin1"}, "invalid reply message: no result of plugin \"plugin1\""},
+	}
+	for _, testCase := range testCases {
+		datagram, err := CreateVersionedDatagram(testCase.version, testCase.t, testCase.content)
+		assert.NoError(t, err)
+		_, err = ParseDatagram(datagram)
+		if assert.Error(t, err) {
+			assert.Equal(t, testCase.expected, err.Error())
+		}
+	}
+
+	_, err := ParseDatagram("a very bad string")
+	assert.Error(t, err)
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	version, ok := negotiateVersion([]string{"1.0", "1.1", "1.2"})
+	assert.True(t, ok)
+	assert.Equal(t, ProtocolVersion1_1, version)
+
+	version, ok = negotiateVersion([]string{"1.0"})
+	assert.True(t, ok)
+	assert.Equal(t, ProtocolVersion1_0, version)
+
+	_, ok = negotiateVersion([]string{"2.0"})
+	assert.False(t, ok)
+}
+
+func TestWorkerBackend_Handshake(t *testing.T) {
+	testCase := CreateTestCase()
+	inputChan := make(chan string, 10)
+	backend := WorkerBackend{
+		ctx:      contextMock,
+		input:    inputChan,
+		stopChan: make(chan int, 1),
+		done:     make(chan bool),
+	}
+	handshake, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshake, HandshakeRequest{SupportedVersions: []string{"1.0", "1.1", "1.2"}})
+	assert.NoError(t, backend.Process(handshake))
+	message, err := ParseDatagram(<-inputChan)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeHandshakeResponse), message.Type)
+	var response HandshakeResponse
+	jsonutil.Unmarshal(message.Content, &response)
+	assert.Equal(t, ProtocolVersion1_1, response.ProtocolVersion)
+
+	// the replies follow the negotiated version
+	statusChan := make(chan contracts.PluginResult)
+	go backend.pluginListener(statusChan)
+	statusChan <- *testCase.results["plugin1"]
+	message, err = ParseDatagram(<-inputChan)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeReply), message.Type)
+	assert.Equal(t, ProtocolVersion1_1, message.Version)
+	close(statusChan)
+	<-inputChan
+	assert.Equal(t, stopTypeShutdown, <-backend.stopChan)
+}
+
+func TestWorkerBackend_RejectInvalidPluginConfig(t *testing.T) {
+	invalidConfig, _ := CreateVersionedDatagram(ProtocolVersion1_0, MessageTypePluginConfig, contracts.DocumentState{
+		InstancePluginsInformation: []contracts.PluginState{{Name: "aws:runScript"}},
+	})
+	newBackend := func() *WorkerBackend {
+		return &WorkerBackend{
+			ctx:        contextMock,
+			input:      make(chan string, 10),
+			stopChan:   make(chan int, 1),
+			done:       make(chan bool),
+			cancelFlag: task.NewChanneledCancelFlag(),
+			runner: func(context context.T, docState contracts.DocumentState, resChan chan contracts.PluginResult, cancelFlag task.CancelFlag) {
+				assert.Fail(t, "the runner should not be called")
+			},
+		}
+	}
+
+	// a master of protocol 1.0 gets a failed result
+	backend := newBackend()
+	assert.Error(t, backend.Process(invalidConfig))
+	message, err := ParseDatagram(<-backend.input)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeComplete), message.Type)
+	var docResult contracts.DocumentResult
+	jsonutil.Unmarshal(message.Content, &docResult)
+	assert.Equal(t, contracts.ResultStatusFailed, docResult.Status)
+	assert.Contains(t, docResult.PluginResults[""].Output, "plugin 0 has no name or id")
+	assert.Equal(t, stopTypeShutdown, <-backend.stopChan)
+	_, more := <-backend.input
+	assert.False(t, more)
+
+	// a master of protocol 1.1 gets a fatal error reply
+	backend = newBackend()
+	handshake, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshake, HandshakeRequest{SupportedVersions: versions})
+	assert.NoError(t, backend.Process(handshake))
+	<-backend.input
+	assert.Error(t, backend.Process(invalidConfig))
+	message, err = ParseDatagram(<-backend.input)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageType(MessageTypeError), message.Type)
+	var reply ErrorReply
+	jsonutil.Unmarshal(message.Content, &reply)
+	assert.True(t, reply.Fatal)
+	assert.Equal(t, MessageType(MessageTypePluginConfig), reply.MessageType)
+	assert.Equal(t, stopTypeShutdown, <-backend.stopChan)
+}
+
+func TestExecuterBackend_ProcessFatalErrorReply(t *testing.T) {
+	testCase := CreateTestCase()
+	testCase.docState.InstancePluginsInformation[0].Result.Status = contracts.ResultStatusSuccess
+	outputChan := make(chan contracts.DocumentResult, 10)
+	stopChan := make(chan int, 1)
+	backend := ExecuterBackend{
+		cancelFlag:    task.NewMockDefault(),
+		output:        outputChan,
+		stopChan:      stopChan,
+		docState:      &testCase.docState,
+		handshakeDone: make(chan bool),
+	}
+	response, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeHandshakeResponse, HandshakeResponse{ProtocolVersion: ProtocolVersion1_1})
+	assert.NoError(t, backend.Process(response))
+	assert.Equal(t, ProtocolVersion1_1, backend.protocolVersion())
+
+	errorReply, _ := CreateVersionedDatagram(ProtocolVersion1_1, MessageTypeError, ErrorReply{MessageType: MessageTypePluginConfig, Message: "no plugins to run", Fatal: true})
+	assert.Error(t, backend.Process(errorReply))
+	res := <-outputChan
+	assert.Equal(t, stopTypeTerminate, <-stopChan)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, testMessageID, res.MessageID)
+	// only the plugins not completed are failed
+	assert.Len(t, res.PluginResults, 1)
+	assert.Equal(t, "document worker rejected the pluginconfig message: no plugins to run", res.PluginResults["plugin2"].Output)
+	assert.Equal(t, contracts.ResultStatusFailed, testCase.docState.DocumentInformation.DocumentStatus)
+
+	// the messages after the failure are dropped
+	assert.Error(t, backend.Process(testPluginReplyRawJSON))
+	assert.Len(t, outputChan, 0)
+}
@@ -66,7 +66,7 @@ func main() {
 	}
 	logger.Infof("document: %v worker started", channelName)
 	//create channel from the given handle identifier by master
-	ipc, err, _ := channel.CreateFileChannel(logger, channel.ModeWorker, channelName)
+	ipc, err, _ := channel.CreateChannel(logger, channel.ModeWorker, "", channelName)
 	if err != nil {
 		logger.Errorf("failed to create channel: %v", err)
 		logger.Close()
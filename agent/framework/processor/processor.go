@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
@@ -45,6 +46,9 @@ const (
 	hardStopTimeout = time.Second * 4
 )
 
+// Assign method to global variables to allow unittest to override
+var isRebootImminent = rebooter.IsRebootImminent
+
 type Processor interface {
 	//Start activate the Processor and pick up the left over document in the last run, it returns a channel to caller to gather DocumentResult
 	Start() (chan contracts.DocumentResult, error)
@@ -69,6 +73,10 @@ type EngineProcessor struct {
 	supportedDocTypes []contracts.DocumentType
 	resChan           chan contracts.DocumentResult
 	documentMgr       docmanager.DocumentMgr
+	historyStore      history.Store
+	// heldDocuments are the documents submitted once the reboot is imminent by job id, they run after the reboot unless cancelled
+	heldDocuments map[string]contracts.DocumentState
+	heldMutex     sync.Mutex
 }
 
 //TODO worker pool should be triggered in the Start() function
@@ -86,6 +94,7 @@ func NewEngineProcessor(ctx context.T, commandWorkerLimit int, cancelWorkerLimit
 		return outofproc.NewOutOfProcExecuter(ctx)
 	}
 	documentMgr := docmanager.NewDocumentFileMgr(appconfig.DefaultDataStorePath, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState)
+	historyStore := history.NewFileStore(appconfig.DefaultDataStorePath, ctx.AppConfig().Ssm)
 	return &EngineProcessor{
 		context:           ctx.With("[EngineProcessor]"),
 		executerCreator:   executerCreator,
@@ -94,6 +103,7 @@ func NewEngineProcessor(ctx context.T, commandWorkerLimit int, cancelWorkerLimit
 		supportedDocTypes: supportedDocs,
 		resChan:           resChan,
 		documentMgr:       documentMgr,
+		historyStore:      historyStore,
 	}
 }
 
@@ -136,6 +146,12 @@ func (p *EngineProcessor) Submit(docState contracts.DocumentState) {
 	log := p.context.Log()
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
+	// the pending documents run once the agent starts again, after the reboot
+	if isRebootImminent() {
+		log.Infof("A reboot is imminent, document %v will run after the reboot", docState.DocumentInformation.DocumentID)
+		p.holdDocument(docState)
+		return
+	}
 	err := p.submit(&docState)
 	if err != nil {
 		log.Error("Document Submission failed", err)
@@ -149,38 +165,25 @@ func (p *EngineProcessor) Submit(docState contracts.DocumentState) {
 
 func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 	log := p.context.Log()
-	//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
-	var jobID string
-	if docState.IsAssociation() {
-		jobID = docState.DocumentInformation.AssociationID
-	} else {
-		jobID = docState.DocumentInformation.MessageID
-	}
-	return p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+	return p.sendCommandPool.Submit(log, jobIDOf(docState), func(cancelFlag task.CancelFlag) {
 		processCommand(
 			p.context,
 			p.executerCreator,
 			cancelFlag,
 			p.resChan,
 			docState,
-			p.documentMgr)
+			p.documentMgr,
+			p.historyStore)
 	})
 
 }
 
 func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
 	log := p.context.Log()
-	//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
-	var jobID string
-	if docState.IsAssociation() {
-		jobID = docState.DocumentInformation.AssociationID
-	} else {
-		jobID = docState.DocumentInformation.MessageID
-	}
 	//queue up the pending document
 	p.documentMgr.PersistDocumentState(log, docState.DocumentInformation.DocumentID, docState.DocumentInformation.InstanceID, appconfig.DefaultLocationOfPending, docState)
-	err := p.cancelCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
-		processCancelCommand(p.context, p.sendCommandPool, &docState, p.documentMgr)
+	err := p.cancelCommandPool.Submit(log, jobIDOf(&docState), func(cancelFlag task.CancelFlag) {
+		processCancelCommand(p.context, p.sendCommandPool, p.cancelHeldDocument, &docState, p.documentMgr, p.historyStore)
 	})
 	if err != nil {
 		log.Error("CancelCommand failed", err)
@@ -188,6 +191,68 @@ func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
 	}
 }
 
+//TODO this is a hack, in future jobID should be managed by Processing engine itself, instead of inferring from job's internal field
+func jobIDOf(docState *contracts.DocumentState) string {
+	if docState.IsAssociation() {
+		return docState.DocumentInformation.AssociationID
+	}
+	return docState.DocumentInformation.MessageID
+}
+
+// holdDocument keeps the document in the pending folder until the reboot, it can still be cancelled meanwhile
+func (p *EngineProcessor) holdDocument(docState contracts.DocumentState) {
+	p.heldMutex.Lock()
+	defer p.heldMutex.Unlock()
+	if p.heldDocuments == nil {
+		p.heldDocuments = make(map[string]contracts.DocumentState)
+	}
+	p.heldDocuments[jobIDOf(&docState)] = docState
+}
+
+// cancelHeldDocument cancels the document of the job held until the reboot: it is removed from the pending folder,
+// recorded as cancelled and reported. It returns false if no document of the job is held.
+func (p *EngineProcessor) cancelHeldDocument(jobID string) bool {
+	p.heldMutex.Lock()
+	docState, held := p.heldDocuments[jobID]
+	delete(p.heldDocuments, jobID)
+	p.heldMutex.Unlock()
+	if !held {
+		return false
+	}
+
+	log := p.context.Log()
+	info := docState.DocumentInformation
+	log.Infof("Cancelling document %v held until the reboot", info.DocumentID)
+	p.documentMgr.RemoveDocumentState(log, info.DocumentID, info.InstanceID, appconfig.DefaultLocationOfPending)
+
+	// none of the steps started
+	now := time.Now()
+	pluginResults := make(map[string]*contracts.PluginResult)
+	for i := range docState.InstancePluginsInformation {
+		plugin := &docState.InstancePluginsInformation[i]
+		plugin.Result = contracts.PluginResult{
+			PluginID:      plugin.Id,
+			PluginName:    plugin.Name,
+			Status:        contracts.ResultStatusCancelled,
+			StartDateTime: now,
+			EndDateTime:   now,
+		}
+		pluginResults[plugin.Id] = &plugin.Result
+	}
+	docState.DocumentInformation.DocumentStatus = contracts.ResultStatusCancelled
+	p.historyStore.Record(log, docState)
+	p.resChan <- contracts.DocumentResult{
+		DocumentName:    info.DocumentName,
+		DocumentVersion: info.DocumentVersion,
+		MessageID:       info.MessageID,
+		AssociationID:   info.AssociationID,
+		PluginResults:   pluginResults,
+		Status:          contracts.ResultStatusCancelled,
+		NPlugins:        len(pluginResults),
+	}
+	return true
+}
+
 //Stop set the cancel flags of all the running jobs, which are to be captured by the command worker and shutdown gracefully
 func (p *EngineProcessor) Stop(stopType contracts.StopType) {
 	var waitTimeout time.Duration
@@ -312,7 +377,7 @@ func (p *EngineProcessor) isSupportedDocumentType(documentType contracts.Documen
 	return false
 }
 
-func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
+func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr, historyStore history.Store) {
 	log := context.Log()
 	//persist the current running document
 	docMgr.MoveDocumentState(log,
@@ -324,6 +389,9 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 	documentID := docState.DocumentInformation.DocumentID
 	instanceID := docState.DocumentInformation.InstanceID
 	messageID := docState.DocumentInformation.MessageID
+	// the reboots requested meanwhile wait for the document
+	rebooter.DocumentStarted(documentID)
+	defer rebooter.DocumentFinished(documentID)
 	e := executerCreator(context)
 	docStore := executer.NewDocumentFileStore(context, instanceID, documentID, appconfig.DefaultLocationOfCurrent, docState, docMgr)
 	statusChan := e.Run(
@@ -357,6 +425,7 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 	//persist : commands execution in completed folder (terminal state folder)
 	log.Infof("execution of %v is over. Removing interimState from current folder", messageID)
+	historyStore.Record(log, docStore.Load())
 
 	docMgr.RemoveDocumentState(log,
 		documentID,
@@ -366,7 +435,7 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 }
 
 //TODO CancelCommand is currently treated as a special type of Command by the Processor, but in general Cancel operation should be seen as a probe to existing commands
-func processCancelCommand(context context.T, sendCommandPool task.Pool, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
+func processCancelCommand(context context.T, sendCommandPool task.Pool, cancelHeldDocument func(jobID string) bool, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr, historyStore history.Store) {
 
 	log := context.Log()
 	//persist the final status of cancel-message in current folder
@@ -376,7 +445,8 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 		appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	log.Debugf("Canceling job with id %v...", docState.CancelInformation.CancelMessageID)
 
-	if found := sendCommandPool.Cancel(docState.CancelInformation.CancelMessageID); !found {
+	// the documents held until the reboot are not in the pool
+	if found := sendCommandPool.Cancel(docState.CancelInformation.CancelMessageID) || cancelHeldDocument(docState.CancelInformation.CancelMessageID); !found {
 		log.Debugf("Job with id %v not found (possibly completed)", docState.CancelInformation.CancelMessageID)
 		docState.CancelInformation.DebugInfo = fmt.Sprintf("Command %v couldn't be cancelled", docState.CancelInformation.CancelCommandID)
 		docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
@@ -387,6 +457,7 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 
 	//persist : commands execution in completed folder (terminal state folder)
 	log.Debugf("Execution of %v is over. Removing interimState file from Current folder", docState.DocumentInformation.MessageID)
+	historyStore.Record(log, *docState)
 
 	docMgr.RemoveDocumentState(log,
 		docState.DocumentInformation.DocumentID,
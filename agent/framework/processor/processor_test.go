@@ -22,9 +22,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/history/mock"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/rebooter"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -53,6 +55,26 @@ func TestEngineProcessor_Submit(t *testing.T) {
 	sendCommandPoolMock.AssertExpectations(t)
 }
 
+func TestEngineProcessor_SubmitRebootImminent(t *testing.T) {
+	defer func() { isRebootImminent = rebooter.IsRebootImminent }()
+	isRebootImminent = func() bool { return true }
+	sendCommandPoolMock := new(task.MockedPool)
+	ctx := context.NewMockDefault()
+	docMock := new(DocumentMgrMock)
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         ctx,
+		documentMgr:     docMock,
+	}
+	docState := contracts.DocumentState{}
+	docState.DocumentInformation.MessageID = "messageID"
+	docMock.On("PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, appconfig.DefaultLocationOfPending, docState)
+	processor.Submit(docState)
+	// the document stays in the pending folder until the agent starts again
+	docMock.AssertExpectations(t)
+	sendCommandPoolMock.AssertNotCalled(t, "Submit", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestEngineProcessor_Cancel(t *testing.T) {
 	cancelCommandPoolMock := new(task.MockedPool)
 	ctx := context.NewMockDefault()
@@ -132,9 +154,12 @@ func TestProcessCommand(t *testing.T) {
 	docMock := new(DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent)
-	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
+	historyMock := new(historymock.MockedStore)
+	historyMock.On("Record", mock.Anything, docState)
+	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock, historyMock)
 	executerMock.AssertExpectations(t)
 	docMock.AssertExpectations(t)
+	historyMock.AssertExpectations(t)
 	close(resChan)
 	//assert channel is not closed, each instance of Processor keeps a distinct copy of channel
 	assert.NotNil(t, resChan)
@@ -165,9 +190,12 @@ func TestProcessCommand_Shutdown(t *testing.T) {
 	}()
 	docMock := new(DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
-	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock)
+	historyMock := new(historymock.MockedStore)
+	processCommand(ctx, creator, cancelFlag, resChan, &docState, docMock, historyMock)
 	executerMock.AssertExpectations(t)
 	docMock.AssertExpectations(t)
+	//the document in progress is not recorded in history
+	historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
 	close(resChan)
 	//assert channel is not closed, each instance of Processor keeps a distinct copy of channel
 	assert.NotNil(t, resChan)
@@ -184,13 +212,73 @@ func TestProcessCancelCommand_Success(t *testing.T) {
 	docMock := new(DocumentMgrMock)
 	docMock.On("MoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
 	docMock.On("RemoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfCurrent, mock.Anything)
-	processCancelCommand(ctx, sendCommandPoolMock, &docState, docMock)
+	historyMock := new(historymock.MockedStore)
+	historyMock.On("Record", mock.Anything, mock.AnythingOfType("contracts.DocumentState"))
+	processCancelCommand(ctx, sendCommandPoolMock, func(string) bool { return false }, &docState, docMock, historyMock)
 	sendCommandPoolMock.AssertExpectations(t)
 	docMock.AssertExpectations(t)
+	historyMock.AssertExpectations(t)
 	assert.Equal(t, docState.DocumentInformation.DocumentStatus, contracts.ResultStatusSuccess)
 
 }
 
+func TestProcessCancelCommand_HeldDocument(t *testing.T) {
+	defer func() { isRebootImminent = rebooter.IsRebootImminent }()
+	isRebootImminent = func() bool { return true }
+	ctx := context.NewMockDefault()
+	sendCommandPoolMock := new(task.MockedPool)
+	sendCommandPoolMock.On("Cancel", "messageID").Return(false)
+	docMock := new(DocumentMgrMock)
+	historyMock := new(historymock.MockedStore)
+	resChan := make(chan contracts.DocumentResult, 1)
+	processor := EngineProcessor{
+		sendCommandPool: sendCommandPoolMock,
+		context:         ctx,
+		documentMgr:     docMock,
+		historyStore:    historyMock,
+		resChan:         resChan,
+	}
+
+	// the document submitted once the reboot is imminent is held in the pending folder
+	docState := contracts.DocumentState{}
+	docState.DocumentInformation.MessageID = "messageID"
+	docState.DocumentInformation.DocumentID = "documentID"
+	docState.DocumentInformation.InstanceID = "instanceID"
+	docState.InstancePluginsInformation = []contracts.PluginState{{Id: "step", Name: "aws:runShellScript"}}
+	docMock.On("PersistDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending, docState)
+	processor.Submit(docState)
+
+	// the cancelled held document is removed from the pending folder, recorded and reported as cancelled
+	cancelState := contracts.DocumentState{}
+	cancelState.CancelInformation.CancelMessageID = "messageID"
+	docMock.On("MoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	docMock.On("RemoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfPending)
+	docMock.On("RemoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfCurrent)
+	historyMock.On("Record", mock.Anything, mock.MatchedBy(func(state contracts.DocumentState) bool {
+		return state.DocumentInformation.DocumentID == "documentID" && state.DocumentInformation.DocumentStatus == contracts.ResultStatusCancelled
+	}))
+	historyMock.On("Record", mock.Anything, mock.MatchedBy(func(state contracts.DocumentState) bool {
+		return state.DocumentInformation.DocumentID == ""
+	}))
+	processCancelCommand(ctx, sendCommandPoolMock, processor.cancelHeldDocument, &cancelState, docMock, historyMock)
+	sendCommandPoolMock.AssertExpectations(t)
+	sendCommandPoolMock.AssertNotCalled(t, "Submit", mock.Anything, mock.Anything, mock.Anything)
+	docMock.AssertExpectations(t)
+	historyMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, cancelState.DocumentInformation.DocumentStatus)
+	result := <-resChan
+	assert.Equal(t, "messageID", result.MessageID)
+	assert.Equal(t, contracts.ResultStatusCancelled, result.Status)
+	assert.Equal(t, contracts.ResultStatusCancelled, result.PluginResults["step"].Status)
+
+	// the document is no longer held
+	cancelState = contracts.DocumentState{}
+	cancelState.CancelInformation.CancelMessageID = "messageID"
+	processCancelCommand(ctx, sendCommandPoolMock, processor.cancelHeldDocument, &cancelState, docMock, historyMock)
+	assert.Equal(t, contracts.ResultStatusFailed, cancelState.DocumentInformation.DocumentStatus)
+	assert.Empty(t, resChan)
+}
+
 type DocumentMgrMock struct {
 	mock.Mock
 }
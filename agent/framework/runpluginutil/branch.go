@@ -0,0 +1,147 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// branchChoiceNextStep is the key of a branch choice holding the step to continue with
+	branchChoiceNextStep = "NextStep"
+	// branchChoiceIsEnd is the key of a branch choice that ends the document
+	branchChoiceIsEnd = "IsEnd"
+)
+
+// branchInput is the input of the aws:branch step, e.g.
+// {
+//   "Choices": [
+//     {"NextStep": "remediate", "StringEquals": ["healthCheck.status", "Failed"]},
+//     {"IsEnd": true, "NumericEquals": ["healthCheck.exitCode", "0"]}
+//   ],
+//   "Default": "report"
+// }
+// Every choice holds the condition operators supported by preconditions, next to NextStep or IsEnd.
+// The first choice whose condition is satisfied decides the step to continue with.
+type branchInput struct {
+	Choices []map[string]interface{}
+	Default string
+}
+
+// stepJump skips the steps of the document up to the target step, or all remaining steps if there is no target
+type stepJump struct {
+	target string
+	reason string
+}
+
+// runBranch evaluates the choices of an aws:branch step against the outputs of the steps that already ran,
+// and returns the step to continue with or whether the document ends.
+func runBranch(
+	log log.T,
+	config contracts.Configuration,
+	variables map[string]interface{}) (res contracts.PluginResult, nextStep string, isEnd bool) {
+
+	var input branchInput
+	if err := jsonutil.Remarshal(config.Properties, &input); err != nil {
+		return failedBranch(log, fmt.Errorf("Invalid format in branch properties %v; error %v", config.Properties, err)), "", false
+	}
+	if len(input.Choices) == 0 {
+		return failedBranch(log, fmt.Errorf("Branch step %s must have at least one choice", config.PluginID)), "", false
+	}
+
+	for index, choice := range input.Choices {
+		condition := make(map[string]interface{})
+		nextStep, isEnd = "", false
+		for key, value := range choice {
+			switch key {
+			case branchChoiceNextStep:
+				nextStep, _ = value.(string)
+			case branchChoiceIsEnd:
+				isEnd, _ = value.(bool)
+			default:
+				condition[key] = value
+			}
+		}
+		if (nextStep == "") == !isEnd || len(condition) == 0 {
+			return failedBranch(log, fmt.Errorf("Choice %v of branch step %s must have a condition and either NextStep or IsEnd", index+1, config.PluginID)), "", false
+		}
+
+		result, unrecognized := evaluateCondition(log, condition, variables)
+		if len(unrecognized) > 0 {
+			return failedBranch(log, fmt.Errorf("Unrecognized condition(s) in choice %v of branch step %s: '%s'", index+1, config.PluginID, strings.Join(unrecognized, ", "))), "", false
+		}
+		if result == preconditionTrue {
+			res.Status = contracts.ResultStatusSuccess
+			if isEnd {
+				res.Output = fmt.Sprintf("Choice %v is satisfied, ending the document", index+1)
+			} else {
+				res.Output = fmt.Sprintf("Choice %v is satisfied, continuing with step %s", index+1, nextStep)
+			}
+			log.Info(res.Output)
+			return res, nextStep, isEnd
+		}
+	}
+
+	res.Status = contracts.ResultStatusSuccess
+	if input.Default != "" {
+		res.Output = fmt.Sprintf("No choice is satisfied, continuing with default step %s", input.Default)
+	} else {
+		res.Output = "No choice is satisfied, continuing with the next step"
+	}
+	log.Info(res.Output)
+	return res, input.Default, false
+}
+
+// failedBranch returns the result of a branch step that could not be evaluated
+func failedBranch(log log.T, err error) (res contracts.PluginResult) {
+	log.Error(err)
+	res.Status = contracts.ResultStatusFailed
+	res.Code = 1
+	res.Error = err
+	res.Output = err.Error()
+	return
+}
+
+// getStepJump returns the jump following a step that completed, if its nextStep or isEnd changes the flow of the document.
+// Steps can only be skipped, the next step must come after the step.
+func getStepJump(remainingSteps []contracts.PluginState, stepName string, nextStep string, isEnd bool) (*stepJump, error) {
+	if isEnd {
+		return &stepJump{
+			reason: fmt.Sprintf("Step execution skipped since step %s ended the document", stepName),
+		}, nil
+	}
+	if nextStep == "" {
+		return nil, nil
+	}
+	for index, step := range remainingSteps {
+		if step.Id != nextStep {
+			continue
+		}
+		if index == 0 {
+			// the next step is already the following one
+			return nil, nil
+		}
+		return &stepJump{
+			target: nextStep,
+			reason: fmt.Sprintf("Step execution skipped since step %s continued with step %s", stepName, nextStep),
+		}, nil
+	}
+	return nil, fmt.Errorf("Next step %s must be a step after step %s", nextStep, stepName)
+}
@@ -0,0 +1,234 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testHealthCheckStep = "healthCheck"
+	testBranchStep      = "checkHealth"
+	testRemediateStep   = "remediate"
+	testReportStep      = "report"
+)
+
+func newBranchState(name string, properties interface{}) contracts.PluginState {
+	return contracts.PluginState{
+		Name: appconfig.PluginNameAwsBranch,
+		Id:   name,
+		Configuration: contracts.Configuration{
+			PluginID:   name,
+			PluginName: appconfig.PluginNameAwsBranch,
+			Properties: properties,
+		},
+	}
+}
+
+func runBranchDocument(t *testing.T, healthCheckBehavior func(task.CancelFlag, iohandler.IOHandler)) map[string]*contracts.PluginResult {
+	orchestrationDir, err := ioutil.TempDir("", "branch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	pluginRegistry := PluginRegistry{}
+	newStepPlugin(pluginRegistry, testHealthCheckStep, healthCheckBehavior)
+	newStepPlugin(pluginRegistry, testRemediateStep, succeedStepBehavior)
+	newStepPlugin(pluginRegistry, testReportStep, succeedStepBehavior)
+
+	// if the health check fails run the remediation, otherwise end the document
+	branch := newBranchState(testBranchStep, map[string]interface{}{
+		"Choices": []interface{}{
+			map[string]interface{}{
+				"NextStep":     testRemediateStep,
+				"StringEquals": []interface{}{"healthCheck.status", "Failed"},
+			},
+			map[string]interface{}{
+				"IsEnd":         true,
+				"NumericEquals": []interface{}{"healthCheck.exitCode", "0"},
+			},
+		},
+	})
+	pluginStates := []contracts.PluginState{
+		newStepState(testHealthCheckStep, contracts.OnFailureContinue, 0, 0),
+		branch,
+		newStepState(testRemediateStep, "", 0, 0),
+		newStepState(testReportStep, "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	outputs := RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	close(ch)
+	assert.Equal(t, len(pluginStates), len(ch))
+	return outputs
+}
+
+func TestRunPluginsWithBranchOnFailedStep(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	outputs := runBranchDocument(t, failStepBehavior)
+
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testHealthCheckStep].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testBranchStep].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testRemediateStep].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testReportStep].Status)
+}
+
+func TestRunPluginsWithBranchOnSucceededStep(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	outputs := runBranchDocument(t, succeedStepBehavior)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testHealthCheckStep].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testBranchStep].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testRemediateStep].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testReportStep].Status)
+	assert.Contains(t, outputs[testReportStep].Output, "ended the document")
+}
+
+func TestRunPluginsWithNextStepAndIsEnd(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, err := ioutil.TempDir("", "branch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	pluginRegistry := PluginRegistry{}
+	healthCheck := newStepPlugin(pluginRegistry, testHealthCheckStep, succeedStepBehavior)
+	remediate := newStepPlugin(pluginRegistry, testRemediateStep, succeedStepBehavior)
+	report := newStepPlugin(pluginRegistry, testReportStep, succeedStepBehavior)
+
+	healthCheckState := newStepState(testHealthCheckStep, "", 0, 0)
+	healthCheckState.Configuration.NextStep = testReportStep
+	reportState := newStepState(testReportStep, "", 0, 0)
+	reportState.Configuration.IsEnd = true
+	pluginStates := []contracts.PluginState{
+		healthCheckState,
+		newStepState(testRemediateStep, "", 0, 0),
+		reportState,
+		newStepState(testRemediateStep+"Again", "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	outputs := RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	close(ch)
+
+	assert.Equal(t, 1, healthCheck.executions())
+	assert.Equal(t, 0, remediate.executions())
+	assert.Equal(t, 1, report.executions())
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testRemediateStep].Status)
+	assert.Contains(t, outputs[testRemediateStep].Output, "continued with step report")
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testRemediateStep+"Again"].Status)
+	assert.Equal(t, len(pluginStates), len(ch))
+}
+
+func TestRunPluginsWithInvalidNextStep(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, err := ioutil.TempDir("", "branch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	pluginRegistry := PluginRegistry{}
+	newStepPlugin(pluginRegistry, testHealthCheckStep, succeedStepBehavior)
+	newStepPlugin(pluginRegistry, testReportStep, succeedStepBehavior)
+
+	reportState := newStepState(testReportStep, "", 0, 0)
+	reportState.Configuration.NextStep = testHealthCheckStep
+	pluginStates := []contracts.PluginState{
+		newStepState(testHealthCheckStep, "", 0, 0),
+		reportState,
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	outputs := RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	close(ch)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testHealthCheckStep].Status)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testReportStep].Status)
+	assert.Error(t, outputs[testReportStep].Error)
+}
+
+func TestRunBranch(t *testing.T) {
+	variables := map[string]interface{}{
+		"healthCheck.status":   "Success",
+		"healthCheck.exitCode": "0",
+		"healthCheck.state":    "degraded",
+	}
+	config := func(properties string) contracts.Configuration {
+		var input interface{}
+		assert.NoError(t, json.Unmarshal([]byte(properties), &input))
+		return contracts.Configuration{PluginID: testBranchStep, Properties: input}
+	}
+
+	// the first satisfied choice wins
+	res, nextStep, isEnd := runBranch(log.NewMockLog(), contracts.Configuration{
+		PluginID: testBranchStep,
+		Properties: map[string]interface{}{
+			"Choices": []interface{}{
+				map[string]interface{}{"NextStep": "a", "StringEquals": []interface{}{"healthCheck.state", "healthy"}},
+				map[string]interface{}{"NextStep": "b", "StringEquals": []interface{}{"healthCheck.state", "degraded"}},
+				map[string]interface{}{"IsEnd": true, "StringEquals": []interface{}{"healthCheck.status", "Success"}},
+			},
+		},
+	}, variables)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, "b", nextStep)
+	assert.False(t, isEnd)
+
+	// no choice satisfied, default step
+	res, nextStep, isEnd = runBranch(log.NewMockLog(), contracts.Configuration{
+		PluginID: testBranchStep,
+		Properties: map[string]interface{}{
+			"Choices": []interface{}{
+				map[string]interface{}{"IsEnd": true, "NumericGreaterThan": []interface{}{"healthCheck.exitCode", "0"}},
+			},
+			"Default": "c",
+		},
+	}, variables)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, "c", nextStep)
+	assert.False(t, isEnd)
+
+	// invalid choices fail the branch step
+	invalidProperties := []string{
+		`{"Choices": []}`,
+		`{"Choices": [{"StringEquals": ["healthCheck.status", "Success"]}]}`,
+		`{"Choices": [{"NextStep": "a", "IsEnd": true, "StringEquals": ["healthCheck.status", "Success"]}]}`,
+		`{"Choices": [{"NextStep": "a"}]}`,
+		`{"Choices": [{"NextStep": "a", "foo": ["healthCheck.status", "Success"]}]}`,
+	}
+	for _, properties := range invalidProperties {
+		res, nextStep, isEnd = runBranch(log.NewMockLog(), config(properties), variables)
+		assert.Equal(t, contracts.ResultStatusFailed, res.Status, properties)
+		assert.Empty(t, nextStep, properties)
+		assert.False(t, isEnd, properties)
+	}
+}
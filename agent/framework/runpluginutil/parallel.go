@@ -0,0 +1,97 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// stepExecution holds the state of a step between its preparation, its execution and the processing of its result
+type stepExecution struct {
+	pluginID           string
+	pluginName         string
+	pluginFactory      Factory
+	pluginHandlerFound bool
+	isBranch           bool
+	configuration      contracts.Configuration
+	ioConfig           contracts.IOConfiguration
+	operation          string
+	logMessage         string
+	nextStep           string
+	isEnd              bool
+	variables          map[string]interface{}
+	result             contracts.PluginResult
+}
+
+// parallelGroupSize returns the number of consecutive steps, starting with the first one,
+// which belong to the same parallel group. Steps without a parallel group form a group of their own.
+func parallelGroupSize(plugins []contracts.PluginState) int {
+	if len(plugins) == 0 {
+		return 0
+	}
+	group := plugins[0].Configuration.ParallelGroup
+	if group == "" {
+		return 1
+	}
+	size := 1
+	for size < len(plugins) && plugins[size].Configuration.ParallelGroup == group {
+		size++
+	}
+	return size
+}
+
+// groupMaxConcurrency returns the number of steps of the group that can run at the same time,
+// which is the lowest maxConcurrency set by the steps of the group.
+func groupMaxConcurrency(plugins []contracts.PluginState) int {
+	maxConcurrency := 0
+	for _, pluginState := range plugins {
+		stepMaxConcurrency := pluginState.Configuration.MaxConcurrency
+		if stepMaxConcurrency > 0 && (maxConcurrency == 0 || stepMaxConcurrency < maxConcurrency) {
+			maxConcurrency = stepMaxConcurrency
+		}
+	}
+	if maxConcurrency == 0 {
+		maxConcurrency = appconfig.DefaultParallelGroupMaxConcurrency
+	}
+	return maxConcurrency
+}
+
+// runStepExecutions runs the steps with at most maxConcurrency of them at the same time, and waits until all of them are done
+func runStepExecutions(steps []*stepExecution, maxConcurrency int, run func(step *stepExecution)) {
+	if len(steps) == 1 || maxConcurrency <= 1 {
+		for _, step := range steps {
+			run(step)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrency)
+	for _, step := range steps {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(step *stepExecution) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			run(step)
+		}(step)
+	}
+	wg.Wait()
+}
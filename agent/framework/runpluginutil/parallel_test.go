@@ -0,0 +1,153 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func newParallelStepState(name string, group string, maxConcurrency int) contracts.PluginState {
+	pluginState := newStepState(name, "", 0, 0)
+	pluginState.Configuration.ParallelGroup = group
+	pluginState.Configuration.MaxConcurrency = maxConcurrency
+	return pluginState
+}
+
+func TestParallelGroupSize(t *testing.T) {
+	plugins := []contracts.PluginState{
+		newParallelStepState("a", "fetch", 0),
+		newParallelStepState("b", "fetch", 0),
+		newParallelStepState("c", "", 0),
+		newParallelStepState("d", "install", 0),
+		newParallelStepState("e", "fetch", 0),
+	}
+
+	assert.Equal(t, 2, parallelGroupSize(plugins))
+	assert.Equal(t, 1, parallelGroupSize(plugins[1:]))
+	assert.Equal(t, 1, parallelGroupSize(plugins[2:]))
+	assert.Equal(t, 1, parallelGroupSize(plugins[3:]))
+	assert.Equal(t, 0, parallelGroupSize(plugins[5:]))
+}
+
+func TestGroupMaxConcurrency(t *testing.T) {
+	assert.Equal(t, appconfig.DefaultParallelGroupMaxConcurrency, groupMaxConcurrency([]contracts.PluginState{
+		newParallelStepState("a", "fetch", 0),
+		newParallelStepState("b", "fetch", 0),
+	}))
+	assert.Equal(t, 2, groupMaxConcurrency([]contracts.PluginState{
+		newParallelStepState("a", "fetch", 3),
+		newParallelStepState("b", "fetch", 0),
+		newParallelStepState("c", "fetch", 2),
+	}))
+}
+
+func TestRunPluginsWithParallelGroup(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, err := ioutil.TempDir("", "parallel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	var running, maxRunning int32
+	concurrentBehavior := func(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		output.MarkAsSucceeded()
+	}
+
+	pluginRegistry := PluginRegistry{}
+	fetchNames := []string{"fetch1", "fetch2", "fetch3"}
+	var fetchPlugins []*stepPlugin
+	var pluginStates []contracts.PluginState
+	for _, name := range fetchNames {
+		fetchPlugins = append(fetchPlugins, newStepPlugin(pluginRegistry, name, concurrentBehavior))
+		pluginStates = append(pluginStates, newParallelStepState(name, "fetch", 2))
+	}
+	install := newStepPlugin(pluginRegistry, "install", succeedStepBehavior)
+	pluginStates = append(pluginStates, newStepState("install", "", 0, 0))
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	outputs := RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	close(ch)
+
+	for index, plugin := range fetchPlugins {
+		assert.Equal(t, 1, plugin.executions())
+		assert.Equal(t, contracts.ResultStatusSuccess, outputs[fetchNames[index]].Status)
+	}
+	assert.Equal(t, 1, install.executions())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+	assert.Equal(t, len(pluginStates), len(ch))
+
+	// results are sent in document order
+	var resultOrder []string
+	for result := range ch {
+		resultOrder = append(resultOrder, result.PluginID)
+	}
+	assert.Equal(t, []string{"fetch1", "fetch2", "fetch3", "install"}, resultOrder)
+}
+
+func TestRunPluginsWithParallelGroupOnFailureExit(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	orchestrationDir, err := ioutil.TempDir("", "parallel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	pluginRegistry := PluginRegistry{}
+	fetch1 := newStepPlugin(pluginRegistry, "fetch1", failStepBehavior)
+	fetch2 := newStepPlugin(pluginRegistry, "fetch2", succeedStepBehavior)
+	install := newStepPlugin(pluginRegistry, "install", succeedStepBehavior)
+
+	fetch1State := newParallelStepState("fetch1", "fetch", 0)
+	fetch1State.Configuration.OnFailure = contracts.OnFailureExit
+	pluginStates := []contracts.PluginState{
+		fetch1State,
+		newParallelStepState("fetch2", "fetch", 0),
+		newStepState("install", "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+	outputs := RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	close(ch)
+
+	// the other steps of the group run anyway, the steps after the group are skipped
+	assert.Equal(t, 1, fetch1.executions())
+	assert.Equal(t, 1, fetch2.executions())
+	assert.Equal(t, 0, install.executions())
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["fetch1"].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["fetch2"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["install"].Status)
+	assert.Equal(t, len(pluginStates), len(ch))
+}
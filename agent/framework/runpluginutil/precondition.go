@@ -0,0 +1,454 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+	"github.com/aws/amazon-ssm-agent/agent/versionutil"
+)
+
+const (
+	// instanceTagVariablePrefix is the prefix of precondition variables referencing instance tags, e.g. tag:Environment
+	instanceTagVariablePrefix = "tag:"
+)
+
+// preconditionResult is the outcome of a precondition expression.
+// Unrecognized expressions evaluate to preconditionUnknown, so that they neither skip the step nor hide
+// the unrecognized precondition error.
+type preconditionResult int
+
+const (
+	preconditionFalse preconditionResult = iota
+	preconditionTrue
+	preconditionUnknown
+)
+
+// preconditionVariables are the variables that can be used as operands of precondition expressions.
+// Assign to a global variable to allow unittest to override
+var preconditionVariables = map[string]func(log log.T) (string, error){
+	"platformType":    platform.PlatformType,
+	"platformName":    platform.PlatformName,
+	"platformVersion": platform.PlatformVersion,
+	"architecture":    architecture,
+	"agentVersion":    agentVersion,
+}
+
+// Assign methods to global variables to allow unittest to override
+var instanceTag = platform.InstanceTag
+var fileExists = func(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+var commandExists = func(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// errNotComparable reports a variable value of this instance that cannot be compared with the literal,
+// the comparison is not satisfied whatever the operator
+var errNotComparable = errors.New("value is not comparable")
+
+// comparisonOperators maps the comparison operators to the function comparing the variable value with the literal
+var comparisonOperators = map[string]func(value string, literal string) (int, error){
+	"StringEquals":             compareStrings,
+	"NumericEquals":            compareNumbers,
+	"NumericGreaterThan":       compareNumbers,
+	"NumericGreaterThanEquals": compareNumbers,
+	"NumericLessThan":          compareNumbers,
+	"NumericLessThanEquals":    compareNumbers,
+	"VersionEquals":            compareVersions,
+	"VersionGreaterThan":       compareVersions,
+	"VersionGreaterThanEquals": compareVersions,
+	"VersionLessThan":          compareVersions,
+	"VersionLessThanEquals":    compareVersions,
+}
+
+// preconditionEvaluator evaluates precondition expressions and collects the unrecognized ones
+type preconditionEvaluator struct {
+	log          log.T
+	variables    map[string]interface{}
+	values       map[string]string
+	unrecognized []string
+}
+
+// Evaluate precondition and return precondition result and unrecognized preconditions (if any).
+// variables holds the outputs of the steps that already ran, which can be used as operands as well.
+func evaluatePreconditions(
+	log log.T,
+	preconditions map[string]interface{},
+	variables map[string]interface{},
+) (bool, []string) {
+
+	result, unrecognized := evaluateCondition(log, preconditions, variables)
+	return result != preconditionFalse, unrecognized
+}
+
+// evaluateCondition evaluates the condition expression and returns its result and the unrecognized expressions (if any)
+func evaluateCondition(
+	log log.T,
+	condition map[string]interface{},
+	variables map[string]interface{},
+) (preconditionResult, []string) {
+
+	evaluator := &preconditionEvaluator{
+		log:       log,
+		variables: variables,
+		values:    make(map[string]string),
+	}
+	result := evaluator.evaluateExpression(condition)
+	return result, evaluator.unrecognized
+}
+
+// evaluateExpression evaluates every operator of the expression, all of them must be satisfied
+func (e *preconditionEvaluator) evaluateExpression(expression map[string]interface{}) preconditionResult {
+	// evaluate the operators in a stable order so that unrecognized preconditions are reported consistently
+	operators := make([]string, 0, len(expression))
+	for operator := range expression {
+		operators = append(operators, operator)
+	}
+	sort.Strings(operators)
+
+	results := make([]preconditionResult, 0, len(operators))
+	for _, operator := range operators {
+		results = append(results, e.evaluateOperator(operator, expression[operator]))
+	}
+	return and(results)
+}
+
+// evaluateOperator evaluates a single operator with its operands
+func (e *preconditionEvaluator) evaluateOperator(operator string, operands interface{}) preconditionResult {
+	switch operator {
+	case "And", "Or":
+		expressions, ok := toExpressionList(operands)
+		if !ok || len(expressions) == 0 {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		results := make([]preconditionResult, 0, len(expressions))
+		for _, expression := range expressions {
+			results = append(results, e.evaluateExpression(expression))
+		}
+		if operator == "And" {
+			return and(results)
+		}
+		return or(results)
+
+	case "Not":
+		expressions, ok := toExpressionList(operands)
+		if !ok || len(expressions) != 1 {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		switch e.evaluateExpression(expressions[0]) {
+		case preconditionTrue:
+			return preconditionFalse
+		case preconditionFalse:
+			return preconditionTrue
+		default:
+			return preconditionUnknown
+		}
+
+	case "FileExists", "CommandExists":
+		values, ok := toStringList(operands)
+		if !ok || len(values) != 1 {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		exists := false
+		if operator == "FileExists" {
+			exists = fileExists(values[0])
+		} else {
+			exists = commandExists(values[0])
+		}
+		e.log.Debugf("Precondition %s %s = %t", operator, values[0], exists)
+		return toPreconditionResult(exists)
+
+	case "StringLike":
+		values, ok := toStringList(operands)
+		if !ok || len(values) != 2 {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		value, pattern, _, ok := e.resolveOperands(values)
+		if !ok {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		return toPreconditionResult(matchesPattern(value, pattern))
+
+	default:
+		compare, isComparison := comparisonOperators[operator]
+		if !isComparison {
+			// mark for unrecognizedPrecondition (which is a form of failure)
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		values, ok := toStringList(operands)
+		if !ok || len(values) != 2 {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		value, literal, swapped, ok := e.resolveOperands(values)
+		if !ok {
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		comparison, err := compare(value, literal)
+		if err == errNotComparable {
+			e.log.Debugf("Precondition %s %v is not satisfied, the value %s of this instance is not comparable", operator, values, value)
+			return preconditionFalse
+		}
+		if err != nil {
+			e.log.Debugf("Failed to evaluate precondition %s %v: %v", operator, values, err)
+			return e.unrecognizedPrecondition(operator, operands)
+		}
+		if swapped {
+			comparison = -comparison
+		}
+		return toPreconditionResult(isComparisonSatisfied(operator, comparison))
+	}
+}
+
+// resolveOperands returns the value of the variable operand and the literal operand.
+// Variable and value can be in any order, i.e. both "StringEquals": ["platformType", "Windows"]
+// and "StringEquals": ["Windows", "platformType"] are valid; swapped reports the latter form.
+// Exactly one of the operands must be a variable.
+func (e *preconditionEvaluator) resolveOperands(operands []string) (value string, literal string, swapped bool, ok bool) {
+	firstValue, firstIsVariable := e.resolveVariable(operands[0])
+	secondValue, secondIsVariable := e.resolveVariable(operands[1])
+
+	if firstIsVariable == secondIsVariable {
+		return "", "", false, false
+	}
+	if firstIsVariable {
+		return firstValue, operands[1], false, true
+	}
+	return secondValue, operands[0], true, true
+}
+
+// resolveVariable returns the value of the variable and whether the name is a known variable
+func (e *preconditionEvaluator) resolveVariable(name string) (string, bool) {
+	if value, ok := e.values[name]; ok {
+		return value, true
+	}
+
+	var value string
+	var err error
+	if stepOutput, ok := e.variables[name]; ok {
+		value = fmt.Sprintf("%v", stepOutput)
+	} else if getter, ok := preconditionVariables[name]; ok {
+		value, err = getter(e.log)
+	} else if strings.HasPrefix(name, instanceTagVariablePrefix) && len(name) > len(instanceTagVariablePrefix) {
+		value, err = instanceTag(strings.TrimPrefix(name, instanceTagVariablePrefix))
+	} else {
+		return "", false
+	}
+
+	if err != nil {
+		// a variable that cannot be resolved on this instance never matches a non empty value
+		e.log.Debugf("Failed to resolve precondition variable %s: %v", name, err)
+		value = ""
+	}
+	e.log.Debugf("Precondition variable %s of this instance = %s", name, value)
+	e.values[name] = value
+	return value, true
+}
+
+// unrecognizedPrecondition records the unrecognized precondition
+func (e *preconditionEvaluator) unrecognizedPrecondition(operator string, operands interface{}) preconditionResult {
+	e.unrecognized = append(e.unrecognized, fmt.Sprintf("\"%s\": %v", operator, operands))
+	return preconditionUnknown
+}
+
+// isComparisonSatisfied checks the result of the comparison of the variable with the literal against the operator
+func isComparisonSatisfied(operator string, comparison int) bool {
+	switch {
+	case strings.HasSuffix(operator, "GreaterThanEquals"):
+		return comparison >= 0
+	case strings.HasSuffix(operator, "GreaterThan"):
+		return comparison > 0
+	case strings.HasSuffix(operator, "LessThanEquals"):
+		return comparison <= 0
+	case strings.HasSuffix(operator, "LessThan"):
+		return comparison < 0
+	default:
+		return comparison == 0
+	}
+}
+
+// compareStrings compares two strings ignoring case, matching the case insensitive platformType comparison
+func compareStrings(value string, literal string) (int, error) {
+	if strings.EqualFold(value, literal) {
+		return 0, nil
+	}
+	return strings.Compare(strings.ToLower(value), strings.ToLower(literal)), nil
+}
+
+// compareNumbers compares two decimal numbers
+func compareNumbers(value string, literal string) (int, error) {
+	literalNumber, err := strconv.ParseFloat(strings.TrimSpace(literal), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number", literal)
+	}
+	valueNumber, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		// the variable of this instance is not numeric, it never satisfies a numeric comparison
+		return 0, errNotComparable
+	}
+	switch {
+	case valueNumber < literalNumber:
+		return -1, nil
+	case valueNumber > literalNumber:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// compareVersions compares two dot separated versions, e.g. 18.04 and 16.04.3
+func compareVersions(value string, literal string) (int, error) {
+	if strings.TrimSpace(literal) == "" {
+		return 0, fmt.Errorf("version must not be empty")
+	}
+	return versionutil.Compare(strings.TrimSpace(value), strings.TrimSpace(literal), false), nil
+}
+
+// matchesPattern checks if the value matches the pattern, where * matches any sequence of characters
+// and ? matches a single character
+func matchesPattern(value string, pattern string) bool {
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.Replace(expression, `\*`, ".*", -1)
+	expression = strings.Replace(expression, `\?`, ".", -1)
+	matched, err := regexp.MatchString("(?i)^"+expression+"$", value)
+	return err == nil && matched
+}
+
+// and combines the results of expressions that all must be satisfied
+func and(results []preconditionResult) preconditionResult {
+	combined := preconditionTrue
+	for _, result := range results {
+		if result == preconditionFalse {
+			return preconditionFalse
+		}
+		if result == preconditionUnknown {
+			combined = preconditionUnknown
+		}
+	}
+	return combined
+}
+
+// or combines the results of expressions of which at least one must be satisfied
+func or(results []preconditionResult) preconditionResult {
+	combined := preconditionFalse
+	for _, result := range results {
+		if result == preconditionTrue {
+			return preconditionTrue
+		}
+		if result == preconditionUnknown {
+			combined = preconditionUnknown
+		}
+	}
+	return combined
+}
+
+func toPreconditionResult(satisfied bool) preconditionResult {
+	if satisfied {
+		return preconditionTrue
+	}
+	return preconditionFalse
+}
+
+// toStringList converts the operands of a precondition operator to a list of strings
+func toStringList(operands interface{}) ([]string, bool) {
+	switch values := operands.(type) {
+	case []string:
+		return values, true
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, value := range values {
+			switch value.(type) {
+			case string, bool, float64, int:
+				result = append(result, fmt.Sprintf("%v", value))
+			default:
+				return nil, false
+			}
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// toExpressionList converts the operands of a logical operator to a list of expressions.
+// A single expression is accepted as well as a list of expressions.
+func toExpressionList(operands interface{}) ([]map[string]interface{}, bool) {
+	if expression, ok := toExpression(operands); ok {
+		return []map[string]interface{}{expression}, true
+	}
+	values, ok := operands.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	result := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		expression, ok := toExpression(value)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, expression)
+	}
+	return result, true
+}
+
+// toExpression converts a json or yaml object to an expression
+func toExpression(value interface{}) (map[string]interface{}, bool) {
+	switch expression := value.(type) {
+	case map[string]interface{}:
+		return expression, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(expression))
+		for key, operands := range expression {
+			operator, ok := key.(string)
+			if !ok {
+				return nil, false
+			}
+			result[operator] = operands
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// architecture returns the processor architecture of the instance
+func architecture(log log.T) (string, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64", nil
+	case "386":
+		return "i386", nil
+	default:
+		return runtime.GOARCH, nil
+	}
+}
+
+// agentVersion returns the version of the running agent
+func agentVersion(log log.T) (string, error) {
+	return version.Version, nil
+}
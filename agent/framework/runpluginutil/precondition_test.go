@@ -0,0 +1,155 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type preconditionTestCase struct {
+	precondition         string
+	expectedAllowed      bool
+	expectedUnrecognized int
+}
+
+func setPreconditionVariablesMock() func() {
+	origVariables := preconditionVariables
+	origInstanceTag := instanceTag
+	origFileExists := fileExists
+	origCommandExists := commandExists
+
+	preconditionVariables = map[string]func(log log.T) (string, error){
+		"platformType":    func(log log.T) (string, error) { return "linux", nil },
+		"platformName":    func(log log.T) (string, error) { return "Ubuntu", nil },
+		"platformVersion": func(log log.T) (string, error) { return "18.04", nil },
+		"architecture":    func(log log.T) (string, error) { return "x86_64", nil },
+		"agentVersion":    func(log log.T) (string, error) { return "2.2.93.0", nil },
+	}
+	instanceTag = func(key string) (string, error) {
+		if key == "Environment" {
+			return "production", nil
+		}
+		return "", fmt.Errorf("tag %s not found", key)
+	}
+	fileExists = func(path string) bool { return path == "/etc/os-release" }
+	commandExists = func(name string) bool { return name == "apt-get" }
+
+	return func() {
+		preconditionVariables = origVariables
+		instanceTag = origInstanceTag
+		fileExists = origFileExists
+		commandExists = origCommandExists
+	}
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	restore := setPreconditionVariablesMock()
+	defer restore()
+
+	testCases := []preconditionTestCase{
+		{`{"StringEquals": ["platformType", "Linux"]}`, true, 0},
+		{`{"StringEquals": ["Windows", "platformType"]}`, false, 0},
+		{`{"StringEquals": ["platformName", "ubuntu"]}`, true, 0},
+		{`{"StringLike": ["platformName", "Ubu*"]}`, true, 0},
+		{`{"StringLike": ["platformName", "Amazon*"]}`, false, 0},
+		{`{"VersionGreaterThanEquals": ["platformVersion", "18.04"]}`, true, 0},
+		{`{"VersionGreaterThan": ["platformVersion", "18.04"]}`, false, 0},
+		{`{"VersionLessThan": ["16.04", "platformVersion"]}`, true, 0},
+		{`{"VersionEquals": ["agentVersion", "2.2.93"]}`, true, 0},
+		{`{"NumericGreaterThan": ["platformVersion", "17"]}`, true, 0},
+		{`{"NumericLessThanEquals": ["platformVersion", "18"]}`, false, 0},
+		{`{"StringEquals": ["architecture", "x86_64"]}`, true, 0},
+		{`{"StringEquals": ["tag:Environment", "production"]}`, true, 0},
+		{`{"StringEquals": ["tag:Owner", "ops"]}`, false, 0},
+		{`{"FileExists": ["/etc/os-release"]}`, true, 0},
+		{`{"FileExists": ["/etc/redhat-release"]}`, false, 0},
+		{`{"CommandExists": ["apt-get"]}`, true, 0},
+		{`{"Not": [{"CommandExists": ["yum"]}]}`, true, 0},
+		{`{"Not": {"StringEquals": ["platformType", "Linux"]}}`, false, 0},
+		{`{"And": [{"StringEquals": ["platformName", "Ubuntu"]}, {"VersionGreaterThanEquals": ["platformVersion", "18.04"]}]}`, true, 0},
+		{`{"And": [{"StringEquals": ["platformName", "Ubuntu"]}, {"VersionGreaterThanEquals": ["platformVersion", "20.04"]}]}`, false, 0},
+		{`{"Or": [{"StringEquals": ["platformName", "CentOS"]}, {"StringEquals": ["platformName", "Ubuntu"]}]}`, true, 0},
+		{`{"Or": [{"StringEquals": ["platformName", "CentOS"]}, {"StringEquals": ["platformName", "RedHat"]}]}`, false, 0},
+		{`{"StringEquals": ["platformType", "Linux"], "StringLike": ["platformName", "Cent*"]}`, false, 0},
+		{`{"StringEquals": ["platform", "Linux"]}`, true, 1},
+		{`{"NumericGreaterThan": ["platformVersion", "abc"]}`, true, 1},
+		{`{"Or": [{"foo": ["platformType", "Linux"]}, {"StringEquals": ["platformName", "CentOS"]}]}`, true, 1},
+		{`{"Or": [{"foo": ["platformType", "Linux"]}, {"StringEquals": ["platformName", "Ubuntu"]}]}`, true, 1},
+		{`{"And": [{"foo": ["platformType", "Linux"]}, {"StringEquals": ["platformName", "CentOS"]}]}`, false, 1},
+		{`{"Not": [{"foo": ["platformType", "Linux"]}]}`, true, 1},
+		{`{"Not": []}`, true, 1},
+	}
+
+	for _, testCase := range testCases {
+		var preconditions map[string]interface{}
+		err := json.Unmarshal([]byte(testCase.precondition), &preconditions)
+		assert.NoError(t, err)
+
+		isAllowed, unrecognized := evaluatePreconditions(log.NewMockLog(), preconditions, nil)
+
+		assert.Equal(t, testCase.expectedAllowed, isAllowed, testCase.precondition)
+		assert.Equal(t, testCase.expectedUnrecognized, len(unrecognized), testCase.precondition)
+	}
+}
+
+func TestEvaluatePreconditionsWithNonNumericVariable(t *testing.T) {
+	restore := setPreconditionVariablesMock()
+	defer restore()
+
+	operators := []string{"NumericEquals", "NumericGreaterThan", "NumericGreaterThanEquals", "NumericLessThan", "NumericLessThanEquals"}
+	// platformName is not numeric and the missing tag resolves to an empty value
+	for _, variable := range []string{"platformName", "tag:Owner"} {
+		for _, operator := range operators {
+			for _, operands := range [][]string{{variable, "0"}, {"0", variable}} {
+				preconditions := map[string]interface{}{operator: []interface{}{operands[0], operands[1]}}
+
+				isAllowed, unrecognized := evaluatePreconditions(log.NewMockLog(), preconditions, nil)
+
+				assert.False(t, isAllowed, "%s %v", operator, operands)
+				assert.Empty(t, unrecognized, "%s %v", operator, operands)
+			}
+		}
+	}
+}
+
+func TestEvaluatePreconditionsWithYamlExpressions(t *testing.T) {
+	restore := setPreconditionVariablesMock()
+	defer restore()
+
+	preconditions := map[string]interface{}{
+		"And": []interface{}{
+			map[interface{}]interface{}{"StringEquals": []interface{}{"platformName", "Ubuntu"}},
+			map[interface{}]interface{}{"VersionGreaterThanEquals": []interface{}{"platformVersion", "18.04"}},
+		},
+	}
+
+	isAllowed, unrecognized := evaluatePreconditions(log.NewMockLog(), preconditions, nil)
+
+	assert.True(t, isAllowed)
+	assert.Empty(t, unrecognized)
+}
+
+func TestMatchesPattern(t *testing.T) {
+	assert.True(t, matchesPattern("Amazon Linux AMI", "Amazon*"))
+	assert.True(t, matchesPattern("CentOS Linux", "*linux"))
+	assert.True(t, matchesPattern("7.4", "7.?"))
+	assert.False(t, matchesPattern("7.4", "7.?.1"))
+	assert.False(t, matchesPattern("7x4", "7.4"))
+}
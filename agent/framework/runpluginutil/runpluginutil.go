@@ -17,6 +17,7 @@ package runpluginutil
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -26,7 +27,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
-	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -70,6 +70,7 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameRefreshAssociation:     {},
 	appconfig.PluginDownloadContent:            {},
 	appconfig.PluginRunDocument:                {},
+	appconfig.PluginNameAwsBranch:              {},
 }
 
 // Assign method to global variables to allow unittest to override
@@ -93,116 +94,184 @@ func RunPlugins(
 	//Contains the logStreamPrefix without the pluginID
 	logStreamPrefix := ioConfig.CloudWatchConfig.LogStreamPrefix
 
-	for _, pluginState := range plugins {
-		pluginID := pluginState.Id     // the identifier of the plugin
-		pluginName := pluginState.Name // the name of the plugin
-		pluginOutput := pluginState.Result
-		pluginOutput.PluginID = pluginID
-		pluginOutput.PluginName = pluginName
-		pluginOutputs[pluginID] = &pluginOutput
-		switch pluginOutput.Status {
-		//TODO properly initialize the plugin status
-		case "":
-			context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
-				pluginName)
-			pluginOutput.StartDateTime = time.Now()
-			pluginOutput.Status = contracts.ResultStatusNotStarted
-
-		case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
-			context.Log().Debugf("plugin - %v status %v",
-				pluginName,
-				pluginOutput.Status)
-			pluginOutput.StartDateTime = time.Now()
-
-		case contracts.ResultStatusSuccessAndReboot:
-			context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
-				pluginName)
-			pluginOutput.Status = contracts.ResultStatusInProgress
-
-		default:
-			context.Log().Debugf("plugin - %v already executed, skipping...",
-				pluginName)
-			continue
-		}
+	// jump skips the steps following a step that changed the flow of the document
+	var jump *stepJump
+
+	for index := 0; index < len(plugins); {
+		// consecutive steps of the same parallel group run concurrently, other steps run one at a time
+		groupSize := parallelGroupSize(plugins[index:])
+		group := plugins[index : index+groupSize]
+		index += groupSize
+
+		var steps []*stepExecution
+		for _, pluginState := range group {
+			pluginID := pluginState.Id     // the identifier of the plugin
+			pluginName := pluginState.Name // the name of the plugin
+			pluginOutput := pluginState.Result
+			pluginOutput.PluginID = pluginID
+			pluginOutput.PluginName = pluginName
+			pluginOutputs[pluginID] = &pluginOutput
+			switch pluginOutput.Status {
+			//TODO properly initialize the plugin status
+			case "":
+				context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
+					pluginName)
+				pluginOutput.StartDateTime = time.Now()
+				pluginOutput.Status = contracts.ResultStatusNotStarted
+
+			case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+				context.Log().Debugf("plugin - %v status %v",
+					pluginName,
+					pluginOutput.Status)
+				pluginOutput.StartDateTime = time.Now()
+
+			case contracts.ResultStatusSuccessAndReboot:
+				// a step exiting with RebootExitCode completed before the reboot, the other steps run again
+				if pluginOutput.Code == appconfig.RebootExitCode {
+					context.Log().Debugf("plugin - %v completed before the reboot, skipping...",
+						pluginName)
+					pluginOutput.Status = contracts.ResultStatusSuccess
+					continue
+				}
+				context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
+					pluginName)
+				pluginOutput.Status = contracts.ResultStatusInProgress
+
+			default:
+				context.Log().Debugf("plugin - %v already executed, skipping...",
+					pluginName)
+				continue
+			}
 
-		context.Log().Debugf("Executing plugin - %v", pluginName)
+			context.Log().Debugf("Executing plugin - %v", pluginName)
 
-		// populate plugin start time and status
-		configuration := pluginState.Configuration
+			// populate plugin start time and status
+			configuration := pluginState.Configuration
+			stepIOConfig := ioConfig
 
-		if ioConfig.OutputS3BucketName != "" {
-			pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
-			if ioConfig.OutputS3KeyPrefix != "" {
-				pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+			if ioConfig.OutputS3BucketName != "" {
+				pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
+				if ioConfig.OutputS3KeyPrefix != "" {
+					pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
 
+				}
 			}
-		}
-		//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
-		if ioConfig.CloudWatchConfig.LogGroupName != "" {
-			ioConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", logStreamPrefix, pluginID)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
+			//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
+			if ioConfig.CloudWatchConfig.LogGroupName != "" {
+				stepIOConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", logStreamPrefix, pluginID)
+				stepIOConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(stepIOConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
+				stepIOConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(stepIOConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
+			}
+
+			//check if the said plugin is a worker plugin
+			p, pluginHandlerFound := pluginRegistry[pluginName]
+
+			// branch steps are run by the document engine itself
+			isBranch := pluginName == appconfig.PluginNameAwsBranch
+			if isBranch {
+				pluginHandlerFound = true
+			}
+
+			var operation, logMessage string
+			if jump != nil && jump.target != pluginID {
+				operation = skipStep
+				logMessage = fmt.Sprintf("%s. Step name: %s", jump.reason, pluginID)
+			} else {
+				jump = nil
+				isKnown, isSupported, _ := isSupportedPlugin(context.Log(), pluginName)
+				operation, logMessage = getStepExecutionOperation(
+					context.Log(),
+					pluginName,
+					pluginID,
+					isKnown,
+					isSupported,
+					pluginHandlerFound,
+					configuration.IsPreconditionEnabled,
+					configuration.Preconditions,
+					stepOutputVariables(pluginOutputs))
+			}
+
+			if operation == executeStep {
+				// the steps of a parallel group can only reference the outputs of the steps that ran before the group
+				configuration = resolveStepOutputReferences(context.Log(), configuration, pluginOutputs)
+			}
+
+			steps = append(steps, &stepExecution{
+				pluginID:           pluginID,
+				pluginName:         pluginName,
+				pluginFactory:      p,
+				pluginHandlerFound: pluginHandlerFound,
+				isBranch:           isBranch,
+				configuration:      configuration,
+				ioConfig:           stepIOConfig,
+				operation:          operation,
+				logMessage:         logMessage,
+				nextStep:           configuration.NextStep,
+				isEnd:              configuration.IsEnd,
+				variables:          stepOutputVariables(pluginOutputs),
+			})
 		}
 
-		var r contracts.PluginResult
-		pluginHandlerFound := false
-
-		//check if the said plugin is a worker plugin
-		p, pluginHandlerFound := pluginRegistry[pluginName]
-
-		isKnown, isSupported, _ := isSupportedPlugin(context.Log(), pluginName)
-		operation, logMessage := getStepExecutionOperation(
-			context.Log(),
-			pluginName,
-			pluginID,
-			isKnown,
-			isSupported,
-			pluginHandlerFound,
-			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
-
-		switch operation {
-		case executeStep:
-			context.Log().Infof("Running plugin %s", pluginName)
-			r = runPlugin(context, p, pluginName, configuration, cancelFlag, ioConfig)
-			pluginOutputs[pluginID].Code = r.Code
-			pluginOutputs[pluginID].Status = r.Status
-			pluginOutputs[pluginID].Error = r.Error
-			pluginOutputs[pluginID].Output = r.Output
-			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
-			pluginOutputs[pluginID].StandardError = r.StandardError
-
-		case skipStep:
-			context.Log().Info(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusSkipped
-			pluginOutputs[pluginID].Code = 0
-			pluginOutputs[pluginID].Output = logMessage
-		case failStep:
-			err := fmt.Errorf(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
-		default:
-			err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err
-			context.Log().Error(err)
+		if groupSize > 1 {
+			context.Log().Infof("Running %v steps of parallel group %v concurrently", len(steps), group[0].Configuration.ParallelGroup)
 		}
+		runStepExecutions(steps, groupMaxConcurrency(group), func(step *stepExecution) {
+			executeStepOperation(context, step, pluginOutputs[step.pluginID], cancelFlag)
+		})
+
+		var groupStatus contracts.ResultStatus
+		rebootRequested := false
+		for _, step := range steps {
+			pluginID := step.pluginID
+			configuration := step.configuration
+
+			if step.operation == executeStep && !isStepFailed(pluginOutputs[pluginID].Status) {
+				if stepJump, err := getStepJump(plugins[index:], pluginID, step.nextStep, step.isEnd); err != nil {
+					pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
+					pluginOutputs[pluginID].Error = err
+					context.Log().Error(err)
+				} else if stepJump != nil {
+					jump = stepJump
+				}
+			}
 
-		// set end time.
-		pluginOutputs[pluginID].EndDateTime = time.Now()
-		context.Log().Infof("Sending plugin %v completion message", pluginID)
+			if isStepFailed(pluginOutputs[pluginID].Status) {
+				exitJump := &stepJump{reason: fmt.Sprintf("Step execution skipped due to failure of step %s", pluginID)}
+				switch configuration.OnFailure {
+				case contracts.OnFailureExit:
+					context.Log().Infof("Step %v failed with onFailure %v, skipping remaining steps", pluginID, configuration.OnFailure)
+					jump = exitJump
+				case contracts.OnFailureSuccessAndExit:
+					context.Log().Infof("Step %v failed with onFailure %v, skipping remaining steps", pluginID, configuration.OnFailure)
+					pluginOutputs[pluginID].Status = contracts.ResultStatusSuccess
+					jump = exitJump
+				}
+			}
+
+			// set end time.
+			pluginOutputs[pluginID].EndDateTime = time.Now()
+			context.Log().Infof("Sending plugin %v completion message", pluginID)
+
+			// truncate the result and send it back to buffer channel.
+			result := *pluginOutputs[pluginID]
+			pluginConfig := iohandler.DefaultOutputConfig()
+			result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+			result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+			// send to buffer channel, guaranteed to not block since buffer size is plugin number
+			resChan <- result
+
+			groupStatus = contracts.MergeResultStatus(groupStatus, pluginOutputs[pluginID].Status)
+			if step.pluginHandlerFound && step.result.Status == contracts.ResultStatusSuccessAndReboot {
+				rebootRequested = true
+			}
+		}
 
-		// truncate the result and send it back to buffer channel.
-		result := *pluginOutputs[pluginID]
-		pluginConfig := iohandler.DefaultOutputConfig()
-		result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		// send to buffer channel, guaranteed to not block since buffer size is plugin number
-		resChan <- result
+		if groupSize > 1 {
+			context.Log().Infof("Parallel group %v completed with status %v", group[0].Configuration.ParallelGroup, groupStatus)
+		}
 
 		//TODO handle cancelFlag here
-		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
+		if rebootRequested {
 			// do not execute the the next plugin
 			break
 		}
@@ -211,6 +280,125 @@ func RunPlugins(
 	return
 }
 
+// executeStepOperation runs, skips or fails the step according to its operation and records the result in the step output
+func executeStepOperation(context context.T, step *stepExecution, pluginOutput *contracts.PluginResult, cancelFlag task.CancelFlag) {
+	switch step.operation {
+	case executeStep:
+		context.Log().Infof("Running plugin %s", step.pluginName)
+		var r contracts.PluginResult
+		if step.isBranch {
+			var branchNextStep string
+			var branchIsEnd bool
+			r, branchNextStep, branchIsEnd = runBranch(context.Log(), step.configuration, step.variables)
+			if branchNextStep != "" || branchIsEnd {
+				step.nextStep, step.isEnd = branchNextStep, branchIsEnd
+			}
+		} else {
+			r = runStep(context, step.pluginFactory, step.pluginName, step.configuration, cancelFlag, step.ioConfig)
+		}
+		step.result = r
+		pluginOutput.Code = r.Code
+		pluginOutput.Status = r.Status
+		pluginOutput.Error = r.Error
+		pluginOutput.Output = r.Output
+		pluginOutput.StandardOutput = r.StandardOutput
+		pluginOutput.StandardError = r.StandardError
+		pluginOutput.StepOutputs = parseStepOutputs(context.Log(), r.StandardOutput)
+
+	case skipStep:
+		context.Log().Info(step.logMessage)
+		pluginOutput.Status = contracts.ResultStatusSkipped
+		pluginOutput.Code = 0
+		pluginOutput.Output = step.logMessage
+	case failStep:
+		err := fmt.Errorf(step.logMessage)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err
+		context.Log().Error(err)
+	default:
+		err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", step.operation, step.pluginName)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err
+		context.Log().Error(err)
+	}
+}
+
+// runStep runs the plugin of a step, retrying a failed execution until the maxAttempts of the step
+// is reached or the document gets cancelled.
+func runStep(
+	context context.T,
+	pluginFactory Factory,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
+
+	maxAttempts := config.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			context.Log().Infof("Step %v failed, starting attempt %v of %v", config.PluginID, attempt, maxAttempts)
+		}
+		res = runPluginWithTimeout(context, pluginFactory, pluginName, config, cancelFlag, ioConfig)
+		if !isStepFailed(res.Status) || cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			break
+		}
+	}
+	return
+}
+
+// runPluginWithTimeout runs the plugin and cancels it through a step level cancel flag
+// once the timeoutSeconds of the step is exceeded.
+func runPluginWithTimeout(
+	context context.T,
+	pluginFactory Factory,
+	pluginName string,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	ioConfig contracts.IOConfiguration) (res contracts.PluginResult) {
+
+	if config.TimeoutSeconds <= 0 {
+		return runPlugin(context, pluginFactory, pluginName, config, cancelFlag, ioConfig)
+	}
+
+	stepCancelFlag := task.NewChanneledCancelFlag()
+	var timedOut int32
+	timer := time.AfterFunc(time.Duration(config.TimeoutSeconds)*time.Second, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		stepCancelFlag.Set(task.Canceled)
+	})
+	// forward document cancellation and shutdown to the step until the attempt returns
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-cancelFlag.Done():
+			if state := cancelFlag.State(); state == task.Canceled || state == task.ShutDown {
+				stepCancelFlag.Set(state)
+			}
+		case <-done:
+		}
+	}()
+
+	res = runPlugin(context, pluginFactory, pluginName, config, stepCancelFlag, ioConfig)
+	close(done)
+	timer.Stop()
+
+	if atomic.LoadInt32(&timedOut) == 1 && !cancelFlag.Canceled() && !cancelFlag.ShutDown() {
+		context.Log().Infof("Step %v exceeded its timeout of %v seconds", config.PluginID, config.TimeoutSeconds)
+		res.Status = contracts.ResultStatusTimedOut
+		res.Code = appconfig.CommandStoppedPreemptivelyExitCode
+		res.Error = fmt.Errorf("Step timed out after %v seconds", config.TimeoutSeconds)
+	}
+	return
+}
+
+// isStepFailed checks whether the step status should be handled as a step failure
+func isStepFailed(status contracts.ResultStatus) bool {
+	return status == contracts.ResultStatusFailed || status == contracts.ResultStatusTimedOut
+}
+
 func runPlugin(
 	context context.T,
 	pluginFactory Factory,
@@ -322,7 +510,8 @@ func getStepExecutionOperation(
 	isSupported bool,
 	isPluginHandlerFound bool,
 	isPreconditionEnabled bool,
-	preconditions map[string][]string,
+	preconditions map[string]interface{},
+	variables map[string]interface{},
 ) (string, string) {
 	log.Debugf("isSupported flag = %t", isSupported)
 	log.Debugf("isPluginHandlerFound flag = %t", isPluginHandlerFound)
@@ -374,7 +563,7 @@ func getStepExecutionOperation(
 		} else {
 			log.Debugf("Cross-platform Precondition is present, precondition = %v", preconditions)
 
-			isAllowed, unrecognizedPreconditionList := evaluatePreconditions(log, preconditions)
+			isAllowed, unrecognizedPreconditionList := evaluatePreconditions(log, preconditions, variables)
 
 			if isAllowed && !isKnown {
 				return failStep, fmt.Sprintf(
@@ -396,50 +585,3 @@ func getStepExecutionOperation(
 		}
 	}
 }
-
-// Evaluate precondition and return precondition result and unrecognized preconditions (if any)
-func evaluatePreconditions(
-	log log.T,
-	preconditions map[string][]string,
-) (bool, []string) {
-
-	var isAllowed = true
-	var unrecognizedPreconditionList []string
-
-	// For current release, we only support "StringEquals" operator and "platformType"
-	// operand, so explicitly checking for those and number of operands must be 2
-	for key, value := range preconditions {
-		switch key {
-		case "StringEquals":
-			// Platform type of OS on the instance
-			instancePlatformType, _ := platform.PlatformType(log)
-			log.Debugf("OS platform type of this instance = %s", instancePlatformType)
-
-			if len(value) != 2 ||
-				(strings.Compare(value[0], "platformType") == 0 && strings.Compare(value[1], "platformType") == 0) ||
-				(strings.Compare(value[0], "platformType") != 0 && strings.Compare(value[1], "platformType") != 0) {
-
-				unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-			} else {
-				// Variable and value can be in any order, i.e. both "StringEquals": ["platformType", "Windows"]
-				// and "StringEquals": ["Windows", "platformType"] are valid
-				var platformTypeValue string
-				if strings.Compare(value[0], "platformType") == 0 {
-					platformTypeValue = value[1]
-				} else {
-					platformTypeValue = value[0]
-				}
-
-				if strings.Compare(instancePlatformType, strings.ToLower(platformTypeValue)) != 0 {
-					// if precondition doesn't match for platformType, mark step for skip
-					isAllowed = false
-				}
-			}
-		default:
-			// mark for unrecognizedPrecondition (which is a form of failure)
-			unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
-		}
-	}
-
-	return isAllowed, unrecognizedPreconditionList
-}
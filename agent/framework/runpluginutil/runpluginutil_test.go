@@ -16,11 +16,14 @@ package runpluginutil
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
@@ -499,7 +502,7 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 	defaultTime := time.Now()
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "Linux"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "Linux"}}
 
 	for index, name := range pluginNames {
 
@@ -589,7 +592,7 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 	defaultTime := time.Now()
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"Linux", "platformType"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"Linux", "platformType"}}
 
 	for index, name := range pluginNames {
 
@@ -678,7 +681,7 @@ func TestRunPluginsWithIncompatiblePrecondition(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "Windows"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "Windows"}}
 
 	for index, name := range pluginNames {
 
@@ -767,7 +770,7 @@ func TestRunPluginsWithCompatiblePreconditionButMissingPluginHandler(t *testing.
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "Linux"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "Linux"}}
 
 	for index, name := range pluginNames {
 
@@ -854,7 +857,7 @@ func TestRunPluginsWithMoreThanOnePrecondition(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{
+	preconditions := map[string]interface{}{
 		"StringEquals": []string{"platformType", "Linux"},
 		"foo":          []string{"operand1", "operand2"},
 	}
@@ -952,7 +955,7 @@ func TestRunPluginsWithUnrecognizedPreconditionOperator(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"foo": []string{"platformType", "Linux"}}
+	preconditions := map[string]interface{}{"foo": []string{"platformType", "Linux"}}
 
 	for index, name := range pluginNames {
 
@@ -1048,7 +1051,7 @@ func TestRunPluginsWithUnrecognizedPreconditionOperand(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"foo", "Linux"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"foo", "Linux"}}
 
 	for index, name := range pluginNames {
 
@@ -1145,7 +1148,7 @@ func TestRunPluginsWithUnrecognizedPreconditionDuplicateVariable(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "platformType"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "platformType"}}
 
 	for index, name := range pluginNames {
 
@@ -1242,7 +1245,7 @@ func TestRunPluginsWithMoreThanTwoPreconditionOperands(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "Linux", "foo"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "Linux", "foo"}}
 
 	for index, name := range pluginNames {
 
@@ -1339,7 +1342,7 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 	defaultOutput := ""
 	pluginConfigs2 := make([]contracts.PluginState, len(pluginNames))
 
-	preconditions := map[string][]string{"StringEquals": []string{"platformType", "Linux"}}
+	preconditions := map[string]interface{}{"StringEquals": []string{"platformType", "Linux"}}
 
 	for index, name := range pluginNames {
 
@@ -1503,3 +1506,218 @@ func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 		assert.Equal(t, pluginResults[pluginID].StandardOutput, output.StandardOutput)
 	}
 }
+
+// stepPlugin is a plugin and plugin factory of the tests running the given behavior on each execution.
+// Unlike PluginMock it does not inspect its arguments, so the steps of a parallel group can run it concurrently.
+type stepPlugin struct {
+	behavior func(task.CancelFlag, iohandler.IOHandler)
+	mutex    sync.Mutex
+	calls    int
+}
+
+// Create returns the plugin itself
+func (p *stepPlugin) Create(context context.T) (T, error) {
+	return p, nil
+}
+
+// Execute counts the execution and runs the behavior
+func (p *stepPlugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	p.mutex.Lock()
+	p.calls++
+	p.mutex.Unlock()
+	p.behavior(cancelFlag, output)
+}
+
+// executions returns the number of executions of the plugin
+func (p *stepPlugin) executions() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.calls
+}
+
+// newStepPlugin registers a plugin for the given step that runs the given behavior on each execution.
+func newStepPlugin(pluginRegistry PluginRegistry, name string, behavior func(task.CancelFlag, iohandler.IOHandler)) *stepPlugin {
+	plugin := &stepPlugin{behavior: behavior}
+	pluginRegistry[name] = plugin
+	return plugin
+}
+
+func failStepBehavior(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	output.MarkAsFailed(fmt.Errorf("step failed"))
+}
+
+func succeedStepBehavior(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	output.MarkAsSucceeded()
+}
+
+func newStepState(name string, onFailure string, maxAttempts int, timeoutSeconds int) contracts.PluginState {
+	return contracts.PluginState{
+		Name: name,
+		Id:   name,
+		Configuration: contracts.Configuration{
+			PluginID:       name,
+			PluginName:     name,
+			OnFailure:      onFailure,
+			MaxAttempts:    maxAttempts,
+			TimeoutSeconds: timeoutSeconds,
+		},
+	}
+}
+
+func TestRunPluginsWithOnFailureExit(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	plugin1 := newStepPlugin(pluginRegistry, testPlugin1, failStepBehavior)
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, succeedStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, contracts.OnFailureExit, 0, 0),
+		newStepState(testPlugin2, "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 1, plugin1.executions())
+	assert.Equal(t, 0, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testPlugin2].Status)
+	assert.Equal(t, 2, len(ch))
+}
+
+func TestRunPluginsWithOnFailureSuccessAndExit(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	newStepPlugin(pluginRegistry, testPlugin1, failStepBehavior)
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, succeedStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, contracts.OnFailureSuccessAndExit, 0, 0),
+		newStepState(testPlugin2, "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 0, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testPlugin2].Status)
+}
+
+func TestRunPluginsWithOnFailureContinue(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	newStepPlugin(pluginRegistry, testPlugin1, failStepBehavior)
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, succeedStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, contracts.OnFailureContinue, 0, 0),
+		newStepState(testPlugin2, "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 1, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin2].Status)
+}
+
+func TestRunPluginsWithMaxAttempts(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	attempts := 0
+	plugin1 := newStepPlugin(pluginRegistry, testPlugin1, func(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		attempts++
+		if attempts < 2 {
+			output.MarkAsFailed(fmt.Errorf("step failed"))
+		} else {
+			output.MarkAsSucceeded()
+		}
+	})
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, failStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, "", 3, 0),
+		newStepState(testPlugin2, "", 3, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 2, plugin1.executions())
+	assert.Equal(t, 3, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin1].Status)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs[testPlugin2].Status)
+}
+
+func TestRunPluginsWithStepTimeout(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	newStepPlugin(pluginRegistry, testPlugin1, func(stepCancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		stepCancelFlag.Wait()
+		output.MarkAsCancelled()
+	})
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, succeedStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, contracts.OnFailureExit, 0, 1),
+		newStepState(testPlugin2, "", 0, 0),
+	}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 0, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusTimedOut, outputs[testPlugin1].Status)
+	assert.False(t, cancelFlag.Canceled())
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs[testPlugin2].Status)
+}
+
+func TestRunPluginsAfterRebootRequestedByExitCode(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	// the first step completed before the reboot, the second step runs again after it
+	plugin1 := newStepPlugin(pluginRegistry, testPlugin1, succeedStepBehavior)
+	plugin2 := newStepPlugin(pluginRegistry, testPlugin2, succeedStepBehavior)
+	pluginStates := []contracts.PluginState{
+		newStepState(testPlugin1, "", 0, 0),
+		newStepState(testPlugin2, "", 0, 0),
+	}
+	pluginStates[0].Result = contracts.PluginResult{Status: contracts.ResultStatusSuccessAndReboot, Code: appconfig.RebootExitCode}
+	pluginStates[1].Result = contracts.PluginResult{Status: contracts.ResultStatusSuccessAndReboot, Code: appconfig.RebootAndRerunExitCode}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, 0, plugin1.executions())
+	assert.Equal(t, 1, plugin2.executions())
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin1].Status)
+	assert.Equal(t, appconfig.RebootExitCode, outputs[testPlugin1].Code)
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin2].Status)
+}
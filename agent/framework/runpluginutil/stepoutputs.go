@@ -0,0 +1,133 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameters"
+)
+
+const (
+	// stepOutputsKey is the key of the json line a step writes to its standard output to publish named outputs, e.g.
+	// {"ssm:outputs": {"instanceId": "i-1234567890abcdef0"}}
+	stepOutputsKey = "ssm:outputs"
+
+	// stepOutputStatus is the built-in output holding the status of a step
+	stepOutputStatus = "status"
+	// stepOutputExitCode is the built-in output holding the exit code of a step
+	stepOutputExitCode = "exitCode"
+)
+
+// parseStepOutputs collects the named outputs published by a step through json lines in its standard output.
+// Outputs published later override the earlier ones with the same name.
+func parseStepOutputs(log log.T, stdout string) (outputs map[string]string) {
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") || !strings.Contains(line, stepOutputsKey) {
+			continue
+		}
+		var outputLine map[string]map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &outputLine); err != nil {
+			log.Debugf("Ignoring invalid step output line %v: %v", line, err)
+			continue
+		}
+		for name, value := range outputLine[stepOutputsKey] {
+			if !isValidStepOutputName(name) {
+				log.Infof("Ignoring step output with invalid name %v", name)
+				continue
+			}
+			if outputs == nil {
+				outputs = make(map[string]string)
+			}
+			outputs[name] = stepOutputValue(value)
+		}
+	}
+	return
+}
+
+// stepOutputValue converts an output value to the string used when the output is referenced
+func stepOutputValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(bytes)
+	}
+}
+
+// isValidStepOutputName checks whether the name can be referenced as {{ stepName.outputName }}
+func isValidStepOutputName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// stepOutputVariables returns the outputs of the steps that already ran, indexed by stepName.outputName
+func stepOutputVariables(pluginOutputs map[string]*contracts.PluginResult) map[string]interface{} {
+	variables := make(map[string]interface{})
+	for stepName, result := range pluginOutputs {
+		if !isStepCompleted(result.Status) {
+			continue
+		}
+		variables[stepName+"."+stepOutputStatus] = string(result.Status)
+		variables[stepName+"."+stepOutputExitCode] = strconv.Itoa(result.Code)
+		for name, value := range result.StepOutputs {
+			variables[stepName+"."+name] = value
+		}
+	}
+	return variables
+}
+
+// resolveStepOutputReferences replaces the {{ stepName.outputName }} references in the step configuration
+// with the outputs of the steps that already ran.
+func resolveStepOutputReferences(log log.T, config contracts.Configuration, pluginOutputs map[string]*contracts.PluginResult) contracts.Configuration {
+	variables := stepOutputVariables(pluginOutputs)
+	if len(variables) == 0 {
+		return config
+	}
+	config.Properties = parameters.ReplaceParameters(config.Properties, variables, log)
+	config.Settings = parameters.ReplaceParameters(config.Settings, variables, log)
+	return config
+}
+
+// isStepCompleted checks whether the step finished running, so that its outputs can be referenced
+func isStepCompleted(status contracts.ResultStatus) bool {
+	switch status {
+	case "", contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseStepOutputs(t *testing.T) {
+	stdout := "fetching\n" +
+		`{"ssm:outputs": {"bucket": "my-bucket", "count": 3}}` + "\n" +
+		`{"other": {"bucket": "ignored"}}` + "\n" +
+		`  {"ssm:outputs": {"bucket": "final-bucket", "invalid name": "x"}}  ` + "\n" +
+		`{"ssm:outputs": not json}` + "\n"
+
+	outputs := parseStepOutputs(log.NewMockLog(), stdout)
+
+	assert.Equal(t, map[string]string{"bucket": "final-bucket", "count": "3"}, outputs)
+	assert.Nil(t, parseStepOutputs(log.NewMockLog(), "no outputs\n"))
+}
+
+func TestResolveStepOutputReferences(t *testing.T) {
+	pluginOutputs := map[string]*contracts.PluginResult{
+		"fetch": {
+			Status:      contracts.ResultStatusSuccess,
+			StepOutputs: map[string]string{"url": "https://example.com/$file"},
+		},
+		"download": {
+			Status: contracts.ResultStatusNotStarted,
+		},
+	}
+	config := contracts.Configuration{
+		Properties: map[string]interface{}{
+			"sourceInfo": "{{ fetch.url }}",
+			"commands":   []interface{}{"echo {{ fetch.status }} {{ fetch.exitCode }}", "echo {{ download.status }}"},
+		},
+	}
+
+	resolved := resolveStepOutputReferences(log.NewMockLog(), config, pluginOutputs)
+
+	assert.Equal(t, map[string]interface{}{
+		"sourceInfo": "https://example.com/$file",
+		"commands":   []interface{}{"echo Success 0", "echo {{ download.status }}"},
+	}, resolved.Properties)
+}
+
+func TestRunPluginsWithStepOutputReferences(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginRegistry := PluginRegistry{}
+	ctx := context.NewMockDefault()
+	cancelFlag := task.NewChanneledCancelFlag()
+
+	newStepPlugin(pluginRegistry, testPlugin1, func(cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		output.SetStdout(`{"ssm:outputs": {"version": "1.2.3"}}`)
+		output.MarkAsSucceeded()
+	})
+	var receivedProperties interface{}
+	plugin2 := new(PluginMock)
+	plugin2.On("Execute", ctx, mock.Anything, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		receivedProperties = args.Get(1).(contracts.Configuration).Properties
+	}).Return()
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin2, nil)
+	pluginRegistry[testPlugin2] = pluginFactory
+
+	step2 := newStepState(testPlugin2, "", 0, 0)
+	step2.Configuration.Properties = map[string]interface{}{"version": "{{ plugin1.version }}"}
+	pluginStates := []contracts.PluginState{newStepState(testPlugin1, "", 0, 0), step2}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	outputs := RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	assert.Equal(t, map[string]string{"version": "1.2.3"}, outputs[testPlugin1].StepOutputs)
+	assert.Equal(t, map[string]interface{}{"version": "1.2.3"}, receivedProperties)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/gorilla/websocket"
+)
+
+var errStopping = errors.New("HummingBird module is stopping")
+
+// receive reads the messages of the connection until it fails, acknowledges them and queues the new commands and session requests for delivery.
+// The service is pinged meanwhile, the connection fails when the pongs stop.
+func (h *HummingBird) receive(log log.T, connection *websocket.Conn, deliveries chan service.ChannelMessage) error {
+	defer connection.Close()
+	log.Info("HummingBird channel connected")
+
+	pongWait := 2 * heartbeatInterval
+	connection.SetReadDeadline(time.Now().Add(pongWait))
+	connection.SetPongHandler(func(string) error {
+		return connection.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	heartbeatDone := make(chan bool)
+	defer close(heartbeatDone)
+	go heartbeat(log, connection, heartbeatInterval, heartbeatDone)
+
+	for {
+		_, content, err := connection.ReadMessage()
+		if err != nil {
+			return err
+		}
+		connection.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg service.ChannelMessage
+		if err = json.Unmarshal(content, &msg); err != nil {
+			log.Errorf("Ignoring invalid message of the channel, %v", err)
+			continue
+		}
+		if msg.MessageType != service.MessageTypeCommand && msg.MessageType != service.MessageTypeStartSession {
+			log.Warnf("Ignoring message %v of unexpected type %v", msg.MessageId, msg.MessageType)
+			continue
+		}
+
+		if h.accept(log, msg) {
+			select {
+			case deliveries <- msg:
+			case <-h.stop:
+				return errStopping
+			}
+		}
+
+		connection.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err = connection.WriteJSON(service.NewAcknowledgeMessage(msg)); err != nil {
+			return err
+		}
+	}
+}
+
+// accept returns true for the messages following the last delivered one.
+// The messages sent again by the service are only acknowledged again, the missing ones are left to the poll of the commands.
+func (h *HummingBird) accept(log log.T, msg service.ChannelMessage) bool {
+	if msg.SequenceNumber <= h.lastSequenceNumber {
+		log.Debugf("Message %v with sequence number %v was already received", msg.MessageId, msg.SequenceNumber)
+		return false
+	}
+	if h.lastSequenceNumber > 0 && msg.SequenceNumber > h.lastSequenceNumber+1 {
+		log.Warnf("Messages with sequence numbers %v to %v were not received", h.lastSequenceNumber+1, msg.SequenceNumber-1)
+	}
+	h.lastSequenceNumber = msg.SequenceNumber
+	return true
+}
+
+// heartbeat pings the service at the interval until done
+func heartbeat(log log.T, connection *websocket.Conn, interval time.Duration, done chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := connection.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				log.Debugf("Failed to ping the service, %v", err)
+				return
+			}
+		}
+	}
+}
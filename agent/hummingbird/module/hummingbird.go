@@ -15,14 +15,26 @@
 package module
 
 import (
+	"math/rand"
+	"sync"
+	"time"
+
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/gorilla/websocket"
 )
 
+// MessageHandler processes the SendCommand and CancelCommand messages pushed by the service
+type MessageHandler func(msg *ssmmds.Message)
+
+// SessionHandler starts the sessions requested by the service
+type SessionHandler func(msg service.ChannelMessage)
+
 // HummingBird encapsulates the logic on configuring, starting and stopping core modules
 type HummingBird struct {
 	context   context.T
@@ -31,6 +43,15 @@ type HummingBird struct {
 	mfsConfig appconfig.MfsCfg
 	service   service.Service
 	channel   Channel
+	handler   MessageHandler
+	sessions  SessionHandler
+
+	// lastSequenceNumber is the sequence number of the last message delivered, kept across the connections of the channel
+	lastSequenceNumber int64
+	mutex              sync.Mutex
+	stopOnce           sync.Once
+	stop               chan bool
+	done               chan bool
 }
 
 type Channel struct {
@@ -41,10 +62,29 @@ type Channel struct {
 const (
 	//TODO will change name
 	name = "HummingBird"
+
+	// deliveryQueueSize is the number of received messages waiting to be processed
+	deliveryQueueSize = 100
+
+	// stopTimeout is how long the stop of the module waits for the channel to be closed
+	stopTimeout = 10 * time.Second
+)
+
+var (
+	// heartbeatInterval is the interval of the pings sent to the service, the connection is closed after two missed pongs
+	heartbeatInterval = 30 * time.Second
+
+	// writeTimeout is the timeout of the messages written to the channel
+	writeTimeout = 10 * time.Second
+
+	// minRetryDelay and maxRetryDelay bound the exponential backoff of the reconnections
+	minRetryDelay = 1 * time.Second
+	maxRetryDelay = 5 * time.Minute
 )
 
 // NewHummingBird gets HM core module that will manage the websocket connection between Agent and HM service.
-func NewHummingBird(context context.T) *HummingBird {
+// The commands pushed on the connection are processed by the handler, the session requests by the session handler if any.
+func NewHummingBird(context context.T, handler MessageHandler, sessions SessionHandler) *HummingBird {
 
 	hummingBirdContext := context.With("[" + name + "]")
 	log := hummingBirdContext.Log()
@@ -76,7 +116,12 @@ func NewHummingBird(context context.T) *HummingBird {
 		config:    agentConfig,
 		name:      name,
 		mfsConfig: config.Mfs,
-		service:   mfsService}
+		service:   mfsService,
+		handler:   handler,
+		sessions:  sessions,
+		channel:   Channel{channelId: config.Mfs.ChannelId},
+		stop:      make(chan bool),
+		done:      make(chan bool)}
 }
 
 // ICoreModule implementation
@@ -86,37 +131,150 @@ func (h *HummingBird) ModuleName() string {
 	return name
 }
 
-// ModuleExecute starts the scheduling of the health check module
+// ModuleExecute starts the channel with the service, the commands are still polled when no endpoint is configured
 func (h *HummingBird) ModuleExecute(context context.T) (err error) {
 
 	log := h.context.Log()
 
+	if h.mfsConfig.Endpoint == "" {
+		log.Info("No HummingBird endpoint configured, commands are received by polling only")
+		close(h.done)
+		return nil
+	}
+
 	instanceID, err := platform.InstanceID()
 	if err != nil {
 		log.Errorf("no instanceID provided, %v", err)
+		close(h.done)
 		return
 	}
 
-	channelId, err := h.service.CreateChannel(log, instanceID)
-	if err != nil {
-		log.Errorf("cannot create connection to MFS, %v", err)
-		return
+	go h.run(instanceID)
+	return nil
+}
+
+// RequestStop handles the termination of the web socket plugin job
+func (h *HummingBird) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	log := h.context.Log()
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+
+	// closing the connection unblocks the read of the channel
+	h.mutex.Lock()
+	if h.channel.connection != nil {
+		h.channel.connection.Close()
 	}
+	h.mutex.Unlock()
 
-	channel, err := h.service.GetChannel(log, channelId)
-	if err != nil {
-		log.Errorf("cannot upgrade to web socket connection to MFS, %v", err)
-		return
+	select {
+	case <-h.done:
+	case <-time.After(stopTimeout):
+		log.Warnf("Timed out waiting for the channel to close")
 	}
 
+	h.mutex.Lock()
+	channelId := h.channel.channelId
+	h.channel = Channel{}
+	h.mutex.Unlock()
+	if channelId != "" && channelId != h.mfsConfig.ChannelId {
+		if err = h.service.DeleteChannel(log, channelId); err != nil {
+			log.Warnf("Failed to delete channel %v, %v", channelId, err)
+		}
+	}
+	return nil
+}
+
+// run connects the channel and receives its messages, and reconnects with an exponential backoff until the module stops
+func (h *HummingBird) run(instanceID string) {
+	log := h.context.Log()
+	defer close(h.done)
+
+	deliveries := make(chan service.ChannelMessage, deliveryQueueSize)
+	defer close(deliveries)
+	go h.deliver(deliveries)
+
+	retryDelay := minRetryDelay
+	for {
+		connection, err := h.connect(log, instanceID)
+		if err == nil {
+			// the backoff restarts once the channel was connected
+			retryDelay = minRetryDelay
+			err = h.receive(log, connection, deliveries)
+		}
+		if h.isStopping() {
+			log.Info("HummingBird channel closed")
+			return
+		}
+
+		delay := retryDelay/2 + time.Duration(rand.Int63n(int64(retryDelay/2)+1))
+		log.Warnf("HummingBird channel disconnected, reconnecting in %v, %v", delay, err)
+		select {
+		case <-h.stop:
+			return
+		case <-time.After(delay):
+		}
+		if retryDelay *= 2; retryDelay > maxRetryDelay {
+			retryDelay = maxRetryDelay
+		}
+	}
+}
+
+// connect opens the connection of the channel, the channel is created first if needed
+func (h *HummingBird) connect(log log.T, instanceID string) (connection *websocket.Conn, err error) {
+	h.mutex.Lock()
+	channelId := h.channel.channelId
+	h.mutex.Unlock()
+
+	if channelId == "" {
+		if channelId, err = h.service.CreateChannel(log, instanceID); err != nil {
+			return nil, err
+		}
+		// the messages of a new channel are numbered from the start
+		h.lastSequenceNumber = 0
+	}
+
+	if connection, err = h.service.GetChannel(log, channelId); err != nil {
+		// the channel may have expired, it is created again on the next attempt unless it is configured
+		channelId = h.mfsConfig.ChannelId
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	h.channel = Channel{
 		channelId:  channelId,
-		connection: channel}
+		connection: connection}
+	if err == nil && h.isStopping() {
+		connection.Close()
+		return nil, errStopping
+	}
+	return connection, err
+}
 
-	return nil
+// isStopping returns true once the stop of the module was requested
+func (h *HummingBird) isStopping() bool {
+	select {
+	case <-h.stop:
+		return true
+	default:
+		return false
+	}
 }
 
-// RequestStop handles the termination of the web socket plugin job
-func (h *HummingBird) ModuleRequestStop(stopType contracts.StopType) (err error) {
-	return nil
+// deliver hands the received commands to the handler and the session requests to the session handler, in order
+func (h *HummingBird) deliver(deliveries chan service.ChannelMessage) {
+	log := h.context.Log()
+	for msg := range deliveries {
+		log.Debugf("Delivering message %v", msg.MessageId)
+		switch msg.MessageType {
+		case service.MessageTypeStartSession:
+			if h.sessions == nil {
+				log.Warnf("Ignoring session request %v, sessions are not supported", msg.MessageId)
+				continue
+			}
+			h.sessions(msg)
+		default:
+			h.handler(msg.ToMdsMessage())
+		}
+	}
 }
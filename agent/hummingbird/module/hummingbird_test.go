@@ -0,0 +1,237 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testInstanceID = "i-1234567890"
+	testTimeout    = 5 * time.Second
+)
+
+func newTestHummingBird(endpoint string, handler MessageHandler) *HummingBird {
+	ctx := context.NewMockDefault()
+	ctx.Log().(*log.Mock).On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return &HummingBird{
+		context:   ctx,
+		name:      name,
+		mfsConfig: appconfig.MfsCfg{Endpoint: endpoint},
+		service:   service.NewService("us-east-1", endpoint, credentials.AnonymousCredentials),
+		handler:   handler,
+		stop:      make(chan bool),
+		done:      make(chan bool),
+	}
+}
+
+// useFastTimers shortens the heartbeat and the reconnection delays for the test
+func useFastTimers() func() {
+	heartbeat, minDelay, maxDelay := heartbeatInterval, minRetryDelay, maxRetryDelay
+	heartbeatInterval, minRetryDelay, maxRetryDelay = 50*time.Millisecond, 10*time.Millisecond, 20*time.Millisecond
+	return func() {
+		heartbeatInterval, minRetryDelay, maxRetryDelay = heartbeat, minDelay, maxDelay
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			assert.FailNow(t, "timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func receiveMessage(t *testing.T, messages chan *ssmmds.Message) *ssmmds.Message {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "no message delivered")
+		return nil
+	}
+}
+
+func receiveAcknowledgement(t *testing.T, server *servicemock.Server) service.ChannelMessage {
+	select {
+	case ack := <-server.Acknowledgements:
+		return ack
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "no acknowledgement received")
+		return service.ChannelMessage{}
+	}
+}
+
+func newCommandMessage(messageID string) service.ChannelMessage {
+	return service.ChannelMessage{
+		MessageId:   messageID,
+		Topic:       "aws.ssm.sendCommand.test",
+		Payload:     "{}",
+		CreatedDate: "2018-03-01T10:00:00.000Z",
+		Destination: testInstanceID,
+	}
+}
+
+func TestPushedMessagesAreDeliveredOnce(t *testing.T) {
+	defer useFastTimers()()
+	server := servicemock.NewServer()
+	defer server.Close()
+
+	messages := make(chan *ssmmds.Message, 10)
+	h := newTestHummingBird(server.URL, func(msg *ssmmds.Message) { messages <- msg })
+	go h.run(testInstanceID)
+	waitFor(t, func() bool { return server.IsConnected(testInstanceID) })
+
+	// a pushed command is delivered as an MDS message and acknowledged
+	msg := newCommandMessage("aws.ssm.11111111-1111-1111-1111-111111111111.i-1234567890")
+	sequenceNumber, err := server.Push(testInstanceID, msg)
+	assert.NoError(t, err)
+	delivered := receiveMessage(t, messages)
+	assert.Equal(t, msg.MessageId, *delivered.MessageId)
+	assert.Equal(t, msg.Topic, *delivered.Topic)
+	assert.Equal(t, msg.Payload, *delivered.Payload)
+	assert.Equal(t, msg.Destination, *delivered.Destination)
+	assert.Equal(t, sequenceNumber, receiveAcknowledgement(t, server).SequenceNumber)
+
+	// a message sent again is acknowledged again but not delivered
+	msg.MessageType = service.MessageTypeCommand
+	msg.SequenceNumber = sequenceNumber
+	assert.NoError(t, server.Resend(testInstanceID, msg))
+	assert.Equal(t, sequenceNumber, receiveAcknowledgement(t, server).SequenceNumber)
+	assert.Empty(t, messages)
+
+	// the channel reconnects after a disconnection
+	server.Disconnect()
+	waitFor(t, func() bool { return server.IsConnected(testInstanceID) })
+	next := newCommandMessage("aws.ssm.22222222-2222-2222-2222-222222222222.i-1234567890")
+	_, err = server.Push(testInstanceID, next)
+	assert.NoError(t, err)
+	assert.Equal(t, next.MessageId, *receiveMessage(t, messages).MessageId)
+	receiveAcknowledgement(t, server)
+
+	// the channel is deleted when the module stops
+	assert.NoError(t, h.ModuleRequestStop(contracts.StopTypeSoftStop))
+	assert.Equal(t, 0, server.ChannelCount())
+}
+
+func TestUnacknowledgedMessagesAreSentOnReconnect(t *testing.T) {
+	defer useFastTimers()()
+	// the agent waits before reconnecting, long enough to push the message meanwhile
+	minRetryDelay, maxRetryDelay = 200*time.Millisecond, 200*time.Millisecond
+	server := servicemock.NewServer()
+	defer server.Close()
+
+	messages := make(chan *ssmmds.Message, 10)
+	h := newTestHummingBird(server.URL, func(msg *ssmmds.Message) { messages <- msg })
+	go h.run(testInstanceID)
+	waitFor(t, func() bool { return server.IsConnected(testInstanceID) })
+
+	server.Disconnect()
+	msg := newCommandMessage("aws.ssm.33333333-3333-3333-3333-333333333333.i-1234567890")
+	_, err := server.Push(testInstanceID, msg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, msg.MessageId, *receiveMessage(t, messages).MessageId)
+	receiveAcknowledgement(t, server)
+	h.ModuleRequestStop(contracts.StopTypeSoftStop)
+}
+
+func TestMissedPongsCloseTheConnection(t *testing.T) {
+	defer useFastTimers()()
+
+	// the service accepts the connections but never answers the pings
+	var mutex sync.Mutex
+	connections := 0
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"ChannelId": "channel-1"}`))
+			return
+		}
+		if r.Method == http.MethodGet {
+			if connection, err := upgrader.Upgrade(w, r, nil); err == nil {
+				mutex.Lock()
+				connections++
+				mutex.Unlock()
+				defer connection.Close()
+				time.Sleep(testTimeout)
+			}
+		}
+	}))
+	defer server.Close()
+
+	h := newTestHummingBird(server.URL, func(msg *ssmmds.Message) {})
+	go h.run(testInstanceID)
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return connections >= 2
+	})
+	h.ModuleRequestStop(contracts.StopTypeSoftStop)
+}
+
+func TestSessionRequestsAreDeliveredToTheSessionHandler(t *testing.T) {
+	defer useFastTimers()()
+	server := servicemock.NewServer()
+	defer server.Close()
+
+	messages := make(chan *ssmmds.Message, 10)
+	sessions := make(chan service.ChannelMessage, 10)
+	h := newTestHummingBird(server.URL, func(msg *ssmmds.Message) { messages <- msg })
+	h.sessions = func(msg service.ChannelMessage) { sessions <- msg }
+	go h.run(testInstanceID)
+	waitFor(t, func() bool { return server.IsConnected(testInstanceID) })
+
+	msg := service.ChannelMessage{
+		MessageType: service.MessageTypeStartSession,
+		MessageId:   "session-message-1",
+		Payload:     `{"SessionId": "session-1", "SessionType": "Shell"}`,
+	}
+	sequenceNumber, err := server.Push(testInstanceID, msg)
+	assert.NoError(t, err)
+	select {
+	case delivered := <-sessions:
+		assert.Equal(t, msg.MessageId, delivered.MessageId)
+		assert.Equal(t, msg.Payload, delivered.Payload)
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "no session request delivered")
+	}
+	assert.Equal(t, sequenceNumber, receiveAcknowledgement(t, server).SequenceNumber)
+	assert.Empty(t, messages)
+	h.ModuleRequestStop(contracts.StopTypeSoftStop)
+}
+
+func TestModuleExecuteWithoutEndpoint(t *testing.T) {
+	h := newTestHummingBird("", func(msg *ssmmds.Message) {})
+	assert.NoError(t, h.ModuleExecute(h.context))
+	assert.NoError(t, h.ModuleRequestStop(contracts.StopTypeSoftStop))
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+)
+
+// MessageType is the type of a message sent on the channel
+type MessageType string
+
+const (
+	// MessageTypeCommand is a message of the service delivering a SendCommand or CancelCommand payload
+	MessageTypeCommand MessageType = "command"
+
+	// MessageTypeStartSession is a message of the service requesting a session, its payload is the input of the session
+	MessageTypeStartSession MessageType = "start_session"
+
+	// MessageTypeAcknowledge is a message of the agent acknowledging the receipt of a message of the service
+	MessageTypeAcknowledge MessageType = "acknowledge"
+)
+
+// ChannelMessage is a message sent on the channel, as a JSON text frame.
+// The messages of the service are numbered in sequence, the agent acknowledges each of them by its sequence number
+// and the service sends the unacknowledged ones again when the agent reconnects.
+type ChannelMessage struct {
+	MessageType    MessageType
+	SequenceNumber int64
+	MessageId      string
+	Topic          string `json:",omitempty"`
+	Payload        string `json:",omitempty"`
+	CreatedDate    string `json:",omitempty"`
+	Destination    string `json:",omitempty"`
+}
+
+// CreateChannelInput is the request to create the channel of an instance
+type CreateChannelInput struct {
+	InstanceId string
+}
+
+// CreateChannelOutput is the response to the creation of a channel
+type CreateChannelOutput struct {
+	ChannelId string
+}
+
+// NewAcknowledgeMessage returns the acknowledgement of the message
+func NewAcknowledgeMessage(msg ChannelMessage) ChannelMessage {
+	return ChannelMessage{
+		MessageType:    MessageTypeAcknowledge,
+		SequenceNumber: msg.SequenceNumber,
+		MessageId:      msg.MessageId,
+	}
+}
+
+// ToMdsMessage returns the MDS message delivered by the command message, as if it was polled from MDS
+func (msg ChannelMessage) ToMdsMessage() *ssmmds.Message {
+	return &ssmmds.Message{
+		MessageId:   aws.String(msg.MessageId),
+		Topic:       aws.String(msg.Topic),
+		Payload:     aws.String(msg.Payload),
+		CreatedDate: aws.String(msg.CreatedDate),
+		Destination: aws.String(msg.Destination),
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package servicemock implements a local HummingBird service for the tests
+package servicemock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/gorilla/websocket"
+)
+
+// Server is a local HummingBird service for the tests.
+// It sends the pushed messages on the open connection of their channel, and again on the next connections until they are acknowledged.
+type Server struct {
+	*httptest.Server
+
+	// Acknowledgements receives the acknowledgements of the agent
+	Acknowledgements chan service.ChannelMessage
+
+	// Sessions receives the connections opened by the agent for the data of the sessions, as the client of the session
+	Sessions chan *SessionConnection
+
+	mutex          sync.Mutex
+	channels       map[string]*testChannel
+	nextChannel    int
+	authorizations []string
+	upgrader       websocket.Upgrader
+}
+
+type testChannel struct {
+	instanceID     string
+	connection     *websocket.Conn
+	sequenceNumber int64
+	unacknowledged []service.ChannelMessage
+}
+
+// SessionConnection is the server side of the connection of a session
+type SessionConnection struct {
+	SessionId  string
+	Connection *websocket.Conn
+}
+
+// NewServer starts a local HummingBird service
+func NewServer() *Server {
+	server := &Server{
+		Acknowledgements: make(chan service.ChannelMessage, 100),
+		Sessions:         make(chan *SessionConnection, 10),
+		channels:         make(map[string]*testChannel),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	return server
+}
+
+// Push sends a message on the channel of the instance, a command message unless its type is set.
+// It returns the sequence number of the message.
+func (s *Server) Push(instanceID string, msg service.ChannelMessage) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, channel := range s.channels {
+		if channel.instanceID == instanceID {
+			channel.sequenceNumber++
+			if msg.MessageType == "" {
+				msg.MessageType = service.MessageTypeCommand
+			}
+			msg.SequenceNumber = channel.sequenceNumber
+			channel.unacknowledged = append(channel.unacknowledged, msg)
+			if channel.connection != nil {
+				return msg.SequenceNumber, channel.connection.WriteJSON(msg)
+			}
+			return msg.SequenceNumber, nil
+		}
+	}
+	return 0, fmt.Errorf("no channel for instance %v", instanceID)
+}
+
+// Resend sends a message again on the channel of the instance, as the service does when it misses an acknowledgement
+func (s *Server) Resend(instanceID string, msg service.ChannelMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, channel := range s.channels {
+		if channel.instanceID == instanceID && channel.connection != nil {
+			return channel.connection.WriteJSON(msg)
+		}
+	}
+	return fmt.Errorf("no connection for instance %v", instanceID)
+}
+
+// Disconnect closes the open connections, the agent is expected to reconnect
+func (s *Server) Disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, channel := range s.channels {
+		if channel.connection != nil {
+			channel.connection.Close()
+			channel.connection = nil
+		}
+	}
+}
+
+// IsConnected returns true if the channel of the instance has an open connection
+func (s *Server) IsConnected(instanceID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, channel := range s.channels {
+		if channel.instanceID == instanceID && channel.connection != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelCount returns the number of channels that are not deleted
+func (s *Server) ChannelCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.channels)
+}
+
+// Authorizations returns the authorization headers of the requests received
+func (s *Server) Authorizations() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.authorizations...)
+}
+
+// handle serves the channel and session resources
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.authorizations = append(s.authorizations, r.Header.Get("Authorization"))
+	s.mutex.Unlock()
+
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, service.SessionsPath+"/") && strings.HasSuffix(r.URL.Path, "/stream") {
+		sessionId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, service.SessionsPath+"/"), "/stream")
+		if connection, err := s.upgrader.Upgrade(w, r, nil); err == nil {
+			s.Sessions <- &SessionConnection{SessionId: sessionId, Connection: connection}
+		}
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, service.ChannelsPath)
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		var input service.CreateChannelInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.InstanceId == "" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		s.mutex.Lock()
+		s.nextChannel++
+		channelId := fmt.Sprintf("channel-%v", s.nextChannel)
+		s.channels[channelId] = &testChannel{instanceID: input.InstanceId}
+		s.mutex.Unlock()
+		json.NewEncoder(w).Encode(service.CreateChannelOutput{ChannelId: channelId})
+
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/stream"):
+		s.stream(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/stream"))
+
+	case r.Method == http.MethodDelete:
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		channelId := strings.TrimPrefix(path, "/")
+		if channel, exists := s.channels[channelId]; exists {
+			if channel.connection != nil {
+				channel.connection.Close()
+			}
+			delete(s.channels, channelId)
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// stream opens the connection of the channel, sends the unacknowledged messages and reads the acknowledgements
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, channelId string) {
+	s.mutex.Lock()
+	_, exists := s.channels[channelId]
+	s.mutex.Unlock()
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	connection, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	channel, exists := s.channels[channelId]
+	if !exists {
+		s.mutex.Unlock()
+		connection.Close()
+		return
+	}
+	channel.connection = connection
+	for _, msg := range channel.unacknowledged {
+		connection.WriteJSON(msg)
+	}
+	s.mutex.Unlock()
+
+	for {
+		var msg service.ChannelMessage
+		if err := connection.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.MessageType != service.MessageTypeAcknowledge {
+			continue
+		}
+		s.mutex.Lock()
+		for i, unacknowledged := range channel.unacknowledged {
+			if unacknowledged.SequenceNumber == msg.SequenceNumber {
+				channel.unacknowledged = append(channel.unacknowledged[:i], channel.unacknowledged[i+1:]...)
+				break
+			}
+		}
+		s.mutex.Unlock()
+		s.Acknowledgements <- msg
+	}
+}
@@ -15,24 +15,61 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/websocketutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// signingName is the service name of the signature of the requests
+	signingName = "ssmmessages"
+
+	// ChannelsPath is the path of the channel resources
+	ChannelsPath = "/v1/channels"
+
+	// SessionsPath is the path of the session resources
+	SessionsPath = "/v1/sessions"
+
+	// requestTimeout is the timeout of the channel requests, other than the stream
+	requestTimeout = 30 * time.Second
+
+	// handshakeTimeout is the timeout of the websocket handshake
+	handshakeTimeout = 30 * time.Second
+)
+
 // Service is an interface to the HM service operation v1.
 type Service interface {
 	CreateChannel(log log.T, instanceID string) (channelId string, err error)
 	GetChannel(log log.T, channelId string) (*websocket.Conn, error)
 	DeleteChannel(log log.T, channelId string) error
+	GetSessionChannel(log log.T, sessionId string) (*websocket.Conn, error)
 }
 
 // sdkService is an service wrapper that delegates to the hm service sdk.
-type sdkService struct{}
+type sdkService struct {
+	endpoint    string
+	region      string
+	credentials *credentials.Credentials
+	client      *http.Client
+	dialer      *websocket.Dialer
+}
 
 // NewService creates a new service instance.
+// The endpoint is the base url of the service, https unless the scheme is given.
 func NewService(region string, endpoint string, creds *credentials.Credentials) Service {
 
 	config := sdkutil.AwsConfig()
@@ -49,20 +86,121 @@ func NewService(region string, endpoint string, creds *credentials.Credentials)
 		config.Credentials = creds
 	}
 
-	return &sdkService{}
+	if endpoint != "" && !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	return &sdkService{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      aws.StringValue(config.Region),
+		credentials: config.Credentials,
+		client:      &http.Client{Timeout: requestTimeout},
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: handshakeTimeout,
+		},
+	}
 }
 
 // CreateChannel makes POST request to service to get channel id
 func (hmService *sdkService) CreateChannel(log log.T, instanceID string) (channelId string, err error) {
-	return "", nil
+	body, err := json.Marshal(CreateChannelInput{InstanceId: instanceID})
+	if err != nil {
+		return "", err
+	}
+	var output CreateChannelOutput
+	if err = hmService.call(log, http.MethodPost, hmService.endpoint+ChannelsPath, body, &output); err != nil {
+		return "", err
+	}
+	if output.ChannelId == "" {
+		return "", fmt.Errorf("no channel id returned for instance %v", instanceID)
+	}
+	log.Infof("Created channel %v", output.ChannelId)
+	return output.ChannelId, nil
 }
 
 // GetChannel makes GET request to service to open web socket connection
 func (hmService *sdkService) GetChannel(log log.T, channelId string) (*websocket.Conn, error) {
-	return nil, nil
+	return hmService.openStream(log, hmService.channelUrl(channelId)+"/stream")
 }
 
 // DeleteChannel closes the web socket
 func (hmService *sdkService) DeleteChannel(log log.T, channelId string) error {
+	return hmService.call(log, http.MethodDelete, hmService.channelUrl(channelId), nil, nil)
+}
+
+// GetSessionChannel makes GET request to service to open the web socket connection carrying the data of a session
+func (hmService *sdkService) GetSessionChannel(log log.T, sessionId string) (*websocket.Conn, error) {
+	return hmService.openStream(log, hmService.endpoint+SessionsPath+"/"+url.PathEscape(sessionId)+"/stream")
+}
+
+// openStream opens the web socket connection of the stream url
+func (hmService *sdkService) openStream(log log.T, streamUrl string) (*websocket.Conn, error) {
+	req, err := http.NewRequest(http.MethodGet, streamUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = hmService.sign(req, nil); err != nil {
+		return nil, err
+	}
+
+	// the handshake is signed as an http request, the websocket scheme follows the scheme of the endpoint
+	websocketUrl, _ := url.Parse(streamUrl)
+	if websocketUrl.Scheme == "http" {
+		websocketUrl.Scheme = "ws"
+	} else {
+		websocketUrl.Scheme = "wss"
+	}
+	return websocketutil.NewWebsocketUtil(log, hmService.dialer).OpenConnectionWithHeader(websocketUrl.String(), req.Header)
+}
+
+// channelUrl returns the url of the channel resource
+func (hmService *sdkService) channelUrl(channelId string) string {
+	return hmService.endpoint + ChannelsPath + "/" + url.PathEscape(channelId)
+}
+
+// call sends a signed JSON request to the service and parses its JSON response into the output if any
+func (hmService *sdkService) call(log log.T, method string, requestUrl string, body []byte, output interface{}) error {
+	var bodyReader io.ReadSeeker
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, requestUrl, bodyReader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err = hmService.sign(req, bodyReader); err != nil {
+		return err
+	}
+
+	log.Debugf("Sending %v %v", method, requestUrl)
+	resp, err := hmService.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%v %v failed with status %v: %v", method, requestUrl, resp.Status, strings.TrimSpace(string(content)))
+	}
+	if output != nil {
+		return json.Unmarshal(content, output)
+	}
 	return nil
 }
+
+// sign signs the request with the credentials of the agent, unless it runs without credentials
+func (hmService *sdkService) sign(req *http.Request, body io.ReadSeeker) error {
+	if hmService.credentials == nil || hmService.credentials == credentials.AnonymousCredentials {
+		return nil
+	}
+	_, err := v4.NewSigner(hmService.credentials).Sign(req, body, signingName, hmService.region, time.Now())
+	return err
+}
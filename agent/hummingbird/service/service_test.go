@@ -0,0 +1,89 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceID = "i-1234567890"
+
+func TestChannelLifecycle(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	logger := log.NewMockLog()
+	hmService := service.NewService("us-east-1", server.URL, credentials.NewStaticCredentials("AKID", "SECRET", ""))
+
+	channelId, err := hmService.CreateChannel(logger, testInstanceID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, channelId)
+	assert.Equal(t, 1, server.ChannelCount())
+
+	connection, err := hmService.GetChannel(logger, channelId)
+	assert.NoError(t, err)
+	defer connection.Close()
+
+	assert.NoError(t, hmService.DeleteChannel(logger, channelId))
+	assert.Equal(t, 0, server.ChannelCount())
+
+	// every request is signed, including the websocket handshake
+	authorizations := server.Authorizations()
+	assert.Len(t, authorizations, 3)
+	for _, authorization := range authorizations {
+		assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 "), authorization)
+		assert.Contains(t, authorization, "/us-east-1/ssmmessages/")
+	}
+}
+
+func TestGetChannelUnknown(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	hmService := service.NewService("us-east-1", server.URL, credentials.AnonymousCredentials)
+
+	_, err := hmService.GetChannel(log.NewMockLog(), "unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.Equal(t, []string{""}, server.Authorizations())
+}
+
+func TestCreateChannelFailure(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	hmService := service.NewService("us-east-1", server.URL, credentials.AnonymousCredentials)
+
+	_, err := hmService.CreateChannel(log.NewMockLog(), "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+func TestGetSessionChannel(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	hmService := service.NewService("us-east-1", server.URL, credentials.NewStaticCredentials("AKID", "SECRET", ""))
+
+	connection, err := hmService.GetSessionChannel(log.NewMockLog(), "session-1")
+	assert.NoError(t, err)
+	defer connection.Close()
+
+	session := <-server.Sessions
+	assert.Equal(t, "session-1", session.SessionId)
+	assert.True(t, strings.HasPrefix(server.Authorizations()[0], "AWS4-HMAC-SHA256 "))
+}
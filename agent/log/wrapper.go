@@ -14,7 +14,10 @@
 package log
 
 import (
+	"fmt"
 	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 )
 
 // DelegateLogger holds the base logger for logging
@@ -39,6 +42,22 @@ type FormatFilter interface {
 	Filterf(format string, params ...interface{}) (newFormat string, newParams []interface{})
 }
 
+// redactf masks the secret values resolved for the documents in the formatted message
+func redactf(format string, params []interface{}) (string, []interface{}) {
+	if !redaction.HasSecrets() {
+		return format, params
+	}
+	return "%s", []interface{}{redaction.Redact(fmt.Sprintf(format, params...))}
+}
+
+// redact masks the secret values resolved for the documents in the message
+func redact(v []interface{}) []interface{} {
+	if !redaction.HasSecrets() {
+		return v
+	}
+	return []interface{}{redaction.Redact(fmt.Sprint(v...))}
+}
+
 // WithContext creates a wrapper logger with context
 func (w *Wrapper) WithContext(context ...string) (contextLogger T) {
 	formatFilter := &ContextFormatFilter{Context: context}
@@ -49,7 +68,7 @@ func (w *Wrapper) WithContext(context ...string) (contextLogger T) {
 // Tracef formats message according to format specifier
 // and writes to log with level = Trace.
 func (w *Wrapper) Tracef(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -59,7 +78,7 @@ func (w *Wrapper) Tracef(format string, params ...interface{}) {
 // Debugf formats message according to format specifier
 // and writes to log with level = Debug.
 func (w *Wrapper) Debugf(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -69,7 +88,7 @@ func (w *Wrapper) Debugf(format string, params ...interface{}) {
 // Infof formats message according to format specifier
 // and writes to log with level = Info.
 func (w *Wrapper) Infof(format string, params ...interface{}) {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -79,7 +98,7 @@ func (w *Wrapper) Infof(format string, params ...interface{}) {
 // Warnf formats message according to format specifier
 // and writes to log with level = Warn.
 func (w *Wrapper) Warnf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -89,7 +108,7 @@ func (w *Wrapper) Warnf(format string, params ...interface{}) error {
 // Errorf formats message according to format specifier
 // and writes to log with level = Error.
 func (w *Wrapper) Errorf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -99,7 +118,7 @@ func (w *Wrapper) Errorf(format string, params ...interface{}) error {
 // Criticalf formats message according to format specifier
 // and writes to log with level = Critical.
 func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
-	format, params = w.Format.Filterf(format, params...)
+	format, params = redactf(w.Format.Filterf(format, params...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -109,7 +128,7 @@ func (w *Wrapper) Criticalf(format string, params ...interface{}) error {
 // Trace formats message using the default formats for its operands
 // and writes to log with level = Trace
 func (w *Wrapper) Trace(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 	w.M.Lock()
 	defer w.M.Unlock()
 	w.Delegate.BaseLoggerInstance.Trace(v...)
@@ -118,7 +137,7 @@ func (w *Wrapper) Trace(v ...interface{}) {
 // Debug formats message using the default formats for its operands
 // and writes to log with level = Debug
 func (w *Wrapper) Debug(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -128,7 +147,7 @@ func (w *Wrapper) Debug(v ...interface{}) {
 // Info formats message using the default formats for its operands
 // and writes to log with level = Info
 func (w *Wrapper) Info(v ...interface{}) {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -138,7 +157,7 @@ func (w *Wrapper) Info(v ...interface{}) {
 // Warn formats message using the default formats for its operands
 // and writes to log with level = Warn
 func (w *Wrapper) Warn(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -148,7 +167,7 @@ func (w *Wrapper) Warn(v ...interface{}) error {
 // Error formats message using the default formats for its operands
 // and writes to log with level = Error
 func (w *Wrapper) Error(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
@@ -158,7 +177,7 @@ func (w *Wrapper) Error(v ...interface{}) error {
 // Critical formats message using the default formats for its operands
 // and writes to log with level = Critical
 func (w *Wrapper) Critical(v ...interface{}) error {
-	v = w.Format.Filter(v...)
+	v = redact(w.Format.Filter(v...))
 
 	w.M.Lock()
 	defer w.M.Unlock()
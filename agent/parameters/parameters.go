@@ -113,10 +113,10 @@ func isSingleParameterString(input string, paramName string) bool {
 }
 
 // ReplaceParameter replaces all occurrences of "{{ paramName }}" in the input by paramValue.
+// The parameter value is inserted literally, i.e. "$" in the value is not expanded.
 func ReplaceParameter(input string, paramName string, paramValue string) string {
-	// this method should be called only on parameter names that have been validated first
-	r := regexp.MustCompile(fmt.Sprintf(`{{\s*%v\s*}}`, paramName))
-	return r.ReplaceAllString(input, paramValue)
+	r := regexp.MustCompile(fmt.Sprintf(`{{\s*%v\s*}}`, regexp.QuoteMeta(paramName)))
+	return r.ReplaceAllLiteralString(input, paramValue)
 }
 
 // ValidParameters checks if parameter names are valid. Returns valid parameters only.
@@ -84,6 +84,11 @@ func TestReplaceParameters(t *testing.T) {
 	}
 }
 
+func TestReplaceParameter(t *testing.T) {
+	assert.Equal(t, "echo $HOME/bin", ReplaceParameter("echo {{ home }}/bin", "home", "$HOME"))
+	assert.Equal(t, "value and {{ stepXvalue }}", ReplaceParameter("{{ step.value }} and {{ stepXvalue }}", "step.value", "value"))
+}
+
 func generateReplaceParamTestCases() []ReplaceParamTestCase {
 	params := map[string]interface{}{
 		"param1": "a parameter",
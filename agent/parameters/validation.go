@@ -0,0 +1,305 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package parameters provides utilities to parse ssm document parameters
+package parameters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+)
+
+// ValidateParameters checks the parameter values against the definitions of the document parameters:
+// type, allowed values, number of items and number of characters. It returns the values converted to the type of their
+// parameter, e.g. the string "10" of an Integer becomes 10, the number 10 of a String becomes "10" and the json of a StringMap
+// becomes a map. The values referencing other parameters, e.g. {{ssm:name}}, are resolved later and are returned as is.
+func ValidateParameters(
+	log log.T,
+	definitions map[string]*contracts.Parameter,
+	values map[string]interface{}) (map[string]interface{}, error) {
+
+	validatedValues := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		validatedValues[name] = value
+	}
+
+	var errorMessages []string
+	for name, definition := range definitions {
+		if definition == nil {
+			continue
+		}
+		value := values[name]
+		if value == nil {
+			// parameters without value and without default are left to the service
+			continue
+		}
+		if isParameterReference(value) {
+			log.Debugf("Skipping the validation of parameter %v referencing another parameter", name)
+			continue
+		}
+
+		validatedValue, err := validateParameter(definition, value)
+		if err != nil {
+			errorMessages = append(errorMessages, fmt.Sprintf("Parameter %v: %v", name, err))
+			continue
+		}
+		validatedValues[name] = validatedValue
+	}
+
+	if len(errorMessages) > 0 {
+		sort.Strings(errorMessages)
+		return nil, fmt.Errorf("Invalid document parameters: %v", strings.Join(errorMessages, "; "))
+	}
+	return validatedValues, nil
+}
+
+// isParameterReference checks whether the value is a string referencing a parameter of SSM Parameter Store or of a provider
+func isParameterReference(value interface{}) bool {
+	text, ok := value.(string)
+	return ok && ssmparameterresolver.ContainsParameterReference(text)
+}
+
+// validateParameter converts the value to the type of the parameter and checks its constraints
+func validateParameter(definition *contracts.Parameter, value interface{}) (interface{}, error) {
+	switch definition.ParamType {
+	case contracts.ParamTypeString:
+		text, err := toString(value)
+		if err != nil {
+			return nil, err
+		}
+		return text, validateString(definition, text)
+
+	case contracts.ParamTypeStringList:
+		list, err := toStringList(value)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateItemCount(definition, len(list)); err != nil {
+			return nil, err
+		}
+		for _, text := range list {
+			if err = validateString(definition, text); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+
+	case contracts.ParamTypeInteger:
+		integer, err := toInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		return integer, validateAllowedValues(definition, strconv.Itoa(integer))
+
+	case contracts.ParamTypeBoolean:
+		boolean, err := toBoolean(value)
+		if err != nil {
+			return nil, err
+		}
+		return boolean, validateAllowedValues(definition, strconv.FormatBool(boolean))
+
+	case contracts.ParamTypeStringMap:
+		stringMap, err := toMap(value)
+		if err != nil {
+			return nil, err
+		}
+		return stringMap, validateItemCount(definition, len(stringMap))
+
+	case contracts.ParamTypeMapList:
+		mapList, err := toMapList(value)
+		if err != nil {
+			return nil, err
+		}
+		return mapList, validateItemCount(definition, len(mapList))
+
+	default:
+		// unknown types are validated by the service, if any
+		return value, nil
+	}
+}
+
+// validateString checks the allowed values and the number of characters of a string
+func validateString(definition *contracts.Parameter, text string) error {
+	if err := validateAllowedValues(definition, text); err != nil {
+		return err
+	}
+	length := utf8.RuneCountInString(text)
+	if definition.MinChars != nil && length < *definition.MinChars {
+		return fmt.Errorf("value %q must have at least %v characters", text, *definition.MinChars)
+	}
+	if definition.MaxChars != nil && length > *definition.MaxChars {
+		return fmt.Errorf("value %q must have at most %v characters", text, *definition.MaxChars)
+	}
+	return nil
+}
+
+// validateAllowedValues checks that the value is one of the allowed values, if any
+func validateAllowedValues(definition *contracts.Parameter, text string) error {
+	if len(definition.AllowedVal) == 0 {
+		return nil
+	}
+	for _, allowedValue := range definition.AllowedVal {
+		if text == allowedValue {
+			return nil
+		}
+	}
+	return fmt.Errorf("value %q is not one of the allowed values [%v]", text, strings.Join(definition.AllowedVal, ", "))
+}
+
+// validateItemCount checks the number of items of a list or a map
+func validateItemCount(definition *contracts.Parameter, count int) error {
+	if definition.MinItems != nil && count < *definition.MinItems {
+		return fmt.Errorf("value must have at least %v items", *definition.MinItems)
+	}
+	if definition.MaxItems != nil && count > *definition.MaxItems {
+		return fmt.Errorf("value must have at most %v items", *definition.MaxItems)
+	}
+	return nil
+}
+
+// toString converts a string or a scalar such as the number 10 or the boolean true of a YAML document to a string
+func toString(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case bool, int, int64, float64, json.Number:
+		return fmt.Sprint(value), nil
+	}
+	return "", fmt.Errorf("value must be a %v", contracts.ParamTypeString)
+}
+
+// toStringList returns the strings of a list
+func toStringList(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case []string:
+		return value, nil
+	case []interface{}:
+		list := make([]string, len(value))
+		for i, item := range value {
+			text, ok := item.(string)
+			if !ok {
+				return nil, errors.New("value must be a list of strings")
+			}
+			list[i] = text
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeStringList)
+	}
+}
+
+// toInteger converts a number or a string to an integer
+func toInteger(value interface{}) (int, error) {
+	switch value := value.(type) {
+	case int:
+		return value, nil
+	case int64:
+		return int(value), nil
+	case float64:
+		if value == math.Trunc(value) {
+			return int(value), nil
+		}
+	case json.Number:
+		if integer, err := strconv.Atoi(value.String()); err == nil {
+			return integer, nil
+		}
+	case string:
+		if integer, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return integer, nil
+		}
+	}
+	return 0, fmt.Errorf("value %v must be an %v", value, contracts.ParamTypeInteger)
+}
+
+// toBoolean converts a boolean or the strings true and false to a boolean
+func toBoolean(value interface{}) (bool, error) {
+	switch value := value.(type) {
+	case bool:
+		return value, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("value %v must be a %v", value, contracts.ParamTypeBoolean)
+}
+
+// toMap converts a map or its json representation to a map
+func toMap(value interface{}) (map[string]interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, nil
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeStringMap)
+			}
+			result[key] = v
+		}
+		return result, nil
+	case string:
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &result); err == nil && result != nil {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeStringMap)
+}
+
+// toMapList converts a list of maps, or a list of json maps, or the json representation of a list of maps to a list of maps
+func toMapList(value interface{}) ([]interface{}, error) {
+	var items []interface{}
+	switch value := value.(type) {
+	case []interface{}:
+		items = value
+	case []map[string]interface{}:
+		for _, item := range value {
+			items = append(items, item)
+		}
+	case []string:
+		for _, item := range value {
+			items = append(items, item)
+		}
+	case string:
+		if err := json.Unmarshal([]byte(value), &items); err != nil || items == nil {
+			return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeMapList)
+		}
+	default:
+		return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeMapList)
+	}
+
+	mapList := make([]interface{}, len(items))
+	for i, item := range items {
+		itemMap, err := toMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("value must be a %v", contracts.ParamTypeMapList)
+		}
+		mapList[i] = itemMap
+	}
+	return mapList, nil
+}
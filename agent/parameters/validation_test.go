@@ -0,0 +1,107 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package parameters provides utilities to parse ssm document parameters
+package parameters
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func TestValidateParameters(t *testing.T) {
+	definitions := map[string]*contracts.Parameter{
+		"name":     {ParamType: contracts.ParamTypeString, MinChars: intPointer(2), MaxChars: intPointer(8)},
+		"packages": {ParamType: contracts.ParamTypeStringList, MinItems: intPointer(1), AllowedVal: []string{"nginx", "redis"}},
+		"count":    {ParamType: contracts.ParamTypeInteger},
+		"reboot":   {ParamType: contracts.ParamTypeBoolean},
+		"tags":     {ParamType: contracts.ParamTypeStringMap, MaxItems: intPointer(2)},
+		"mounts":   {ParamType: contracts.ParamTypeMapList},
+		"token":    {ParamType: contracts.ParamTypeString, AllowedVal: []string{"a"}},
+		"optional": {ParamType: contracts.ParamTypeString},
+		"port":     {ParamType: contracts.ParamTypeString},
+		"debug":    {ParamType: contracts.ParamTypeString},
+		"hostname": {ParamType: contracts.ParamTypeString, AllowedVal: []string{"a"}},
+	}
+	values := map[string]interface{}{
+		"name":     "web",
+		"packages": []interface{}{"nginx"},
+		"count":    "3",
+		"reboot":   "True",
+		"tags":     `{"env": "prod"}`,
+		"mounts":   []interface{}{map[string]interface{}{"path": "/data"}, `{"path": "/logs"}`},
+		"token":    "{{ssm:token}}",
+		"extra":    "not defined",
+		"port":     10,
+		"debug":    true,
+		"hostname": "{{env:HOSTNAME}}",
+	}
+
+	validatedValues, err := ValidateParameters(log.NewMockLog(), definitions, values)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "web",
+		"packages": []interface{}{"nginx"},
+		"count":    3,
+		"reboot":   true,
+		"tags":     map[string]interface{}{"env": "prod"},
+		"mounts":   []interface{}{map[string]interface{}{"path": "/data"}, map[string]interface{}{"path": "/logs"}},
+		"token":    "{{ssm:token}}",
+		"extra":    "not defined",
+		"port":     "10",
+		"debug":    "true",
+		"hostname": "{{env:HOSTNAME}}",
+	}, validatedValues)
+}
+
+func TestValidateParametersErrors(t *testing.T) {
+	testCases := []struct {
+		definition contracts.Parameter
+		value      interface{}
+		error      string
+	}{
+		{contracts.Parameter{ParamType: contracts.ParamTypeString}, []interface{}{"a"}, "value must be a String"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeString, AllowedVal: []string{"a"}}, "{{ b }}", `value "{{ b }}" is not one of the allowed values [a]`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeString, AllowedVal: []string{"a"}}, "{{unknown:b}}", `value "{{unknown:b}}" is not one of the allowed values [a]`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeString, AllowedVal: []string{"a", "b"}}, "c", `value "c" is not one of the allowed values [a, b]`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeString, MinChars: intPointer(2)}, "a", `value "a" must have at least 2 characters`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeString, MaxChars: intPointer(2)}, "abc", `value "abc" must have at most 2 characters`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringList}, "a", "value must be a StringList"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringList}, []interface{}{1}, "value must be a list of strings"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringList, MaxItems: intPointer(1)}, []string{"a", "b"}, "value must have at most 1 items"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringList, MaxChars: intPointer(1)}, []string{"a", "bc"}, `value "bc" must have at most 1 characters`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeInteger}, "1.5", "value 1.5 must be an Integer"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeInteger}, 1.5, "value 1.5 must be an Integer"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeInteger, AllowedVal: []string{"1", "2"}}, 3.0, `value "3" is not one of the allowed values [1, 2]`},
+		{contracts.Parameter{ParamType: contracts.ParamTypeBoolean}, "yes", "value yes must be a Boolean"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringMap}, "[]", "value must be a StringMap"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeStringMap, MinItems: intPointer(1)}, map[string]interface{}{}, "value must have at least 1 items"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeMapList}, []interface{}{"a"}, "value must be a MapList"},
+		{contracts.Parameter{ParamType: contracts.ParamTypeMapList, MaxItems: intPointer(1)}, `[{}, {}]`, "value must have at most 1 items"},
+	}
+
+	for _, testCase := range testCases {
+		definition := testCase.definition
+		_, err := ValidateParameters(log.NewMockLog(), map[string]*contracts.Parameter{"param": &definition}, map[string]interface{}{"param": testCase.value})
+		if assert.Error(t, err) {
+			assert.Equal(t, "Invalid document parameters: Parameter param: "+testCase.error, err.Error())
+		}
+	}
+}
@@ -21,14 +21,16 @@ import (
 )
 
 var cachedRegion, cachedAvailabilityZone, cachedInstanceType, cachedInstanceID string
-var lock sync.RWMutex
+
+// lock guards the cached values, which the getters fetch and store on first use
+var lock sync.Mutex
 
 const errorMessage = "Failed to fetch %s. Data from vault is empty. %v"
 
 // InstanceID returns the current instance id
 func InstanceID() (string, error) {
-	lock.RLock()
-	defer lock.RUnlock()
+	lock.Lock()
+	defer lock.Unlock()
 	if cachedInstanceID != "" {
 		return cachedInstanceID, nil
 	} else {
@@ -51,8 +53,8 @@ func SetInstanceID(instanceID string) error {
 
 // InstanceType returns the current instance type
 func InstanceType() (string, error) {
-	lock.RLock()
-	defer lock.RUnlock()
+	lock.Lock()
+	defer lock.Unlock()
 	if cachedInstanceType != "" {
 		return cachedInstanceType, nil
 	} else {
@@ -76,8 +78,8 @@ func SetInstanceType(instanceType string) error {
 // Region returns the instance region
 func Region() (string, error) {
 	var err error
-	lock.RLock()
-	defer lock.RUnlock()
+	lock.Lock()
+	defer lock.Unlock()
 	if cachedRegion != "" {
 		return cachedRegion, nil
 	}
@@ -101,8 +103,8 @@ func SetRegion(region string) error {
 // AvailabilityZone returns the instance availability zone
 func AvailabilityZone() (string, error) {
 	var err error
-	lock.RLock()
-	defer lock.RUnlock()
+	lock.Lock()
+	defer lock.Unlock()
 	if cachedAvailabilityZone != "" {
 		return cachedAvailabilityZone, nil
 	}
@@ -135,6 +137,15 @@ func IsManagedInstance() (bool, error) {
 	return false, nil
 }
 
+// InstanceTag returns the value of the instance tag with the given key.
+// Tags are only available on EC2 instances that allow access to tags in instance metadata.
+func InstanceTag(key string) (string, error) {
+	if isManaged, _ := IsManagedInstance(); isManaged {
+		return "", fmt.Errorf("instance tags are not available on managed instances")
+	}
+	return metadata.GetMetadata("tags/instance/" + key)
+}
+
 // fetchInstanceID fetches the instance id with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
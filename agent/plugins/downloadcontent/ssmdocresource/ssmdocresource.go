@@ -30,6 +30,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // S3Resource is a struct for the remote resource of type git
@@ -86,7 +87,7 @@ func (ssmdoc *SSMDocResource) DownloadRemoteResource(log log.T, filesys filemana
 
 	var destinationFilePath string
 	if filesys.Exists(destinationPath) && filesys.IsDirectory(destinationPath) || os.IsPathSeparator(destinationPath[len(destinationPath)-1]) {
-		destinationFilePath = filepath.Join(destinationPath, filepath.Base(docName)+remoteresource.JSONExtension)
+		destinationFilePath = filepath.Join(destinationPath, filepath.Base(docName)+documentExtension(docResponse))
 
 	} else {
 		destinationFilePath = destinationPath
@@ -108,3 +109,11 @@ func (s3 *SSMDocResource) ValidateLocationInfo() (valid bool, err error) {
 	}
 	return true, nil
 }
+
+// documentExtension returns the file extension of the format of the document, JSON unless the service returned a YAML document
+func documentExtension(docResponse *ssm.GetDocumentOutput) string {
+	if docResponse.DocumentFormat != nil && strings.EqualFold(*docResponse.DocumentFormat, ssm.DocumentFormatYaml) {
+		return remoteresource.YAMLExtension
+	}
+	return remoteresource.JSONExtension
+}
@@ -202,6 +202,41 @@ func TestSSMDocResource_DownloadNoDestination(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestSSMDocResource_DownloadYaml(t *testing.T) {
+	depMock := new(ssmDocDepMock)
+	fileMock := filemock.FileSystemMock{}
+
+	locationInfo := `{
+		"name": "AWS-ExecuteCommand:10"
+	}`
+	content := "schemaVersion: '2.2'"
+	format := ssm.DocumentFormatYaml
+	docOutput := ssm.GetDocumentOutput{
+		Content:        &content,
+		DocumentFormat: &format,
+	}
+	ssmDocInfo, err := parseSourceInfo(locationInfo)
+	ssmresource := &SSMDocResource{
+		Info: ssmDocInfo,
+	}
+	dir := "destination"
+	depMock.On("GetDocument", logMock, "AWS-ExecuteCommand", "10").Return(&docOutput, nil)
+
+	fileMock.On("Exists", "destination").Return(true)
+	fileMock.On("IsDirectory", "destination").Return(true)
+	fileMock.On("MakeDirs", dir).Return(nil)
+	fileMock.On("WriteFile", filepath.Join(dir, "AWS-ExecuteCommand.yaml"), content).Return(nil)
+
+	ssmresource.ssmdocdep = depMock
+
+	err, result := ssmresource.DownloadRemoteResource(logMock, fileMock, "destination")
+
+	assert.NoError(t, err)
+	depMock.AssertExpectations(t)
+	fileMock.AssertExpectations(t)
+	assert.Equal(t, filepath.Join(dir, "AWS-ExecuteCommand.yaml"), result.Files[0])
+}
+
 func TestSSMDocResource_DownloadToOtherName(t *testing.T) {
 	depMock := new(ssmDocDepMock)
 	fileMock := filemock.FileSystemMock{}
@@ -19,7 +19,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -110,6 +113,14 @@ func TestValidateExecutionTimeout(t *testing.T) {
 	assert.Equal(t, defaultExecutionTimeoutInSeconds, num)
 }
 
+// TestGetStatusOfRebootExitCodes tests that the reserved exit codes request a reboot on every platform
+func TestGetStatusOfRebootExitCodes(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, GetStatus(appconfig.RebootExitCode, cancelFlag))
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, GetStatus(appconfig.RebootAndRerunExitCode, cancelFlag))
+	assert.Equal(t, contracts.ResultStatusFailed, GetStatus(appconfig.ErrorExitCode, cancelFlag))
+}
+
 func TestGetProxySetting(t *testing.T) {
 	var input []string
 	var outUrl, outNoProxy string
@@ -34,7 +34,7 @@ func GetStatus(exitCode int, cancelFlag task.CancelFlag) contracts.ResultStatus
 	switch exitCode {
 	case appconfig.SuccessExitCode:
 		return contracts.ResultStatusSuccess
-	case appconfig.RebootExitCode:
+	case appconfig.RebootExitCode, appconfig.RebootAndRerunExitCode:
 		return contracts.ResultStatusSuccessAndReboot
 	case appconfig.CommandStoppedPreemptivelyExitCode:
 		if cancelFlag.ShutDown() {
@@ -40,6 +40,10 @@ var TestCases = []TestCase{
 		Input:  appconfig.RebootExitCode,
 		Output: contracts.ResultStatusSuccessAndReboot,
 	},
+	{
+		Input:  appconfig.RebootAndRerunExitCode,
+		Output: contracts.ResultStatusSuccessAndReboot,
+	},
 	{
 		Input:  commandStoppedPreemptivelyExitCode,
 		Output: contracts.ResultStatusTimedOut,
@@ -16,8 +16,6 @@
 package rundocument
 
 import (
-	"encoding/json"
-
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -28,7 +26,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
-	"github.com/go-yaml/yaml"
 )
 
 type ExecDocument interface {
@@ -48,12 +45,10 @@ type ExecDocumentImpl struct {
 func (exec ExecDocumentImpl) ParseDocument(log log.T, documentRaw []byte, orchestrationDir string,
 	s3Bucket string, s3KeyPrefix string, messageID string, documentID string, defaultWorkingDirectory string,
 	params map[string]interface{}) (pluginsInfo []contracts.PluginState, err error) {
-	docContent := contracts.DocumentContent{}
-	if err := json.Unmarshal(documentRaw, &docContent); err != nil {
-		if err := yaml.Unmarshal(documentRaw, &docContent); err != nil {
-			log.Error("Unmarshaling remote resource document failed. Please make sure the document is in the correct JSON or YAML formal")
-			return pluginsInfo, err
-		}
+	docContent, err := docparser.ParseDocumentContent(documentRaw)
+	if err != nil {
+		log.Errorf("Unmarshaling remote resource document failed. Please make sure the document is in the correct JSON or YAML format - %v", err)
+		return pluginsInfo, err
 	}
 	parserInfo := docparser.DocumentParserInfo{
 		OrchestrationDir:  orchestrationDir,
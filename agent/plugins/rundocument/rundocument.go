@@ -210,7 +210,12 @@ func (p *Plugin) downloadDocumentFromSSM(log log.T, config contracts.Configurati
 		return "", err
 	}
 
-	pathToFile := filepath.Join(destination, filepath.Base(docName)+jsonExtension)
+	// the document is saved with the extension of its format, JSON unless the service returned a YAML document
+	extension := jsonExtension
+	if docResponse.DocumentFormat != nil && strings.EqualFold(*docResponse.DocumentFormat, ssm.DocumentFormatYaml) {
+		extension = yamlExtension
+	}
+	pathToFile := filepath.Join(destination, filepath.Base(docName)+extension)
 
 	if err = p.filesys.WriteFile(pathToFile, *docResponse.Content); err != nil {
 		log.Errorf("Error writing to file %v - %v", pathToFile, err)
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -36,6 +37,13 @@ const (
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
 )
 
+// Assign method to global variables to allow unittest to override
+var grantRunAsAccess = executers.GrantRunAsAccess
+var resolveParameters = func(log log.T, text string) (string, error) {
+	service := ssmparameterresolver.NewService()
+	return ssmparameterresolver.ResolveParametersInText(&service, log, text, ssmparameterresolver.ResolveOptions{IgnoreSecureParameters: false})
+}
+
 // Plugin is the type for the runscript plugin.
 type Plugin struct {
 	// ExecuteCommand is an object that can execute commands.
@@ -55,6 +63,9 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	RunAsUser        string
+	RunAsGroup       string
+	Environment      map[string]string
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -69,34 +80,85 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	} else if cancelFlag.Canceled() {
 		output.MarkAsCancelled()
 	} else {
-		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+		p.runCommandsRawInput(log, config, cancelFlag, output)
 	}
 }
 
 // runCommandsRawInput executes one set of commands and returns their output.
 // The input is in the default json unmarshal format (e.g. map[string]interface{}).
-func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+func (p *Plugin) runCommandsRawInput(log log.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var pluginInput RunScriptPluginInput
-	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	err := jsonutil.Remarshal(config.Properties, &pluginInput)
 	if err != nil {
-		errorString := fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err)
+		errorString := fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err)
 		output.MarkAsFailed(errorString)
 		return
 	}
-	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
+	// the user and group of the step default to the ones of the document
+	if pluginInput.RunAsUser == "" {
+		pluginInput.RunAsUser = config.RunAsUser
+	}
+	if pluginInput.RunAsGroup == "" {
+		pluginInput.RunAsGroup = config.RunAsGroup
+	}
+	pluginInput.Environment = addStandardEnvironment(pluginInput.Environment, config)
+	p.runCommands(log, config.PluginID, pluginInput, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+}
+
+// addStandardEnvironment adds the variables describing the step to the environment of the step,
+// they take precedence over the variables of the same name in the step input.
+func addStandardEnvironment(environment map[string]string, config contracts.Configuration) map[string]string {
+	standardEnvironment := map[string]string{
+		executers.EnvVarCommandID:              config.CommandID,
+		executers.EnvVarDocumentName:           config.DocumentName,
+		executers.EnvVarStepName:               config.PluginID,
+		executers.EnvVarOrchestrationDirectory: config.OrchestrationDirectory,
+	}
+	for name, value := range standardEnvironment {
+		if value == "" {
+			continue
+		}
+		if environment == nil {
+			environment = make(map[string]string)
+		}
+		environment[name] = value
+	}
+	return environment
+}
+
+// resolveEnvironment validates the names of the environment variables and resolves the parameter references in their values
+func resolveEnvironment(log log.T, environment map[string]string) (resolved map[string]string, err error) {
+	if len(environment) == 0 {
+		return environment, nil
+	}
+	resolved = make(map[string]string, len(environment))
+	for name, value := range environment {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if strings.Contains(value, "{{") {
+			// NOTE: Do not log the resolved value
+			if value, err = resolveParameters(log, value); err != nil {
+				return nil, fmt.Errorf("failed to resolve environment variable %v: %v", name, err)
+			}
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
 }
 
 // runCommands executes one set of commands and returns their output.
 func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var err error
 	var workingDir string
+	// documentOrchestrationDir is the orchestration directory of the document, it is private to the agent
+	documentOrchestrationDir := strings.TrimSuffix(orchestrationDirectory, pluginID)
 
 	if filepath.IsAbs(pluginInput.WorkingDirectory) {
 		workingDir = pluginInput.WorkingDirectory
 	} else {
-		orchestrationDir := strings.TrimSuffix(orchestrationDirectory, pluginID)
 		// The Document path is expected to have the name of the document
-		workingDir = filepath.Join(orchestrationDir, downloadsDir, pluginInput.WorkingDirectory)
+		workingDir = filepath.Join(documentOrchestrationDir, downloadsDir, pluginInput.WorkingDirectory)
 		if !fileutil.Exists(workingDir) {
 			workingDir = defaultWorkingDirectory
 		}
@@ -122,6 +184,26 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		return
 	}
 
+	// Resolve the secure parameters of the environment, they are only passed to the process and never written to disk
+	environment, err := resolveEnvironment(log, pluginInput.Environment)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to resolve environment variables. %v", err))
+		return
+	}
+
+	// Let the user running the commands access the script and the downloaded files until the step ends
+	executeOptions := executers.ExecuteOptions{
+		RunAsUser:   pluginInput.RunAsUser,
+		RunAsGroup:  pluginInput.RunAsGroup,
+		Environment: environment,
+	}
+	scriptPath, workingDir, revokeRunAsAccess, err := grantRunAsAccess(scriptPath, workingDir, documentOrchestrationDir, executeOptions)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to grant user %v group %v access to the script and working directory. %v", pluginInput.RunAsUser, pluginInput.RunAsGroup, err))
+		return
+	}
+	defer revokeRunAsAccess()
+
 	// Set execution time
 	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
 
@@ -130,7 +212,7 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	commandArguments := append(p.ShellArguments, scriptPath, appconfig.ExitCodeTrap)
 
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	exitCode, err := p.CommandExecuter.ExecuteWithOptions(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, executeOptions)
 
 	// Set output status
 	output.SetExitCode(exitCode)
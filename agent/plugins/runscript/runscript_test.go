@@ -15,8 +15,11 @@ package runscript
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
@@ -140,7 +143,13 @@ func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 			err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
 			assert.Nil(t, err)
 
-			p.runCommandsRawInput(logger, pluginID, rawPluginInput, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+			config := contracts.Configuration{
+				PluginID:                pluginID,
+				Properties:              rawPluginInput,
+				OrchestrationDirectory:  orchestrationDirectory,
+				DefaultWorkingDirectory: defaultWorkingDirectory,
+			}
+			p.runCommandsRawInput(logger, config, mockCancelFlag, mockIOHandler)
 		} else {
 			p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
 		}
@@ -149,6 +158,170 @@ func testRunScripts(t *testing.T, testCase TestCase, rawInput bool) {
 	testExecution(t, runScriptTester)
 }
 
+// TestRunScriptsWithRunAs tests that the steps run as the user and group of the step or else of the document.
+func TestRunScriptsWithRunAs(t *testing.T) {
+	origGrantRunAsAccess := grantRunAsAccess
+	defer func() { grantRunAsAccess = origGrantRunAsAccess }()
+	var grantedOptions []executers.ExecuteOptions
+	revoked := 0
+	grantRunAsAccess = func(scriptPath string, workingDir string, privateDir string, options executers.ExecuteOptions) (string, string, func(), error) {
+		grantedOptions = append(grantedOptions, options)
+		return scriptPath, workingDir, func() { revoked++ }, nil
+	}
+
+	// the group of the step overrides the one of the document, the user of the document applies
+	testCase := generateTestCaseOk("0")
+	testCase.Input.RunAsGroup = "deploy"
+	var rawPluginInput interface{}
+	err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
+	assert.Nil(t, err)
+	testCase.Input.RunAsUser = "ec2-user"
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		config := contracts.Configuration{
+			PluginID:                pluginID,
+			Properties:              rawPluginInput,
+			OrchestrationDirectory:  orchestrationDirectory,
+			DefaultWorkingDirectory: defaultWorkingDirectory,
+			RunAsUser:               "ec2-user",
+			RunAsGroup:              "ec2-user",
+		}
+		p.runCommandsRawInput(logger, config, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+	assert.Len(t, grantedOptions, 1)
+	assert.Equal(t, "ec2-user", grantedOptions[0].RunAsUser)
+	assert.Equal(t, "deploy", grantedOptions[0].RunAsGroup)
+	assert.Equal(t, 1, revoked)
+}
+
+// TestRunScriptsWithRunAsInDownloadsDirectory tests that a relative working directory, inside the private orchestration
+// directory, is handed to the user of the step along with the script and that the commands run from the granted copy.
+func TestRunScriptsWithRunAsInDownloadsDirectory(t *testing.T) {
+	origGrantRunAsAccess := grantRunAsAccess
+	defer func() { grantRunAsAccess = origGrantRunAsAccess }()
+	downloadsWorkingDir := filepath.Join(orchestrationDirectory, downloadsDir, "scripts")
+	assert.NoError(t, os.MkdirAll(downloadsWorkingDir, 0700))
+	defer os.RemoveAll(orchestrationDirectory)
+	var grantedWorkingDir, grantedPrivateDir string
+	grantRunAsAccess = func(scriptPath string, workingDir string, privateDir string, options executers.ExecuteOptions) (string, string, func(), error) {
+		grantedWorkingDir, grantedPrivateDir = workingDir, privateDir
+		return scriptPath, "/tmp/ssm-runas-1/scripts", func() {}, nil
+	}
+
+	testCase := generateTestCaseOk("0")
+	testCase.Input.WorkingDirectory = "scripts"
+	testCase.Input.RunAsUser = "ec2-user"
+
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockExecuter.On("ExecuteWithOptions", mock.Anything, "/tmp/ssm-runas-1/scripts", testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+			testCase.Output.ExitCode, testCase.ExecuterError)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+		p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+	assert.Equal(t, downloadsWorkingDir, grantedWorkingDir)
+	assert.Equal(t, orchestrationDirectory, grantedPrivateDir)
+}
+
+// TestRunScriptsWithEnvironment tests that the environment of the step is resolved and passed to the commands along with the standard variables.
+func TestRunScriptsWithEnvironment(t *testing.T) {
+	origResolveParameters := resolveParameters
+	defer func() { resolveParameters = origResolveParameters }()
+	resolveParameters = func(log log.T, text string) (string, error) {
+		assert.Equal(t, "{{ssm-secure:db-password}}", text)
+		return "secret", nil
+	}
+
+	testCase := generateTestCaseOk("0")
+	testCase.Input.Environment = map[string]string{
+		"DB_PASSWORD":       "{{ssm-secure:db-password}}",
+		"APP_ENV":           "production",
+		"AWS_SSM_STEP_NAME": "overridden",
+	}
+	var rawPluginInput interface{}
+	err := jsonutil.Remarshal(testCase.Input, &rawPluginInput)
+	assert.Nil(t, err)
+
+	var executeOptions executers.ExecuteOptions
+	runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		mockExecuter.On("ExecuteWithOptions", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			executeOptions = args.Get(8).(executers.ExecuteOptions)
+		}).Return(testCase.Output.ExitCode, testCase.ExecuterError)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		config := contracts.Configuration{
+			PluginID:                pluginID,
+			Properties:              rawPluginInput,
+			OrchestrationDirectory:  orchestrationDirectory,
+			DefaultWorkingDirectory: defaultWorkingDirectory,
+			CommandID:               "commandID",
+			DocumentName:            "AWS-RunShellScript",
+		}
+		p.runCommandsRawInput(logger, config, mockCancelFlag, mockIOHandler)
+	}
+
+	testExecution(t, runScriptTester)
+	assert.Equal(t, map[string]string{
+		"DB_PASSWORD":                     "secret",
+		"APP_ENV":                         "production",
+		"AWS_SSM_STEP_NAME":               pluginID,
+		"AWS_SSM_COMMAND_ID":              "commandID",
+		"AWS_SSM_DOCUMENT_NAME":           "AWS-RunShellScript",
+		"AWS_SSM_ORCHESTRATION_DIRECTORY": orchestrationDirectory,
+	}, executeOptions.Environment)
+}
+
+// TestRunScriptsRequestingReboot tests that the reserved exit codes request a reboot without failing the step.
+func TestRunScriptsRequestingReboot(t *testing.T) {
+	for _, exitCode := range []int{appconfig.RebootExitCode, appconfig.RebootAndRerunExitCode} {
+		testCase := generateTestCaseOk("0")
+		testCase.Output.ExitCode = exitCode
+		testCase.Output.Status = contracts.ResultStatusSuccessAndReboot
+		testCase.ExecuterError = fmt.Errorf("exit status %v", exitCode)
+
+		runScriptTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+			setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+			mockIOHandler.On("GetStdoutWriter").Return(testCase.Output.StdoutWriter)
+			mockIOHandler.On("GetStderrWriter").Return(testCase.Output.StderrWriter)
+			mockIOHandler.On("SetExitCode", exitCode).Return()
+			mockIOHandler.On("SetStatus", contracts.ResultStatusSuccessAndReboot).Return()
+			mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccessAndReboot)
+
+			p.runCommands(logger, pluginID, testCase.Input, orchestrationDirectory, defaultWorkingDirectory, mockCancelFlag, mockIOHandler)
+		}
+
+		testExecution(t, runScriptTester)
+	}
+}
+
+// TestResolveEnvironment tests that invalid variable names and unresolved parameters fail the step.
+func TestResolveEnvironment(t *testing.T) {
+	origResolveParameters := resolveParameters
+	defer func() { resolveParameters = origResolveParameters }()
+	resolveParameters = func(log log.T, text string) (string, error) {
+		return "", fmt.Errorf("parameter not found")
+	}
+
+	environment, err := resolveEnvironment(logger, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, environment)
+
+	_, err = resolveEnvironment(logger, map[string]string{"": "value"})
+	assert.Error(t, err)
+
+	_, err = resolveEnvironment(logger, map[string]string{"NAME=VALUE": "value"})
+	assert.Error(t, err)
+
+	_, err = resolveEnvironment(logger, map[string]string{"DB_PASSWORD": "{{ssm-secure:db-password}}"})
+	assert.Error(t, err)
+}
+
 // TestBucketsInDifferentRegions tests runScripts when S3Buckets are present in IAD and PDX region.
 func TestBucketsInDifferentRegions(t *testing.T) {
 	for _, testCase := range TestCases {
@@ -323,7 +496,10 @@ func testExecution(t *testing.T, commandtester CommandTester) {
 }
 
 func setExecuterExpectations(mockExecuter *executers.MockCommandExecuter, t TestCase, cancelFlag task.CancelFlag, p *Plugin) {
-	mockExecuter.On("NewExecute", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything).Return(
+	executeOptions := mock.MatchedBy(func(options executers.ExecuteOptions) bool {
+		return options.RunAsUser == t.Input.RunAsUser && options.RunAsGroup == t.Input.RunAsGroup
+	})
+	mockExecuter.On("ExecuteWithOptions", mock.Anything, t.Input.WorkingDirectory, t.Output.StdoutWriter, t.Output.StderrWriter, cancelFlag, mock.Anything, mock.Anything, mock.Anything, executeOptions).Return(
 		t.Output.ExitCode, t.ExecuterError)
 }
 
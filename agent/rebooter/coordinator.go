@@ -0,0 +1,190 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rebooter
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/association/scheduleexpression"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+var (
+	// waitInterval is the interval of the checks of a deferred reboot
+	waitInterval = 10 * time.Second
+
+	// preRebootHooksDir is the directory of the scripts run before the reboots, in the order of their names
+	preRebootHooksDir = filepath.Join(appconfig.DefaultProgramFolder, appconfig.PreRebootHooksDirName)
+
+	// rebootImminent is set once the pending reboot may proceed, until the reboot fails
+	rebootImminent int32
+)
+
+// Coordinator decides when the pending reboot proceeds: once the other documents finished and the reboot window is open,
+// or once the reboot was deferred for the maximum time since its request.
+type Coordinator struct {
+	log            log.T
+	window         scheduleexpression.ScheduleExpression
+	windowDuration time.Duration
+	maxDeferral    time.Duration
+	hookTimeout    time.Duration
+	hooksDir       string
+}
+
+// NewCoordinator returns the coordinator of the reboots with the configuration.
+// The request of a reboot issued before the agent started is complete, it is removed.
+func NewCoordinator(log log.T, config appconfig.RebootCfg) *Coordinator {
+	coordinator := &Coordinator{
+		log:            log,
+		windowDuration: time.Duration(config.WindowDurationMinutes) * time.Minute,
+		maxDeferral:    time.Duration(config.MaxDeferralMinutes) * time.Minute,
+		hookTimeout:    time.Duration(config.HookTimeoutSeconds) * time.Second,
+		hooksDir:       preRebootHooksDir,
+	}
+	if config.WindowExpression != "" {
+		window, err := scheduleexpression.CreateScheduleExpression(log, config.WindowExpression)
+		if err != nil {
+			log.Errorf("Ignoring the invalid reboot window %v, reboots are allowed any time, %v", config.WindowExpression, err)
+		} else {
+			coordinator.window = window
+		}
+	}
+	clearCompletedReboot(log)
+	return coordinator
+}
+
+// IsRebootPending returns true if a reboot was requested and not issued yet
+func (c *Coordinator) IsRebootPending() bool {
+	request, exists := loadPendingReboot()
+	return exists && !request.Issued
+}
+
+// IsRebootImminent returns true once the coordinator let the pending reboot proceed, the new documents then wait for the reboot
+func IsRebootImminent() bool {
+	return atomic.LoadInt32(&rebootImminent) == 1
+}
+
+// WaitForReboot blocks until the pending reboot may proceed
+func (c *Coordinator) WaitForReboot() {
+	defer atomic.StoreInt32(&rebootImminent, 1)
+	request, _ := loadPendingReboot()
+	deadline := request.RequestedAt.Add(c.maxDeferral)
+	deferral := ""
+	for {
+		now := time.Now()
+		if !now.Before(deadline) {
+			if deferral != "" {
+				c.log.Warnf("The reboot requested at %v was deferred for the maximum time, %v", request.RequestedAt, deferral)
+			}
+			return
+		}
+
+		reason := ""
+		if count := runningDocumentCount(); count > 0 {
+			reason = fmt.Sprintf("%v other documents are running", count)
+		} else if !c.isWindowOpen(now) {
+			reason = fmt.Sprintf("the reboot window opens at %v", c.window.Next(now))
+		}
+		if reason == "" {
+			return
+		}
+		if reason != deferral {
+			c.log.Infof("Deferring the reboot, %v", reason)
+			deferral = reason
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// isWindowOpen returns true if reboots are allowed at the time, that is a window started less than its duration before
+func (c *Coordinator) isWindowOpen(t time.Time) bool {
+	if c.window == nil {
+		return true
+	}
+	return !c.window.Next(t.Add(-c.windowDuration)).After(t)
+}
+
+// RunPreRebootHooks runs the scripts of the pre-reboot hooks directory in the order of their names.
+// The failures of the hooks are logged, they do not prevent the reboot.
+func (c *Coordinator) RunPreRebootHooks() {
+	files, err := ioutil.ReadDir(c.hooksDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.log.Errorf("Failed to list the pre-reboot hooks, %v", err)
+		}
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		c.runHook(filepath.Join(c.hooksDir, file.Name()), file)
+	}
+}
+
+// runHook runs the hook script, it is killed after the hook timeout
+func (c *Coordinator) runHook(path string, file os.FileInfo) {
+	command, err := hookCommand(path, file)
+	if err != nil {
+		c.log.Warnf("Skipping the pre-reboot hook %v, %v", path, err)
+		return
+	}
+	var output bytes.Buffer
+	command.Stdout = &output
+	command.Stderr = &output
+
+	c.log.Infof("Running the pre-reboot hook %v", path)
+	if err = command.Start(); err != nil {
+		c.log.Errorf("Failed to start the pre-reboot hook %v, %v", path, err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(c.hookTimeout):
+		killHook(command)
+		<-done
+		err = fmt.Errorf("timed out after %v", c.hookTimeout)
+	}
+
+	if err != nil {
+		c.log.Errorf("The pre-reboot hook %v failed, %v: %v", path, err, strings.TrimSpace(output.String()))
+	} else {
+		c.log.Debugf("The pre-reboot hook %v completed: %v", path, strings.TrimSpace(output.String()))
+	}
+}
+
+// Reboot marks the pending reboot as issued and reboots the machine, the request is removed once the agent starts again.
+// The request stays pending if the reboot cannot be issued.
+func (c *Coordinator) Reboot() {
+	if err := setRebootIssued(true); err != nil {
+		c.log.Errorf("Failed to persist the reboot, %v", err)
+	}
+	if err := reboot(c.log); err != nil {
+		c.log.Errorf("error in rebooting the machine, %v", err)
+		setRebootIssued(false)
+		atomic.StoreInt32(&rebootImminent, 0)
+	}
+}
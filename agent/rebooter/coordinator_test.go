@@ -0,0 +1,135 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rebooter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestMain keeps the reboot requests of the tests out of the data store of the agent
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "rebooter")
+	if err != nil {
+		panic(err)
+	}
+	pendingRebootPath = filepath.Join(dir, appconfig.RebootRootDirName, appconfig.PendingRebootFileName)
+	preRebootHooksDir = filepath.Join(dir, appconfig.PreRebootHooksDirName)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestCoordinator(config appconfig.RebootCfg) *Coordinator {
+	logger := log.NewMockLog()
+	logger.On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	return NewCoordinator(logger, config)
+}
+
+func waitForReboot(coordinator *Coordinator) chan bool {
+	done := make(chan bool)
+	go func() {
+		coordinator.WaitForReboot()
+		close(done)
+	}()
+	return done
+}
+
+func TestPendingRebootSurvivesRestarts(t *testing.T) {
+	defer os.Remove(pendingRebootPath)
+	assert.False(t, newTestCoordinator(appconfig.RebootCfg{}).IsRebootPending())
+
+	assert.NoError(t, persistPendingReboot())
+	first, _ := loadPendingReboot()
+	assert.NoError(t, persistPendingReboot())
+	second, _ := loadPendingReboot()
+	assert.True(t, first.RequestedAt.Equal(second.RequestedAt), "the first request is kept")
+
+	// the request is pending until the reboot is issued, and removed when the agent starts again
+	assert.True(t, newTestCoordinator(appconfig.RebootCfg{}).IsRebootPending())
+	assert.NoError(t, setRebootIssued(true))
+	coordinator := newTestCoordinator(appconfig.RebootCfg{})
+	assert.False(t, coordinator.IsRebootPending())
+	_, exists := loadPendingReboot()
+	assert.False(t, exists)
+}
+
+func TestWaitForRebootWaitsForTheRunningDocuments(t *testing.T) {
+	defer func(interval time.Duration) { waitInterval = interval }(waitInterval)
+	waitInterval = 10 * time.Millisecond
+	defer os.Remove(pendingRebootPath)
+	atomic.StoreInt32(&rebootImminent, 0)
+	defer atomic.StoreInt32(&rebootImminent, 0)
+	assert.NoError(t, persistPendingReboot())
+
+	DocumentStarted("document-1")
+	DocumentStarted("document-2")
+	done := waitForReboot(newTestCoordinator(appconfig.RebootCfg{MaxDeferralMinutes: 60}))
+
+	DocumentFinished("document-1")
+	select {
+	case <-done:
+		assert.FailNow(t, "the reboot did not wait for the running document")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.False(t, IsRebootImminent())
+	DocumentFinished("document-2")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "the reboot was not released once the documents finished")
+	}
+	assert.True(t, IsRebootImminent())
+}
+
+func TestWaitForRebootIsBoundedByTheMaxDeferral(t *testing.T) {
+	defer func(interval time.Duration) { waitInterval = interval }(waitInterval)
+	waitInterval = 10 * time.Millisecond
+	defer os.Remove(pendingRebootPath)
+	defer atomic.StoreInt32(&rebootImminent, 0)
+
+	// the reboot was requested before the agent restarted, longer ago than the max deferral
+	assert.NoError(t, savePendingReboot(pendingReboot{RequestedAt: time.Now().Add(-2 * time.Minute)}))
+	DocumentStarted("document")
+	defer DocumentFinished("document")
+
+	select {
+	case <-waitForReboot(newTestCoordinator(appconfig.RebootCfg{MaxDeferralMinutes: 1})):
+	case <-time.After(5 * time.Second):
+		assert.FailNow(t, "the reboot was deferred beyond the max deferral")
+	}
+}
+
+func TestRebootWindow(t *testing.T) {
+	// the window opens every day at 2:00 for an hour
+	coordinator := newTestCoordinator(appconfig.RebootCfg{WindowExpression: "cron(0 0 2 * * ? *)", WindowDurationMinutes: 60})
+	day := time.Date(2018, 3, 1, 0, 0, 0, 0, time.Local)
+	assert.False(t, coordinator.isWindowOpen(day.Add(time.Hour+59*time.Minute)))
+	assert.True(t, coordinator.isWindowOpen(day.Add(2*time.Hour)))
+	assert.True(t, coordinator.isWindowOpen(day.Add(2*time.Hour+30*time.Minute)))
+	assert.False(t, coordinator.isWindowOpen(day.Add(3*time.Hour)))
+
+	// an invalid window is ignored
+	logger := log.NewMockLog()
+	assert.True(t, NewCoordinator(logger, appconfig.RebootCfg{WindowExpression: "cron(invalid)"}).isWindowOpen(day))
+}
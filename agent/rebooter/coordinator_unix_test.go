@@ -0,0 +1,57 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package rebooter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPreRebootHooks(t *testing.T) {
+	assert.NoError(t, os.MkdirAll(preRebootHooksDir, appconfig.ReadWriteExecuteAccess))
+	defer os.RemoveAll(preRebootHooksDir)
+	trace := filepath.Join(filepath.Dir(preRebootHooksDir), "trace")
+	defer os.Remove(trace)
+	hooks := map[string]string{
+		"10-first":   "#!/bin/sh\necho first >> " + trace,
+		"20-fails":   "#!/bin/sh\necho failing >> " + trace + "\nexit 1",
+		"30-hangs":   "#!/bin/sh\nsleep 10",
+		"40-last":    "#!/bin/sh\necho last >> " + trace,
+		"50-ignored": "#!/bin/sh\necho ignored >> " + trace,
+	}
+	for name, script := range hooks {
+		mode := os.FileMode(0700)
+		if name == "50-ignored" {
+			mode = 0600
+		}
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(preRebootHooksDir, name), []byte(script), mode))
+	}
+
+	coordinator := newTestCoordinator(appconfig.RebootCfg{HookTimeoutSeconds: 1})
+	start := time.Now()
+	coordinator.RunPreRebootHooks()
+	assert.True(t, time.Since(start) < 5*time.Second, "the hanging hook was not killed")
+
+	content, err := ioutil.ReadFile(trace)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nfailing\nlast\n", string(content))
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rebooter
+
+import (
+	"sync"
+)
+
+// runningDocuments counts the documents running by document id, a pending reboot waits for them
+var runningDocuments = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: make(map[string]int)}
+
+// DocumentStarted records that the document is running
+func DocumentStarted(documentID string) {
+	runningDocuments.Lock()
+	defer runningDocuments.Unlock()
+	runningDocuments.counts[documentID]++
+}
+
+// DocumentFinished records that the document is not running anymore, it either completed or stopped for a reboot
+func DocumentFinished(documentID string) {
+	runningDocuments.Lock()
+	defer runningDocuments.Unlock()
+	if runningDocuments.counts[documentID]--; runningDocuments.counts[documentID] <= 0 {
+		delete(runningDocuments.counts, documentID)
+	}
+}
+
+// runningDocumentCount returns the number of documents running
+func runningDocumentCount() int {
+	runningDocuments.Lock()
+	defer runningDocuments.Unlock()
+	return len(runningDocuments.counts)
+}
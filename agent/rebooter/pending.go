@@ -0,0 +1,95 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rebooter
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// pendingReboot is the reboot request persisted until the machine reboots, it survives the restarts of the agent
+type pendingReboot struct {
+	RequestedAt time.Time
+	// Issued is set once the reboot is issued, the request is complete when the agent starts again
+	Issued bool
+}
+
+var (
+	// pendingRebootPath is the file of the pending reboot request
+	pendingRebootPath = filepath.Join(appconfig.DefaultDataStorePath, appconfig.RebootRootDirName, appconfig.PendingRebootFileName)
+
+	pendingMutex sync.Mutex
+)
+
+// persistPendingReboot records the reboot request, unless a request is already pending
+func persistPendingReboot() error {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	if _, exists := loadPendingReboot(); exists {
+		return nil
+	}
+	return savePendingReboot(pendingReboot{RequestedAt: time.Now()})
+}
+
+// loadPendingReboot returns the pending reboot request if any
+func loadPendingReboot() (request pendingReboot, exists bool) {
+	if err := jsonutil.UnmarshalFile(pendingRebootPath, &request); err != nil {
+		return request, false
+	}
+	return request, true
+}
+
+// savePendingReboot writes the pending reboot request
+func savePendingReboot(request pendingReboot) error {
+	content, err := jsonutil.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(filepath.Dir(pendingRebootPath)); err != nil {
+		return err
+	}
+	_, err = fileutil.WriteIntoFileWithPermissions(pendingRebootPath, content, os.FileMode(int(appconfig.ReadWriteAccess)))
+	return err
+}
+
+// setRebootIssued marks the pending reboot request as issued or not
+func setRebootIssued(issued bool) error {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	request, exists := loadPendingReboot()
+	if !exists {
+		request.RequestedAt = time.Now()
+	}
+	request.Issued = issued
+	return savePendingReboot(request)
+}
+
+// clearCompletedReboot removes the request of the reboot issued before the agent started, the machine rebooted since
+func clearCompletedReboot(log log.T) {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	if request, exists := loadPendingReboot(); exists && request.Issued {
+		log.Infof("The reboot requested at %v is complete", request.RequestedAt)
+		if err := fileutil.DeleteFile(pendingRebootPath); err != nil {
+			log.Errorf("Failed to remove the completed reboot request, %v", err)
+		}
+	}
+}
@@ -38,7 +38,12 @@ func RebootMachine(log log.T) {
 	}
 }
 
+// RequestPendingReboot requests a reboot of the machine, it returns false if a reboot is already being processed.
+// The request is persisted first, it stays pending across the restarts of the agent until the machine reboots.
 func RequestPendingReboot(log log.T) bool {
+	if err := persistPendingReboot(); err != nil {
+		log.Errorf("failed to persist the reboot request, %v", err)
+	}
 
 	//non-blocking send
 	select {
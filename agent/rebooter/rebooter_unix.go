@@ -18,6 +18,8 @@ package rebooter
 
 import (
 	"bytes"
+	"fmt"
+	"os"
 	"os/exec"
 	"syscall"
 
@@ -46,3 +48,19 @@ func reboot(log log.T) (err error) {
 	}
 	return
 }
+
+// hookCommand returns the command running the pre-reboot hook, the hooks must be executable.
+// The hook leads its process group, killed as a whole on timeout.
+func hookCommand(path string, file os.FileInfo) (*exec.Cmd, error) {
+	if file.Mode().Perm()&0111 == 0 {
+		return nil, fmt.Errorf("the file is not executable")
+	}
+	command := exec.Command(path)
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return command, nil
+}
+
+// killHook kills the process group of the hook
+func killHook(command *exec.Cmd) error {
+	return syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+}
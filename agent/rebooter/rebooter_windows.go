@@ -18,8 +18,12 @@ package rebooter
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
@@ -44,3 +48,16 @@ func reboot(log log.T) (err error) {
 	}
 	return
 }
+
+// hookCommand returns the command running the pre-reboot hook, the PowerShell scripts run with PowerShell
+func hookCommand(path string, file os.FileInfo) (*exec.Cmd, error) {
+	if strings.EqualFold(filepath.Ext(path), ".ps1") {
+		return exec.Command(appconfig.PowerShellPluginCommandName, "-ExecutionPolicy", "Bypass", "-File", path), nil
+	}
+	return exec.Command(path), nil
+}
+
+// killHook kills the process of the hook
+func killHook(command *exec.Cmd) error {
+	return command.Process.Kill()
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redaction tracks the secret values resolved while running documents and masks them in the output,
+// the logs and the replies of the agent.
+package redaction
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Mask replaces the secret values
+	Mask = "********"
+
+	// minSecretLength is the length under which values are not masked,
+	// masking very short values would make any output unreadable without protecting anything
+	minSecretLength = 4
+)
+
+// store holds the secret values registered by the process
+var store = &secretStore{secrets: make(map[string]bool)}
+
+// secretStore holds secret values and the replacer masking them
+type secretStore struct {
+	mutex     sync.RWMutex
+	secrets   map[string]bool
+	replacer  *strings.Replacer
+	maxLength int
+}
+
+// Register adds secret values to mask. Every line of a multi-line secret is masked as well,
+// since commands often print them line by line.
+func Register(values ...string) {
+	store.register(values...)
+}
+
+// HasSecrets returns whether any secret value was registered
+func HasSecrets() bool {
+	return store.hasSecrets()
+}
+
+// Redact masks the registered secret values in the text
+func Redact(text string) string {
+	return store.redact(text)
+}
+
+// Reset forgets all the registered secret values
+func Reset() {
+	store.reset()
+}
+
+func (s *secretStore) register(values ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := false
+	for _, value := range values {
+		for _, secret := range append([]string{value}, strings.Split(value, "\n")...) {
+			secret = strings.TrimRight(secret, "\r")
+			if len(strings.TrimSpace(secret)) < minSecretLength || s.secrets[secret] {
+				continue
+			}
+			s.secrets[secret] = true
+			added = true
+		}
+	}
+	if added {
+		s.buildReplacer()
+	}
+}
+
+// buildReplacer creates the replacer masking the secrets, the longest secrets are matched first
+func (s *secretStore) buildReplacer() {
+	secrets := make([]string, 0, len(s.secrets))
+	for secret := range s.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return secrets[i] < secrets[j]
+	})
+
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, Mask)
+	}
+	s.replacer = strings.NewReplacer(pairs...)
+	s.maxLength = len(secrets[0])
+}
+
+func (s *secretStore) hasSecrets() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.replacer != nil
+}
+
+func (s *secretStore) redact(text string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.replacer == nil || text == "" {
+		return text
+	}
+	return s.replacer.Replace(text)
+}
+
+// pendingLength returns the length of the longest suffix of the text that starts a secret,
+// which cannot be released before knowing whether the secret follows.
+func (s *secretStore) pendingLength(text string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	length := s.maxLength - 1
+	if length > len(text) {
+		length = len(text)
+	}
+	for ; length > 0; length-- {
+		suffix := text[len(text)-length:]
+		for secret := range s.secrets {
+			if strings.HasPrefix(secret, suffix) {
+				return length
+			}
+		}
+	}
+	return 0
+}
+
+func (s *secretStore) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.secrets = make(map[string]bool)
+	s.replacer = nil
+	s.maxLength = 0
+}
+
+// StreamRedactor masks the secret values in a stream written in chunks,
+// including the secrets split across several chunks.
+type StreamRedactor struct {
+	pending string
+}
+
+// Redact returns the redacted part of the stream that can be released after writing the chunk.
+// The end of the chunk is held back while it may be the beginning of a secret.
+func (r *StreamRedactor) Redact(chunk []byte) []byte {
+	if r.pending == "" && !store.hasSecrets() {
+		return chunk
+	}
+	text := store.redact(r.pending + string(chunk))
+	pendingLength := store.pendingLength(text)
+	r.pending = text[len(text)-pendingLength:]
+	return []byte(text[:len(text)-pendingLength])
+}
+
+// Flush returns the part of the stream held back, at the end of the stream
+func (r *StreamRedactor) Flush() []byte {
+	text := store.redact(r.pending)
+	r.pending = ""
+	return []byte(text)
+}
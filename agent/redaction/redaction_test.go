@@ -0,0 +1,61 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package redaction tracks the secret values resolved while running documents and masks them in the output,
+// the logs and the replies of the agent.
+package redaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	defer Reset()
+
+	assert.False(t, HasSecrets())
+	assert.Equal(t, "password is secret", Redact("password is secret"))
+
+	Register("secret", "secret-value", "abc", "")
+	assert.True(t, HasSecrets())
+	assert.Equal(t, "password is "+Mask+", token is "+Mask+", abc", Redact("password is secret, token is secret-value, abc"))
+
+	Reset()
+	assert.False(t, HasSecrets())
+	assert.Equal(t, "password is secret", Redact("password is secret"))
+}
+
+func TestRedactMultiLineSecret(t *testing.T) {
+	defer Reset()
+
+	Register("-----BEGIN KEY-----\r\nMIIEvQIBADANBgkqhkiG9w0BAQEFAASC\r\n-----END KEY-----")
+	assert.Equal(t, "line "+Mask+"\n", Redact("line MIIEvQIBADANBgkqhkiG9w0BAQEFAASC\n"))
+}
+
+func TestStreamRedactor(t *testing.T) {
+	defer Reset()
+
+	redactor := new(StreamRedactor)
+	assert.Equal(t, []byte("no secret yet"), redactor.Redact([]byte("no secret yet")))
+
+	Register("Pa55w0rd!")
+	var output []byte
+	for _, chunk := range []string{"password: Pa5", "5w0", "rd! and Pa55"} {
+		output = append(output, redactor.Redact([]byte(chunk))...)
+	}
+	assert.Equal(t, "password: "+Mask+" and ", string(output))
+
+	output = append(output, redactor.Flush()...)
+	assert.Equal(t, "password: "+Mask+" and Pa55", string(output))
+}
@@ -27,6 +27,29 @@ type CancelPayload struct {
 	CancelMessageID string `json:"CancelMessageId"`
 }
 
+// OfflineCancelRequest represents the json structure of a cancel request submitted to the offline service.
+type OfflineCancelRequest struct {
+	CommandID string `json:"CommandId"`
+}
+
+// OfflineCommandErrorExtension is the extension of the file written next to an invalid offline command with the reason it is invalid
+const OfflineCommandErrorExtension = ".error"
+
+// OfflineCommand represents the json structure of a command submitted to the offline service.
+// The document is either inline in DocumentContent or loaded by the service from the JSON or YAML file at DocumentPath.
+type OfflineCommand struct {
+	DocumentContent         *contracts.DocumentContent `json:"DocumentContent,omitempty"`
+	DocumentPath            string                     `json:"DocumentPath,omitempty"`
+	DocumentName            string                     `json:"DocumentName,omitempty"`
+	Parameters              map[string]interface{}     `json:"Parameters,omitempty"`
+	OutputS3BucketName      string                     `json:"OutputS3BucketName,omitempty"`
+	OutputS3KeyPrefix       string                     `json:"OutputS3KeyPrefix,omitempty"`
+	CloudWatchLogGroupName  string                     `json:"CloudWatchLogGroupName,omitempty"`
+	CloudWatchOutputEnabled string                     `json:"CloudWatchOutputEnabled,omitempty"`
+	StepTimeoutSeconds      int                        `json:"StepTimeoutSeconds,omitempty"`
+	Comment                 string                     `json:"Comment,omitempty"`
+}
+
 // SendCommandPayload parallels the structure of a send command MDS message payload.
 type SendCommandPayload struct {
 	Parameters              map[string]interface{}    `json:"Parameters"`
@@ -37,6 +60,7 @@ type SendCommandPayload struct {
 	OutputS3BucketName      string                    `json:"OutputS3BucketName"`
 	CloudWatchLogGroupName  string                    `json:"CloudWatchLogGroupName"`
 	CloudWatchOutputEnabled string                    `json:"CloudWatchOutputEnabled"`
+	StepTimeoutSeconds      int                       `json:"StepTimeoutSeconds,omitempty"`
 }
 
 // SendReplyPayload represents the json structure of a reply sent to MDS.
@@ -68,6 +68,7 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 		log.Errorf("initial processing in EngineProcessor encountered error: %v", err)
 		return
 	}
+	s.messages.setStarted(true)
 
 	log.Info("Starting message polling")
 	if s.messagePollJob, err = scheduler.Every(pollMessageFrequencyMinutes).Minutes().Run(s.messagePollLoop); err != nil {
@@ -87,7 +88,8 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 }
 
 func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err error) {
-	//first stop sending failed replies to the service and the message poller
+	//first stop sending failed replies to the service, the message poller and the pushed messages
+	s.messages.setStarted(false)
 	s.stop()
 	//second stop the message processor
 	s.processor.Stop(stopType)
@@ -159,6 +161,16 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 		return
 	}
 
+	// the message may be both polled and pushed, only the first delivery is processed
+	if !s.messages.begin(*msg.MessageId) {
+		log.Debug("Message already received, ignoring")
+		return
+	}
+	processed := false
+	defer func() {
+		s.messages.end(*msg.MessageId, processed)
+	}()
+
 	if strings.HasPrefix(*msg.Topic, string(SendCommandTopicPrefix)) {
 		docState, err = loadDocStateFromSendCommand(context, msg, s.orchestrationRootDir)
 		if err != nil {
@@ -177,12 +189,14 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 		if err = s.service.FailMessage(log, *msg.MessageId, mdsService.InternalHandlerException); err != nil {
 			sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
 		}
+		processed = true
 		return
 	}
 	if err = s.service.AcknowledgeMessage(log, *msg.MessageId); err != nil {
 		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
 		return
 	}
+	processed = true
 
 	log.Debugf("Ack done. Received message - messageId - %v", *msg.MessageId)
 
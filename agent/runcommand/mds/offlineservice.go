@@ -23,7 +23,7 @@ import (
 	"errors"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
-	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -35,14 +35,16 @@ import (
 
 type offlineService struct {
 	TopicPrefix         string
+	CancelTopicPrefix   string
 	newCommandDir       string
+	cancelCommandDir    string
 	submittedCommandDir string
 	commandResultDir    string
 	invalidCommandDir   string
 }
 
-// NewOfflineService initializes a service that looks for work in a local command folder
-func NewOfflineService(log log.T, topicPrefix string) (Service, error) {
+// NewOfflineService initializes a service that looks for work in a local command folder, and for cancel requests in a local cancel folder
+func NewOfflineService(log log.T, topicPrefix string, cancelTopicPrefix string) (Service, error) {
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	// Create and harden local document folder if needed
 	err := fileutil.MakeDirs(appconfig.LocalCommandRoot)
@@ -50,10 +52,18 @@ func NewOfflineService(log log.T, topicPrefix string) (Service, error) {
 		log.Errorf("Failed to create local command directory %v : %v", appconfig.LocalCommandRoot, err.Error())
 		return nil, err
 	}
+	// the cancel requests are submitted the same way as the command documents
+	err = fileutil.MakeDirs(appconfig.LocalCommandRootCancel)
+	if err != nil {
+		log.Errorf("Failed to create local cancel directory %v : %v", appconfig.LocalCommandRootCancel, err.Error())
+		return nil, err
+	}
 	err = fileutil.MakeDirs(appconfig.LocalCommandRootCompleted)
 	return &offlineService{
 		TopicPrefix:         topicPrefix,
+		CancelTopicPrefix:   cancelTopicPrefix,
 		newCommandDir:       appconfig.LocalCommandRoot,
+		cancelCommandDir:    appconfig.LocalCommandRootCancel,
 		submittedCommandDir: appconfig.LocalCommandRootSubmitted,
 		invalidCommandDir:   appconfig.LocalCommandRootInvalid,
 		commandResultDir:    appconfig.LocalCommandRootCompleted,
@@ -84,29 +94,29 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 		messageID := fmt.Sprintf("aws.ssm.%v.%v", commandID, instanceID)
 
 		// Parse file
-		var content contracts.DocumentContent
-		if errContent := jsonutil.UnmarshalFile(docPath, &content); errContent != nil {
+		payload, errContent := loadOfflineCommand(docPath, docName)
+		if errContent != nil {
 			log.Errorf("Error parsing command document %v:\n%v", docName, errContent)
-			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
+			if errMove := moveInvalidCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID, errContent); errMove != nil {
 				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
 			}
 			continue
 		}
-		debugContent, _ := jsonutil.Marshal(content)
+		payload.CommandID = commandID
+		debugContent, _ := jsonutil.Marshal(payload.DocumentContent)
 		log.Debugf("Local command content:\n%v", debugContent)
 
 		// Turn it into a message
-		payload := &messageContracts.SendCommandPayload{DocumentContent: content, CommandID: commandID, DocumentName: docName}
 		var payloadstr string
 		if payloadstr, err = jsonutil.Marshal(payload); err != nil {
 			log.Errorf("Error marshalling message for command document %v with message ID %v:\n%v", docName, messageID, err)
-			if errMove := moveCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID); errMove != nil {
+			if errMove := moveInvalidCommandDocument(ols.newCommandDir, ols.invalidCommandDir, docName, commandID, err); errMove != nil {
 				log.Errorf("Command %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
 			}
 			continue
 		}
 		created := times.ToIso8601UTC(time.Now())
-		topic := fmt.Sprintf("%v.%v", ols.TopicPrefix, docName)
+		topic := fmt.Sprintf("%v.%v", ols.TopicPrefix, payload.DocumentName)
 		message := &ssmmds.Message{
 			CreatedDate: &created,
 			Destination: &instanceID,
@@ -123,9 +133,108 @@ func (ols *offlineService) GetMessages(log log.T, instanceID string) (messages *
 		messages.Messages = append(messages.Messages, message)
 	}
 
+	messages.Messages = append(messages.Messages, ols.getCancelMessages(log, instanceID)...)
 	return messages, nil
 }
 
+// loadOfflineCommand parses a submitted command into the send command payload. The submitted file is either an
+// OfflineCommand envelope, with the document inline or at a path on disk, or the command document itself.
+func loadOfflineCommand(docPath string, docName string) (*messageContracts.SendCommandPayload, error) {
+	var command messageContracts.OfflineCommand
+	if err := jsonutil.UnmarshalFile(docPath, &command); err != nil || (command.DocumentContent == nil && command.DocumentPath == "") {
+		// the submitted files have no extension, the format of the document is detected by its content
+		content, err := docparser.LoadDocumentContent(docPath)
+		if err != nil {
+			return nil, err
+		}
+		return &messageContracts.SendCommandPayload{DocumentContent: content, DocumentName: docName}, nil
+	}
+
+	payload := &messageContracts.SendCommandPayload{
+		Parameters:              command.Parameters,
+		DocumentName:            command.DocumentName,
+		OutputS3BucketName:      command.OutputS3BucketName,
+		OutputS3KeyPrefix:       command.OutputS3KeyPrefix,
+		CloudWatchLogGroupName:  command.CloudWatchLogGroupName,
+		CloudWatchOutputEnabled: command.CloudWatchOutputEnabled,
+		StepTimeoutSeconds:      command.StepTimeoutSeconds,
+	}
+	if command.DocumentContent != nil {
+		payload.DocumentContent = *command.DocumentContent
+	} else {
+		content, err := docparser.LoadDocumentContent(command.DocumentPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load document %v: %v", command.DocumentPath, err)
+		}
+		payload.DocumentContent = content
+		if payload.DocumentName == "" {
+			payload.DocumentName = strings.TrimSuffix(filepath.Base(command.DocumentPath), filepath.Ext(command.DocumentPath))
+		}
+	}
+	if payload.DocumentName == "" {
+		payload.DocumentName = docName
+	}
+	return payload, nil
+}
+
+// getCancelMessages looks for new local cancel requests on the filesystem and parses them into cancel messages
+func (ols *offlineService) getCancelMessages(log log.T, instanceID string) []*ssmmds.Message {
+	var filenames []string
+	var err error
+	if filenames, err = fileutil.GetFileNames(ols.cancelCommandDir); err != nil {
+		// the cancel folder only exists once a cancel request was submitted
+		return nil
+	}
+	messages := make([]*ssmmds.Message, 0, len(filenames))
+	for _, requestName := range filenames {
+		requestPath := filepath.Join(ols.cancelCommandDir, requestName)
+		log.Debugf("Found local cancel request %v | %v", requestName, requestPath)
+
+		commandID := uuid.NewV4().String()
+		messageID := fmt.Sprintf("aws.ssm.%v.%v", commandID, instanceID)
+
+		var request messageContracts.OfflineCancelRequest
+		errContent := jsonutil.UnmarshalFile(requestPath, &request)
+		if errContent == nil && request.CommandID == "" {
+			errContent = errors.New("CommandId is required")
+		}
+		if errContent != nil {
+			log.Errorf("Error parsing cancel request %v: %v", requestName, errContent)
+			if errMove := moveInvalidCommandDocument(ols.cancelCommandDir, ols.invalidCommandDir, requestName, commandID, errContent); errMove != nil {
+				log.Errorf("Cancel request %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
+			}
+			continue
+		}
+
+		// the offline processor identifies its commands by the message ID built in GetMessages
+		payload := &messageContracts.CancelPayload{CancelMessageID: fmt.Sprintf("aws.ssm.%v.%v", request.CommandID, instanceID)}
+		payloadstr, errMarshal := jsonutil.Marshal(payload)
+		if errMarshal != nil {
+			log.Errorf("Error marshalling message for cancel request %v with message ID %v:\n%v", requestName, messageID, errMarshal)
+			if errMove := moveInvalidCommandDocument(ols.cancelCommandDir, ols.invalidCommandDir, requestName, commandID, errMarshal); errMove != nil {
+				log.Errorf("Cancel request %v was invalid but failed to move to invalid folder: %v", commandID, errMove.Error())
+			}
+			continue
+		}
+		created := times.ToIso8601UTC(time.Now())
+		topic := fmt.Sprintf("%v%v", ols.CancelTopicPrefix, request.CommandID)
+		message := &ssmmds.Message{
+			CreatedDate: &created,
+			Destination: &instanceID,
+			MessageId:   &messageID,
+			Payload:     &payloadstr,
+			Topic:       &topic,
+		}
+		if errMove := moveCommandDocument(ols.cancelCommandDir, ols.submittedCommandDir, requestName, commandID); errMove != nil {
+			log.Errorf("Cancel request %v was valid but failed to move to submitted folder: %v", commandID, errMove.Error())
+			continue
+		}
+
+		messages = append(messages, message)
+	}
+	return messages
+}
+
 // TODO:MF: clean up old documents in dstDir?  Or maybe do that in SendReply?  Maybe both
 // moveCommandDocument moves a command into its final destination and attaches the command ID file extension
 func moveCommandDocument(srcDir string, dstDir string, docName string, commandID string) error {
@@ -148,6 +257,15 @@ func moveCommandDocument(srcDir string, dstDir string, docName string, commandID
 	return nil
 }
 
+// moveInvalidCommandDocument moves an invalid command into the invalid folder and writes the reason it is invalid next to it
+func moveInvalidCommandDocument(srcDir string, dstDir string, docName string, commandID string, reason error) error {
+	if err := moveCommandDocument(srcDir, dstDir, docName, commandID); err != nil {
+		return err
+	}
+	reasonPath := filepath.Join(dstDir, strings.Join([]string{docName, commandID}, ".")+messageContracts.OfflineCommandErrorExtension)
+	return fileutil.WriteAllText(reasonPath, reason.Error())
+}
+
 func (ols *offlineService) AcknowledgeMessage(log log.T, messageID string) error {
 	return nil
 }
@@ -16,10 +16,13 @@ package service
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +30,7 @@ var logger = log.NewMockLog()
 
 const (
 	newCommands       = "testdata/new"
+	cancelCommands    = "testdata/new/cancel"
 	submittedCommands = "testdata/new/submitted"
 	invalidCommands   = "testdata/new/invalid"
 	completeDir       = "testdata/new/completed"
@@ -59,7 +63,49 @@ func TestInvalid(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(messages.Messages))
 	assert.Equal(t, 0, FileCount(newCommands))
-	assert.Equal(t, 1, FileCount(invalidCommands))
+	// the invalid command and the reason it is invalid
+	assert.Equal(t, 2, FileCount(invalidCommands))
+}
+
+func TestYaml(t *testing.T) {
+	service := GetTestService()
+
+	defer CleanTestDirs()
+	err := SubmitTestDoc("validcommand22.yaml")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "2.2", payload.DocumentContent.SchemaVersion)
+	assert.Equal(t, "aws:runShellScript", payload.DocumentContent.MainSteps[0].Action)
+	assert.Equal(t, 0, FileCount(newCommands))
+	assert.Equal(t, 1, FileCount(submittedCommands))
+}
+
+func TestInvalidYaml(t *testing.T) {
+	service := GetTestService()
+
+	defer CleanTestDirs()
+	err := SubmitTestDoc("invalidcommand.yaml")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(messages.Messages))
+	files, _ := fileutil.GetFileNames(invalidCommands)
+	assert.Equal(t, 2, len(files))
+	for _, file := range files {
+		if strings.HasSuffix(file, messageContracts.OfflineCommandErrorExtension) {
+			reason, err := fileutil.ReadAllText(filepath.Join(invalidCommands, file))
+			assert.Nil(t, err)
+			assert.Contains(t, reason, "line 4")
+		}
+	}
 }
 
 func TestBothVersions(t *testing.T) {
@@ -80,6 +126,74 @@ func TestBothVersions(t *testing.T) {
 	assert.Equal(t, 2, FileCount(submittedCommands))
 }
 
+func TestEnvelope(t *testing.T) {
+	service := GetTestService()
+
+	defer CleanTestDirs()
+	err := SubmitTestDoc("envelopecommand.json")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	assert.Equal(t, "foo.RunShellScript", *messages.Messages[0].Topic)
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "RunShellScript", payload.DocumentName)
+	assert.Equal(t, "2.2", payload.DocumentContent.SchemaVersion)
+	assert.Equal(t, map[string]interface{}{"commands": []interface{}{"echo hello"}}, payload.Parameters)
+	assert.Equal(t, "bucket", payload.OutputS3BucketName)
+	assert.Equal(t, 600, payload.StepTimeoutSeconds)
+	assert.NotEmpty(t, payload.CommandID)
+	assert.Equal(t, 1, FileCount(submittedCommands))
+}
+
+func TestEnvelopeDocumentPath(t *testing.T) {
+	service := GetTestService()
+
+	defer CleanTestDirs()
+	var err error
+	err = SubmitTestDoc("envelopepathcommand.json")
+	assert.Nil(t, err)
+	err = SubmitTestDoc("envelopemissingpathcommand.json")
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "RunShellScript", payload.DocumentName)
+	assert.Equal(t, 1, len(payload.DocumentContent.MainSteps))
+	assert.Equal(t, "aws:runShellScript", payload.DocumentContent.MainSteps[0].Action)
+	assert.Equal(t, 1, FileCount(submittedCommands))
+	assert.Equal(t, 2, FileCount(invalidCommands))
+}
+
+func TestCancel(t *testing.T) {
+	service := GetTestService()
+
+	defer CleanTestDirs()
+	err := fileutil.WriteAllText(filepath.Join(cancelCommands, "request"), `{"CommandId": "commandID"}`)
+	assert.Nil(t, err)
+	err = fileutil.WriteAllText(filepath.Join(cancelCommands, "invalidrequest"), `{}`)
+	assert.Nil(t, err)
+
+	messages, err := service.GetMessages(logger, "i-bar")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+	assert.Equal(t, "bar.commandID", *messages.Messages[0].Topic)
+	var payload messageContracts.CancelPayload
+	assert.Nil(t, jsonutil.Unmarshal(*messages.Messages[0].Payload, &payload))
+	assert.Equal(t, "aws.ssm.commandID.i-bar", payload.CancelMessageID)
+	assert.Equal(t, 0, FileCount(cancelCommands))
+	assert.Equal(t, 1, FileCount(submittedCommands))
+	assert.Equal(t, 2, FileCount(invalidCommands))
+}
+
 func TestOfflineService_SendReply(t *testing.T) {
 	service := GetTestService()
 	defer CleanTestDirs()
@@ -91,7 +205,9 @@ func GetTestService() Service {
 	CleanTestDirs()
 	return &offlineService{
 		TopicPrefix:         "foo",
+		CancelTopicPrefix:   "bar.",
 		newCommandDir:       newCommands,
+		cancelCommandDir:    cancelCommands,
 		submittedCommandDir: submittedCommands,
 		invalidCommandDir:   invalidCommands,
 		commandResultDir:    completeDir,
@@ -120,6 +236,10 @@ func CleanTestDirs() {
 	for _, file := range files {
 		fileutil.DeleteFile(filepath.Join(newCommands, file))
 	}
+	files, _ = fileutil.GetFileNames(cancelCommands)
+	for _, file := range files {
+		fileutil.DeleteFile(filepath.Join(cancelCommands, file))
+	}
 	files, _ = fileutil.GetFileNames(completeDir)
 	for _, file := range files {
 		fileutil.DeleteFile(filepath.Join(completeDir, file))
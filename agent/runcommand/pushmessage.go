@@ -0,0 +1,94 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+)
+
+// processedMessageRetention is how long the ID of a processed message is kept to drop its other deliveries
+const processedMessageRetention = 1 * time.Hour
+
+// messageTracker tracks the messages received both by polling and by a push transport, so that each is processed once.
+// Its zero value is ready to use.
+type messageTracker struct {
+	mutex    sync.Mutex
+	started  bool
+	stopped  bool
+	messages map[string]time.Time
+}
+
+// setStarted records that the service processes the messages, or not anymore
+func (t *messageTracker) setStarted(started bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if started {
+		t.started = true
+	} else {
+		t.stopped = true
+	}
+}
+
+// isRunning returns true if the service was started and not stopped
+func (t *messageTracker) isRunning() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.started && !t.stopped
+}
+
+// begin returns false if the message is being processed or was processed already, else records it as being processed
+func (t *messageTracker) begin(messageID string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	for id, processed := range t.messages {
+		if !processed.IsZero() && now.Sub(processed) > processedMessageRetention {
+			delete(t.messages, id)
+		}
+	}
+	if _, exists := t.messages[messageID]; exists {
+		return false
+	}
+	if t.messages == nil {
+		t.messages = make(map[string]time.Time)
+	}
+	t.messages[messageID] = time.Time{}
+	return true
+}
+
+// end records that the message was processed, or forgets it if it was not, so that it can be received again
+func (t *messageTracker) end(messageID string, processed bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if processed {
+		t.messages[messageID] = time.Now()
+	} else {
+		delete(t.messages, messageID)
+	}
+}
+
+// ProcessPushedMessage processes a message pushed to the agent by a push transport, as a polled message.
+// The messages pushed while the service is not running are dropped, they are received again by polling.
+func (s *RunCommandService) ProcessPushedMessage(msg *ssmmds.Message) {
+	log := s.context.Log()
+	if !s.messages.isRunning() {
+		log.Debugf("%v is not running, the pushed message will be polled", s.name)
+		return
+	}
+	processMessage(s, msg)
+}
@@ -97,6 +97,7 @@ type RunCommandService struct {
 	processorStopPolicy *sdkutil.StopPolicy
 	pollAssociations    bool
 	processor           processor.Processor
+	messages            messageTracker
 }
 
 // NewOfflineProcessor initialize a new offline command document processor
@@ -214,7 +215,7 @@ func processSendReply(log log.T, messageID string, mdsService mdsService.Service
 }
 
 var newOfflineService = func(log log.T) (mdsService.Service, error) {
-	return mdsService.NewOfflineService(log, string(SendCommandTopicPrefixOffline))
+	return mdsService.NewOfflineService(log, string(SendCommandTopicPrefixOffline), string(CancelCommandTopicPrefixOffline))
 }
 
 var newMdsService = func(config appconfig.SsmagentConfig) mdsService.Service {
@@ -195,6 +195,7 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	if err != nil {
 		return nil, err
 	}
+	applyStepTimeout(docState.InstancePluginsInformation, parsedMessage.StepTimeoutSeconds)
 	parsedMessageContent, _ := jsonutil.Marshal(parsedMessage)
 
 	var parsedContentJson *gabs.Container
@@ -242,6 +243,19 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	return &docState, nil
 }
 
+// applyStepTimeout bounds the timeout of each step by the step timeout of the command, the steps with a shorter timeout keep theirs.
+// It is not a deadline of the whole command, the steps are timed out one by one.
+func applyStepTimeout(plugins []contracts.PluginState, timeoutSeconds int) {
+	if timeoutSeconds <= 0 {
+		return
+	}
+	for i := range plugins {
+		if plugins[i].Configuration.TimeoutSeconds <= 0 || plugins[i].Configuration.TimeoutSeconds > timeoutSeconds {
+			plugins[i].Configuration.TimeoutSeconds = timeoutSeconds
+		}
+	}
+}
+
 func isUpdatePlugin(plugins map[string]*contracts.PluginResult) bool {
 	for name, _ := range plugins {
 		if name == appconfig.PluginEC2ConfigUpdate || name == appconfig.PluginNameAwsAgentUpdate {
@@ -84,6 +84,27 @@ func TestGenerateCloudWatchConfigWithoutEmptyValuesInParsedMessage(t *testing.T)
 	assert.NotNil(t, err)
 }
 
+func TestApplyStepTimeout(t *testing.T) {
+	plugins := []contracts.PluginState{
+		{Id: "noTimeout"},
+		{Id: "shorterTimeout", Configuration: contracts.Configuration{TimeoutSeconds: 30}},
+		{Id: "longerTimeout", Configuration: contracts.Configuration{TimeoutSeconds: 7200}},
+	}
+
+	applyStepTimeout(plugins, 600)
+	assert.Equal(t, 600, plugins[0].Configuration.TimeoutSeconds)
+	assert.Equal(t, 30, plugins[1].Configuration.TimeoutSeconds)
+	assert.Equal(t, 600, plugins[2].Configuration.TimeoutSeconds)
+}
+
+func TestApplyStepTimeoutWithoutTimeout(t *testing.T) {
+	plugins := []contracts.PluginState{{Id: "noTimeout"}, {Id: "timeout", Configuration: contracts.Configuration{TimeoutSeconds: 30}}}
+
+	applyStepTimeout(plugins, 0)
+	assert.Equal(t, 0, plugins[0].Configuration.TimeoutSeconds)
+	assert.Equal(t, 30, plugins[1].Configuration.TimeoutSeconds)
+}
+
 //getSampleParsedMessage returns a mocked SendCommandPayload
 func getSampleParsedMessage(logGroupName string, outputEnabled string) messageContracts.SendCommandPayload {
 
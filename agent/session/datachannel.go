@@ -0,0 +1,160 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var errChannelClosed = errors.New("session channel is closed")
+
+var (
+	// outputWindow is the number of output messages sent without being acknowledged by the client
+	outputWindow int64 = 100
+
+	// writeTimeout is the timeout of the messages written to the connection
+	writeTimeout = 10 * time.Second
+)
+
+// DataChannel carries the data of a session over its websocket connection, with flow control on the output.
+type DataChannel struct {
+	connection *websocket.Conn
+
+	// sendMutex keeps the output messages in sequence, writeMutex serializes the writes to the connection
+	sendMutex  sync.Mutex
+	writeMutex sync.Mutex
+
+	mutex        sync.Mutex
+	acknowledged *sync.Cond
+	closed       bool
+
+	// sequenceNumber is the number of the last output message sent, acknowledgedNumber the last one acknowledged
+	sequenceNumber     int64
+	acknowledgedNumber int64
+
+	// receivedNumber is the number of the last input message received
+	receivedNumber int64
+}
+
+// NewDataChannel returns the data channel of the connection
+func NewDataChannel(connection *websocket.Conn) *DataChannel {
+	channel := &DataChannel{connection: connection}
+	channel.acknowledged = sync.NewCond(&channel.mutex)
+	return channel
+}
+
+// Send sends the payload as an output message, it blocks while the window of unacknowledged output messages is full
+func (c *DataChannel) Send(payload []byte) error {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	c.mutex.Lock()
+	for !c.closed && c.sequenceNumber-c.acknowledgedNumber >= outputWindow {
+		c.acknowledged.Wait()
+	}
+	if c.closed {
+		c.mutex.Unlock()
+		return errChannelClosed
+	}
+	c.sequenceNumber++
+	sequenceNumber := c.sequenceNumber
+	c.mutex.Unlock()
+
+	return c.write(DataMessage{
+		MessageType:    DataMessageTypeOutput,
+		SequenceNumber: sequenceNumber,
+		Payload:        payload,
+	})
+}
+
+// Receive returns the next input, size, eof or close message of the client.
+// The acknowledgements of the output are handled on the way and the input messages received again are skipped,
+// the new input messages must be acknowledged once processed.
+func (c *DataChannel) Receive() (msg DataMessage, err error) {
+	for {
+		msg = DataMessage{}
+		if err = c.connection.ReadJSON(&msg); err != nil {
+			c.markClosed()
+			return msg, err
+		}
+
+		switch msg.MessageType {
+		case DataMessageTypeAcknowledge:
+			c.mutex.Lock()
+			if msg.SequenceNumber > c.acknowledgedNumber && msg.SequenceNumber <= c.sequenceNumber {
+				c.acknowledgedNumber = msg.SequenceNumber
+				c.acknowledged.Broadcast()
+			}
+			c.mutex.Unlock()
+
+		case DataMessageTypeInput:
+			if msg.SequenceNumber <= c.receivedNumber {
+				if err = c.Acknowledge(msg); err != nil {
+					return msg, err
+				}
+				continue
+			}
+			c.receivedNumber = msg.SequenceNumber
+			return msg, nil
+
+		case DataMessageTypeSize, DataMessageTypeEOF, DataMessageTypeClose:
+			return msg, nil
+		}
+	}
+}
+
+// SendEOF tells the client that the output is complete, after the output messages sent before
+func (c *DataChannel) SendEOF() error {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	return c.write(DataMessage{MessageType: DataMessageTypeEOF})
+}
+
+// Acknowledge acknowledges the input message and the ones before it
+func (c *DataChannel) Acknowledge(msg DataMessage) error {
+	return c.write(DataMessage{
+		MessageType:    DataMessageTypeAcknowledge,
+		SequenceNumber: msg.SequenceNumber,
+	})
+}
+
+// Close sends the close message with the reason and closes the connection
+func (c *DataChannel) Close(reason string) error {
+	c.write(DataMessage{
+		MessageType: DataMessageTypeClose,
+		Reason:      reason,
+	})
+	c.markClosed()
+	return c.connection.Close()
+}
+
+// write writes the message to the connection
+func (c *DataChannel) write(msg DataMessage) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.connection.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.connection.WriteJSON(msg)
+}
+
+// markClosed unblocks the pending sends once the connection is closed
+func (c *DataChannel) markClosed() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	c.acknowledged.Broadcast()
+}
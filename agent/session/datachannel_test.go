@@ -0,0 +1,133 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimeout = 5 * time.Second
+
+// newConnectionPair returns the two ends of a websocket connection, the agent end and the client end
+func newConnectionPair(t *testing.T) (*websocket.Conn, *websocket.Conn, func()) {
+	connections := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if connection, err := upgrader.Upgrade(w, r, nil); err == nil {
+			connections <- connection
+		}
+	}))
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	agent := <-connections
+	return agent, client, func() {
+		agent.Close()
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestSendBlocksWhileTheWindowIsFull(t *testing.T) {
+	defer func(window int64) { outputWindow = window }(outputWindow)
+	outputWindow = 2
+	agent, client, closeConnections := newConnectionPair(t)
+	defer closeConnections()
+	channel := NewDataChannel(agent)
+	go channel.Receive()
+
+	assert.NoError(t, channel.Send([]byte("1")))
+	assert.NoError(t, channel.Send([]byte("2")))
+	sent := make(chan error)
+	go func() { sent <- channel.Send([]byte("3")) }()
+	select {
+	case <-sent:
+		assert.FailNow(t, "the output was sent beyond the window")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	var msg DataMessage
+	for i := int64(1); i <= 2; i++ {
+		assert.NoError(t, client.ReadJSON(&msg))
+		assert.Equal(t, DataMessageTypeOutput, msg.MessageType)
+		assert.Equal(t, i, msg.SequenceNumber)
+	}
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeAcknowledge, SequenceNumber: 1}))
+	select {
+	case err := <-sent:
+		assert.NoError(t, err)
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "the output was not sent after the acknowledgement")
+	}
+	assert.NoError(t, client.ReadJSON(&msg))
+	assert.Equal(t, int64(3), msg.SequenceNumber)
+	assert.Equal(t, []byte("3"), msg.Payload)
+}
+
+func TestSendFailsOnceClosed(t *testing.T) {
+	defer func(window int64) { outputWindow = window }(outputWindow)
+	outputWindow = 1
+	agent, client, closeConnections := newConnectionPair(t)
+	defer closeConnections()
+	channel := NewDataChannel(agent)
+
+	assert.NoError(t, channel.Send([]byte("1")))
+	sent := make(chan error)
+	go func() { sent <- channel.Send([]byte("2")) }()
+	channel.Close("done")
+	select {
+	case err := <-sent:
+		assert.Equal(t, errChannelClosed, err)
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "the blocked output was not released")
+	}
+
+	var output, closing DataMessage
+	assert.NoError(t, client.ReadJSON(&output))
+	assert.Equal(t, DataMessageTypeOutput, output.MessageType)
+	assert.NoError(t, client.ReadJSON(&closing))
+	assert.Equal(t, DataMessage{MessageType: DataMessageTypeClose, Reason: "done"}, closing)
+}
+
+func TestReceiveSkipsRepeatedInput(t *testing.T) {
+	agent, client, closeConnections := newConnectionPair(t)
+	defer closeConnections()
+	channel := NewDataChannel(agent)
+
+	first := DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 1, Payload: []byte("ls\n")}
+	assert.NoError(t, client.WriteJSON(first))
+	assert.NoError(t, client.WriteJSON(first))
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeSize, Cols: 120, Rows: 40}))
+
+	msg, err := channel.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, first, msg)
+	assert.NoError(t, channel.Acknowledge(msg))
+
+	// the repeated input is acknowledged again but not returned
+	msg, err = channel.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, DataMessage{MessageType: DataMessageTypeSize, Cols: 120, Rows: 40}, msg)
+	for i := 0; i < 2; i++ {
+		var ack DataMessage
+		assert.NoError(t, client.ReadJSON(&ack))
+		assert.Equal(t, DataMessage{MessageType: DataMessageTypeAcknowledge, SequenceNumber: 1}, ack)
+	}
+}
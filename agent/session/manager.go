@@ -0,0 +1,163 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
+
+const (
+	name = "SessionManager"
+
+	// stopTimeout is how long the stop of the module waits for the sessions to end
+	stopTimeout = 10 * time.Second
+)
+
+// sessionIdPattern matches the valid session ids, the id names the orchestration directory of the session
+var sessionIdPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Manager is the core module running the sessions requested over the HummingBird channel
+type Manager struct {
+	context              context.T
+	config               appconfig.SessionCfg
+	service              service.Service
+	orchestrationRootDir string
+	idleTimeout          time.Duration
+
+	mutex    sync.Mutex
+	sessions map[string]*session
+	stopped  bool
+	running  sync.WaitGroup
+}
+
+// NewManager returns the core module running the sessions
+func NewManager(context context.T) *Manager {
+	sessionContext := context.With("[" + name + "]")
+	log := sessionContext.Log()
+	config := context.AppConfig()
+
+	instanceID, err := platform.InstanceID()
+	if instanceID == "" {
+		log.Errorf("no instanceID provided, %v", err)
+		return nil
+	}
+
+	return &Manager{
+		context: sessionContext,
+		config:  config.Session,
+		service: service.NewService(config.Agent.Region, config.Mfs.Endpoint, nil),
+		orchestrationRootDir: filepath.Join(
+			appconfig.DefaultDataStorePath,
+			instanceID,
+			appconfig.DefaultDocumentRootDirName,
+			config.Agent.OrchestrationRootDir),
+		idleTimeout: time.Duration(config.Session.IdleSessionTimeoutMinutes) * time.Minute,
+		sessions:    make(map[string]*session),
+	}
+}
+
+// ICoreModule implementation
+
+// ModuleName returns the name of module
+func (m *Manager) ModuleName() string {
+	return name
+}
+
+// ModuleExecute has nothing to start, the sessions are started by the requests of the HummingBird channel
+func (m *Manager) ModuleExecute(context context.T) (err error) {
+	if m.config.RunAsUser != "" {
+		m.context.Log().Infof("Sessions run as user %v", m.config.RunAsUser)
+	}
+	return nil
+}
+
+// ModuleRequestStop ends the running sessions and rejects the new ones
+func (m *Manager) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	log := m.context.Log()
+	m.mutex.Lock()
+	m.stopped = true
+	for _, s := range m.sessions {
+		s.requestStop()
+	}
+	m.mutex.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		m.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(stopTimeout):
+		log.Warnf("Timed out waiting for the sessions to end")
+	}
+	return nil
+}
+
+// StartSession starts the session requested by the message of the service, the requests of running sessions are ignored
+func (m *Manager) StartSession(msg service.ChannelMessage) {
+	log := m.context.Log()
+	var input StartSessionInput
+	if err := json.Unmarshal([]byte(msg.Payload), &input); err != nil {
+		log.Errorf("Ignoring invalid session request %v, %v", msg.MessageId, err)
+		return
+	}
+	if !sessionIdPattern.MatchString(input.SessionId) {
+		log.Errorf("Ignoring session request %v with invalid session id %v", msg.MessageId, input.SessionId)
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped {
+		log.Warnf("Ignoring session request %v, the agent is stopping", msg.MessageId)
+		return
+	}
+	if _, exists := m.sessions[input.SessionId]; exists {
+		log.Debugf("Session %v is already running", input.SessionId)
+		return
+	}
+
+	s := &session{
+		context:          m.context,
+		input:            input,
+		config:           m.config,
+		service:          m.service,
+		orchestrationDir: fileutil.BuildPath(m.orchestrationRootDir, input.SessionId),
+		idleTimeout:      m.idleTimeout,
+		activity:         make(chan bool, 1),
+		stop:             make(chan bool),
+	}
+	m.sessions[input.SessionId] = s
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		s.run()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		delete(m.sessions, input.SessionId)
+	}()
+}
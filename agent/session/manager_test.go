@@ -0,0 +1,173 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestManager(t *testing.T, server *servicemock.Server) (*Manager, func()) {
+	ctx := context.NewMockDefault()
+	ctx.Log().(*log.Mock).On("Warnf", mock.Anything, mock.Anything).Return(nil)
+	dir, err := ioutil.TempDir("", "sessions")
+	assert.NoError(t, err)
+	manager := &Manager{
+		context:              ctx,
+		service:              service.NewService("us-east-1", server.URL, credentials.AnonymousCredentials),
+		orchestrationRootDir: dir,
+		idleTimeout:          time.Minute,
+		sessions:             make(map[string]*session),
+	}
+	return manager, func() { os.RemoveAll(dir) }
+}
+
+func newStartSessionMessage(sessionId string, sessionType SessionType) service.ChannelMessage {
+	return service.ChannelMessage{
+		MessageType: service.MessageTypeStartSession,
+		MessageId:   "message-" + sessionId,
+		Payload:     `{"SessionId": "` + sessionId + `", "SessionType": "` + string(sessionType) + `"}`,
+	}
+}
+
+func receiveSession(t *testing.T, server *servicemock.Server) *servicemock.SessionConnection {
+	select {
+	case session := <-server.Sessions:
+		return session
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "no session connection opened")
+		return nil
+	}
+}
+
+// readUntil acknowledges the output of the session until the condition on the output or a close message,
+// it returns the output and the reason of the close if any
+func readUntil(t *testing.T, connection *servicemock.SessionConnection, condition func(output string) bool) (output string, reason string) {
+	connection.Connection.SetReadDeadline(time.Now().Add(testTimeout))
+	for !condition(output) {
+		var msg DataMessage
+		if !assert.NoError(t, connection.Connection.ReadJSON(&msg)) {
+			return
+		}
+		switch msg.MessageType {
+		case DataMessageTypeOutput:
+			output += string(msg.Payload)
+			connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeAcknowledge, SequenceNumber: msg.SequenceNumber})
+		case DataMessageTypeClose:
+			return output, msg.Reason
+		}
+	}
+	return
+}
+
+func untilClosed(output string) bool {
+	return false
+}
+
+func TestShellSession(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+
+	manager.StartSession(newStartSessionMessage("session-1", SessionTypeShell))
+	connection := receiveSession(t, server)
+	assert.Equal(t, "session-1", connection.SessionId)
+
+	client := connection.Connection
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeSize, Cols: 100, Rows: 30}))
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 1, Payload: []byte("echo hello $((40 + 2))\n")}))
+	output, reason := readUntil(t, connection, func(output string) bool { return strings.Contains(output, "hello 42") })
+	assert.Empty(t, reason, output)
+
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 2, Payload: []byte("exit\n")}))
+	_, reason = readUntil(t, connection, untilClosed)
+	assert.Equal(t, "shell exited", reason)
+
+	// the session is gone once its transcript is recorded
+	assert.NoError(t, manager.ModuleRequestStop(contracts.StopTypeSoftStop))
+	assert.Empty(t, manager.sessions)
+	transcript, err := ioutil.ReadFile(filepath.Join(manager.orchestrationRootDir, "session-1", transcriptFileName))
+	assert.NoError(t, err)
+	assert.Contains(t, string(transcript), "hello 42")
+}
+
+func TestSessionsEnd(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+
+	// a session without input ends after the idle timeout
+	manager.idleTimeout = 200 * time.Millisecond
+	manager.StartSession(newStartSessionMessage("idle", SessionTypeShell))
+	_, reason := readUntil(t, receiveSession(t, server), untilClosed)
+	assert.Equal(t, "no input for 200ms", reason)
+
+	// the sessions of unknown types and users end right away
+	manager.StartSession(newStartSessionMessage("unknown-type", "Unknown"))
+	_, reason = readUntil(t, receiveSession(t, server), untilClosed)
+	assert.Equal(t, "unsupported session type Unknown", reason)
+
+	manager.config.RunAsUser = "no-such-session-user"
+	manager.StartSession(newStartSessionMessage("unknown-user", SessionTypeShell))
+	_, reason = readUntil(t, receiveSession(t, server), untilClosed)
+	assert.Contains(t, reason, "failed to run the session as user no-such-session-user")
+
+	// the client closes its session
+	manager.config.RunAsUser = ""
+	manager.idleTimeout = time.Minute
+	manager.StartSession(newStartSessionMessage("closed", SessionTypeShell))
+	connection := receiveSession(t, server)
+	assert.NoError(t, connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeClose}))
+	_, reason = readUntil(t, connection, untilClosed)
+	assert.Equal(t, "closed by the client", reason)
+
+	// the agent stops its sessions, and ignores the requests once stopping
+	manager.StartSession(newStartSessionMessage("stopped", SessionTypeShell))
+	connection = receiveSession(t, server)
+	manager.StartSession(newStartSessionMessage("stopped", SessionTypeShell))
+	assert.NoError(t, manager.ModuleRequestStop(contracts.StopTypeSoftStop))
+	_, reason = readUntil(t, connection, untilClosed)
+	assert.Equal(t, "agent stopping", reason)
+	manager.StartSession(newStartSessionMessage("late", SessionTypeShell))
+	assert.Empty(t, manager.sessions)
+	assert.Empty(t, server.Sessions)
+}
+
+func TestStartSessionIgnoresInvalidRequests(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+
+	manager.StartSession(service.ChannelMessage{MessageId: "invalid", Payload: "not json"})
+	manager.StartSession(newStartSessionMessage("../escape", SessionTypeShell))
+	manager.StartSession(newStartSessionMessage("", SessionTypeShell))
+	assert.Empty(t, manager.sessions)
+	assert.Empty(t, server.Sessions)
+}
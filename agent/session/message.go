@@ -0,0 +1,72 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+// SessionType is the type of a session
+type SessionType string
+
+const (
+	// SessionTypeShell is an interactive shell running in a terminal of the instance
+	SessionTypeShell SessionType = "Shell"
+
+	// SessionTypePort forwards the data of the session to a TCP port of the instance
+	SessionTypePort SessionType = "Port"
+)
+
+// StartSessionInput is the payload of the message of the service requesting a session
+type StartSessionInput struct {
+	SessionId              string
+	SessionType            SessionType
+	OutputS3BucketName     string `json:",omitempty"`
+	OutputS3KeyPrefix      string `json:",omitempty"`
+	CloudWatchLogGroupName string `json:",omitempty"`
+
+	// PortNumber is the local port the data of a port session is forwarded to
+	PortNumber int `json:",omitempty"`
+}
+
+// DataMessageType is the type of a message sent on the connection of a session
+type DataMessageType string
+
+const (
+	// DataMessageTypeInput is a message of the client carrying the bytes typed in the session
+	DataMessageTypeInput DataMessageType = "input"
+
+	// DataMessageTypeOutput is a message of the agent carrying the bytes written by the session
+	DataMessageTypeOutput DataMessageType = "output"
+
+	// DataMessageTypeSize is a message of the client giving the size of its terminal
+	DataMessageTypeSize DataMessageType = "size"
+
+	// DataMessageTypeAcknowledge acknowledges the input or output messages up to its sequence number
+	DataMessageTypeAcknowledge DataMessageType = "acknowledge"
+
+	// DataMessageTypeEOF is sent by either side once it has no more input or output to send, it still receives the data of the other side
+	DataMessageTypeEOF DataMessageType = "eof"
+
+	// DataMessageTypeClose is sent by either side to end the session, with the reason of the agent
+	DataMessageTypeClose DataMessageType = "close"
+)
+
+// DataMessage is a message sent on the connection of a session, as a JSON text frame.
+// The input and output messages are numbered in sequence from 1 and acknowledged by the other side once processed,
+// each side stops sending when too many of its messages are unacknowledged.
+type DataMessage struct {
+	MessageType    DataMessageType
+	SequenceNumber int64  `json:",omitempty"`
+	Payload        []byte `json:",omitempty"`
+	Cols           uint16 `json:",omitempty"`
+	Rows           uint16 `json:",omitempty"`
+	Reason         string `json:",omitempty"`
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// portHost is the host of the ports forwarded by the sessions, only the local ports of the instance are reachable
+	portHost = "localhost"
+
+	// dialTimeout is the timeout of the connection to the forwarded port
+	dialTimeout = 10 * time.Second
+)
+
+// runPort forwards the data of the session to a connection to the local port, until both sides closed their output,
+// the client closes the session or the session ends. The data flowing either way keeps the session from being idle.
+func runPort(s *session) string {
+	port := s.input.PortNumber
+	if port < 1 || port > 65535 {
+		return fmt.Sprintf("invalid port number %v", port)
+	}
+	connection, err := net.DialTimeout("tcp", net.JoinHostPort(portHost, strconv.Itoa(port)), dialTimeout)
+	if err != nil {
+		return fmt.Sprintf("failed to connect to port %v, %v", port, err)
+	}
+	defer connection.Close()
+	tcpConnection := connection.(*net.TCPConn)
+
+	ended := make(chan string, 3)
+	inputClosed := make(chan bool)
+	outputClosed := make(chan bool)
+	finished := make(chan bool)
+	defer close(finished)
+
+	go func() {
+		if reason := s.forwardOutput(tcpConnection); reason != "" {
+			ended <- reason
+			return
+		}
+		close(outputClosed)
+	}()
+	go func() { ended <- s.forwardInput(tcpConnection, inputClosed) }()
+	go func() {
+		for _, closed := range []chan bool{inputClosed, outputClosed} {
+			select {
+			case <-closed:
+			case <-finished:
+				return
+			}
+		}
+		ended <- "connection closed"
+	}()
+	return s.wait(ended)
+}
+
+// forwardOutput sends the data read from the connection to the client, then an eof message once the port closed its output.
+// It returns the reason of the end of the session if the forward fails.
+func (s *session) forwardOutput(connection *net.TCPConn) string {
+	buffer := make([]byte, outputBufferSize)
+	for {
+		n, err := connection.Read(buffer)
+		if n > 0 {
+			s.touch()
+			if err := s.channel.Send(append([]byte{}, buffer[:n]...)); err != nil {
+				return fmt.Sprintf("connection lost, %v", err)
+			}
+		}
+		if err == io.EOF {
+			if err = s.channel.SendEOF(); err != nil {
+				return fmt.Sprintf("connection lost, %v", err)
+			}
+			return ""
+		}
+		if err != nil {
+			return fmt.Sprintf("connection to port %v lost, %v", s.input.PortNumber, err)
+		}
+	}
+}
+
+// forwardInput writes the input of the client to the connection and closes the input of the port once the client sent an eof message.
+// The input messages are acknowledged once written, the client stops sending while the port does not read its input.
+// The messages of the client are received until it closes the session, for the acknowledgements of the output.
+func (s *session) forwardInput(connection *net.TCPConn, inputClosed chan bool) string {
+	eof := false
+	for {
+		msg, err := s.channel.Receive()
+		if err != nil {
+			return fmt.Sprintf("connection lost, %v", err)
+		}
+		switch msg.MessageType {
+		case DataMessageTypeInput:
+			if eof {
+				continue
+			}
+			s.touch()
+			if _, err = connection.Write(msg.Payload); err != nil {
+				return fmt.Sprintf("connection to port %v lost, %v", s.input.PortNumber, err)
+			}
+			if err = s.channel.Acknowledge(msg); err != nil {
+				return fmt.Sprintf("connection lost, %v", err)
+			}
+		case DataMessageTypeEOF:
+			if !eof {
+				eof = true
+				connection.CloseWrite()
+				close(inputClosed)
+			}
+		case DataMessageTypeClose:
+			return "closed by the client"
+		}
+	}
+}
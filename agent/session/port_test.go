@@ -0,0 +1,185 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// startEchoServer listens on a local port and sends back the data it receives,
+// it closes the connection once the client closed its output
+func startEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer connection.Close()
+				io.Copy(connection, connection)
+			}()
+		}
+	}()
+	return listener
+}
+
+func newStartPortSessionMessage(sessionId string, port int) service.ChannelMessage {
+	payload, _ := json.Marshal(StartSessionInput{SessionId: sessionId, SessionType: SessionTypePort, PortNumber: port})
+	return service.ChannelMessage{
+		MessageType: service.MessageTypeStartSession,
+		MessageId:   "message-" + sessionId,
+		Payload:     string(payload),
+	}
+}
+
+// readOutput acknowledges the output of the session until an eof or close message, skipping the acknowledgements of the input.
+// It returns the output and the last message.
+func readOutput(t *testing.T, connection *servicemock.SessionConnection) (output string, last DataMessage) {
+	connection.Connection.SetReadDeadline(time.Now().Add(testTimeout))
+	for {
+		var msg DataMessage
+		if !assert.NoError(t, connection.Connection.ReadJSON(&msg)) {
+			return
+		}
+		switch msg.MessageType {
+		case DataMessageTypeOutput:
+			output += string(msg.Payload)
+			connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeAcknowledge, SequenceNumber: msg.SequenceNumber})
+		case DataMessageTypeAcknowledge:
+		default:
+			return output, msg
+		}
+	}
+}
+
+func TestPortSession(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+
+	manager.StartSession(newStartPortSessionMessage("port-1", echoServer.Addr().(*net.TCPAddr).Port))
+	connection := receiveSession(t, server)
+	client := connection.Connection
+
+	// the input is forwarded and acknowledged, the echo is sent back
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 1, Payload: []byte("ping")}))
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 2, Payload: []byte("pong")}))
+	echo := ""
+	acknowledged := int64(0)
+	client.SetReadDeadline(time.Now().Add(testTimeout))
+	for echo != "pingpong" || acknowledged != 2 {
+		var msg DataMessage
+		if !assert.NoError(t, client.ReadJSON(&msg)) {
+			return
+		}
+		switch msg.MessageType {
+		case DataMessageTypeOutput:
+			echo += string(msg.Payload)
+			client.WriteJSON(DataMessage{MessageType: DataMessageTypeAcknowledge, SequenceNumber: msg.SequenceNumber})
+		case DataMessageTypeAcknowledge:
+			acknowledged = msg.SequenceNumber
+		}
+	}
+
+	// the eof of the client closes the input of the port, the echo server closes its output in turn
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 3, Payload: []byte("last")}))
+	assert.NoError(t, client.WriteJSON(DataMessage{MessageType: DataMessageTypeEOF}))
+	output, last := readOutput(t, connection)
+	assert.Equal(t, "last", output)
+	assert.Equal(t, DataMessageTypeEOF, last.MessageType)
+	_, last = readOutput(t, connection)
+	assert.Equal(t, DataMessage{MessageType: DataMessageTypeClose, Reason: "connection closed"}, last)
+
+	assert.NoError(t, manager.ModuleRequestStop(contracts.StopTypeSoftStop))
+	assert.Empty(t, manager.sessions)
+}
+
+func TestPortSessionKeepsReceivingAfterEOF(t *testing.T) {
+	// the port answers once its input is closed, as a request and response protocol would
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		request, _ := io.ReadAll(connection)
+		connection.Write([]byte(strings.ToUpper(string(request))))
+	}()
+
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+
+	manager.StartSession(newStartPortSessionMessage("port-2", listener.Addr().(*net.TCPAddr).Port))
+	connection := receiveSession(t, server)
+	assert.NoError(t, connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeInput, SequenceNumber: 1, Payload: []byte("request")}))
+	assert.NoError(t, connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeEOF}))
+
+	output, last := readOutput(t, connection)
+	assert.Equal(t, "REQUEST", output)
+	assert.Equal(t, DataMessageTypeEOF, last.MessageType)
+	_, last = readOutput(t, connection)
+	assert.Equal(t, "connection closed", last.Reason)
+	manager.ModuleRequestStop(contracts.StopTypeSoftStop)
+}
+
+func TestPortSessionFailures(t *testing.T) {
+	server := servicemock.NewServer()
+	defer server.Close()
+	manager, cleanup := newTestManager(t, server)
+	defer cleanup()
+
+	// nothing listens on the port of a closed listener
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	manager.StartSession(newStartPortSessionMessage("refused", port))
+	_, last := readOutput(t, receiveSession(t, server))
+	assert.Contains(t, last.Reason, "failed to connect to port")
+
+	manager.StartSession(newStartPortSessionMessage("invalid", 0))
+	_, last = readOutput(t, receiveSession(t, server))
+	assert.Equal(t, "invalid port number 0", last.Reason)
+
+	// the client closes its session
+	echoServer := startEchoServer(t)
+	defer echoServer.Close()
+	manager.StartSession(newStartPortSessionMessage("closed", echoServer.Addr().(*net.TCPAddr).Port))
+	connection := receiveSession(t, server)
+	assert.NoError(t, connection.Connection.WriteJSON(DataMessage{MessageType: DataMessageTypeClose}))
+	_, last = readOutput(t, connection)
+	assert.Equal(t, "closed by the client", last.Reason)
+	manager.ModuleRequestStop(contracts.StopTypeSoftStop)
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package session implements the interactive shell and port forwarding sessions requested over the HummingBird channel.
+// The data of each session is carried by a websocket connection of its own.
+package session
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/hummingbird/service"
+)
+
+// sessionRunners run the sessions of each type until they end, they return the reason of the end
+var sessionRunners = map[SessionType]func(s *session) string{
+	SessionTypeShell: runShell,
+	SessionTypePort:  runPort,
+}
+
+// session is a session running on the instance
+type session struct {
+	context          context.T
+	input            StartSessionInput
+	config           appconfig.SessionCfg
+	service          service.Service
+	channel          *DataChannel
+	orchestrationDir string
+	idleTimeout      time.Duration
+
+	// activity receives the input of the client, which resets the idle timeout
+	activity  chan bool
+	stop      chan bool
+	stopOnce  sync.Once
+	closeOnce sync.Once
+}
+
+// run opens the connection of the session and runs the session until it ends, the client receives the reason of the end
+func (s *session) run() {
+	log := s.context.Log()
+	connection, err := s.service.GetSessionChannel(log, s.input.SessionId)
+	if err != nil {
+		log.Errorf("Failed to open the channel of session %v, %v", s.input.SessionId, err)
+		return
+	}
+	s.channel = NewDataChannel(connection)
+	log.Infof("Session %v of type %v started", s.input.SessionId, s.input.SessionType)
+
+	var reason string
+	if runner, supported := sessionRunners[s.input.SessionType]; supported {
+		reason = runner(s)
+	} else {
+		reason = fmt.Sprintf("unsupported session type %v", s.input.SessionType)
+	}
+	s.close(reason)
+}
+
+// close closes the connection of the session, the first reason given is sent to the client
+func (s *session) close(reason string) {
+	s.closeOnce.Do(func() {
+		s.context.Log().Infof("Session %v ended, %v", s.input.SessionId, reason)
+		s.channel.Close(reason)
+	})
+}
+
+// requestStop ends the session
+func (s *session) requestStop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// touch resets the idle timeout of the session
+func (s *session) touch() {
+	select {
+	case s.activity <- true:
+	default:
+	}
+}
+
+// wait returns the first reason received from the goroutines of the session,
+// or the reason of the idle timeout or of the stop of the agent if they come first
+func (s *session) wait(ended chan string) string {
+	deadline := time.Now().Add(s.idleTimeout)
+	idle := time.NewTimer(s.idleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case reason := <-ended:
+			return reason
+		case <-s.activity:
+			deadline = time.Now().Add(s.idleTimeout)
+		case <-idle.C:
+			if remaining := deadline.Sub(time.Now()); remaining > 0 {
+				idle.Reset(remaining)
+				continue
+			}
+			return fmt.Sprintf("no input for %v", s.idleTimeout)
+		case <-s.stop:
+			return "agent stopping"
+		}
+	}
+}
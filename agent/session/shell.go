@@ -0,0 +1,144 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
+)
+
+const (
+	// transcriptFileName is the name of the file recording the output of a shell session
+	transcriptFileName = "transcript"
+
+	// outputBufferSize is the largest payload of the output messages
+	outputBufferSize = 4096
+)
+
+// runShell runs the shell of the session in a terminal until the shell exits, the client closes the session or the session ends.
+// The output of the shell is recorded in the transcript of the session.
+func runShell(s *session) string {
+	command := exec.Command(shellCommand[0], shellCommand[1:]...)
+	env, err := shellEnvironment(s.config.RunAsUser)
+	if err != nil {
+		return fmt.Sprintf("failed to run the session as user %v, %v", s.config.RunAsUser, err)
+	}
+	command.Env = env
+	if err := executers.PrepareRunAs(command, executers.ExecuteOptions{RunAsUser: s.config.RunAsUser}); err != nil {
+		return fmt.Sprintf("failed to run the session as user %v, %v", s.config.RunAsUser, err)
+	}
+	// the processes started from the shell, including the ones left in the background, are killed along with it
+	processTree := executers.TrackProcessTree(command)
+
+	shellTerminal, err := startTerminal(command)
+	if err != nil {
+		return fmt.Sprintf("failed to start the shell, %v", err)
+	}
+
+	transcript, transcriptDone := s.startTranscript()
+	ended := make(chan string, 2)
+	go func() { ended <- s.sendOutput(shellTerminal, transcript) }()
+	go func() { ended <- s.receiveInput(shellTerminal) }()
+	reason := s.wait(ended)
+
+	hangUp(command)
+	leftovers, err := processTree.Kill(s.context.Log())
+	if len(leftovers) > 0 {
+		s.context.Log().Infof("Stopped processes left behind by session %v: %v", s.input.SessionId, leftovers)
+	}
+	if err != nil {
+		s.context.Log().Warnf("Failed to stop the shell of session %v, %v", s.input.SessionId, err)
+	}
+	command.Wait()
+	shellTerminal.Close()
+	s.close(reason)
+
+	// the transcript is uploaded once complete, after the client is gone
+	transcript.Close()
+	<-transcriptDone
+	return reason
+}
+
+// sendOutput sends the output of the terminal to the client and writes it to the transcript
+func (s *session) sendOutput(shellTerminal terminal, transcript io.Writer) string {
+	buffer := make([]byte, outputBufferSize)
+	for {
+		n, err := shellTerminal.Read(buffer)
+		if n > 0 {
+			payload := append([]byte{}, buffer[:n]...)
+			transcript.Write(payload)
+			if err := s.channel.Send(payload); err != nil {
+				return fmt.Sprintf("connection lost, %v", err)
+			}
+		}
+		if err != nil {
+			return "shell exited"
+		}
+	}
+}
+
+// receiveInput writes the input of the client to the terminal and resizes the terminal as requested.
+// The input messages are acknowledged once written, the client stops sending while the shell does not read its input.
+func (s *session) receiveInput(shellTerminal terminal) string {
+	log := s.context.Log()
+	for {
+		msg, err := s.channel.Receive()
+		if err != nil {
+			return fmt.Sprintf("connection lost, %v", err)
+		}
+		switch msg.MessageType {
+		case DataMessageTypeInput:
+			s.touch()
+			if _, err = shellTerminal.Write(msg.Payload); err != nil {
+				return "shell exited"
+			}
+			if err = s.channel.Acknowledge(msg); err != nil {
+				return fmt.Sprintf("connection lost, %v", err)
+			}
+		case DataMessageTypeSize:
+			s.touch()
+			if err = shellTerminal.SetSize(msg.Cols, msg.Rows); err != nil {
+				log.Debugf("Failed to resize the terminal of session %v, %v", s.input.SessionId, err)
+			}
+		case DataMessageTypeClose:
+			return "closed by the client"
+		}
+	}
+}
+
+// startTranscript records the output of the session in the orchestration directory, and uploads it to S3 and CloudWatch if requested.
+// The transcript is complete once the returned writer is closed and the returned channel is closed in turn.
+func (s *session) startTranscript() (*io.PipeWriter, chan bool) {
+	reader, writer := io.Pipe()
+	file := iomodule.File{
+		FileName:               transcriptFileName,
+		OrchestrationDirectory: s.orchestrationDir,
+		OutputS3BucketName:     s.input.OutputS3BucketName,
+		OutputS3KeyPrefix:      fileutil.BuildS3Path(s.input.OutputS3KeyPrefix, s.input.SessionId),
+		LogGroupName:           s.input.CloudWatchLogGroupName,
+		LogStreamName:          s.input.SessionId,
+	}
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		file.Read(s.context.Log(), reader)
+	}()
+	return writer, done
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package session
+
+import (
+	"fmt"
+	osuser "os/user"
+
+	"github.com/aws/amazon-ssm-agent/agent/user"
+)
+
+const (
+	// shellPath is the SHELL of the sessions
+	shellPath = "/bin/sh"
+	// shellSearchPath is the PATH of the sessions, the one of a login rather than the one of the agent
+	shellSearchPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// shellCommand is the command of the shell of the sessions
+var shellCommand = []string{"sh"}
+
+// lookupUser returns the user the shell runs as, the agent user if the name is empty
+var lookupUser = func(username string) (*osuser.User, error) {
+	if username == "" {
+		return user.Current()
+	}
+	return user.Lookup(username)
+}
+
+// shellEnvironment returns the environment of a login of the user instead of the environment of the agent,
+// which holds credentials the user of the session must not see.
+func shellEnvironment(runAsUser string) ([]string, error) {
+	u, err := lookupUser(runAsUser)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		fmt.Sprintf("HOME=%v", u.HomeDir),
+		fmt.Sprintf("USER=%v", u.Username),
+		fmt.Sprintf("LOGNAME=%v", u.Username),
+		fmt.Sprintf("SHELL=%v", shellPath),
+		fmt.Sprintf("PATH=%v", shellSearchPath),
+		"TERM=xterm",
+	}, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package session
+
+import (
+	"os"
+	osuser "os/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellEnvironment(t *testing.T) {
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+	defer func(original func(string) (*osuser.User, error)) { lookupUser = original }(lookupUser)
+	lookupUser = func(username string) (*osuser.User, error) {
+		assert.Equal(t, "ssm-user", username)
+		return &osuser.User{Username: "ssm-user", HomeDir: "/home/ssm-user"}, nil
+	}
+
+	env, err := shellEnvironment("ssm-user")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"HOME=/home/ssm-user",
+		"USER=ssm-user",
+		"LOGNAME=ssm-user",
+		"SHELL=/bin/sh",
+		"PATH=" + shellSearchPath,
+		"TERM=xterm",
+	}, env)
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package session
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// shellCommand is the command of the shell of the sessions
+var shellCommand = []string{appconfig.PowerShellPluginCommandName, "-NoLogo"}
+
+// systemVariables are the variables of the agent environment passed to the shell, powershell does not start without them
+var systemVariables = []string{
+	"ALLUSERSPROFILE", "APPDATA", "COMPUTERNAME", "ComSpec", "HOMEDRIVE", "HOMEPATH", "LOCALAPPDATA",
+	"NUMBER_OF_PROCESSORS", "OS", "Path", "PATHEXT", "PROCESSOR_ARCHITECTURE", "ProgramData", "ProgramFiles",
+	"ProgramFiles(x86)", "PSModulePath", "PUBLIC", "SystemDrive", "SystemRoot", "TEMP", "TMP",
+	"USERDOMAIN", "USERNAME", "USERPROFILE", "windir",
+}
+
+// shellEnvironment returns the system variables of the agent environment instead of the whole environment of the agent,
+// which holds credentials the user of the session must not see. Sessions run as the agent user on windows.
+func shellEnvironment(runAsUser string) ([]string, error) {
+	var env []string
+	for _, variable := range os.Environ() {
+		for _, name := range systemVariables {
+			if strings.HasPrefix(strings.ToLower(variable), strings.ToLower(name)+"=") {
+				env = append(env, variable)
+				break
+			}
+		}
+	}
+	return env, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package session
+
+import (
+	"io"
+)
+
+// terminal is the terminal of the process of a shell session, started by startTerminal:
+// a pseudo terminal on linux and pipes on the other platforms
+type terminal interface {
+	io.ReadWriteCloser
+
+	// SetSize sets the size of the terminal in characters
+	SetSize(cols uint16, rows uint16) error
+}
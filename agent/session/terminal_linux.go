@@ -0,0 +1,101 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package session
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// pseudoTerminal is the master side of a pseudo terminal
+type pseudoTerminal struct {
+	*os.File
+}
+
+// windowSize is the winsize structure of the TIOCSWINSZ request
+type windowSize struct {
+	rows   uint16
+	cols   uint16
+	xPixel uint16
+	yPixel uint16
+}
+
+// startTerminal opens a pseudo terminal and starts the command as the leader of a new session controlled by it
+func startTerminal(command *exec.Cmd) (terminal, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slave, err := openSlave(master)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	// the agent keeps the master side only, the reads of the master fail once the process tree closed the slave side
+	defer slave.Close()
+
+	command.Stdin = slave
+	command.Stdout = slave
+	command.Stderr = slave
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Setsid = true
+	command.SysProcAttr.Setctty = true
+	command.SysProcAttr.Ctty = 0
+
+	if err = command.Start(); err != nil {
+		master.Close()
+		return nil, err
+	}
+	return &pseudoTerminal{File: master}, nil
+}
+
+// hangUp sends SIGHUP to the session of the command as the hang up of its terminal would,
+// the interactive shell exits on SIGHUP while it ignores SIGTERM
+func hangUp(command *exec.Cmd) {
+	syscall.Kill(-command.Process.Pid, syscall.SIGHUP)
+}
+
+// openSlave unlocks and opens the slave side of the pseudo terminal
+func openSlave(master *os.File) (*os.File, error) {
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		return nil, fmt.Errorf("failed to unlock the pseudo terminal, %v", err)
+	}
+	var number uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number))); err != nil {
+		return nil, fmt.Errorf("failed to get the pseudo terminal number, %v", err)
+	}
+	return os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+}
+
+// SetSize sets the window size of the pseudo terminal, the process receives a SIGWINCH
+func (t *pseudoTerminal) SetSize(cols uint16, rows uint16) error {
+	size := windowSize{rows: rows, cols: cols}
+	return ioctl(t.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+}
+
+func ioctl(fd uintptr, request uintptr, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+package session
+
+import (
+	"os"
+	"os/exec"
+)
+
+// pipeTerminal connects the process with pipes where pseudo terminals are not supported,
+// the output of the process is not echoed nor line edited and its size is fixed
+type pipeTerminal struct {
+	input  *os.File
+	output *os.File
+}
+
+// startTerminal starts the command with its input and output redirected to pipes
+func startTerminal(command *exec.Cmd) (terminal, error) {
+	inputReader, inputWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	outputReader, outputWriter, err := os.Pipe()
+	if err != nil {
+		inputReader.Close()
+		inputWriter.Close()
+		return nil, err
+	}
+	// the agent keeps its ends of the pipes only, the reads of the output end once the process tree exits
+	defer inputReader.Close()
+	defer outputWriter.Close()
+
+	command.Stdin = inputReader
+	command.Stdout = outputWriter
+	command.Stderr = outputWriter
+	if err = command.Start(); err != nil {
+		inputWriter.Close()
+		outputReader.Close()
+		return nil, err
+	}
+	return &pipeTerminal{input: inputWriter, output: outputReader}, nil
+}
+
+// hangUp does nothing, the process has no terminal to hang up
+func hangUp(command *exec.Cmd) {
+}
+
+func (t *pipeTerminal) Read(p []byte) (int, error) {
+	return t.output.Read(p)
+}
+
+func (t *pipeTerminal) Write(p []byte) (int, error) {
+	return t.input.Write(p)
+}
+
+func (t *pipeTerminal) Close() error {
+	t.input.Close()
+	return t.output.Close()
+}
+
+// SetSize is not supported by pipes
+func (t *pipeTerminal) SetSize(cols uint16, rows uint16) error {
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/vault/fsvault"
+)
+
+const (
+	// EnvironmentPrefix is the prefix of the references to the environment variables of the agent, e.g. {{env:HOSTNAME}}
+	EnvironmentPrefix = "env:"
+
+	// FsVaultPrefix is the prefix of the references to the secrets of the local vault, e.g. {{fsvault:db-password}}
+	FsVaultPrefix = "fsvault:"
+
+	// FsVaultKeyPrefix is the prefix of the keys of the local vault holding the document secrets,
+	// the secret db-password is stored under the key DocumentParameter.db-password
+	FsVaultKeyPrefix = "DocumentParameter."
+
+	// VaultPrefix is the prefix of the references to the secrets of a HashiCorp Vault compatible endpoint,
+	// e.g. {{vault:secret/data/db#password}} references the field password of the secret secret/data/db
+	VaultPrefix = "vault:"
+
+	// vaultTokenEnvVariable is the environment variable holding the vault token when no token file is configured
+	vaultTokenEnvVariable = "VAULT_TOKEN"
+
+	// vaultRequestTimeout is the timeout of the requests to the vault endpoint
+	vaultRequestTimeout = 30 * time.Second
+)
+
+func init() {
+	RegisterProvider(EnvironmentPrefix, environmentProvider{})
+	RegisterProvider(FsVaultPrefix, fsVaultProvider{})
+	RegisterProvider(VaultPrefix, vaultProvider{})
+}
+
+// environmentProvider resolves the parameters from the environment variables of the agent
+type environmentProvider struct{}
+
+// IsSecure returns true, the environment of the agent holds credentials such as AWS_SECRET_ACCESS_KEY or VAULT_TOKEN
+func (environmentProvider) IsSecure() bool {
+	return true
+}
+
+// GetParameters returns the values of the environment variables, the variables not set are omitted
+func (environmentProvider) GetParameters(log log.T, names []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, name := range names {
+		if value, found := os.LookupEnv(name); found {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// Assign method to global variables to allow unittest to override
+var fsVaultRetrieve = fsvault.Retrieve
+var loadSecretsConfig = func() (appconfig.SecretsCfg, error) {
+	config, err := appconfig.Config(false)
+	return config.Secrets, err
+}
+
+// fsVaultProvider resolves the parameters from the local file system vault
+type fsVaultProvider struct{}
+
+// IsSecure returns true, the values of the vault are secrets
+func (fsVaultProvider) IsSecure() bool {
+	return true
+}
+
+// GetParameters returns the secrets stored in the vault under the document parameter keys
+func (fsVaultProvider) GetParameters(log log.T, names []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, name := range names {
+		data, err := fsVaultRetrieve(FsVaultKeyPrefix + name)
+		if err != nil {
+			// NOTE: Do not log the parameter value
+			log.Debugf("Failed to retrieve %v from the vault: %v", name, err)
+			continue
+		}
+		values[name] = string(data)
+	}
+	return values, nil
+}
+
+// vaultProvider resolves the parameters from a HashiCorp Vault compatible HTTP endpoint
+type vaultProvider struct{}
+
+// IsSecure returns true, the values of the vault are secrets
+func (vaultProvider) IsSecure() bool {
+	return true
+}
+
+// GetParameters reads every secret referenced by the names once, and returns the requested fields
+func (vaultProvider) GetParameters(log log.T, names []string) (map[string]string, error) {
+	config, err := loadSecretsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.VaultAddress == "" {
+		return nil, fmt.Errorf("vault address is not configured, cannot resolve %v", strings.Join(names, ","))
+	}
+	token, err := getVaultToken(config)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]map[string]interface{})
+	values := make(map[string]string)
+	for _, name := range names {
+		path, field := name, ""
+		if index := strings.Index(name, "#"); index >= 0 {
+			path, field = name[:index], name[index+1:]
+		}
+
+		secret, read := secrets[path]
+		if !read {
+			if secret, err = readVaultSecret(config.VaultAddress, token, path); err != nil {
+				return nil, fmt.Errorf("failed to read secret %v from vault: %v", path, err)
+			}
+			secrets[path] = secret
+		}
+
+		if value, found := vaultSecretField(secret, field); found {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// getVaultToken returns the token read from the configured token file, or else from the environment of the agent
+func getVaultToken(config appconfig.SecretsCfg) (string, error) {
+	if config.VaultTokenPath == "" {
+		return os.Getenv(vaultTokenEnvVariable), nil
+	}
+	token, err := ioutil.ReadFile(config.VaultTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault token: %v", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// readVaultSecret reads the data of the secret at the path, for both versions of the key/value secrets engine
+func readVaultSecret(address string, token string, path string) (map[string]interface{}, error) {
+	request, err := http.NewRequest(http.MethodGet, strings.TrimRight(address, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set("X-Vault-Token", token)
+	}
+
+	client := &http.Client{Timeout: vaultRequestTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", response.Status)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+
+	// version 2 of the key/value secrets engine wraps the data along with its metadata
+	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := secret.Data["metadata"]; hasMetadata {
+			return data, nil
+		}
+	}
+	return secret.Data, nil
+}
+
+// vaultSecretField returns the field of the secret, the field can be omitted when the secret has a single field
+func vaultSecretField(secret map[string]interface{}, field string) (string, bool) {
+	if field == "" {
+		if len(secret) != 1 {
+			return "", false
+		}
+		for _, value := range secret {
+			return secretFieldString(value), true
+		}
+	}
+	value, found := secret[field]
+	if !found {
+		return "", false
+	}
+	return secretFieldString(value), true
+}
+
+// secretFieldString converts a field of a secret to the string replacing the reference
+func secretFieldString(value interface{}) string {
+	if value, ok := value.(string); ok {
+		return value
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
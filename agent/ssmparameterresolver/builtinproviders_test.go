@@ -0,0 +1,109 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentProvider(t *testing.T) {
+	os.Setenv("SSM_RESOLVER_TEST_VARIABLE", "value")
+	defer os.Unsetenv("SSM_RESOLVER_TEST_VARIABLE")
+
+	defer redaction.Reset()
+
+	output, err := ResolveProviderParameters(log.NewMockLog(), "{{ env:SSM_RESOLVER_TEST_VARIABLE }}", ResolveOptions{IgnoreSecureParameters: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "{{ env:SSM_RESOLVER_TEST_VARIABLE }}", output)
+
+	output, err = ResolveProviderParameters(log.NewMockLog(), "{{ env:SSM_RESOLVER_TEST_VARIABLE }}", ResolveOptions{IgnoreSecureParameters: false})
+	assert.NoError(t, err)
+	assert.Equal(t, "value", output)
+	assert.Equal(t, "password="+redaction.Mask, redaction.Redact("password=value"))
+
+	_, err = ResolveProviderParameters(log.NewMockLog(), "{{env:SSM_RESOLVER_TEST_UNSET_VARIABLE}}", ResolveOptions{IgnoreSecureParameters: false})
+	assert.Error(t, err)
+}
+
+func TestFsVaultProvider(t *testing.T) {
+	origFsVaultRetrieve := fsVaultRetrieve
+	defer func() { fsVaultRetrieve = origFsVaultRetrieve }()
+	fsVaultRetrieve = func(key string) ([]byte, error) {
+		if key == FsVaultKeyPrefix+"db-password" {
+			return []byte("secret"), nil
+		}
+		return nil, fmt.Errorf("%s does not exist.", key)
+	}
+
+	values, err := fsVaultProvider{}.GetParameters(log.NewMockLog(), []string{"db-password", "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"db-password": "secret"}, values)
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			fmt.Fprint(w, `{"data": {"data": {"password": "secret", "port": 5432}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/api":
+			fmt.Fprint(w, `{"data": {"key": "api-key"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	origLoadSecretsConfig := loadSecretsConfig
+	defer func() { loadSecretsConfig = origLoadSecretsConfig }()
+	loadSecretsConfig = func() (appconfig.SecretsCfg, error) {
+		return appconfig.SecretsCfg{VaultAddress: server.URL}, nil
+	}
+	os.Setenv(vaultTokenEnvVariable, "token")
+	defer os.Unsetenv(vaultTokenEnvVariable)
+
+	values, err := vaultProvider{}.GetParameters(log.NewMockLog(), []string{"secret/data/db#password", "secret/data/db#port", "kv/api", "kv/api#unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"secret/data/db#password": "secret",
+		"secret/data/db#port":     "5432",
+		"kv/api":                  "api-key",
+	}, values)
+
+	_, err = vaultProvider{}.GetParameters(log.NewMockLog(), []string{"secret/data/unknown#password"})
+	assert.Error(t, err)
+
+	os.Setenv(vaultTokenEnvVariable, "invalid")
+	_, err = vaultProvider{}.GetParameters(log.NewMockLog(), []string{"kv/api"})
+	assert.Error(t, err)
+
+	loadSecretsConfig = func() (appconfig.SecretsCfg, error) {
+		return appconfig.SecretsCfg{}, nil
+	}
+	_, err = vaultProvider{}.GetParameters(log.NewMockLog(), []string{"kv/api"})
+	assert.Error(t, err)
+}
@@ -39,7 +39,8 @@ type SsmParameterInfo struct {
 
 // ResolveOptions structure represents a set of options for the parameter resolution.
 // At this time it has only one flag IgnoreSecureParameters
-// if IgnoreSecureParameters == true the parameters prefixed with ssm-secure: will not be resolved.
+// if IgnoreSecureParameters == true the parameters prefixed with ssm-secure: will not be resolved,
+// neither will the parameters of the secure providers (see ParameterProvider).
 type ResolveOptions struct {
 	IgnoreSecureParameters bool
 }
@@ -0,0 +1,250 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// ParameterProvider resolves the parameter references of one prefix, e.g. {{env:HOSTNAME}},
+// so that secrets kept outside of SSM Parameter Store can be referenced by documents.
+type ParameterProvider interface {
+	// IsSecure returns whether the values of the provider are secrets, which are not resolved
+	// when IgnoreSecureParameters is set and are masked in the output and the logs
+	IsSecure() bool
+
+	// GetParameters returns the values of the parameters, indexed by parameter name
+	GetParameters(log log.T, names []string) (map[string]string, error)
+}
+
+var (
+	providersLock sync.RWMutex
+	providers     = make(map[string]ParameterProvider)
+)
+
+// Parameter placeholder of a registered provider, the name may contain / . # to address a field of a secret
+var providerParameterPlaceholderRegEx = regexp.MustCompile("{{\\s*([a-zA-Z][\\w-]*:[\\w-/.#]+)\\s*}}")
+
+// Prefix of a provider
+var providerPrefixRegEx = regexp.MustCompile("^[a-zA-Z][\\w-]*:$")
+
+// RegisterProvider registers the provider resolving the parameter references starting with the prefix, e.g. "env:"
+func RegisterProvider(prefix string, provider ParameterProvider) error {
+	if !providerPrefixRegEx.MatchString(prefix) {
+		return fmt.Errorf("invalid parameter provider prefix %v", prefix)
+	}
+	if prefix == ssmNonSecurePrefix || prefix == ssmSecurePrefix {
+		return fmt.Errorf("parameter provider prefix %v is reserved for SSM Parameter Store", prefix)
+	}
+
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	if _, exists := providers[prefix]; exists {
+		return fmt.Errorf("parameter provider %v is already registered", prefix)
+	}
+	providers[prefix] = provider
+	return nil
+}
+
+// UnregisterProvider removes the provider of the prefix
+func UnregisterProvider(prefix string) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	delete(providers, prefix)
+}
+
+// getProvider returns the provider of the parameter reference, if any
+func getProvider(parameterReference string) (provider ParameterProvider, prefix string, found bool) {
+	index := strings.Index(parameterReference, ":")
+	if index < 0 {
+		return nil, "", false
+	}
+	prefix = parameterReference[:index+1]
+
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	provider, found = providers[prefix]
+	return provider, prefix, found
+}
+
+// isProviderReferenceResolved checks whether the reference belongs to a provider and has to be resolved according to the options
+func isProviderReferenceResolved(parameterReference string, ignoreSecureParameters bool) bool {
+	provider, _, found := getProvider(parameterReference)
+	return found && !(ignoreSecureParameters && provider.IsSecure())
+}
+
+// parseProviderParametersFromText returns the references to the registered providers in the text
+func parseProviderParametersFromText(text string, ignoreSecureParameters bool) []string {
+	var references []string
+	for _, match := range providerParameterPlaceholderRegEx.FindAllStringSubmatch(text, -1) {
+		if isProviderReferenceResolved(match[1], ignoreSecureParameters) {
+			references = append(references, match[1])
+		}
+	}
+	return references
+}
+
+// ContainsParameterReference checks whether the text references a parameter resolved when the document runs,
+// i.e. a parameter of SSM Parameter Store such as {{ssm:name}} or a parameter of a registered provider such as {{env:HOSTNAME}}
+func ContainsParameterReference(text string) bool {
+	if ssmParameterPlaceholderRegEx.MatchString(text) || secureSsmParameterPlaceholderRegEx.MatchString(text) {
+		return true
+	}
+	for _, match := range providerParameterPlaceholderRegEx.FindAllStringSubmatch(text, -1) {
+		if _, _, found := getProvider(match[1]); found {
+			return true
+		}
+	}
+	return false
+}
+
+// getParameterValues resolves the references through their providers, the references without provider
+// are resolved from SSM Parameter Store. It returns a map <reference, SsmParameterInfo>.
+func getParameterValues(
+	service ISsmParameterService,
+	log log.T,
+	parameterReferences []string) (map[string]SsmParameterInfo, error) {
+
+	var ssmParameterReferences []string
+	providerParameterNames := make(map[string][]string)
+	for _, reference := range parameterReferences {
+		if _, prefix, found := getProvider(reference); found {
+			providerParameterNames[prefix] = append(providerParameterNames[prefix], reference[len(prefix):])
+		} else {
+			ssmParameterReferences = append(ssmParameterReferences, reference)
+		}
+	}
+
+	outputMap := make(map[string]SsmParameterInfo)
+	if len(ssmParameterReferences) > 0 {
+		ssmParameters, err := getParametersFromSsmParameterStore(service, log, ssmParameterReferences)
+		if err != nil {
+			return nil, err
+		}
+		for reference, value := range ssmParameters {
+			outputMap[reference] = value
+		}
+	}
+
+	for prefix, names := range providerParameterNames {
+		provider, _, found := getProvider(prefix)
+		if !found {
+			return nil, fmt.Errorf("parameter provider %v is not registered", prefix)
+		}
+		values, err := provider.GetParameters(log, names)
+		if err != nil {
+			return nil, err
+		}
+
+		parameterType := stringType
+		if provider.IsSecure() {
+			parameterType = secureStringType
+		}
+		var missing []string
+		for _, name := range names {
+			value, found := values[name]
+			if !found {
+				missing = append(missing, prefix+name)
+				continue
+			}
+			outputMap[prefix+name] = SsmParameterInfo{Name: name, Type: parameterType, Value: value}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("The following parameter(s) cannot be resolved: %v", strings.Join(missing, ","))
+		}
+	}
+
+	return outputMap, nil
+}
+
+// ResolveProviderParameters resolves the references to the registered providers in the strings of the input
+// according to ResolveOptions, and returns the resolved input. The references to SSM Parameter Store are left as is.
+func ResolveProviderParameters(log log.T, input interface{}, options ResolveOptions) (interface{}, error) {
+	var references []string
+	visitStrings(input, func(text string) string {
+		references = append(references, parseProviderParametersFromText(text, options.IgnoreSecureParameters)...)
+		return text
+	})
+	if len(references) == 0 {
+		return input, nil
+	}
+
+	resolvedParametersMap, err := getParameterValues(nil, log, dedupSlice(references))
+	if err != nil {
+		return input, err
+	}
+	registerSecureParameters(resolvedParametersMap)
+
+	return visitStrings(input, func(text string) string {
+		return replaceParameterReferences(text, resolvedParametersMap)
+	}), nil
+}
+
+// replaceParameterReferences replaces the {{ reference }} placeholders with the values of the parameters
+func replaceParameterReferences(text string, resolvedParametersMap map[string]SsmParameterInfo) string {
+	for ref, param := range resolvedParametersMap {
+		var placeholder = regexp.MustCompile("{{\\s*" + regexp.QuoteMeta(ref) + "\\s*}}")
+		text = placeholder.ReplaceAllLiteralString(text, param.Value)
+	}
+	return text
+}
+
+// visitStrings returns a copy of the input where every string is replaced by the result of the visit
+func visitStrings(input interface{}, visit func(string) string) interface{} {
+	switch input := input.(type) {
+	case string:
+		return visit(input)
+	case []string:
+		out := make([]string, len(input))
+		for i, v := range input {
+			out[i] = visit(v)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(input))
+		for i, v := range input {
+			out[i] = visitStrings(v, visit)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(input))
+		for i, v := range input {
+			out[i] = visitStrings(v, visit).(map[string]interface{})
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(input))
+		for k, v := range input {
+			out[k] = visitStrings(v, visit)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(input))
+		for k, v := range input {
+			out[k] = visit(v)
+		}
+		return out
+	default:
+		// any other type, return as is
+		return input
+	}
+}
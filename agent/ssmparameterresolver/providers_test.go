@@ -0,0 +1,119 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ssmparameterresolver contains types and methods for resolving SSM Parameter references.
+package ssmparameterresolver
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProviderPrefix = "test:"
+
+type testProvider struct {
+	secure bool
+	values map[string]string
+}
+
+func (p testProvider) IsSecure() bool {
+	return p.secure
+}
+
+func (p testProvider) GetParameters(log log.T, names []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, name := range names {
+		if value, found := p.values[name]; found {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+func registerTestProvider(t *testing.T, secure bool) {
+	err := RegisterProvider(testProviderPrefix, testProvider{
+		secure: secure,
+		values: map[string]string{"db/password": "$ecret-value", "user": "admin"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestRegisterProvider(t *testing.T) {
+	defer UnregisterProvider(testProviderPrefix)
+
+	assert.Error(t, RegisterProvider(ssmNonSecurePrefix, testProvider{}))
+	assert.Error(t, RegisterProvider(ssmSecurePrefix, testProvider{}))
+	assert.Error(t, RegisterProvider("test", testProvider{}))
+	assert.Error(t, RegisterProvider(EnvironmentPrefix, testProvider{}))
+	assert.NoError(t, RegisterProvider(testProviderPrefix, testProvider{}))
+	assert.Error(t, RegisterProvider(testProviderPrefix, testProvider{}))
+}
+
+func TestResolveParametersInTextWithProvider(t *testing.T) {
+	registerTestProvider(t, true)
+	defer UnregisterProvider(testProviderPrefix)
+	defer redaction.Reset()
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:param1": {Name: "param1", Type: stringType, Value: "value_param1"},
+	})
+
+	text := "{{ssm:param1}} {{ test:user }} {{test:db/password}}"
+	output, err := ResolveParametersInText(&serviceObject, log.NewMockLog(), text, ResolveOptions{IgnoreSecureParameters: false})
+	assert.NoError(t, err)
+	assert.Equal(t, "value_param1 admin $ecret-value", output)
+	assert.Equal(t, redaction.Mask, redaction.Redact("$ecret-value"))
+
+	// the parameters of secure providers are ignored like the ssm-secure ones
+	output, err = ResolveParametersInText(&serviceObject, log.NewMockLog(), text, ResolveOptions{IgnoreSecureParameters: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "value_param1 {{ test:user }} {{test:db/password}}", output)
+
+	_, err = ResolveParametersInText(&serviceObject, log.NewMockLog(), "{{test:unknown}}", ResolveOptions{IgnoreSecureParameters: false})
+	assert.Error(t, err)
+}
+
+func TestResolveParameterReferenceListWithProvider(t *testing.T) {
+	registerTestProvider(t, false)
+	defer UnregisterProvider(testProviderPrefix)
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
+
+	resolvedParameters, err := ResolveParameterReferenceList(&serviceObject, log.NewMockLog(), []string{"test:user"}, ResolveOptions{IgnoreSecureParameters: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]SsmParameterInfo{
+		"test:user": {Name: "user", Type: stringType, Value: "admin"},
+	}, resolvedParameters)
+}
+
+func TestResolveProviderParameters(t *testing.T) {
+	registerTestProvider(t, false)
+	defer UnregisterProvider(testProviderPrefix)
+
+	input := map[string]interface{}{
+		"runCommand": []interface{}{"echo {{test:user}}", "echo {{ssm:param1}}"},
+		"timeout":    3600,
+		"nested":     []map[string]interface{}{{"password": "{{test:db/password}}"}},
+	}
+	output, err := ResolveProviderParameters(log.NewMockLog(), input, ResolveOptions{IgnoreSecureParameters: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"runCommand": []interface{}{"echo admin", "echo {{ssm:param1}}"},
+		"timeout":    3600,
+		"nested":     []map[string]interface{}{{"password": "$ecret-value"}},
+	}, output)
+
+	_, err = ResolveProviderParameters(log.NewMockLog(), "{{test:unknown}}", ResolveOptions{IgnoreSecureParameters: true})
+	assert.Error(t, err)
+}
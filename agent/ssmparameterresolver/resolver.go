@@ -16,10 +16,10 @@ package ssmparameterresolver
 
 import (
 	"errors"
-	"regexp"
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 )
 
 // ExtractParametersFromText takes text document and resolves all parameters in it according to ResolveOptions.
@@ -35,7 +35,7 @@ func ExtractParametersFromText(
 		return nil, err
 	}
 
-	parametersWithValues, err := getParametersFromSsmParameterStore(service, log, uniqueParameterReferences)
+	parametersWithValues, err := getParameterValues(service, log, uniqueParameterReferences)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +45,7 @@ func ExtractParametersFromText(
 		return nil, prefixValidationError
 	}
 
+	registerSecureParameters(parametersWithValues)
 	return parametersWithValues, nil
 }
 
@@ -60,7 +61,7 @@ func ResolveParameterReferenceList(
 	parameterReferencesToResolve := []string{}
 	if options.IgnoreSecureParameters {
 		for _, ref := range uniqueParameterReferences {
-			if strings.HasPrefix(ref, ssmNonSecurePrefix) {
+			if strings.HasPrefix(ref, ssmNonSecurePrefix) || isProviderReferenceResolved(ref, true) {
 				parameterReferencesToResolve = append(parameterReferencesToResolve, ref)
 			}
 		}
@@ -68,7 +69,7 @@ func ResolveParameterReferenceList(
 		parameterReferencesToResolve = append(parameterReferencesToResolve, uniqueParameterReferences...)
 	}
 
-	parametersWithValues, err := getParametersFromSsmParameterStore(service, log, parameterReferencesToResolve)
+	parametersWithValues, err := getParameterValues(service, log, parameterReferencesToResolve)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +79,7 @@ func ResolveParameterReferenceList(
 		return nil, prefixValidationError
 	}
 
+	registerSecureParameters(parametersWithValues)
 	return parametersWithValues, nil
 }
 
@@ -94,12 +96,7 @@ func ResolveParametersInText(
 		return input, err
 	}
 
-	for ref, param := range resolvedParametersMap {
-		var placeholder = regexp.MustCompile("{{\\s*" + ref + "\\s*}}")
-		input = placeholder.ReplaceAllString(input, param.Value)
-	}
-
-	return input, nil
+	return replaceParameterReferences(input, resolvedParametersMap), nil
 }
 
 func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParameterInfo) error {
@@ -116,6 +113,15 @@ func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParam
 	return nil
 }
 
+// registerSecureParameters registers the values of the secure parameters so that they are masked in the output and the logs
+func registerSecureParameters(resolvedParametersMap map[string]SsmParameterInfo) {
+	for _, value := range resolvedParametersMap {
+		if value.Type == secureStringType {
+			redaction.Register(value.Value)
+		}
+	}
+}
+
 func dedupSlice(slice []string) []string {
 	ht := map[string]bool{}
 
@@ -142,6 +148,10 @@ func parseParametersFromTextIntoDedupedSlice(text string, ignoreSecureParameters
 		parameterNamesDeduped[matchedPhrases[i][1]] = true
 	}
 
+	for _, reference := range parseProviderParametersFromText(text, ignoreSecureParameters) {
+		parameterNamesDeduped[reference] = true
+	}
+
 	if !ignoreSecureParameters {
 		matchedSecurePhrases := secureSsmParameterPlaceholderRegEx.FindAllStringSubmatch(text, -1)
 		for i := 0; i < len(matchedSecurePhrases); i++ {
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/redaction"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -185,6 +186,22 @@ func TestResolveParametersInText(t *testing.T) {
 	assert.True(t, expectedOutput == output)
 }
 
+func TestResolveParametersInTextRegistersSecureParams(t *testing.T) {
+	defer redaction.Reset()
+	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/a/b/c/param1": {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
+		"ssm-secure:param2": {Name: "param2", Type: secureStringType, Value: "value_param2"},
+	})
+
+	text := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}."
+	_, err := ResolveParametersInText(&serviceObject, log.NewMockLog(), text, ResolveOptions{
+		IgnoreSecureParameters: false,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value_/a/b/c/param1, "+redaction.Mask, redaction.Redact("value_/a/b/c/param1, value_param2"))
+}
+
 func TestResolveParametersInTextIgnoreSecureParams(t *testing.T) {
 	serviceObject := newServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
 		"ssm:/a/b/c/param1": {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
@@ -58,6 +58,9 @@ type CancelFlag interface {
 	// In the go routine, once Wait returns, if the return value indicates that a cancel
 	// request has been received, the go routine wakes up the running job.
 	Wait() (state State)
+
+	// Done returns a channel that is closed once the flag is set, to wait for the flag along with other events.
+	Done() <-chan struct{}
 }
 
 // ChanneledCancelFlag is a default implementation of the task.CancelFlag interface.
@@ -101,6 +104,11 @@ func (t *ChanneledCancelFlag) Wait() (state State) {
 	return t.State()
 }
 
+// Done returns a channel that is closed once the flag is set.
+func (t *ChanneledCancelFlag) Done() <-chan struct{} {
+	return t.ch
+}
+
 // Set sets the state of this flag and wakes up waiting callers.
 func (t *ChanneledCancelFlag) Set(state State) {
 	t.m.Lock()
@@ -81,6 +81,11 @@ func (flag *MockCancelFlag) Wait() (state State) {
 	return flag.Called().Get(0).(State)
 }
 
+// Done mocks the method with the same name.
+func (flag *MockCancelFlag) Done() <-chan struct{} {
+	return flag.Called().Get(0).(<-chan struct{})
+}
+
 func (flag *MockCancelFlag) Set(state State) {
 	flag.Called(state)
 }
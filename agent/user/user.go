@@ -22,3 +22,18 @@ import (
 func Current() (*user.User, error) {
 	return current()
 }
+
+// Lookup looks up a user by username or by user id.
+func Lookup(username string) (*user.User, error) {
+	return lookup(username)
+}
+
+// LookupGroup looks up a group by name or by group id.
+func LookupGroup(name string) (*user.Group, error) {
+	return lookupGroup(name)
+}
+
+// LookupGroupIds returns the ids of the groups the user is a member of, starting with its primary group.
+func LookupGroupIds(u *user.User) ([]string, error) {
+	return lookupGroupIds(u)
+}
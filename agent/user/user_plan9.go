@@ -20,5 +20,20 @@ import (
 
 func current() (*user.User, error) {
 	// calls the Current function of os/user
-	return Current()
+	return user.Current()
+}
+
+func lookup(username string) (*user.User, error) {
+	// calls the Lookup function of os/user
+	return user.Lookup(username)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	// calls the LookupGroup function of os/user
+	return user.LookupGroup(name)
+}
+
+func lookupGroupIds(u *user.User) ([]string, error) {
+	// calls the GroupIds method of os/user
+	return u.GroupIds()
 }
@@ -29,24 +29,118 @@ import (
 
 const (
 	PASSWD_PATH       = "/etc/passwd"
+	GROUP_PATH        = "/etc/group"
 	CURRENT_ERROR_MSG = "failed to get the current user from the system user database"
+	LOOKUP_ERROR_MSG  = "failed to look up the user from the system user database"
+	GROUP_ERROR_MSG   = "failed to look up the group from the system group database"
 
 	PASSWD_USERNAME_INDEX = 0
 	PASSWD_UID_INDEX      = 2
 	PASSWD_GID_INDEX      = 3
 	PASSWD_GEOCS_INDEX    = 4
 	PASSWD_HOME_DIR_INDEX = 5
+
+	GROUP_NAME_INDEX    = 0
+	GROUP_GID_INDEX     = 2
+	GROUP_MEMBERS_INDEX = 3
 )
 
 func current() (*user.User, error) {
 
 	// get current user's UID
-	uid := syscall.Getuid()
+	uid := strconv.Itoa(syscall.Getuid())
+
+	// return user if UIDs match
+	return findPasswdUser(CURRENT_ERROR_MSG, func(user *user.User) bool {
+		return user.Uid == uid
+	})
+}
+
+func lookup(username string) (*user.User, error) {
+	// return user if usernames or UIDs match
+	return findPasswdUser(fmt.Sprintf("%v %v", LOOKUP_ERROR_MSG, username), func(user *user.User) bool {
+		return user.Username == username || user.Uid == username
+	})
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	var found *user.Group
+	err := scanGroups(fmt.Sprintf("%v %v", GROUP_ERROR_MSG, name), func(group *user.Group, members []string) bool {
+		// stop at the group with a matching name or GID
+		if group.Name == name || group.Gid == name {
+			found = group
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errors.New(fmt.Sprintf("%v %v", GROUP_ERROR_MSG, name))
+	}
+	return found, nil
+}
+
+func lookupGroupIds(u *user.User) ([]string, error) {
+	// the primary group comes first, followed by the groups listing the user as a member
+	groupIds := []string{u.Gid}
+	err := scanGroups(fmt.Sprintf("%v of user %v", GROUP_ERROR_MSG, u.Username), func(group *user.Group, members []string) bool {
+		if group.Gid == u.Gid {
+			return true
+		}
+		for _, member := range members {
+			if member == u.Username {
+				groupIds = append(groupIds, group.Gid)
+				break
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groupIds, nil
+}
+
+// scanGroups calls the visit function with the groups of the system group database and their members until it returns false
+func scanGroups(errorMsg string, visit func(group *user.Group, members []string) bool) error {
+
+	// open group path
+	f, err := os.Open(GROUP_PATH)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%v - %v", errorMsg, err.Error()))
+	}
+
+	defer f.Close()
+
+	// read file by line
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// parse group from line string
+		group, members, err := parseGroup(line)
+		if err != nil {
+			continue
+		}
+
+		if !visit(group, members) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// findPasswdUser returns the first user of the system user database accepted by the match function
+func findPasswdUser(errorMsg string, match func(user *user.User) bool) (*user.User, error) {
 
 	// open passwd path
 	f, err := os.Open(PASSWD_PATH)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("%v - %v", CURRENT_ERROR_MSG, err.Error()))
+		return nil, errors.New(fmt.Sprintf("%v - %v", errorMsg, err.Error()))
 	}
 
 	defer f.Close()
@@ -63,13 +157,35 @@ func current() (*user.User, error) {
 			continue
 		}
 
-		// return user if UIDs match
-		if user.Uid == strconv.Itoa(uid) {
+		if match(user) {
 			return user, nil
 		}
 	}
 
-	return nil, errors.New(CURRENT_ERROR_MSG)
+	return nil, errors.New(errorMsg)
+}
+
+func parseGroup(groupStr string) (*user.Group, []string, error) {
+	// format - group_name:password:GID:user_list
+	parsed_str := strings.Split(groupStr, ":")
+	if len(parsed_str) != 4 {
+		return nil, nil, errors.New("invalid format to parse Group")
+	}
+
+	if _, err := strconv.Atoi(parsed_str[GROUP_GID_INDEX]); err != nil {
+		return nil, nil, errors.New("invalid GID to parse Group")
+	}
+
+	// the user list is comma separated and empty for groups without supplementary members
+	var members []string
+	if parsed_str[GROUP_MEMBERS_INDEX] != "" {
+		members = strings.Split(parsed_str[GROUP_MEMBERS_INDEX], ",")
+	}
+
+	return &user.Group{
+		Name: parsed_str[GROUP_NAME_INDEX], // group name
+		Gid:  parsed_str[GROUP_GID_INDEX],  // GID
+	}, members, nil
 }
 
 func parsePasswdUser(passwdUserStr string) (*user.User, error) {
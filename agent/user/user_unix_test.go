@@ -52,3 +52,57 @@ func TestParseUser_InvalidFormat(t *testing.T) {
 	_, err := parsePasswdUser("root-*-0-0-root-/root-/bin/sh")
 	assert.NotNil(t, err)
 }
+
+func TestParseGroup(t *testing.T) {
+	group, members, err := parseGroup("wheel:x:10:root,ec2-user")
+
+	assert.Nil(t, err)
+	assert.Equal(t, group.Name, "wheel")
+	assert.Equal(t, group.Gid, "10")
+	assert.Equal(t, []string{"root", "ec2-user"}, members)
+
+	_, members, err = parseGroup("ec2-user:x:1000:")
+	assert.Nil(t, err)
+	assert.Empty(t, members)
+}
+
+func TestParseGroup_InvalidGID(t *testing.T) {
+	_, _, err := parseGroup("wheel:x:a:root")
+	assert.NotNil(t, err)
+}
+
+func TestParseGroup_InvalidFormat(t *testing.T) {
+	_, _, err := parseGroup("wheel:x:10")
+	assert.NotNil(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	user, err := Lookup("root")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Uid, "0")
+
+	user, err = Lookup("0")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Username, "root")
+
+	_, err = Lookup("no-such-user-for-ssm-agent")
+	assert.NotNil(t, err)
+}
+
+func TestLookupGroup(t *testing.T) {
+	group, err := LookupGroup("0")
+	assert.Nil(t, err)
+	assert.Equal(t, group.Gid, "0")
+
+	_, err = LookupGroup("no-such-group-for-ssm-agent")
+	assert.NotNil(t, err)
+}
+
+func TestLookupGroupIds(t *testing.T) {
+	root, err := Lookup("root")
+	assert.Nil(t, err)
+
+	groupIds, err := LookupGroupIds(root)
+	assert.Nil(t, err)
+	assert.Equal(t, root.Gid, groupIds[0])
+}
@@ -16,6 +16,8 @@ package websocketutil
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/gorilla/websocket"
@@ -55,8 +57,17 @@ func NewWebsocketUtil(logger log.T, dialerInput *websocket.Dialer) *WebsocketUti
 
 // OpenConnection opens a websocket connection provided an input url.
 func (u *WebsocketUtil) OpenConnection(url string) (*websocket.Conn, error) {
-	conn, _, err := u.dialer.Dial(url, nil)
+	return u.OpenConnectionWithHeader(url, nil)
+}
+
+// OpenConnectionWithHeader opens a websocket connection provided an input url and the headers of the handshake request,
+// such as the authorization of a signed request.
+func (u *WebsocketUtil) OpenConnectionWithHeader(url string, header http.Header) (*websocket.Conn, error) {
+	conn, resp, err := u.dialer.Dial(url, header)
 	if err != nil {
+		if resp != nil {
+			err = fmt.Errorf("%v, response status %v", err, resp.Status)
+		}
 		u.log.Errorf("Failed to dial websocket: %s", err.Error())
 		return nil, err
 	}
@@ -66,3 +66,21 @@ func TestWebsocketUtilOpenConnectionInvalidUrl(t *testing.T) {
 	conn, _ := ws.OpenConnection("InvalidUrl")
 	assert.Nil(t, conn, "Open connection failed.")
 }
+
+func TestWebsocketUtilOpenConnectionWithHeader(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		handlerToBeTested(w, req)
+	}))
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+	var ws = NewWebsocketUtil(log, nil)
+	conn, err := ws.OpenConnectionWithHeader(u.String(), http.Header{"Authorization": []string{"signature"}})
+	assert.Nil(t, err, "Open connection failed.")
+	assert.Equal(t, "signature", authorization)
+
+	err = ws.CloseConnection(conn)
+	assert.Nil(t, err, "Error closing the websocket connection.")
+}